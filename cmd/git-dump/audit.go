@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/s3rgeym/git-dump/internal/httpclient"
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+// runVerifyAudit checks the hash chain of -audit-log files.
+func runVerifyAudit(args []string) {
+	fs := newFlagSet("verify-audit", "verify-audit [flags] <audit.jsonl>...")
+	logLevel := fs.String("log", "error", "Logging level")
+	files := parseInterspersed(fs, args)
+	logger.SetupLogger(*logLevel)
+
+	if len(files) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, fileName := range files {
+		n, err := httpclient.VerifyAuditLog(fileName)
+		if err != nil {
+			fmt.Printf("%s: BROKEN: %v\n", fileName, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: OK, %d entries\n", fileName, n)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+// runCatFile prints a recovered object like git cat-file, without git.
+func runCatFile(args []string) {
+	fs := newFlagSet("cat-file", "cat-file [-t|-s|-p] <loose-object-file> | <repository> <object|ref>")
+	showType := fs.Bool("t", false, "Print the type of the object")
+	showSize := fs.Bool("s", false, "Print the size of the object")
+	fs.Bool("p", true, "Pretty-print the object (default)")
+	logLevel := fs.String("log", "error", "Logging level")
+	rest := parseInterspersed(fs, args)
+	logger.SetupLogger(*logLevel)
+
+	if len(rest) == 0 || len(rest) > 2 || *showType && *showSize {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var obj *gitobj.Object
+	var err error
+	if len(rest) == 1 {
+		obj, err = readLooseFile(rest[0])
+	} else {
+		obj, err = readRepoObject(rest[0], rest[1])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	switch {
+	case *showType:
+		fmt.Println(obj.Type)
+	case *showSize:
+		fmt.Println(len(obj.Data))
+	case obj.Type == gitobj.TypeTree:
+		entries, err := gitobj.ParseTree(obj.Data)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		for _, entry := range entries {
+			fmt.Printf("%06s %s %s\t%s\n", entry.Mode, entryType(entry), entry.Hash, entry.Name)
+		}
+	default:
+		os.Stdout.Write(obj.Data)
+	}
+}
+
+// readLooseFile decodes a loose object file and checks it against the hash
+// given by its path.
+func readLooseFile(fileName string) (*gitobj.Object, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	objType, body, err := gitobj.DecodeLoose(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	hash := gitobj.HashObject(objType, body)
+	if name := filepath.Base(filepath.Dir(fileName)) + filepath.Base(fileName); name != hash {
+		logger.Warnf("%s hashes to %s", fileName, hash)
+	}
+	return &gitobj.Object{Hash: hash, Type: objType, Data: body}, nil
+}
+
+// readRepoObject reads an object by a full or abbreviated hash from loose
+// objects and packs of a .git directory or the one repository below dir.
+func readRepoObject(dir, name string) (*gitobj.Object, error) {
+	gitDir := dir
+	if _, err := os.Stat(filepath.Join(dir, "objects")); err != nil {
+		gitDirs, err := gitobj.FindGitDirs(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(gitDirs) != 1 {
+			return nil, fmt.Errorf("expected one repository in %s, found %d: %s", dir, len(gitDirs), strings.Join(gitDirs, ", "))
+		}
+		gitDir = gitDirs[0]
+	}
+	repo, err := gitobj.Open(gitDir)
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Close()
+	hash, err := repo.Resolve(name)
+	if err != nil {
+		if hash = resolveRef(repo, name); hash == "" {
+			return nil, err
+		}
+	}
+	return repo.ReadObject(hash)
+}
+
+// resolveRef returns the hash of HEAD, a full ref name or a branch or tag name.
+func resolveRef(repo *gitobj.Repo, name string) string {
+	if name == "HEAD" {
+		hash, _ := repo.Head()
+		return hash
+	}
+	refs, _ := repo.Refs()
+	for _, ref := range []string{name, "refs/heads/" + name, "refs/tags/" + name} {
+		if hash, ok := refs[ref]; ok {
+			return hash
+		}
+	}
+	return ""
+}
+
+func entryType(entry gitobj.TreeEntry) string {
+	switch {
+	case entry.IsTree():
+		return gitobj.TypeTree
+	case entry.IsSubmodule():
+		return gitobj.TypeCommit
+	default:
+		return gitobj.TypeBlob
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+)
+
+// Completion scripts ask the binary for candidates with
+// `git-dump __complete [command [subcommand]]`, so they never go stale.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for git-dump
+_git_dump() {
+    local cur=${COMP_WORDS[COMP_CWORD]} args=()
+    [[ $COMP_CWORD -gt 1 ]] && args+=("${COMP_WORDS[1]}")
+    [[ $COMP_CWORD -gt 2 ]] && args+=("${COMP_WORDS[2]}")
+    local IFS=$'\n'
+    COMPREPLY=($(compgen -W "$(git-dump __complete "${args[@]}" 2>/dev/null | cut -f1)" -- "$cur"))
+}
+complete -o default -F _git_dump git-dump
+`,
+	"zsh": `#compdef git-dump
+_git_dump() {
+    local -a args items
+    (( CURRENT > 2 )) && args+=("${words[2]}")
+    (( CURRENT > 3 )) && args+=("${words[3]}")
+    items=("${(@f)$(git-dump __complete "${args[@]}" 2>/dev/null | sed 's/:/\\:/g; s/\t/:/')}")
+    _describe 'git-dump' items
+    _files
+}
+compdef _git_dump git-dump
+`,
+	"fish": `# fish completion for git-dump
+function __git_dump_complete
+    set -l words (commandline -opc)
+    git-dump __complete $words[2..3] 2>/dev/null
+end
+complete -c git-dump -a '(__git_dump_complete)'
+`,
+}
+
+// runCompletion prints a shell completion script.
+func runCompletion(args []string) {
+	fs := newFlagSet("completion", "completion bash|zsh|fish")
+	shells := parseInterspersed(fs, args)
+	if len(shells) != 1 || completionScripts[shells[0]] == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	fmt.Print(completionScripts[shells[0]])
+}
+
+// runComplete prints completion candidates as "word<TAB>description" lines:
+// commands and flags of the dump command, subcommands of a command, or flags
+// of a command.
+func runComplete(args []string) {
+	cmd, ok := command{}, false
+	if len(args) > 0 {
+		cmd, ok = commands[args[0]]
+	}
+	if !ok {
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("%s\t%s\n", name, commands[name].summary)
+		}
+		var c config.Config
+		fs := flag.NewFlagSet("git-dump", flag.ContinueOnError)
+		config.RegisterFlags(fs, &c)
+		printCompletions(fs)
+		return
+	}
+	args = args[1:]
+	if len(cmd.subcommands) > 0 {
+		if len(args) == 0 || !contains(cmd.subcommands, args[0]) {
+			for _, sub := range cmd.subcommands {
+				fmt.Println(sub)
+			}
+			return
+		}
+		args = args[:1]
+	} else {
+		args = nil
+	}
+	// -h печатает флаги команды вместо справки и завершает процесс
+	describing = true
+	cmd.run(append(args, "-h"))
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/dumper"
+	"github.com/s3rgeym/git-dump/internal/httpclient"
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+// runConfig manages config files: `config init` writes the defaults and
+// `config validate` checks a file before a long run.
+func runConfig(args []string) {
+	usage := "Usage: git-dump config init [-o FILE] | git-dump config validate FILE"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "init":
+		runConfigInit(args[1:])
+	case "validate":
+		runConfigValidate(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func runConfigInit(args []string) {
+	fs := newFlagSet("config init", "config init [-o FILE] [-f]")
+	output := fs.String("o", "-", "Write the config file here (default is stdout)")
+	force := fs.Bool("f", false, "Overwrite an existing file")
+	fs.Parse(args)
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		mode := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if !*force {
+			mode |= os.O_EXCL
+		}
+		f, err := os.OpenFile(*output, mode, 0644)
+		if err != nil {
+			logger.Fatalf("Failed to create config file: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := config.WriteDefaultFile(w); err != nil {
+		logger.Fatalf("Failed to write config file: %v", err)
+	}
+}
+
+func runConfigValidate(args []string) {
+	fs := newFlagSet("config validate", "config validate FILE")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	fileName := fs.Arg(0)
+
+	var c config.Config
+	flags := flag.NewFlagSet("git-dump", flag.ContinueOnError)
+	config.RegisterFlags(flags, &c)
+	errs := config.LoadFile(flags, fileName)
+	for _, conflict := range c.Conflicts() {
+		errs = append(errs, fmt.Errorf("%s: %s", fileName, conflict))
+	}
+	if err := httpclient.ValidateConfig(c); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", fileName, err))
+	}
+	if err := dumper.ValidateConfig(c); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", fileName, err))
+	}
+	for _, err := range errs {
+		fmt.Println(err)
+	}
+	if len(errs) > 0 {
+		os.Exit(1)
+	}
+	fmt.Printf("%s: OK\n", fileName)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/dumper"
+	"github.com/s3rgeym/git-dump/internal/httpclient"
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+// runDebugTarget explains step by step why a single target can or can't be
+// dumped.
+func runDebugTarget(args []string) {
+	fs := newFlagSet("debug-target", "debug-target [flags] <url> [host=NAME]")
+	config := config.ParseArgs(fs, args)
+	logger.SetupLogger(config.LogLevel)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	// Трассировка не должна оставлять следов на диске
+	config.ReportFile, config.ReportHtml, config.ReportCsv, config.DatabaseFile = "", "", "", ""
+	config.HttpLogFile, config.HarFile, config.AuditLogFile = "", "", ""
+	// Каждая ошибка должна быть видна как есть, без повторов и размыкателя
+	config.MaxRetries, config.BreakerFailures = 0, 0
+
+	client := httpclient.NewHttpClient(config)
+	defer closeClient(client)
+	if !dumper.New(config, client).Diagnose(os.Stdout, strings.Join(fs.Args(), " ")) {
+		closeClient(client)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/rundiff"
+)
+
+func runDiff(args []string) {
+	fs := newFlagSet("diff", "diff [flags] <runA-dir> <runB-dir>")
+	asJSON := fs.Bool("json", false, "Output JSON")
+	logLevel := fs.String("log", "error", "Logging level")
+	dirs := parseInterspersed(fs, args)
+	logger.SetupLogger(*logLevel)
+
+	if len(dirs) != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	result, err := rundiff.Compare(dirs[0], dirs[1])
+	if err != nil {
+		logger.Fatalf("Failed to compare runs: %v", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(result)
+		return
+	}
+
+	for _, target := range result.NewlyExposed {
+		fmt.Printf("+ %s (newly exposed)\n", target)
+	}
+	for _, target := range result.NewlyFixed {
+		fmt.Printf("- %s (fixed)\n", target)
+	}
+	for _, d := range result.Changed {
+		fmt.Printf("~ %s\n", d.Target)
+		for _, hash := range d.NewCommits {
+			fmt.Printf("    new commit %s\n", hash)
+		}
+		for _, path := range d.NewFiles {
+			fmt.Printf("    new file %s\n", path)
+		}
+		for _, path := range d.ModifiedFiles {
+			fmt.Printf("    modified %s\n", path)
+		}
+		for _, path := range d.RemovedFiles {
+			fmt.Printf("    removed %s\n", path)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/bench"
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/db"
+	"github.com/s3rgeym/git-dump/internal/dumper"
+	"github.com/s3rgeym/git-dump/internal/httpclient"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/queue"
+)
+
+// runEnqueue pushes targets from -i into the shared queue (coordinator role).
+func runEnqueue(args []string) {
+	fs := newFlagSet("enqueue", "enqueue [flags]")
+	redisUrl := fs.String("redis", "redis://127.0.0.1:6379/0", "Redis URL")
+	key := fs.String("key", "git-dump", "Prefix for redis keys")
+	reset := fs.Bool("reset", false, "Clear the shared set of seen URLs of targets queued without a run ID")
+	config := config.ParseArgs(fs, args)
+	logger.SetupLogger(config.LogLevel)
+
+	urlList, err := readTargets(config)
+	if err != nil {
+		logger.Fatalf("Failed to read URLs from file: %v", err)
+	}
+
+	client, err := queue.NewRedisClient(*redisUrl)
+	if err != nil {
+		logger.Fatalf("%v", err)
+	}
+	defer client.Close()
+
+	if *reset {
+		if _, err := client.Do("DEL", *key+":seen"); err != nil {
+			logger.Fatalf("Failed to reset seen URLs: %v", err)
+		}
+	}
+
+	// Каждый вызов enqueue получает свой набор просмотренных URL, поэтому
+	// повторно поставленная цель не пропускается из-за прошлых запусков
+	runID := strconv.FormatInt(time.Now().UnixNano(), 36)
+	entries := make([]string, 0, len(urlList))
+	for _, u := range urlList {
+		if u = strings.TrimSpace(u); u != "" {
+			entries = append(entries, runID+"\t"+u)
+		}
+	}
+	if err := queue.NewRedisQueue(client, *key+":targets").Push(entries...); err != nil {
+		logger.Fatalf("Failed to enqueue targets: %v", err)
+	}
+
+	logger.Infof("Enqueued %d targets as run %s", len(entries), runID)
+}
+
+// runWorker pulls targets from the shared queue and dumps them. Seen URLs are
+// shared between all workers, reports are pushed to <key>:results.
+func runWorker(args []string) {
+	fs := newFlagSet("worker", "worker [flags]")
+	redisUrl := fs.String("redis", "redis://127.0.0.1:6379/0", "Redis URL")
+	key := fs.String("key", "git-dump", "Prefix for redis keys")
+	idleExit := fs.Duration("idle-exit", 0, "Exit after the queue stays empty for this long (0 = run forever)")
+	seenTTL := fs.Duration("seen-ttl", 24*time.Hour, "Expire the shared set of seen URLs of a run after this long without new URLs")
+	config := config.ParseArgs(fs, args)
+	logger.SetupLogger(config.LogLevel)
+	logger.SetScanID(config.ScanID)
+
+	redisClient, err := queue.NewRedisClient(*redisUrl)
+	if err != nil {
+		logger.Fatalf("%v", err)
+	}
+	defer redisClient.Close()
+
+	targets := queue.NewRedisQueue(redisClient, *key+":targets")
+	results := queue.NewRedisQueue(redisClient, *key+":results")
+	httpclient.RemoveStaleParts(config.OutputDir)
+	recorder := startProfiling(config)
+	defer recorder.WriteSummary(os.Stderr)
+	client := httpclient.NewHttpClient(config)
+	defer closeClient(client)
+	client.SetBench(recorder)
+	database := openDatabase(config)
+	if database != nil {
+		defer database.Close()
+	}
+
+	w := newWorker(config, client, results, func(runID string) queue.SeenSet {
+		seenKey := *key + ":seen"
+		if runID != "" {
+			seenKey += ":" + runID
+		}
+		return queue.NewRedisSeenSet(redisClient, seenKey, *seenTTL)
+	})
+	w.recorder, w.database = recorder, database
+	w.run(targets, *idleExit)
+}
+
+// worker dumps targets popped from the shared queue one at a time.
+type worker struct {
+	config   config.Config
+	client   *httpclient.HttpClient
+	recorder *bench.Recorder
+	database *db.DB
+	results  queue.Queue
+	seenSet  func(runID string) queue.SeenSet
+}
+
+func newWorker(config config.Config, client *httpclient.HttpClient, results queue.Queue, seenSet func(runID string) queue.SeenSet) *worker {
+	// Каждая цель получает свой дампер, и -report следующей затирал бы
+	// предыдущий: отчёты уходят в <key>:results, находки - в общую базу
+	config.ReportFile, config.ReportHtml, config.ReportCsv = "", "", ""
+	return &worker{config: config, client: client, results: results, seenSet: seenSet}
+}
+
+// run processes targets until the queue stays empty for idleExit, or forever
+// if idleExit is 0.
+func (w *worker) run(targets queue.Queue, idleExit time.Duration) {
+	idleSince := time.Now()
+	for {
+		entry, ok, err := targets.Pop(5 * time.Second)
+		if err != nil {
+			logger.Errorf("Failed to pop target: %v", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		if !ok {
+			if idleExit > 0 && time.Since(idleSince) > idleExit {
+				logger.Info("Queue is empty, exiting")
+				return
+			}
+			continue
+		}
+
+		w.process(entry)
+		idleSince = time.Now()
+	}
+}
+
+// process dumps a "<run id>\t<url>" entry and pushes its report.
+func (w *worker) process(entry string) {
+	runID, target, found := strings.Cut(entry, "\t")
+	if !found {
+		runID, target = "", entry
+	}
+
+	logger.Infof("Processing target %s", target)
+	d := dumper.New(w.config, w.client)
+	d.SetBench(w.recorder)
+	d.SetSeenSet(w.seenSet(runID))
+	if w.database != nil {
+		d.SetDatabase(w.database)
+	}
+	d.Run([]string{target})
+
+	data, err := json.Marshal(d.Report())
+	if err != nil {
+		logger.Errorf("Failed to encode report: %v", err)
+	} else if err := w.results.Push(string(data)); err != nil {
+		logger.Errorf("Failed to push report: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/httpclient"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/queue"
+	"github.com/s3rgeym/git-dump/internal/report"
+)
+
+// memQueue is an in-process queue.Queue whose Pop never blocks.
+type memQueue struct {
+	mu     sync.Mutex
+	values []string
+}
+
+func (q *memQueue) Push(values ...string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.values = append(q.values, values...)
+	return nil
+}
+
+func (q *memQueue) Pop(timeout time.Duration) (string, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.values) == 0 {
+		return "", false, nil
+	}
+	v := q.values[0]
+	q.values = q.values[1:]
+	return v, true, nil
+}
+
+func TestWorkerRunsQueuedTargets(t *testing.T) {
+	site := httptest.NewServer(http.NotFoundHandler())
+	defer site.Close()
+
+	dir := t.TempDir()
+	reportFile := filepath.Join(dir, "report.json")
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	config := config.ParseArgs(fs, []string{"-no-banner", "-o", dir, "-report", reportFile, "-report-csv", filepath.Join(dir, "report.csv")})
+	logger.SetupLogger(config.LogLevel)
+	client := httpclient.NewHttpClient(config)
+	defer closeClient(client)
+
+	// Разные хосты, иначе вторая цель совпала бы с первой по общему набору URL
+	other := strings.Replace(site.URL, "127.0.0.1", "localhost", 1)
+	targets, results := &memQueue{}, &memQueue{}
+	targets.Push("run1\t"+site.URL+"/", "run1\t"+other+"/")
+	seen := &queue.LocalSeenSet{}
+	w := newWorker(config, client, results, func(runID string) queue.SeenSet { return seen })
+	w.run(targets, time.Millisecond)
+
+	if len(results.values) != 2 {
+		t.Fatalf("pushed %d reports, want 2", len(results.values))
+	}
+	for i, want := range []string{site.URL + "/.git/", other + "/.git/"} {
+		var r report.Report
+		if err := json.Unmarshal([]byte(results.values[i]), &r); err != nil {
+			t.Fatal(err)
+		}
+		if len(r.Targets) != 1 || r.Targets[0].Url != want {
+			t.Fatalf("report %d = %+v, want target %s", i, r.Targets, want)
+		}
+	}
+	for _, name := range []string{"report.json", "report.csv"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			t.Fatalf("worker wrote %s", name)
+		}
+	}
+}
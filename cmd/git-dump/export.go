@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/fastexport"
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+// runExport writes the recovered history of a dump as a fast-import stream.
+func runExport(args []string) {
+	fs := newFlagSet("export", "export [flags] <repository>")
+	output := fs.String("o", "-", "Write the stream to this file, - for stdout")
+	bare := fs.String("bare", "", "Import into a new bare repository at this path with git fast-import instead of writing the stream")
+	logLevel := fs.String("log", "error", "Logging level")
+	roots := parseInterspersed(fs, args)
+	logger.SetupLogger(*logLevel)
+
+	if len(roots) != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	gitDirs, err := gitobj.FindGitDirs(roots[0])
+	if err != nil {
+		logger.Fatalf("Failed to search %s: %v", roots[0], err)
+	}
+	if len(gitDirs) != 1 {
+		logger.Fatalf("Expected one repository in %s, found %d: %s", roots[0], len(gitDirs), strings.Join(gitDirs, ", "))
+	}
+	repo, err := gitobj.Open(gitDirs[0])
+	if err != nil {
+		logger.Fatalf("Failed to open repository: %v", err)
+	}
+	defer repo.Close()
+
+	var result *fastexport.Result
+	if *bare != "" {
+		result, err = fastexport.ImportBare(repo, *bare, fastexport.HeadRef(gitDirs[0]))
+	} else {
+		result, err = exportStream(repo, *output)
+	}
+	if err != nil {
+		logger.Fatalf("Failed to export: %v", err)
+	}
+	// Поток может идти в stdout, поэтому сводка пишется в stderr
+	json.NewEncoder(os.Stderr).Encode(result)
+}
+
+func exportStream(repo *gitobj.Repo, output string) (*fastexport.Result, error) {
+	var w io.Writer = os.Stdout
+	if output != "-" {
+		f, err := os.Create(output)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		w = f
+	}
+	return fastexport.Write(w, repo)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/search"
+)
+
+func runExtract(args []string) {
+	fs := newFlagSet("extract", "extract [flags] <output-dir> -path <glob>")
+	var paths config.StringList
+	fs.Var(&paths, "path", "Glob of files to extract, matched against the full path or the file name (can be repeated)")
+	outputDir := fs.String("o", "extracted", "Directory to copy extracted files to")
+	history := fs.Bool("history", false, "Also extract every historical version of matching files")
+	allowSymlinks := fs.Bool("allow-symlinks", false, "Extract symlinks as symlinks instead of files holding the link target")
+	logLevel := fs.String("log", "error", "Logging level")
+	roots := parseInterspersed(fs, args)
+	logger.SetupLogger(*logLevel)
+
+	if len(paths) == 0 || len(roots) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	for _, p := range paths {
+		if _, err := path.Match(p, ""); err != nil {
+			logger.Fatalf("Invalid glob %q: %v", p, err)
+		}
+	}
+
+	opts := search.ExtractOptions{Paths: paths, OutputDir: *outputDir, History: *history, AllowSymlinks: *allowSymlinks}
+	enc := json.NewEncoder(os.Stdout)
+	for _, root := range roots {
+		err := search.Extract(root, opts, func(e search.Extracted) {
+			enc.Encode(e)
+		})
+		if err != nil {
+			logger.Errorf("Failed to extract from %s: %v", root, err)
+		}
+	}
+}
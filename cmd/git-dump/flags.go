@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+)
+
+// describing is set by __complete: -h makes flag sets print their flags for
+// shell completion instead of the usage.
+var describing bool
+
+// newFlagSet returns a flag set of a subcommand whose usage starts with
+// "git-dump <synopsis>" and groups the common flags by topic.
+func newFlagSet(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		if describing {
+			printCompletions(fs)
+			return
+		}
+		fmt.Fprintf(fs.Output(), "Usage: git-dump %s\n", synopsis)
+		config.PrintFlags(fs.Output(), fs)
+	}
+	return fs
+}
+
+// printCompletions writes the flags of fs as "-name<TAB>usage" lines.
+func printCompletions(fs *flag.FlagSet) {
+	fs.VisitAll(func(f *flag.Flag) {
+		_, usage := flag.UnquoteUsage(f)
+		fmt.Fprintf(os.Stdout, "-%s\t%s\n", f.Name, usage)
+	})
+}
+
+// parseInterspersed parses fs allowing positional arguments before flags
+// (e.g. `grep ./output -e foo`) and returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...)
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/s3rgeym/git-dump/internal/fsck"
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+// maxListed limits missing objects printed per repository without -all.
+const maxListed = 20
+
+// runVerify checks the integrity of dumped repositories without the network.
+func runVerify(args []string) {
+	fs := newFlagSet("verify", "verify [flags] <output-dir>...")
+	asJSON := fs.Bool("json", false, "Output JSON")
+	all := fs.Bool("all", false, fmt.Sprintf("List all missing objects, not only the first %d", maxListed))
+	logLevel := fs.String("log", "error", "Logging level")
+	roots := parseInterspersed(fs, args)
+	logger.SetupLogger(*logLevel)
+
+	if len(roots) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var results []*fsck.Result
+	for _, root := range roots {
+		gitDirs, err := gitobj.FindGitDirs(root)
+		if err != nil {
+			logger.Fatalf("Failed to search %s: %v", root, err)
+		}
+		for _, gitDir := range gitDirs {
+			result, err := fsck.Check(gitDir)
+			if err != nil {
+				logger.Errorf("Failed to check %s: %v", gitDir, err)
+				continue
+			}
+			results = append(results, result)
+		}
+	}
+
+	failed := false
+	for _, r := range results {
+		failed = failed || !r.OK()
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(results)
+	} else {
+		for _, r := range results {
+			printFsck(r, *all)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func printFsck(r *fsck.Result, all bool) {
+	status := "OK"
+	if !r.OK() {
+		status = fmt.Sprintf("%d corrupt, %d missing", len(r.Corrupt), len(r.Missing))
+	}
+	fmt.Printf("%s: %s\n", r.GitDir, status)
+	fmt.Printf("    %d loose objects, %d packs, %d index entries, %d reachable objects\n", r.LooseObjects, r.Packs, r.IndexEntries, r.Reachable)
+	for _, p := range r.Corrupt {
+		fmt.Printf("    corrupt %s: %s\n", p.Path, p.Error)
+	}
+	for i, m := range r.Missing {
+		if i == maxListed && !all {
+			fmt.Printf("    ... %d more missing objects (-all lists them)\n", len(r.Missing)-i)
+			break
+		}
+		fmt.Printf("    missing %s %s (%s)\n", m.Type, m.Hash, m.From)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/search"
+)
+
+func runGrep(args []string) {
+	fs := newFlagSet("grep", "grep [flags] <output-dir> -e <regexp>")
+	var patterns config.StringList
+	fs.Var(&patterns, "e", "Regular expression to search for (can be repeated)")
+	ignoreCase := fs.Bool("i", false, "Case insensitive matching")
+	history := fs.Bool("history", false, "Also search decompressed historical blobs")
+	maxLine := fs.Int("max-line", 500, "Truncate matched lines to this length")
+	logLevel := fs.String("log", "error", "Logging level")
+	roots := parseInterspersed(fs, args)
+	logger.SetupLogger(*logLevel)
+
+	if len(patterns) == 0 || len(roots) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	opts := search.Options{History: *history, MaxLine: *maxLine}
+	for _, p := range patterns {
+		if *ignoreCase {
+			p = "(?i)" + p
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			logger.Fatalf("Invalid pattern %q: %v", p, err)
+		}
+		opts.Patterns = append(opts.Patterns, re)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	for _, root := range roots {
+		err := search.Grep(root, opts, func(m search.Match) {
+			enc.Encode(m)
+		})
+		if err != nil {
+			logger.Errorf("Failed to search %s: %v", root, err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/search"
+)
+
+// runKeywords ranks commit messages and file paths of dumps by keywords.
+func runKeywords(args []string) {
+	fs := newFlagSet("keywords", "keywords [flags] <output-dir>...")
+	var words config.StringList
+	fs.Var(&words, "k", "Keyword as word or word:weight, replaces the default list (can be repeated)")
+	asJSON := fs.Bool("json", false, "Output JSON lines")
+	limit := fs.Int("limit", 50, "Show at most this many hits, 0 for all")
+	logLevel := fs.String("log", "error", "Logging level")
+	roots := parseInterspersed(fs, args)
+	logger.SetupLogger(*logLevel)
+
+	if len(roots) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if len(words) == 0 {
+		words = search.DefaultKeywords
+	}
+	var keywords []search.Keyword
+	for _, w := range words {
+		k, err := search.ParseKeyword(w)
+		if err != nil {
+			logger.Fatalf("%v", err)
+		}
+		keywords = append(keywords, k)
+	}
+
+	var hits []search.Hit
+	for _, root := range roots {
+		found, err := search.Keywords(root, keywords)
+		if err != nil {
+			logger.Errorf("Failed to search %s: %v", root, err)
+			continue
+		}
+		hits = append(hits, found...)
+	}
+	// Результаты нескольких каталогов ранжируются вместе
+	if len(roots) > 1 {
+		search.SortHits(hits)
+	}
+	if *limit > 0 && len(hits) > *limit {
+		hits = hits[:*limit]
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	for _, h := range hits {
+		if *asJSON {
+			enc.Encode(h)
+			continue
+		}
+		text, _, _ := strings.Cut(h.Text, "\n")
+		where := h.Target
+		if h.Commit != "" {
+			where += "@" + h.Commit[:8]
+		}
+		fmt.Printf("%3d  %-7s  %s  %s  [%s]\n", h.Score, h.Source, where, text, strings.Join(h.Keywords, ", "))
+	}
+}
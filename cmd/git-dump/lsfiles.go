@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/gitindex"
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/httpclient"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/utils"
+)
+
+// runLsFiles prints the entries of dumped indexes, or of the index of a
+// site, which is the only file fetched.
+func runLsFiles(args []string) {
+	fs := newFlagSet("ls-files", "ls-files [flags] <output-dir|index-file|url>...")
+	asJSON := fs.Bool("json", false, "Output JSON lines")
+	asCSV := fs.Bool("csv", false, "Output CSV")
+	// Баннер испортил бы JSON и CSV в stdout
+	config := config.ParseArgs(fs, append([]string{"-no-banner"}, args...))
+	logger.SetupLogger(config.LogLevel)
+
+	if fs.NArg() == 0 || *asJSON && *asCSV {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var print func(source string, entry *gitindex.GitIndexEntry)
+	enc := json.NewEncoder(os.Stdout)
+	cw := csv.NewWriter(os.Stdout)
+	defer cw.Flush()
+	switch {
+	case *asJSON:
+		print = func(source string, entry *gitindex.GitIndexEntry) {
+			enc.Encode(struct {
+				Source string `json:"source"`
+				gitindex.ExportedEntry
+			}{source, entry.Export()})
+		}
+	case *asCSV:
+		cw.Write(append([]string{"source"}, gitindex.CSVHeader...))
+		print = func(source string, entry *gitindex.GitIndexEntry) {
+			cw.Write(append([]string{source}, entry.CSVRecord()...))
+		}
+	default:
+		print = func(source string, entry *gitindex.GitIndexEntry) {
+			fmt.Printf("%06o %s %d %10d %s\t%s\n", entry.Mode, entry.Sha1, entry.Stage(), entry.Size, entry.Mtime.UTC().Format("2006-01-02 15:04:05"), entry.FileName)
+		}
+	}
+
+	var client *httpclient.HttpClient
+	failed := false
+	for _, arg := range fs.Args() {
+		indexes, err := readIndexes(arg, func() *httpclient.HttpClient {
+			if client == nil {
+				client = httpclient.NewHttpClient(config)
+			}
+			return client
+		})
+		if err != nil {
+			// Уровень логирования по умолчанию скрыл бы ошибку
+			fmt.Fprintf(os.Stderr, "Failed to read index of %s: %v\n", arg, err)
+			failed = true
+			continue
+		}
+		sources := make([]string, 0, len(indexes))
+		for source := range indexes {
+			sources = append(sources, source)
+		}
+		sort.Strings(sources)
+		for _, source := range sources {
+			for _, entry := range indexes[source].Entries {
+				print(source, entry)
+			}
+		}
+	}
+	cw.Flush()
+	if client != nil {
+		closeClient(client)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// readIndexes reads the index of a URL, an index file or every repository
+// below a directory, keyed by where it was read from.
+func readIndexes(arg string, client func() *httpclient.HttpClient) (map[string]gitindex.GitIndex, error) {
+	if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
+		baseUrl, err := utils.NormalizeUrl(arg)
+		if err != nil {
+			return nil, err
+		}
+		indexUrl, err := utils.UrlJoin(baseUrl, "index")
+		if err != nil {
+			return nil, err
+		}
+		resp, cancel, err := client().Fetch(indexUrl)
+		if err != nil {
+			return nil, err
+		}
+		defer cancel()
+		defer resp.Body.Close()
+		index, err := gitindex.ReadIndex(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]gitindex.GitIndex{indexUrl: index}, nil
+	}
+
+	if fi, err := os.Stat(arg); err == nil && !fi.IsDir() {
+		index, err := gitindex.ParseGitIndex(arg)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]gitindex.GitIndex{arg: index}, nil
+	}
+	gitDirs, err := gitobj.FindGitDirs(arg)
+	if err != nil {
+		return nil, err
+	}
+	indexes := make(map[string]gitindex.GitIndex)
+	for _, gitDir := range gitDirs {
+		indexFile := filepath.Join(gitDir, "index")
+		index, err := gitindex.ParseGitIndex(indexFile)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logger.Errorf("Failed to parse %s: %v", indexFile, err)
+			}
+			continue
+		}
+		indexes[indexFile] = index
+	}
+	return indexes, nil
+}
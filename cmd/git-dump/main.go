@@ -1,336 +1,168 @@
 package main
 
 import (
-	"bytes"
+	"flag"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
-	"os/exec"
-	"path/filepath"
-	"strings"
-	"sync"
+	"sort"
 
 	"github.com/s3rgeym/git-dump/internal/config"
-	"github.com/s3rgeym/git-dump/internal/gitindex"
+	"github.com/s3rgeym/git-dump/internal/db"
+	"github.com/s3rgeym/git-dump/internal/dumper"
 	"github.com/s3rgeym/git-dump/internal/httpclient"
 	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/sources"
 	"github.com/s3rgeym/git-dump/internal/utils"
 )
 
-var (
-	commonGitFiles = []string{
-		".", // Проверка на directory listing
-		"COMMIT_EDITMSG",
-		"config",
-		"description",
-		"FETCH_HEAD",
-		"HEAD",
-		"index",
-		"info/exclude",
-		"info/refs",
-		"logs/HEAD",
-		"objects/info/packs",
-		"ORIG_HEAD",
-		"packed-refs",
-		"refs/remotes/origin/HEAD",
-	}
+// command is a subcommand of git-dump.
+type command struct {
+	run     func(args []string)
+	summary string
+	// subcommands are completed after the command name
+	subcommands []string
+}
 
-	nonDownloadableExtensions = []string{".php", ".php4", ".php5"}
-)
+// commands maps subcommand names to their entry points. Without a subcommand
+// the tool dumps URLs read from -i.
+var commands = map[string]command{
+	"cat-file":     {runCatFile, "Print the type, size or content of a recovered object", nil},
+	"completion":   {runCompletion, "Print a bash, zsh or fish completion script", []string{"bash", "zsh", "fish"}},
+	"config":       {runConfig, "Write a default config file or validate one", []string{"init", "validate"}},
+	"debug-target": {runDebugTarget, "Explain step by step why a target can or can't be dumped", nil},
+	"diff":         {runDiff, "Compare two output directories", nil},
+	"enqueue":      {runEnqueue, "Push targets into a Redis queue for workers", nil},
+	"export":       {runExport, "Export recovered history as a git fast-import stream", nil},
+	"extract":      {runExtract, "Copy matching files out of dumped repositories", nil},
+	"grep":         {runGrep, "Search dumped repositories", nil},
+	"keywords":     {runKeywords, "Rank commit messages and paths of dumps by keywords", nil},
+	"ls-files":     {runLsFiles, "List index entries of dumps or of a site as text, JSON or CSV", nil},
+	"report":       {runReport, "Merge reports of several runs or export their findings to DefectDojo", []string{"merge", "defectdojo"}},
+	"serve":        {runServe, "Run the HTTP API server", nil},
+	"stats":        {runStats, "Summarize branches, commits, committers, files and languages of dumps", nil},
+	"verify":       {runVerify, "Check hashes and checksums of dumped repositories and list missing objects", nil},
+	"verify-audit": {runVerifyAudit, "Check the hash chain of audit logs", nil},
+	"web":          {runWeb, "Browse dumped repositories in a web UI", nil},
+	"worker":       {runWorker, "Dump targets taken from a Redis queue", nil},
+}
 
 func main() {
-	config := config.ParseFlags()
-	logger.SetupLogger(config.LogLevel)
-
-	urlList, err := utils.ReadLines(config.InputFile)
-	if err != nil {
-		logger.Fatalf("Failed to read URLs from file: %v", err)
-	}
-
-	client := httpclient.NewHttpClient(config)
-
-	var seen sync.Map
-	sem := make(chan struct{}, config.WorkersNum)
-	var wg sync.WaitGroup
-	repos := make([]string, 0)
-	downloadUrls := make([]string, 0)
-	var mu sync.Mutex // Мьютекс для защиты доступа к downloadUrls
-
-	logger.Info("Starting to download Git files...")
-
-	for _, url := range urlList {
-		baseUrl, err := utils.NormalizeUrl(url)
-		if err != nil {
-			logger.Errorf("Failed to normalize URL %s: %v", url, err)
-			continue
-		}
-		repoPath, err := utils.UrlToLocalPath(baseUrl, config.OutputDir)
-		if err != nil {
-			logger.Errorf("Failed to convert URL %s to local repo path: %v", baseUrl, err)
-			continue
+	if len(os.Args) > 1 {
+		if os.Args[1] == "__complete" {
+			runComplete(os.Args[2:])
+			return
 		}
-		repos = append(repos, repoPath)
-		for _, file := range commonGitFiles {
-			targetUrl, err := utils.UrlJoin(baseUrl, file)
-			if err != nil {
-				logger.Errorf("Failed to convert URL %s to target URL for file %s: %v", baseUrl, file, err)
-				continue
-			}
-
-			sem <- struct{}{}
-			wg.Add(1)
-			go processGitUrl(client, targetUrl, baseUrl, &downloadUrls, &mu, &seen, sem, &wg, config)
+		if cmd, ok := commands[os.Args[1]]; ok {
+			cmd.run(os.Args[2:])
+			return
 		}
 	}
 
-	wg.Wait()
-
-	logger.Info("Finished downloading Git files. Restoring repositories...")
-
-	if err := restoreRepositories(repos); err != nil {
-		logger.Errorf("Failed to restore repositories: %v", err)
-	}
-
-	logger.Info("Finished restoring repositories. Downloading found files...")
-
-	downloadFiles(client, downloadUrls, sem, &wg, &config)
+	flag.CommandLine.Usage = usage
 
-	logger.Info("🎉 Finished!")
-}
-
-func processGitUrl(client *httpclient.HttpClient, targetUrl, baseUrl string, downloadUrls *[]string, mu *sync.Mutex, seen *sync.Map, sem chan struct{}, wg *sync.WaitGroup, config config.Config) {
-	defer func() {
-		<-sem
-		wg.Done()
-	}()
-
-	if _, ok := seen.LoadOrStore(targetUrl, true); ok {
-		logger.Warnf("URL already seen: %s", targetUrl)
-		return
-	}
+	config := config.ParseFlags()
+	logger.SetupLogger(config.LogLevel)
+	logger.SetScanID(config.ScanID)
+	logger.Infof("Scan %s started by %s", config.ScanID, config.Operator)
 
-	fileName, err := utils.UrlToLocalPath(targetUrl, config.OutputDir)
+	urlList, err := readTargets(config)
 	if err != nil {
-		logger.Errorf("Failed to convert URL to save path: %v", err)
-		return
+		logger.Fatalf("Failed to read URLs: %v", err)
 	}
 
-	needFetch := true
-	if !config.ForceFetch && utils.FileExists(fileName) {
-		logger.Debugf("File %s already exists, skipping fetch", fileName)
-		needFetch = false
+	if config.DryRun {
+		// Никаких файлов и запросов, кроме поиска целей
+		config.ReportFile, config.ReportHtml, config.ReportCsv, config.DatabaseFile = "", "", "", ""
+		config.HttpLogFile, config.HarFile, config.AuditLogFile = "", "", ""
 	}
-
-	if needFetch {
-		resp, cancel, err := client.Fetch(targetUrl)
-		if err != nil {
-			logger.Errorf("Failed to fetch URL %s: %v", targetUrl, err)
-			return
-		}
-		defer cancel()
-		defer resp.Body.Close()
-
-		contentType := resp.Header.Get("Content-Type")
-		mimeType, err := utils.GetMimeType(contentType)
-
-		if err != nil {
-			logger.Errorf("Invalid Content-Type for %s: %v", targetUrl, err)
-			return
-		}
-
-		logger.Debugf("MIME Type for %s: %s", targetUrl, mimeType)
-
-		if mimeType == "text/html" {
-			handleHTMLContent(client, resp, targetUrl, baseUrl, downloadUrls, mu, seen, sem, wg, config)
-			return
-		}
-
-		if err := client.SaveResponse(resp, fileName); err != nil {
-			logger.Errorf("Failed to save response %s: %v", fileName, err)
-			return
-		} else {
-			logger.Debugf("Saved %s", fileName)
-		}
+	if config.Memory {
+		// Ответы разбираются в памяти, на диск попадает только -report
+		config.DatabaseFile, config.CacheDir, config.RunDir = "", "", false
+		config.HttpLogFile, config.HarFile, config.AuditLogFile = "", "", ""
 	}
-
-	gitUrls, additionalUrls, err := extractUrls(fileName, baseUrl)
-	if err != nil {
-		logger.Errorf("Error extracting URLs from file %s: %v", fileName, err)
-		os.Remove(fileName)
-		return
+	if err := config.StartRun(); err != nil {
+		logger.Fatalf("%v", err)
 	}
-
-	processGitUrls(client, gitUrls, baseUrl, downloadUrls, mu, seen, sem, wg, config)
-
-	mu.Lock()
-	*downloadUrls = append(*downloadUrls, additionalUrls...)
-	mu.Unlock()
-}
-
-func handleHTMLContent(client *httpclient.HttpClient, resp *http.Response, targetUrl, baseUrl string, downloadUrls *[]string, mu *sync.Mutex, seen *sync.Map, sem chan struct{}, wg *sync.WaitGroup, config config.Config) {
-	buf := new(bytes.Buffer)
-	_, err := io.Copy(buf, resp.Body)
-	if err != nil {
-		logger.Errorf("Failed to read response %s: %v", targetUrl, err)
-		return
+	if config.RunID != "" {
+		logger.Infof("Run %s, writing into %s", config.RunID, config.OutputDir)
 	}
-
-	htmlContent := buf.String()
-	///logger.Debugf("Content: %s", htmlContent)
-
-	if strings.Contains(htmlContent, "Index of /") || strings.Contains(htmlContent, "Directory listing for /") {
-		logger.Infof("Found directory listing: %s", targetUrl)
-		links := utils.ExtractLinks(htmlContent)
-		for _, link := range links {
-			if strings.Contains(link, "?") {
-				continue
-			}
-			newUrl, err := utils.UrlJoin(targetUrl, link)
-			if err != nil {
-				logger.Errorf("Failed to join URL %s with path %s: %v", baseUrl, link, err)
-				continue
-			}
-
-			sem <- struct{}{}
-			wg.Add(1)
-			go processGitUrl(client, newUrl, baseUrl, downloadUrls, mu, seen, sem, wg, config)
-		}
-	} else {
-		logger.Warnf("Skip URL: %s", targetUrl)
+	if !config.DryRun && !config.Memory {
+		httpclient.RemoveStaleParts(config.OutputDir)
 	}
-}
-
-func processGitUrls(client *httpclient.HttpClient, gitUrls []string, baseUrl string, downloadUrls *[]string, mu *sync.Mutex, seen *sync.Map, sem chan struct{}, wg *sync.WaitGroup, config config.Config) {
-	for _, newUrl := range gitUrls {
-		if _, ok := seen.Load(newUrl); ok {
-			continue
-		}
+	recorder := startProfiling(config)
+	client := httpclient.NewHttpClient(config)
+	defer closeClient(client)
+	client.SetBench(recorder)
+	d := dumper.New(config, client)
+	d.SetBench(recorder)
+	if database := openDatabase(config); database != nil {
+		defer database.Close()
+		d.SetDatabase(database)
+	}
+	stopSignals := watchSignals(d, os.Args[1:])
+	d.Run(urlList)
+	stopSignals()
+	recorder.WriteSummary(os.Stderr)
 
-		sem <- struct{}{}
-		wg.Add(1)
-		go processGitUrl(client, newUrl, baseUrl, downloadUrls, mu, seen, sem, wg, config)
-	}
+	logger.Info("🎉 Finished!")
 }
 
-func extractUrls(fileName, baseUrl string) ([]string, []string, error) {
-	var gitPaths []string
-	var additionalUrls []string
-
-	if strings.HasSuffix(fileName, "/index") {
-		gitIndex, err := gitindex.ParseGitIndex(fileName)
-		if err != nil {
-			return nil, nil, fmt.Errorf("error parsing git index %s: %w", fileName, err)
-		}
-
-		for _, entry := range gitIndex.Entries {
-			gitPaths = append(gitPaths, utils.Sha1ToPath(entry.Sha1))
-			if !isDownloadable(entry.FileName) {
-				continue
-			}
-			downloadUrl, err := utils.UrlJoin(baseUrl, "../"+strings.TrimLeft(entry.FileName, "/"))
-			if err != nil {
-				logger.Errorf("Error joining URL: %v", err)
-				continue
-			}
-			additionalUrls = append(additionalUrls, downloadUrl)
-		}
-	} else {
-		var err error
-		gitPaths, err = utils.GetHashesAndRefs(fileName)
-		if err != nil {
-			return nil, nil, fmt.Errorf("error getting object hashes and refs from file %s: %w", fileName, err)
-		}
+// readTargets returns targets from -i, the offline data and search engines.
+// Unless -i is given, stdin is read only if there is no other source.
+func readTargets(config config.Config) ([]string, error) {
+	srcs, err := sources.FromConfig(config)
+	if err != nil {
+		return nil, err
 	}
-
-	gitUrls := make([]string, 0, len(gitPaths))
-	for _, path := range gitPaths {
-		newUrl, err := utils.UrlJoin(baseUrl, path)
-		if err != nil {
-			logger.Errorf("Failed to join URL %s with path %s: %v", baseUrl, path, err)
-			continue
-		}
-		gitUrls = append(gitUrls, newUrl)
+	var urlList []string
+	if config.Offline != "" && config.InputFile == "-" {
+		// Цели берутся из сохранённых данных, сеть не используется
+		urlList, err = httpclient.OfflineTargets(config.Offline)
+	} else if len(srcs) == 0 || config.InputFile != "-" {
+		urlList, err = utils.ReadLines(config.InputFile)
 	}
-
-	return gitUrls, additionalUrls, nil
-}
-
-func restoreRepositories(repos []string) error {
-	cwd, err := os.Getwd()
 	if err != nil {
-		return fmt.Errorf("failed to get current working directory: %v", err)
+		return nil, err
 	}
-
-	for _, repoPath := range repos {
-		absRepoPath, err := filepath.Abs(repoPath)
-		if err != nil {
-			logger.Errorf("Error getting absolute path for %s: %v", repoPath, err)
-			continue
-		}
-
-		parentDir := filepath.Dir(absRepoPath)
-
-		if err := os.Chdir(parentDir); err != nil {
-			logger.Errorf("Error changing directory to %s: %v", parentDir, err)
-			continue
-		}
-
-		if err := restoreRepository(parentDir); err != nil {
-			logger.Errorf("Error restoring repository in %s: %v", parentDir, err)
-		}
-
-		if err := os.Chdir(cwd); err != nil {
-			logger.Errorf("Error changing directory to %s: %v", cwd, err)
-			continue
-		}
+	found, err := sources.Collect(config, srcs)
+	if err != nil {
+		return nil, err
 	}
-
-	return nil
+	return sources.ExpandSubdomains(config, append(urlList, found...))
 }
 
-func restoreRepository(parentDir string) error {
-	cmd := exec.Command("git", "checkout", ".")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("error restoring repository in %s: %v", parentDir, err)
+// openDatabase opens the -db database or returns nil if it is not set.
+func openDatabase(config config.Config) *db.DB {
+	if config.DatabaseFile == "" {
+		return nil
 	}
-	logger.Infof("Restored repository in %s", parentDir)
-	return nil
-}
-
-func downloadFiles(client *httpclient.HttpClient, downloadUrls []string, sem chan struct{}, wg *sync.WaitGroup, config *config.Config) {
-	for _, url := range downloadUrls {
-		fileName, err := utils.UrlToLocalPath(url, config.OutputDir)
-		if err != nil {
-			logger.Errorf("Failed to convert URL to save path: %v", err)
-			continue
-		}
-
-		sem <- struct{}{}
-		wg.Add(1)
-		go func(url, fileName string) {
-			defer func() {
-				<-sem
-				wg.Done()
-			}()
-
-			if _, err := client.FetchFile(url, fileName); err != nil {
-				logger.Errorf("Failed to fetch file %s: %v", url, err)
-			} else {
-				logger.Infof("Downloaded file %s", fileName)
-			}
-		}(url, fileName)
+	database, err := db.Open(config.DatabaseFile)
+	if err != nil {
+		logger.Fatalf("Failed to open database: %v", err)
 	}
-
-	wg.Wait()
+	return database
 }
 
-func isDownloadable(fileName string) bool {
-	for _, ext := range nonDownloadableExtensions {
-		if strings.HasSuffix(fileName, ext) {
-			return false
-		}
+// closeClient flushes the HAR file and the HTTP log.
+func closeClient(client *httpclient.HttpClient) {
+	if err := client.Close(); err != nil {
+		logger.Errorf("Failed to close HTTP client: %v", err)
 	}
+}
 
-	return true
+// usage prints the subcommands and the grouped flags of the dump command.
+func usage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintln(w, "Usage: git-dump [flags] < urls.txt")
+	fmt.Fprintln(w, "       git-dump <command> [flags] [args]")
+	fmt.Fprintln(w, "\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-14s %s\n", name, commands[name].summary)
+	}
+	config.PrintFlags(w, flag.CommandLine)
 }
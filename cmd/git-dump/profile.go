@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/s3rgeym/git-dump/internal/bench"
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+// startProfiling serves pprof profiles on -pprof and returns the recorder of
+// -bench timings, or nil without -bench.
+func startProfiling(config config.Config) *bench.Recorder {
+	if config.Pprof != "" {
+		// Свой mux, чтобы профили не попали в DefaultServeMux других серверов
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		go func() {
+			logger.Infof("Serving pprof on http://%s/debug/pprof/", config.Pprof)
+			if err := http.ListenAndServe(config.Pprof, mux); err != nil {
+				logger.Errorf("pprof server failed: %v", err)
+			}
+		}()
+	}
+	if config.Bench {
+		return bench.New()
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/defectdojo"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+)
+
+// runReport works with -report files: `report merge` combines reports of
+// several runs into one summary and `report defectdojo` exports findings to
+// DefectDojo.
+func runReport(args []string) {
+	usage := "Usage: git-dump report merge [-format md|html] [-o FILE] REPORT...\n" +
+		"       git-dump report defectdojo [-o FILE | -url URL (-engagement ID | -product NAME -engagement-name NAME)] REPORT..."
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "merge":
+		runReportMerge(args[1:])
+	case "defectdojo":
+		runReportDefectDojo(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func runReportMerge(args []string) {
+	fs := newFlagSet("report merge", "report merge [flags] REPORT...")
+	format := fs.String("format", "", "Output format: md or html (default is by the extension of -o, otherwise md)")
+	output := fs.String("o", "-", "Write the summary here (default is stdout)")
+	var severityRules []string
+	fs.Var((*config.StringList)(&severityRules), "severity", severityUsage)
+	logLevel := fs.String("log", "error", "Logging level")
+	files := parseInterspersed(fs, args)
+	logger.SetupLogger(*logLevel)
+
+	if len(files) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *format == "" {
+		*format = "md"
+		if ext := filepath.Ext(*output); ext == ".html" || ext == ".htm" {
+			*format = "html"
+		}
+	}
+	if *format != "md" && *format != "html" {
+		logger.Fatalf("Invalid -format %q: expected md or html", *format)
+	}
+
+	summary := report.Merge(files, readReports(files, severityRules))
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			logger.Fatalf("Failed to create summary: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	write := summary.WriteMarkdown
+	if *format == "html" {
+		write = summary.WriteHTML
+	}
+	if err := write(w); err != nil {
+		logger.Fatalf("Failed to write summary: %v", err)
+	}
+}
+
+func runReportDefectDojo(args []string) {
+	fs := newFlagSet("report defectdojo", "report defectdojo [flags] REPORT...")
+	output := fs.String("o", "-", "Write the Generic Findings Import file here unless -url is set (default is stdout)")
+	dojoUrl := fs.String("url", os.Getenv("DEFECTDOJO_URL"), "Upload findings to this DefectDojo instance; the API key is taken from DEFECTDOJO_TOKEN")
+	engagement := fs.Int("engagement", 0, "ID of the engagement to import into")
+	product := fs.String("product", "", "Name of the product to import into, created if missing (instead of -engagement)")
+	engagementName := fs.String("engagement-name", "git-dump", "Name of the engagement of -product, created if missing")
+	reimport := fs.Bool("reimport", false, "Update the latest test of the engagement instead of creating a new one")
+	minSeverity := fs.String("min-severity", "", "Skip findings below this severity: Info, Low, Medium, High or Critical")
+	timeout := fs.Duration("timeout", 5*time.Minute, "Timeout of the upload")
+	var severityRules []string
+	fs.Var((*config.StringList)(&severityRules), "severity", severityUsage)
+	logLevel := fs.String("log", "error", "Logging level")
+	files := parseInterspersed(fs, args)
+	logger.SetupLogger(*logLevel)
+
+	if len(files) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	imp := defectdojo.Convert(readReports(files, severityRules))
+
+	if *dojoUrl == "" {
+		var w io.Writer = os.Stdout
+		if *output != "-" {
+			f, err := os.Create(*output)
+			if err != nil {
+				logger.Fatalf("Failed to create import file: %v", err)
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := imp.Write(w); err != nil {
+			logger.Fatalf("Failed to write import file: %v", err)
+		}
+		return
+	}
+
+	token := os.Getenv("DEFECTDOJO_TOKEN")
+	if token == "" {
+		logger.Fatalf("DEFECTDOJO_TOKEN is not set")
+	}
+	if *engagement == 0 && *product == "" {
+		logger.Fatalf("Either -engagement or -product is required with -url")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	client := &defectdojo.Client{Url: *dojoUrl, Token: token}
+	target := defectdojo.Target{
+		Engagement:     *engagement,
+		ProductName:    *product,
+		EngagementName: *engagementName,
+		Reimport:       *reimport,
+		MinSeverity:    *minSeverity,
+	}
+	if err := client.Push(ctx, target, imp); err != nil {
+		logger.Fatalf("%v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Uploaded %d findings to %s\n", len(imp.Findings), *dojoUrl)
+}
+
+const severityUsage = "Rescore targets with this severity rule as in the scan flag -severity; without it only reports of older versions are scored, with the defaults (can be repeated)"
+
+// readReports loads the -report files of runs or exits on the first error.
+// Targets are scored with severityRules if any, otherwise only those saved
+// without severities are scored with the default rules.
+func readReports(files []string, severityRules []string) []*report.Report {
+	rules, err := report.ParseSeverityRules(severityRules)
+	if err != nil {
+		logger.Fatalf("Invalid -severity: %v", err)
+	}
+	reports := make([]*report.Report, len(files))
+	for i, fileName := range files {
+		r, err := report.ReadFile(fileName)
+		if err != nil {
+			logger.Fatalf("%v", err)
+		}
+		for _, t := range r.Targets {
+			if len(severityRules) > 0 || t.Severity == "" {
+				rules.Score(t)
+			}
+		}
+		reports[i] = r
+	}
+	return reports
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/dumper"
+	"github.com/s3rgeym/git-dump/internal/httpclient"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/server"
+)
+
+func runServe(args []string) {
+	fs := newFlagSet("serve", "serve [flags]")
+	listenAddr := fs.String("listen", "127.0.0.1:8080", "Address to listen on")
+	jobsNum := fs.Int("jobs", 2, "Number of jobs processed concurrently")
+	queueSize := fs.Int("queue", 1000, "Maximum number of queued jobs")
+	jobTTL := fs.Duration("job-ttl", 24*time.Hour, "Forget finished jobs and their reports this long after they finish (0 means never)")
+	keepJobs := fs.Int("keep-jobs", 1000, "Maximum number of finished jobs kept, the oldest are forgotten first (0 means no limit)")
+	config := config.ParseArgs(fs, args)
+	logger.SetupLogger(config.LogLevel)
+
+	// Ошибки конфигурации должны всплыть до первого задания
+	if err := httpclient.ValidateConfig(config); err != nil {
+		logger.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := dumper.ValidateConfig(config); err != nil {
+		logger.Fatalf("Invalid configuration: %v", err)
+	}
+
+	httpclient.RemoveStaleParts(config.OutputDir)
+	srv := server.New(config, *queueSize)
+	srv.SetRetention(*jobTTL, *keepJobs)
+	if database := openDatabase(config); database != nil {
+		defer database.Close()
+		srv.SetDatabase(database)
+	}
+	srv.Start(*jobsNum)
+
+	logger.Info("Listening on ", *listenAddr)
+	if err := http.ListenAndServe(*listenAddr, srv.Handler()); err != nil {
+		logger.Fatalf("Server error: %v", err)
+	}
+}
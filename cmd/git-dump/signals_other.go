@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "github.com/s3rgeym/git-dump/internal/dumper"
+
+// watchSignals does nothing: SIGUSR1, SIGUSR2 and SIGHUP exist only on Unix.
+func watchSignals(d *dumper.Dumper, args []string) func() {
+	return func() {}
+}
@@ -0,0 +1,50 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/dumper"
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+// watchSignals controls a running dump: SIGUSR1 prints its status to stderr,
+// SIGUSR2 pauses or resumes it and SIGHUP reloads rate and scope limits from
+// the -config file. The returned function stops watching.
+func watchSignals(d *dumper.Dumper, args []string) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-signals:
+				switch sig {
+				case syscall.SIGUSR1:
+					d.WriteStatus(os.Stderr)
+				case syscall.SIGUSR2:
+					d.TogglePause()
+				case syscall.SIGHUP:
+					c, err := config.Reload(args)
+					if err == nil {
+						err = d.Reload(c)
+					}
+					if err != nil {
+						// Прежние настройки остаются в силе
+						logger.Errorf("Failed to reload settings: %v", err)
+					}
+				}
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/stats"
+)
+
+// runStats prints statistics of dumped repositories to judge their value.
+func runStats(args []string) {
+	fs := newFlagSet("stats", "stats [flags] <output-dir>...")
+	asJSON := fs.Bool("json", false, "Output JSON")
+	top := fs.Int("top", 10, "Number of committers and largest files to show")
+	logLevel := fs.String("log", "error", "Logging level")
+	roots := parseInterspersed(fs, args)
+	logger.SetupLogger(*logLevel)
+
+	if len(roots) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var results []*stats.Stats
+	for _, root := range roots {
+		gitDirs, err := gitobj.FindGitDirs(root)
+		if err != nil {
+			logger.Fatalf("Failed to search %s: %v", root, err)
+		}
+		for _, gitDir := range gitDirs {
+			s, err := stats.Collect(gitDir, *top)
+			if err != nil {
+				logger.Errorf("Failed to collect statistics of %s: %v", gitDir, err)
+				continue
+			}
+			results = append(results, s)
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(results)
+		return
+	}
+	for _, s := range results {
+		printStats(s)
+	}
+}
+
+func printStats(s *stats.Stats) {
+	fmt.Printf("%s\n", s.GitDir)
+	fmt.Printf("    %d branches, %d tags, %d commits", s.Branches, s.Tags, s.Commits)
+	if s.Commits > 0 {
+		fmt.Printf(" from %s to %s", s.FirstCommit.Format("2006-01-02"), s.LastCommit.Format("2006-01-02"))
+	}
+	fmt.Println()
+	if len(s.Committers) > 0 {
+		fmt.Println("    Top committers:")
+		for _, c := range s.Committers {
+			fmt.Printf("      %6d  %s\n", c.Commits, c.Name)
+		}
+	}
+	if len(s.LargestFiles) > 0 {
+		fmt.Printf("    Largest of %d files:\n", s.Files)
+		for _, f := range s.LargestFiles {
+			fmt.Printf("      %10d  %s\n", f.Size, f.Path)
+		}
+	}
+	if len(s.Languages) > 0 {
+		var total int64
+		for _, lang := range s.Languages {
+			total += lang.Bytes
+		}
+		fmt.Println("    Languages:")
+		for _, lang := range s.Languages {
+			share := 0.0
+			if total > 0 {
+				share = float64(lang.Bytes) * 100 / float64(total)
+			}
+			fmt.Printf("      %5.1f%%  %-12s %d files\n", share, lang.Name, lang.Files)
+		}
+	}
+}
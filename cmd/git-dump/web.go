@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+	"github.com/s3rgeym/git-dump/internal/web"
+)
+
+func runWeb(args []string) {
+	fs := newFlagSet("web", "web [flags] <output-dir>")
+	listenAddr := fs.String("listen", "127.0.0.1:8081", "Address to listen on")
+	var reportFiles config.StringList
+	fs.Var(&reportFiles, "report", "JSON report with findings to display (can be repeated)")
+	logLevel := fs.String("log", "info", "Logging level")
+	roots := parseInterspersed(fs, args)
+	logger.SetupLogger(*logLevel)
+
+	if len(roots) != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var reports []*report.Report
+	for _, fileName := range reportFiles {
+		r, err := report.ReadFile(fileName)
+		if err != nil {
+			logger.Fatalf("%v", err)
+		}
+		reports = append(reports, r)
+	}
+
+	srv := web.New(roots[0], reports)
+	logger.Infof("Serving %s on http://%s/", roots[0], *listenAddr)
+	if err := http.ListenAndServe(*listenAddr, srv.Handler()); err != nil {
+		logger.Fatalf("Server error: %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+// Package bench measures where the time of a dump goes: name resolution,
+// connecting, waiting for responses, reading bodies, parsing and writing files.
+package bench
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Phase is a part of fetching and processing a file.
+type Phase int
+
+const (
+	DNS     Phase = iota
+	Connect       // TCP и TLS
+	TTFB          // От отправки запроса до первого байта ответа
+	Body
+	Parse
+	Disk
+	numPhases
+)
+
+var phaseNames = [numPhases]string{"dns", "connect", "ttfb", "body", "parse", "disk"}
+
+func (p Phase) String() string {
+	return phaseNames[p]
+}
+
+// Recorder sums the time spent in each phase by all workers. A nil Recorder
+// records nothing, so callers don't need to check whether -bench is set.
+type Recorder struct {
+	start    time.Time
+	total    [numPhases]atomic.Int64
+	count    [numPhases]atomic.Int64
+	requests atomic.Int64
+	bytes    atomic.Int64
+}
+
+func New() *Recorder {
+	return &Recorder{start: time.Now()}
+}
+
+// Add adds d to the time of phase p.
+func (r *Recorder) Add(p Phase, d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.total[p].Add(int64(d))
+	r.count[p].Add(1)
+}
+
+// Since adds the time elapsed since start to phase p.
+func (r *Recorder) Since(p Phase, start time.Time) {
+	if r == nil {
+		return
+	}
+	r.Add(p, time.Since(start))
+}
+
+// WithTrace returns ctx with hooks which record DNS, connect and TTFB times
+// of a request, including its retries.
+func (r *Recorder) WithTrace(ctx context.Context) context.Context {
+	if r == nil {
+		return ctx
+	}
+	r.requests.Add(1)
+	var mu sync.Mutex
+	var dnsStart, tlsStart, wrote time.Time
+	connectStart := make(map[string]time.Time) // Happy Eyeballs соединяется с несколькими адресами сразу
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			mu.Lock()
+			dnsStart = time.Now()
+			mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			mu.Lock()
+			r.Since(DNS, dnsStart)
+			mu.Unlock()
+		},
+		ConnectStart: func(network, addr string) {
+			mu.Lock()
+			connectStart[network+addr] = time.Now()
+			mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			mu.Lock()
+			r.Since(Connect, connectStart[network+addr])
+			mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			mu.Lock()
+			tlsStart = time.Now()
+			mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			mu.Lock()
+			// Рукопожатие считается частью соединения, но не отдельным соединением
+			r.total[Connect].Add(int64(time.Since(tlsStart)))
+			mu.Unlock()
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			mu.Lock()
+			wrote = time.Now()
+			mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			mu.Lock()
+			r.Since(TTFB, wrote)
+			mu.Unlock()
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace)
+}
+
+// Reader returns rd which records the time spent in reads as Body.
+func (r *Recorder) Reader(rd io.Reader) io.Reader {
+	if r == nil {
+		return rd
+	}
+	return &timedReader{Reader: rd, r: r}
+}
+
+// Writer returns w which records the time spent in writes as Disk.
+func (r *Recorder) Writer(w io.Writer) io.Writer {
+	if r == nil {
+		return w
+	}
+	return &timedWriter{Writer: w, r: r}
+}
+
+type timedReader struct {
+	io.Reader
+	r *Recorder
+}
+
+func (t *timedReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := t.Reader.Read(p)
+	t.r.total[Body].Add(int64(time.Since(start)))
+	t.r.bytes.Add(int64(n))
+	return n, err
+}
+
+type timedWriter struct {
+	io.Writer
+	r *Recorder
+}
+
+func (t *timedWriter) Write(p []byte) (int, error) {
+	start := time.Now()
+	n, err := t.Writer.Write(p)
+	t.r.total[Disk].Add(int64(time.Since(start)))
+	return n, err
+}
+
+// CountFile counts a file whose body was read and written, so averages of
+// Body and Disk are per file rather than per read.
+func (r *Recorder) CountFile() {
+	if r == nil {
+		return
+	}
+	r.count[Body].Add(1)
+	r.count[Disk].Add(1)
+}
+
+// WriteSummary prints the total, count, average and share of every phase.
+// Totals are summed over workers and can exceed the wall time.
+func (r *Recorder) WriteSummary(w io.Writer) {
+	if r == nil {
+		return
+	}
+	var sum int64
+	for p := Phase(0); p < numPhases; p++ {
+		sum += r.total[p].Load()
+	}
+	fmt.Fprintf(w, "Benchmark: wall time %s, %d requests, %d bytes received\n",
+		time.Since(r.start).Round(time.Millisecond), r.requests.Load(), r.bytes.Load())
+	fmt.Fprintf(w, "%-8s %12s %8s %12s %7s\n", "phase", "total", "count", "average", "share")
+	for p := Phase(0); p < numPhases; p++ {
+		total, count := time.Duration(r.total[p].Load()), r.count[p].Load()
+		var avg time.Duration
+		if count > 0 {
+			avg = total / time.Duration(count)
+		}
+		var share float64
+		if sum > 0 {
+			share = float64(total) * 100 / float64(sum)
+		}
+		fmt.Fprintf(w, "%-8s %12s %8d %12s %6.1f%%\n", p, total.Round(time.Microsecond), count, avg.Round(time.Microsecond), share)
+	}
+}
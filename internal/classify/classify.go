@@ -0,0 +1,68 @@
+package classify
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// File classes.
+const (
+	Source = "source"
+	Config = "config"
+	Secret = "secret"
+	Binary = "binary"
+	Media  = "media"
+	Other  = "other"
+)
+
+var (
+	sourceExtensions = extensionSet(".c", ".cc", ".cpp", ".cs", ".css", ".go", ".h", ".hpp", ".html", ".java",
+		".js", ".jsx", ".kt", ".lua", ".php", ".php4", ".php5", ".phtml", ".pl", ".py", ".rb", ".rs", ".scala",
+		".sh", ".sql", ".swift", ".ts", ".tsx", ".twig", ".vue")
+	configExtensions = extensionSet(".cfg", ".cnf", ".conf", ".ini", ".json", ".properties", ".toml", ".xml",
+		".yaml", ".yml")
+	mediaExtensions = extensionSet(".avi", ".bmp", ".gif", ".ico", ".jpeg", ".jpg", ".mov", ".mp3", ".mp4",
+		".ogg", ".png", ".svg", ".wav", ".webm", ".webp")
+	binaryExtensions = extensionSet(".7z", ".bin", ".class", ".dll", ".exe", ".gz", ".jar", ".o", ".pdf",
+		".so", ".sqlite", ".tar", ".woff", ".woff2", ".zip")
+
+	// Файлы, которые часто содержат ключи и пароли
+	secretRegex = regexp.MustCompile(`(?i)(^|/)(\.env(\..*)?|id_(rsa|dsa|ecdsa|ed25519)|.*\.(pem|key|p12|pfx|jks|keystore|kdbx|ovpn)|\.htpasswd|\.pgpass|\.netrc|\.npmrc|\.pypirc|\.git-credentials|credentials(\.json)?|secrets?\.(ya?ml|json)|wp-config\.php|config\.inc\.php|settings\.py|database\.yml|local_settings\.py|docker-compose\.ya?ml|.*\.sql(\.gz)?|.*\.(bak|old|swp))$`)
+)
+
+func extensionSet(exts ...string) map[string]bool {
+	ret := make(map[string]bool, len(exts))
+	for _, ext := range exts {
+		ret[ext] = true
+	}
+	return ret
+}
+
+// Classify returns the class of a file by its path and, if given, the
+// beginning of its content.
+func Classify(file string, head []byte) string {
+	if IsNotable(file) {
+		return Secret
+	}
+	ext := strings.ToLower(path.Ext(file))
+	switch {
+	case sourceExtensions[ext]:
+		return Source
+	case configExtensions[ext]:
+		return Config
+	case mediaExtensions[ext]:
+		return Media
+	case binaryExtensions[ext]:
+		return Binary
+	case bytes.IndexByte(head, 0) != -1:
+		return Binary
+	}
+	return Other
+}
+
+// IsNotable reports whether a file is likely to contain secrets.
+func IsNotable(file string) bool {
+	return secretRegex.MatchString(file)
+}
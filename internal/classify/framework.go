@@ -0,0 +1,68 @@
+package classify
+
+import "strings"
+
+// frameworkRule names a framework by files all present in a working tree.
+type frameworkRule struct {
+	name  string
+	files []string
+}
+
+// frameworkRules are checked in order, so specific frameworks go before the
+// languages they are written in.
+var frameworkRules = []frameworkRule{
+	{"WordPress", []string{"wp-includes/version.php"}},
+	{"WordPress", []string{"wp-config.php"}},
+	{"Drupal", []string{"core/lib/Drupal.php"}},
+	{"Drupal", []string{"sites/default/settings.php"}},
+	{"Joomla", []string{"configuration.php", "administrator/index.php"}},
+	{"Magento", []string{"bin/magento"}},
+	{"Magento", []string{"app/etc/env.php"}},
+	{"Laravel", []string{"artisan"}},
+	{"Symfony", []string{"bin/console", "symfony.lock"}},
+	{"Symfony", []string{"bin/console", "config/bundles.php"}},
+	{"Yii", []string{"yii"}},
+	{"CodeIgniter", []string{"system/core/CodeIgniter.php"}},
+	{"Ruby on Rails", []string{"config/application.rb"}},
+	{"Django", []string{"manage.py"}},
+	{"Next.js", []string{"next.config.js"}},
+	{"Next.js", []string{"next.config.mjs"}},
+	{"Nuxt", []string{"nuxt.config.js"}},
+	{"Nuxt", []string{"nuxt.config.ts"}},
+	{"Angular", []string{"angular.json"}},
+	{"Node.js", []string{"package.json"}},
+	{"PHP", []string{"composer.json"}},
+	{"Go", []string{"go.mod"}},
+	{"Java", []string{"pom.xml"}},
+	{"Java", []string{"build.gradle"}},
+	{"Python", []string{"pyproject.toml"}},
+	{"Python", []string{"requirements.txt"}},
+	{"Ruby", []string{"Gemfile"}},
+}
+
+// Framework guesses the framework of a working tree from the slash-separated
+// paths of its files, or returns an empty string. Marker files count at the
+// root and under a single top-level directory such as public_html/, but not
+// deeper, where they usually belong to vendored packages.
+func Framework(paths []string) string {
+	present := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		present[p] = true
+		if _, rest, ok := strings.Cut(p, "/"); ok {
+			present[rest] = true
+		}
+	}
+	for _, rule := range frameworkRules {
+		found := true
+		for _, file := range rule.files {
+			if !present[file] {
+				found = false
+				break
+			}
+		}
+		if found {
+			return rule.name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,23 @@
+package classify
+
+import "testing"
+
+func TestFramework(t *testing.T) {
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{[]string{"index.php", "wp-config.php", "wp-includes/version.php"}, "WordPress"},
+		{[]string{"public_html/wp-includes/version.php"}, "WordPress"},
+		{[]string{"artisan", "composer.json", "package.json"}, "Laravel"},
+		{[]string{"bin/console", "composer.json"}, "PHP"},
+		{[]string{"package.json", "node_modules/next/package.json"}, "Node.js"},
+		{[]string{"vendor/pkg/sub/go.mod", "README.md"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := Framework(tt.paths); got != tt.want {
+			t.Errorf("Framework(%v) = %q, want %q", tt.paths, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"flag"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -10,45 +11,135 @@ import (
 )
 
 type Config struct {
-	InputFile        string
-	OutputDir        string
-	LogLevel         string
-	UserAgent        string
-	ConnTimeout      time.Duration
-	HeaderTimeout    time.Duration
-	KeepAliveTimeout time.Duration
-	RequestTimeout   time.Duration
-	MaxRetries       int
-	MaxHostErrors    int
-	WorkersNum       int
-	MaxRPS           int
-	ProxyUrl         string
-	ForceFetch       bool
-	CommonGitFiles   []string
-	NoBanner         bool
+	InputFile         string
+	ShodanQuery       string
+	CensysQuery       string
+	FofaQuery         string
+	UrlscanQuery      string
+	CommonCrawlDomain string
+	SourceLimit       int
+	SubdomainWordlist string
+	CrtSh             bool
+	KeepWildcardSubs  bool
+	OutputDir         string
+	Layout            string
+	RunDir            bool
+	RunID             string // Set by StartRun
+	ScanID            string
+	Operator          string
+	ScanIdHeader      bool
+	LogLevel          string
+	UserAgent         string
+	ConnTimeout       time.Duration
+	DialTimeout       time.Duration
+	IpVersion         string
+	SourceIp          string
+	Interface         string
+	HeaderTimeout     time.Duration
+	KeepAliveTimeout  time.Duration
+	MaxConnsPerHost   int
+	MaxIdleConns      int
+	MaxIdlePerHost    int
+	Http2             bool
+	Http3             bool
+	RequestTimeout    time.Duration
+	ProbeTimeout      time.Duration
+	ObjectTimeout     time.Duration
+	PackTimeout       time.Duration
+	WorktreeTimeout   time.Duration
+	MaxRetries        int
+	RetryOn           string
+	RetryBackoff      string
+	RetryJitter       float64
+	MinSpeed          int64
+	StallTime         time.Duration
+	HedgePercentile   int
+	MaxHostErrors     int
+	BreakerFailures   int
+	BreakerCooldown   time.Duration
+	WorkersNum        int
+	ParseWorkers      int
+	AutoTune          bool
+	Pprof             string
+	Bench             bool
+	Stealth           bool
+	Passive           bool
+	RespectRobots     bool
+	Jitter            time.Duration
+	MaxRPS            float64
+	Interval          time.Duration
+	Burst             int
+	ProxyUrl          string
+	ProxyMap          []string
+	ConnectTo         []string
+	ForceFetch        bool
+	CommonGitFiles    []string
+	ProbeFile         string
+	ExtraProbes       []string
+	ReflogFirst       bool
+	ForgeFallback     bool
+	ProbeRemotes      bool
+	Verify            bool
+	Memory            bool
+	DryRun            bool
+	NoBanner          bool
+	Plugins           []string
+	ReportFile        string
+	ReportHtml        string
+	ReportListings    bool
+	ReportCsv         string
+	SeverityRules     []string
+	DatabaseFile      string
+	HttpLogFile       string
+	AuditLogFile      string
+	NtpServer         string
+	HarFile           string
+	HarMaxBody        int
+	Offline           string
+	CacheDir          string
+	CacheNegativeTTL  time.Duration
+	MaxDepth          int
+	MaxListingLinks   int
+	MaxListings       int
+	MaxHostRequests   int
+	MaxHostObjects    int
+	MaxTotalBytes     ByteSize
+	MaxHostBytes      ByteSize
+	Deadline          time.Duration
+	HostDeadline      time.Duration
+	DownloadInclude   string
+	DownloadExclude   string
+	DownloadMaxSize   int64
+	RestoreFilter     []string
+	WorktreeOnly      bool
+	AllowSymlinks     bool
+	MirrorTo          string
+	Dedup             string
+	DumpAliases       bool
+	Store             string
+	MinFreeMB         int64
+	LowSpace          string
+	PauseOnFind       string
 }
 
-func ParseFlags() Config {
-	var config Config
+// StringList is a flag value which may be specified multiple times.
+type StringList []string
 
-	// Добавляем флаг для отключения баннера
-	flag.BoolVar(&config.NoBanner, "no-banner", false, "Disable banner output")
+func (s *StringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *StringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
 
-	flag.StringVar(&config.InputFile, "i", "-", "Path to the file containing a list of URLs to dump (default is stdin)")
-	flag.StringVar(&config.OutputDir, "o", "output", "Directory to store the dumped files (default is 'output')")
-	flag.StringVar(&config.LogLevel, "log", "fatal", "Logging level (options: debug, info, warn, error, fatal, panic)")
-	flag.StringVar(&config.UserAgent, "ua", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36", "User-Agent string to use in HTTP requests")
-	flag.DurationVar(&config.ConnTimeout, "connect-timeout", 10*time.Second, "Connection timeout duration")
-	flag.DurationVar(&config.HeaderTimeout, "header-timeout", 5*time.Second, "Read Header timeout duration")
-	flag.DurationVar(&config.KeepAliveTimeout, "keepalive-timeout", 90*time.Second, "Keep-Alive timeout duration")
-	flag.DurationVar(&config.RequestTimeout, "request-timeout", 30*time.Second, "Total request timeout duration")
-	flag.IntVar(&config.MaxRetries, "retries", 3, "Maximum number of retries for each request")
-	flag.IntVar(&config.MaxHostErrors, "maxhe", 5, "Maximum number of errors per host before skipping")
-	flag.IntVar(&config.WorkersNum, "w", 50, "Number of worker goroutines")
-	flag.IntVar(&config.MaxRPS, "rps", 150, "Maximum number of requests per second")
-	flag.StringVar(&config.ProxyUrl, "proxy", "", "Proxy URL (e.g., socks5://localhost:1080)")
-	flag.BoolVar(&config.ForceFetch, "f", false, "Force fetch URLs, even if files already exist")
+func ParseFlags() Config {
+	var config Config
+	RegisterFlags(flag.CommandLine, &config)
+	applyConfigFile(flag.CommandLine, os.Args[1:])
 	flag.Parse()
+	config.startScan()
 
 	// Выводим баннер, если флаг --no-banner не установлен
 	if !config.NoBanner {
@@ -58,6 +149,137 @@ func ParseFlags() Config {
 	return config
 }
 
+// ParseArgs parses subcommand arguments using the given flag set, which may
+// already contain subcommand specific flags.
+func ParseArgs(fs *flag.FlagSet, args []string) Config {
+	var config Config
+	RegisterFlags(fs, &config)
+	applyConfigFile(fs, args)
+	// Ошибки обрабатываются самим FlagSet (ExitOnError)
+	fs.Parse(args)
+	config.startScan()
+
+	if !config.NoBanner {
+		printBanner()
+	}
+
+	return config
+}
+
+// RegisterFlags defines the common flags on fs.
+func RegisterFlags(fs *flag.FlagSet, config *Config) {
+	// Добавляем флаг для отключения баннера
+	fs.BoolVar(&config.NoBanner, "no-banner", false, "Disable banner output")
+	fs.String("config", "", "Read flags from this file, see git-dump config init")
+
+	fs.StringVar(&config.InputFile, "i", "-", "Path to the file containing a list of URLs to dump (default is stdin)")
+	fs.StringVar(&config.ShodanQuery, "shodan-query", "", "Take targets from a Shodan search, e.g. 'http.title:\"Index of /.git\"' (needs SHODAN_API_KEY)")
+	fs.StringVar(&config.CensysQuery, "censys-query", "", "Take targets from a Censys hosts search (needs CENSYS_API_ID and CENSYS_API_SECRET)")
+	fs.StringVar(&config.FofaQuery, "fofa-query", "", "Take targets from a FOFA search (needs FOFA_KEY)")
+	fs.StringVar(&config.UrlscanQuery, "urlscan-query", "", "Take targets from sites and directories of urlscan.io search results, e.g. 'domain:example.com' (URLSCAN_API_KEY is optional)")
+	fs.StringVar(&config.CommonCrawlDomain, "commoncrawl-domain", "", "Take targets from sites and directories of a domain and its subdomains in the latest Common Crawl index")
+	fs.IntVar(&config.SourceLimit, "source-limit", 1000, "Maximum number of targets taken from each search engine or URL source (0 means no limit)")
+	fs.StringVar(&config.SubdomainWordlist, "subdomains", "", "Also scan resolvable subdomains of apex domain targets made from this wordlist (one label per line)")
+	fs.BoolVar(&config.CrtSh, "crtsh", false, "Also scan resolvable subdomains of apex domain targets found in certificate transparency logs (crt.sh)")
+	fs.BoolVar(&config.KeepWildcardSubs, "keep-wildcard-subdomains", false, "Scan subdomains from -subdomains and -crtsh even if they only hit the wildcard DNS catch-all of their domain")
+	fs.StringVar(&config.OutputDir, "o", "output", "Directory to store the dumped files (default is 'output')")
+	fs.BoolVar(&config.RunDir, "run-dir", false, "Write into a new <output>/<run-id> directory with a manifest.json of the run")
+	fs.StringVar(&config.ScanID, "scan-id", "", "ID of this scan written to logs, reports, manifests and HTTP logs (default is the start time and a random suffix)")
+	fs.StringVar(&config.Operator, "operator", "", "Who runs the scan, recorded in reports and manifests (default is user@host)")
+	fs.StringVar(&config.Layout, "layout", "{{.Host}}", "Template of host directories inside the output directory, with .Scheme, .Host, .Port, .Date and .Time fields")
+	fs.StringVar(&config.LogLevel, "log", "fatal", "Logging level (options: debug, info, warn, error, fatal, panic)")
+	fs.StringVar(&config.UserAgent, "ua", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36", "User-Agent string to use in HTTP requests")
+	fs.BoolVar(&config.ScanIdHeader, "scan-id-header", false, "Send the scan ID in an X-Scan-Id header of every request")
+	fs.DurationVar(&config.ConnTimeout, "connect-timeout", 10*time.Second, "Connection timeout duration")
+	fs.DurationVar(&config.DialTimeout, "dial-timeout", 10*time.Second, "Timeout for establishing a single TCP connection")
+	fs.StringVar(&config.IpVersion, "ip-version", "auto", "IP version to connect over: 4, 6 or auto (both, preferring whichever answers first)")
+	fs.StringVar(&config.SourceIp, "source-ip", "", "Local address to connect from, or an IPv4 and an IPv6 address separated by a comma")
+	fs.StringVar(&config.Interface, "interface", "", "Connect from the addresses of this network interface, e.g. eth1")
+	fs.DurationVar(&config.HeaderTimeout, "header-timeout", 5*time.Second, "Read Header timeout duration")
+	fs.DurationVar(&config.KeepAliveTimeout, "keepalive-timeout", 90*time.Second, "Keep-Alive timeout duration")
+	fs.IntVar(&config.MaxConnsPerHost, "max-conns-per-host", 0, "Maximum number of connections to a single host (0 means no limit)")
+	fs.IntVar(&config.MaxIdleConns, "max-idle-conns", 100, "Maximum number of idle keep-alive connections to all hosts (0 means no limit)")
+	fs.IntVar(&config.MaxIdlePerHost, "max-idle-conns-per-host", 10, "Maximum number of idle keep-alive connections to a single host")
+	fs.BoolVar(&config.Http2, "http2", false, "Negotiate HTTP/2 over TLS")
+	fs.BoolVar(&config.Http3, "http3", false, "Experimental: send https requests over HTTP/3 (QUIC), falling back to TCP")
+	fs.DurationVar(&config.RequestTimeout, "request-timeout", 30*time.Second, "Total request timeout duration")
+	fs.DurationVar(&config.ProbeTimeout, "probe-timeout", 0, "Timeout of requests for files of .git other than objects, e.g. HEAD and refs (0 uses -request-timeout and -connect-timeout)")
+	fs.DurationVar(&config.ObjectTimeout, "object-timeout", 0, "Timeout of requests for loose objects (0 uses -request-timeout and -connect-timeout)")
+	fs.DurationVar(&config.PackTimeout, "pack-timeout", 0, "Timeout of requests for packs and pack indexes (0 uses -request-timeout and -connect-timeout)")
+	fs.DurationVar(&config.WorktreeTimeout, "worktree-timeout", 0, "Timeout of requests for working tree files (0 uses -request-timeout and -connect-timeout)")
+	fs.Int64Var(&config.MinSpeed, "min-speed", 0, "Abort downloads slower than this many bytes per second over -stall-time (0 disables)")
+	fs.DurationVar(&config.StallTime, "stall-time", 30*time.Second, "How long a download may stay below -min-speed before it is aborted")
+	fs.IntVar(&config.MaxRetries, "retries", 3, "Maximum number of retries for each request")
+	fs.StringVar(&config.RetryOn, "retry-on", "429,500,502-599", "Response status codes to retry: codes, ranges and classes like 5xx, or none to retry only network errors")
+	fs.StringVar(&config.RetryBackoff, "retry-backoff", "1s..30s", "Delay before retries as min..max, doubled from min with every retry; also caps Retry-After")
+	fs.Float64Var(&config.RetryJitter, "retry-jitter", 0, "Randomly shorten every retry delay by up to this fraction, e.g. 0.5")
+	fs.IntVar(&config.HedgePercentile, "hedge-percentile", 0, "Send a second request if headers take longer than this percentile of recent response times of the host, e.g. 95 (0 disables)")
+	fs.IntVar(&config.MaxHostErrors, "maxhe", 5, "Maximum number of errors per host before skipping")
+	fs.IntVar(&config.BreakerFailures, "breaker-failures", 3, "Stop connecting to a host:port after this many DNS or connect errors in a row (0 disables)")
+	fs.DurationVar(&config.BreakerCooldown, "breaker-cooldown", time.Minute, "How long to wait before trying a host:port again after -breaker-failures")
+	fs.IntVar(&config.WorkersNum, "w", 50, "Number of worker goroutines")
+	fs.IntVar(&config.ParseWorkers, "parse-workers", 0, "Number of goroutines decompressing and parsing fetched files (0 uses the number of CPUs)")
+	fs.BoolVar(&config.AutoTune, "auto-tune", false, "Adapt parallel requests to each host: start at 2 and go up to -w while responses stay fast and successful, halve on errors")
+	fs.StringVar(&config.Pprof, "pprof", "", "Serve net/http/pprof profiles on this address, e.g. localhost:6060")
+	fs.BoolVar(&config.Bench, "bench", false, "Print the time spent in DNS, connect, TTFB, body, parse and disk when finished")
+	fs.BoolVar(&config.Stealth, "stealth", false, "Send one request per host at a time in random order with random delays and skip directory listing probes")
+	fs.BoolVar(&config.Passive, "passive", false, "Never guess paths: crawl directory listings, or HEAD and files referenced by fetched content if there is none")
+	fs.BoolVar(&config.RespectRobots, "respect-robots", false, "Fetch robots.txt of every host and skip paths it disallows for the -ua User-Agent")
+	fs.DurationVar(&config.Jitter, "jitter", 0, "Wait a random time up to this long before every request (2s with -stealth)")
+	fs.Float64Var(&config.MaxRPS, "rps", 150, "Maximum number of requests per second, may be fractional, e.g. 0.2 for one request every 5 seconds (0 disables the limit)")
+	fs.DurationVar(&config.Interval, "interval", 0, "Minimum time between requests, e.g. 5s; overrides -rps")
+	fs.IntVar(&config.Burst, "burst", 0, "Number of requests allowed at once before the rate limit applies (0 uses -rps rounded up, or 1 with -interval)")
+	fs.StringVar(&config.ProxyUrl, "proxy", "", "Proxy URL: http://, https://, socks5:// (names resolved locally) or socks5h:// (names resolved by the proxy), with optional user:pass@, e.g. socks5h://127.0.0.1:9050; +unix schemes take a socket path, and a comma-separated list is a chain")
+	fs.Var((*StringList)(&config.ProxyMap), "proxy-map", "Send hosts of a scope through another proxy as scope=proxy; the scope is a host, *.domain, CIDR or a file of those, the proxy a URL or direct, e.g. internal.txt=socks5://jump:1080 (can be repeated)")
+	fs.Var((*StringList)(&config.ConnectTo), "connect-to", "Connect to this address instead of resolving the host, sending the host name in the Host header and SNI, e.g. app.example.com=203.0.113.7 (can be repeated)")
+	fs.BoolVar(&config.ForceFetch, "f", false, "Force fetch URLs, even if files already exist")
+	fs.Var((*StringList)(&config.Plugins), "plugin", "External extractor command run for every fetched file (can be repeated)")
+	fs.StringVar(&config.PauseOnFind, "pause-on-find", "", "Pause new requests and ask whether to go on when a finding of these comma-separated kinds appears: secrets (credentials and notable files), honeypot, any or a finding kind such as suspicious-hook")
+	fs.StringVar(&config.ProbeFile, "probe-files", "", "File with paths relative to .git/ to probe instead of the built-in list")
+	fs.Var((*StringList)(&config.ExtraProbes), "extra-probe", "Additional path relative to .git/ to probe (can be repeated)")
+	fs.BoolVar(&config.ReflogFirst, "reflog-first", false, "Fetch all reflogs first and walk every referenced commit including its trees and blobs")
+	fs.BoolVar(&config.ForgeFallback, "forge-fallback", false, "Fetch objects missing over the dumb protocol via gitweb, cgit, Gitea or GitLab raw endpoints")
+	fs.BoolVar(&config.ProbeRemotes, "probe-remotes", false, "Scan hosts of remotes found in dumped configs if they share the target domain")
+	fs.BoolVar(&config.Verify, "verify", false, "Only confirm exposure by fetching HEAD and one object and write an evidence report; source code is not reconstructed")
+	fs.BoolVar(&config.Memory, "memory", false, "Like -verify, but probes are analyzed in memory and nothing is written to disk except -report; prints one verdict line per target")
+	fs.BoolVar(&config.DryRun, "dry-run", false, "Print the probe URLs of every target and their local paths without sending requests")
+	fs.IntVar(&config.MaxDepth, "max-depth", 16, "Maximum directory depth below .git/ followed from directory listings (0 means no limit)")
+	fs.IntVar(&config.MaxListingLinks, "max-listing-links", 10000, "Maximum number of links followed from a single directory listing (0 means no limit)")
+	fs.IntVar(&config.MaxListings, "max-listings", 10000, "Maximum number of directory listing pages crawled per target before its listings are abandoned (0 means no limit)")
+	fs.IntVar(&config.MaxHostRequests, "max-requests-per-host", 0, "Maximum number of requests sent to a single host (0 means no limit)")
+	fs.IntVar(&config.MaxHostObjects, "max-objects-per-host", 0, "Maximum number of loose objects fetched from a single host (0 means no limit)")
+	fs.Var(&config.MaxTotalBytes, "max-total-bytes", "Stop starting new downloads once this much was received in total and proceed to restore, e.g. 50GB (0 means no limit)")
+	fs.Var(&config.MaxHostBytes, "max-host-bytes", "Stop starting new downloads from a host once this much was received from it, e.g. 2GB (0 means no limit)")
+	fs.DurationVar(&config.Deadline, "deadline", 0, "Stop crawling after this time and proceed to restore, e.g. 2h (0 means no limit)")
+	fs.DurationVar(&config.HostDeadline, "host-deadline", 0, "Stop crawling a host this long after its first request, e.g. 10m (0 means no limit)")
+	fs.StringVar(&config.DownloadInclude, "download-include", "", "Comma-separated globs or /regexps/ of working tree files to download, e.g. '*.env,*.yml' (default is all)")
+	fs.StringVar(&config.DownloadExclude, "download-exclude", "*.php,*.php4,*.php5", "Comma-separated globs or /regexps/ of working tree files not to download")
+	fs.Int64Var(&config.DownloadMaxSize, "download-max-size", 0, "Skip working tree files larger than this many bytes according to the index (0 means no limit)")
+	fs.Var((*StringList)(&config.RestoreFilter), "restore-filter", "Gitignore-style pattern of paths not to restore or download, e.g. node_modules/ (can be repeated)")
+	fs.BoolVar(&config.WorktreeOnly, "worktree-only", false, "Delete the dumped .git directory of every restored target, keeping only the working tree")
+	fs.BoolVar(&config.AllowSymlinks, "allow-symlinks", false, "Create symlinks of restored repositories instead of files holding the link target")
+	fs.StringVar(&config.MirrorTo, "mirror-to", "", "Push all refs of every dumped repository to this remote, a template with the -layout fields and .Path, e.g. ssh://git@backup/{{.Host}}.git")
+	fs.StringVar(&config.Dedup, "dedup", "", "Replace restored files identical across targets with links: hardlink or reflink")
+	fs.BoolVar(&config.DumpAliases, "dump-aliases", false, "Dump a target even if it resolves to the same IP and serves the same HEAD, config and HEAD commit as an earlier one, instead of recording it as an alias")
+	fs.StringVar(&config.Store, "store", "fs", "Where to save files: fs, cas (identical files are stored once and hardlinked) or s3://bucket/prefix (also upload to S3)")
+	fs.Int64Var(&config.MinFreeMB, "min-free-mb", 0, "Minimum free space in MB on the output filesystem (0 disables the check)")
+	fs.StringVar(&config.LowSpace, "low-space", "abort", "What to do when free space is below -min-free-mb: pause or abort")
+	fs.StringVar(&config.ReportFile, "report", "", "Write a JSON report to this file")
+	fs.StringVar(&config.ReportHtml, "report-html", "", "Write a self-contained HTML report with findings, evidence and file trees of every target to this file")
+	fs.BoolVar(&config.ReportListings, "report-html-listings", false, "Embed the directory listing page of .git/ of every target into the -report-html file")
+	fs.StringVar(&config.ReportCsv, "report-csv", "", "Write a CSV summary with one row per target to this file")
+	fs.Var((*StringList)(&config.SeverityRules), "severity", "Severity rule as condition=level overriding the defaults; the condition is exposed-empty, exposed-source, notable-file, finding, a finding kind or file:GLOB, the level info, low, medium, high or critical, e.g. file:*.sql=critical (can be repeated)")
+	fs.StringVar(&config.HttpLogFile, "http-log", "", "Append status, headers, timing and redirects of every request to this JSONL file")
+	fs.StringVar(&config.AuditLogFile, "audit-log", "", "Append a hash-chained record of every request with its body SHA-256 to this JSONL file")
+	fs.StringVar(&config.NtpServer, "ntp-server", "pool.ntp.org", "NTP server for -audit-log timestamps (empty means the local clock)")
+	fs.StringVar(&config.HarFile, "har", "", "Save all requests and responses to this HAR file")
+	fs.IntVar(&config.HarMaxBody, "har-max-body", 0, "Truncate response bodies in the HAR file to this many bytes (0 means no limit)")
+	fs.StringVar(&config.Offline, "offline", "", "Serve requests from a saved host directory (e.g. output/example.com) or a HAR file instead of the network")
+	fs.StringVar(&config.CacheDir, "cache-dir", "", "Cache responses in this directory and serve repeated requests from it")
+	fs.DurationVar(&config.CacheNegativeTTL, "cache-negative-ttl", 24*time.Hour, "How long cached 404, 410 and temporary redirect responses stay valid")
+	fs.StringVar(&config.DatabaseFile, "db", "", "Record targets, fetches, objects, files and findings into this SQLite database")
+}
+
 func printBanner() {
 	banner := figure.NewFigure("Git Dump", "doom", true)
 	banner.Print()
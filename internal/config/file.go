@@ -0,0 +1,193 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A config file holds flags as "name = value" lines, with flag names without
+// the dash. Repeatable flags may be given several times. Flags given on the
+// command line override the file.
+
+// FileError is a problem found on a line of a config file.
+type FileError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// LoadFile sets the flags of fs from a config file. Every bad line is
+// reported; the valid ones are still applied.
+func LoadFile(fs *flag.FlagSet, fileName string) []error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return []error{fmt.Errorf("failed to open config file: %w", err)}
+	}
+	defer f.Close()
+
+	var errs []error
+	set := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fail := func(format string, args ...any) {
+			errs = append(errs, &FileError{File: fileName, Line: n, Err: fmt.Errorf(format, args...)})
+		}
+		name, value, ok := strings.Cut(line, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" {
+			fail("expected name = value")
+			continue
+		}
+		f := fs.Lookup(name)
+		if f == nil || name == "config" {
+			fail("unknown key %q", name)
+			continue
+		}
+		if prev, ok := set[name]; ok {
+			if _, repeatable := f.Value.(*StringList); !repeatable {
+				fail("%q is already set on line %d", name, prev)
+				continue
+			}
+		}
+		set[name] = n
+		// Значения в кавычках позволяют задавать пробелы по краям и пустые строки
+		if unquoted, err := strconv.Unquote(value); err == nil && strings.HasPrefix(value, `"`) {
+			value = unquoted
+		}
+		if err := fs.Set(name, value); err != nil {
+			fail("invalid value for %q: %v", name, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to read config file %s: %w", fileName, err))
+	}
+	return errs
+}
+
+// configFileArg returns the value of -config in args. Flags are parsed after
+// the file is loaded, so it has to be found before.
+func configFileArg(args []string) string {
+	fileName := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		fileName = value
+	}
+	return fileName
+}
+
+// Reload parses args again after re-reading the -config file they name, so a
+// running dump can pick up edits of the file. Flags given in args still
+// override the file.
+func Reload(args []string) (Config, error) {
+	fileName := configFileArg(args)
+	if fileName == "" {
+		return Config{}, fmt.Errorf("no -config file to reload")
+	}
+	fs := flag.NewFlagSet("git-dump", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var config Config
+	RegisterFlags(fs, &config)
+	if errs := LoadFile(fs, fileName); len(errs) > 0 {
+		return Config{}, errors.Join(errs...)
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// applyConfigFile loads the file given with -config in args into fs and
+// exits on errors, like flag.ExitOnError does for bad flags.
+func applyConfigFile(fs *flag.FlagSet, args []string) {
+	fileName := configFileArg(args)
+	if fileName == "" {
+		return
+	}
+	if errs := LoadFile(fs, fileName); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(2)
+	}
+}
+
+// WriteDefaultFile writes a config file with every flag commented out at its
+// default value.
+func WriteDefaultFile(w io.Writer) error {
+	var config Config
+	fs := flag.NewFlagSet("git-dump", flag.ContinueOnError)
+	RegisterFlags(fs, &config)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# git-dump configuration, used with -config FILE.")
+	fmt.Fprintln(bw, "# Keys are flag names without the dash. Uncomment a line to change the default;")
+	fmt.Fprintln(bw, "# repeatable flags may be given several times. Flags on the command line win.")
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == "config" {
+			return
+		}
+		fmt.Fprintf(bw, "\n# %s\n", f.Usage)
+		value := f.DefValue
+		if value == "" || strings.TrimSpace(value) != value || strings.HasPrefix(value, `"`) {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(bw, "#%s = %s\n", f.Name, value)
+	})
+	return bw.Flush()
+}
+
+// Conflicts returns descriptions of options which don't work together.
+func (c Config) Conflicts() []string {
+	var conflicts []string
+	add := func(format string, args ...any) {
+		conflicts = append(conflicts, fmt.Sprintf(format, args...))
+	}
+	if c.Verify && c.DryRun {
+		add("-verify and -dry-run can't be used together")
+	}
+	if c.Verify && c.WorktreeOnly {
+		add("-worktree-only has no effect with -verify, which never restores")
+	}
+	if c.Verify && c.MirrorTo != "" {
+		add("-mirror-to has no effect with -verify, which doesn't dump repositories")
+	}
+	if c.Passive && c.ReflogFirst {
+		add("-reflog-first has no effect with -passive, which doesn't guess reflog paths")
+	}
+	if c.Passive && c.ProbeFile != "" {
+		add("-probe-files has no effect with -passive, which doesn't probe paths")
+	}
+	if c.MinFreeMB == 0 && c.LowSpace == "pause" {
+		add("-low-space pause has no effect without -min-free-mb")
+	}
+	return conflicts
+}
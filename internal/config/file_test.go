@@ -0,0 +1,34 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReload(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "git-dump.conf")
+	os.WriteFile(fileName, []byte("rps = 2\nburst = 1\n"), 0644)
+	args := []string{"-config", fileName, "-burst", "3"}
+
+	c, err := Reload(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MaxRPS != 2 || c.Burst != 3 {
+		t.Errorf("rps %g, burst %d; want 2 and 3 from the command line", c.MaxRPS, c.Burst)
+	}
+
+	os.WriteFile(fileName, []byte("rps = 0.5\nmax-total-bytes = 1G\n"), 0644)
+	if c, err = Reload(args); err != nil || c.MaxRPS != 0.5 || c.MaxTotalBytes != 1<<30 {
+		t.Errorf("after edit: %+v, %v", c, err)
+	}
+
+	os.WriteFile(fileName, []byte("rps = fast\n"), 0644)
+	if _, err := Reload(args); err == nil {
+		t.Error("invalid file accepted")
+	}
+	if _, err := Reload([]string{"-rps", "1"}); err == nil {
+		t.Error("reload without -config succeeded")
+	}
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Group is a titled set of common flags shown together in the help output.
+type Group struct {
+	Title string
+	Flags []string
+}
+
+// Groups lists the flags of RegisterFlags by topic. Flags missing here are
+// shown under "Other".
+var Groups = []Group{
+	{"Targets", []string{"i", "config", "shodan-query", "censys-query", "fofa-query", "urlscan-query", "commoncrawl-domain", "source-limit", "subdomains", "crtsh", "keep-wildcard-subdomains", "offline"}},
+	{"Probing", []string{"verify", "memory", "dry-run", "passive", "f", "probe-files", "extra-probe", "reflog-first", "forge-fallback", "probe-remotes", "plugin", "pause-on-find", "dump-aliases"}},
+	{"Pace and limits", []string{"w", "parse-workers", "auto-tune", "rps", "interval", "burst", "stealth", "jitter", "respect-robots", "max-depth", "max-listing-links", "max-listings", "max-requests-per-host", "max-objects-per-host", "max-total-bytes", "max-host-bytes", "deadline", "host-deadline", "maxhe", "pprof", "bench"}},
+	{"Connection", []string{"ua", "scan-id-header", "proxy", "proxy-map", "connect-to", "ip-version", "source-ip", "interface", "connect-timeout", "dial-timeout", "header-timeout", "request-timeout", "probe-timeout", "object-timeout", "pack-timeout", "worktree-timeout", "min-speed", "stall-time", "keepalive-timeout", "retries", "retry-on", "retry-backoff", "retry-jitter", "hedge-percentile", "breaker-failures", "breaker-cooldown", "http2", "http3", "max-conns-per-host", "max-idle-conns", "max-idle-conns-per-host", "cache-dir", "cache-negative-ttl"}},
+	{"Working tree", []string{"download-include", "download-exclude", "download-max-size", "restore-filter", "worktree-only", "allow-symlinks", "mirror-to", "dedup"}},
+	{"Output", []string{"o", "store", "run-dir", "scan-id", "operator", "layout", "report", "report-html", "report-html-listings", "report-csv", "severity", "db", "http-log", "har", "har-max-body", "audit-log", "ntp-server", "min-free-mb", "low-space", "log", "no-banner"}},
+}
+
+// PrintFlags writes the flags of fs like flag.PrintDefaults. If fs has the
+// common flags of RegisterFlags, they are grouped by topic after the flags of
+// the command itself.
+func PrintFlags(w io.Writer, fs *flag.FlagSet) {
+	grouped := make(map[string]bool)
+	// Общие флаги есть только у наборов, прошедших через RegisterFlags
+	if fs.Lookup("config") != nil {
+		for _, g := range Groups {
+			for _, name := range g.Flags {
+				grouped[name] = true
+			}
+		}
+	}
+	var own, other []*flag.Flag
+	fs.VisitAll(func(f *flag.Flag) {
+		if !grouped[f.Name] {
+			own = append(own, f)
+		}
+	})
+	if len(grouped) > 0 {
+		// Флаги команды отделяются от общих, не попавших в группы
+		var common Config
+		commonFlags := flag.NewFlagSet("", flag.ContinueOnError)
+		RegisterFlags(commonFlags, &common)
+		cmd := own[:0]
+		for _, f := range own {
+			if commonFlags.Lookup(f.Name) != nil {
+				other = append(other, f)
+			} else {
+				cmd = append(cmd, f)
+			}
+		}
+		own = cmd
+	}
+	if len(own) > 0 {
+		fmt.Fprintln(w, "\nFlags:")
+		printFlagList(w, own)
+	}
+	for _, g := range Groups {
+		var flags []*flag.Flag
+		for _, name := range g.Flags {
+			if f := fs.Lookup(name); f != nil && grouped[name] {
+				flags = append(flags, f)
+			}
+		}
+		if len(flags) > 0 {
+			fmt.Fprintf(w, "\n%s:\n", g.Title)
+			printFlagList(w, flags)
+		}
+	}
+	if len(other) > 0 {
+		fmt.Fprintln(w, "\nOther:")
+		printFlagList(w, other)
+	}
+}
+
+// printFlagList formats flags the same way as flag.PrintDefaults.
+func printFlagList(w io.Writer, flags []*flag.Flag) {
+	for _, f := range flags {
+		var b strings.Builder
+		fmt.Fprintf(&b, "  -%s", f.Name)
+		name, usage := flag.UnquoteUsage(f)
+		if name != "" {
+			b.WriteString(" " + name)
+		}
+		if b.Len() <= 4 {
+			b.WriteString("\t")
+		} else {
+			b.WriteString("\n    \t")
+		}
+		b.WriteString(strings.ReplaceAll(usage, "\n", "\n    \t"))
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" && f.DefValue != "0s" && f.DefValue != "[]" {
+			if isStringFlag(f) {
+				fmt.Fprintf(&b, " (default %q)", f.DefValue)
+			} else {
+				fmt.Fprintf(&b, " (default %v)", f.DefValue)
+			}
+		}
+		fmt.Fprintln(w, b.String())
+	}
+}
+
+// isStringFlag reports whether f is a plain string flag, whose default is
+// quoted by flag.PrintDefaults.
+func isStringFlag(f *flag.Flag) bool {
+	name, _ := flag.UnquoteUsage(f)
+	return name == "string"
+}
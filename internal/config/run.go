@@ -0,0 +1,105 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StartRun creates a new <output>/<run-id> directory for -run-dir and points
+// OutputDir at it. The run ID is the UTC start time, with a counter appended
+// if several runs start within the same second.
+func (c *Config) StartRun() error {
+	if !c.RunDir {
+		return nil
+	}
+	base := time.Now().UTC().Format("20060102T150405Z")
+	if c.DryRun {
+		// Пробный запуск ничего не создаёт на диске
+		c.RunID, c.OutputDir = base, filepath.Join(c.OutputDir, base)
+		return nil
+	}
+	if err := os.MkdirAll(c.OutputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory %s: %w", c.OutputDir, err)
+	}
+	for i := 1; ; i++ {
+		id := base
+		if i > 1 {
+			id = fmt.Sprintf("%s-%d", base, i)
+		}
+		dir := filepath.Join(c.OutputDir, id)
+		// Mkdir атомарно резервирует каталог для параллельных запусков
+		err := os.Mkdir(dir, 0755)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create run directory %s: %w", dir, err)
+		}
+		c.RunID = id
+		c.OutputDir = dir
+		return nil
+	}
+}
+
+// startScan fills in the scan ID and the operator unless they were given.
+func (c *Config) startScan() {
+	if c.ScanID == "" {
+		c.ScanID = NewScanID()
+	}
+	if c.Operator == "" {
+		c.Operator = currentOperator()
+	}
+}
+
+// NewScanID returns the UTC time followed by a random suffix, so IDs of scans
+// started at the same time by different operators differ.
+func NewScanID() string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+// currentOperator returns user@host of the current process.
+func currentOperator() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	if host == "" {
+		return name
+	}
+	return name + "@" + host
+}
+
+// Redacted returns a copy of the config which is safe to store, without
+// credentials in proxy URLs.
+func (c Config) Redacted() Config {
+	c.ProxyUrl = redactProxies(c.ProxyUrl)
+	c.ProxyMap = append([]string(nil), c.ProxyMap...)
+	for i, entry := range c.ProxyMap {
+		if scope, proxies, ok := strings.Cut(entry, "="); ok {
+			c.ProxyMap[i] = scope + "=" + redactProxies(proxies)
+		}
+	}
+	return c
+}
+
+// redactProxies hides passwords in a comma-separated chain of proxy URLs.
+func redactProxies(chain string) string {
+	parts := strings.Split(chain, ",")
+	for i, part := range parts {
+		if u, err := url.Parse(strings.TrimSpace(part)); err == nil && u.User != nil {
+			parts[i] = u.Redacted()
+		}
+	}
+	return strings.Join(parts, ",")
+}
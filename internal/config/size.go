@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a flag value in bytes written with an optional unit: 1500,
+// 512K, 20MB, 50GB or 1TiB. Units are powers of 1024.
+type ByteSize int64
+
+var sizeUnits = []struct {
+	suffix string
+	shift  uint
+}{
+	{"t", 40}, {"g", 30}, {"m", 20}, {"k", 10},
+}
+
+// ParseByteSize parses a size like 50GB.
+func ParseByteSize(s string) (ByteSize, error) {
+	text := strings.ToLower(strings.TrimSpace(s))
+	// 50GB, 50GiB и 50G означают одно и то же
+	text = strings.TrimSuffix(strings.TrimSuffix(text, "b"), "i")
+	var shift uint
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(text, unit.suffix) {
+			text = strings.TrimSuffix(text, unit.suffix)
+			shift = unit.shift
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return ByteSize(value * float64(int64(1)<<shift)), nil
+}
+
+func (b *ByteSize) String() string {
+	if b == nil || *b == 0 {
+		return "0"
+	}
+	for _, unit := range sizeUnits {
+		if *b%(1<<unit.shift) == 0 {
+			return strconv.FormatInt(int64(*b)>>unit.shift, 10) + strings.ToUpper(unit.suffix) + "B"
+		}
+	}
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+func (b *ByteSize) Set(value string) error {
+	size, err := ParseByteSize(value)
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
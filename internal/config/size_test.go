@@ -0,0 +1,30 @@
+package config
+
+import "testing"
+
+func TestParseByteSize(t *testing.T) {
+	for in, want := range map[string]ByteSize{
+		"0":      0,
+		"1500":   1500,
+		"512K":   512 << 10,
+		"20MB":   20 << 20,
+		"50GB":   50 << 30,
+		"1TiB":   1 << 40,
+		"1.5g":   3 << 29,
+		" 2 mb ": 2 << 20,
+	} {
+		got, err := ParseByteSize(in)
+		if err != nil || got != want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "GB", "-1K", "10XB"} {
+		if _, err := ParseByteSize(bad); err == nil {
+			t.Errorf("ParseByteSize(%q) succeeded", bad)
+		}
+	}
+	size := ByteSize(50 << 30)
+	if s := size.String(); s != "50GB" {
+		t.Errorf("String() = %q", s)
+	}
+}
@@ -0,0 +1,187 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/extractor"
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS targets (
+	id INTEGER PRIMARY KEY,
+	url TEXT NOT NULL UNIQUE,
+	repo_path TEXT NOT NULL,
+	restored INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL
+);
+CREATE TABLE IF NOT EXISTS fetches (
+	id INTEGER PRIMARY KEY,
+	target_id INTEGER NOT NULL REFERENCES targets(id),
+	url TEXT NOT NULL,
+	local_path TEXT,
+	status INTEGER NOT NULL,
+	size INTEGER NOT NULL DEFAULT 0,
+	error TEXT,
+	fetched_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS fetches_target_id ON fetches(target_id);
+CREATE INDEX IF NOT EXISTS fetches_url ON fetches(url);
+CREATE TABLE IF NOT EXISTS objects (
+	target_id INTEGER NOT NULL REFERENCES targets(id),
+	sha1 TEXT NOT NULL,
+	PRIMARY KEY (target_id, sha1)
+);
+CREATE INDEX IF NOT EXISTS objects_sha1 ON objects(sha1);
+CREATE TABLE IF NOT EXISTS files (
+	target_id INTEGER NOT NULL REFERENCES targets(id),
+	path TEXT NOT NULL,
+	source TEXT NOT NULL,
+	PRIMARY KEY (target_id, path)
+);
+CREATE INDEX IF NOT EXISTS files_path ON files(path);
+CREATE TABLE IF NOT EXISTS findings (
+	id INTEGER PRIMARY KEY,
+	target_id INTEGER NOT NULL REFERENCES targets(id),
+	extractor TEXT NOT NULL,
+	url TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	detail TEXT NOT NULL,
+	severity TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS findings_target_id ON findings(target_id);
+CREATE INDEX IF NOT EXISTS findings_kind ON findings(kind);
+`
+
+// columns are added to tables of databases created by older versions.
+var columns = []struct{ table, definition string }{
+	{"findings", "severity TEXT NOT NULL DEFAULT ''"},
+}
+
+// DB records scan results into a SQLite database.
+type DB struct {
+	db        *sql.DB
+	mu        sync.Mutex
+	targetIDs map[string]int64
+}
+
+func Open(fileName string) (*DB, error) {
+	sqlDB, err := sql.Open("sqlite", fileName+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %w", fileName, err)
+	}
+	// SQLite не любит конкурентную запись
+	sqlDB.SetMaxOpenConns(1)
+
+	if _, err := sqlDB.Exec(schema); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+	for _, c := range columns {
+		// Ошибка означает, что колонка уже есть
+		if _, err := sqlDB.Exec(`ALTER TABLE ` + c.table + ` ADD COLUMN ` + c.definition); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			sqlDB.Close()
+			return nil, fmt.Errorf("failed to add column to %s: %w", c.table, err)
+		}
+	}
+
+	return &DB{db: sqlDB, targetIDs: make(map[string]int64)}, nil
+}
+
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// AddTarget registers a target URL and remembers its id.
+func (d *DB) AddTarget(url, repoPath string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, err := d.db.Exec(
+		`INSERT INTO targets (url, repo_path, created_at) VALUES (?, ?, ?)
+		ON CONFLICT(url) DO UPDATE SET repo_path = excluded.repo_path`,
+		url, repoPath, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to insert target %s: %w", url, err)
+	}
+
+	var id int64
+	if err := d.db.QueryRow(`SELECT id FROM targets WHERE url = ?`, url).Scan(&id); err != nil {
+		return fmt.Errorf("failed to select target %s: %w", url, err)
+	}
+	d.targetIDs[url] = id
+	return nil
+}
+
+func (d *DB) targetID(url string) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	id, ok := d.targetIDs[url]
+	if !ok {
+		return 0, fmt.Errorf("unknown target %s", url)
+	}
+	return id, nil
+}
+
+// AddFetch records a single HTTP fetch. status is 0 if no response was received.
+func (d *DB) AddFetch(targetUrl, url, localPath string, status int, size int64, fetchErr error) error {
+	id, err := d.targetID(targetUrl)
+	if err != nil {
+		return err
+	}
+	var errText sql.NullString
+	if fetchErr != nil {
+		errText = sql.NullString{String: fetchErr.Error(), Valid: true}
+	}
+	_, err = d.db.Exec(
+		`INSERT INTO fetches (target_id, url, local_path, status, size, error, fetched_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id, url, localPath, status, size, errText, time.Now())
+	return err
+}
+
+func (d *DB) AddObject(targetUrl, sha1 string) error {
+	id, err := d.targetID(targetUrl)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec(`INSERT OR IGNORE INTO objects (target_id, sha1) VALUES (?, ?)`, id, sha1)
+	return err
+}
+
+// AddFile records a working tree file. source is "restore" or "download".
+func (d *DB) AddFile(targetUrl, path, source string) error {
+	id, err := d.targetID(targetUrl)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec(
+		`INSERT INTO files (target_id, path, source) VALUES (?, ?, ?)
+		ON CONFLICT(target_id, path) DO UPDATE SET source = excluded.source`,
+		id, path, source)
+	return err
+}
+
+func (d *DB) AddFinding(targetUrl string, f extractor.Finding) error {
+	id, err := d.targetID(targetUrl)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec(
+		`INSERT INTO findings (target_id, extractor, url, kind, detail, severity, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id, f.Extractor, f.Url, f.Kind, f.Detail, f.Severity, time.Now())
+	return err
+}
+
+func (d *DB) SetRestored(targetUrl string) error {
+	id, err := d.targetID(targetUrl)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec(`UPDATE targets SET restored = 1 WHERE id = ?`, id)
+	return err
+}
@@ -0,0 +1,242 @@
+// Package defectdojo converts reports into the Generic Findings Import format
+// of DefectDojo and uploads them through its API.
+package defectdojo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/report"
+)
+
+// ScanType is the DefectDojo parser of the exported file.
+const ScanType = "Generic Findings Import"
+
+// CWE identifiers of findings.
+const (
+	cweExposedRepository = 527 // Exposure of Version-Control Repository
+	cweCredentials       = 798 // Use of Hard-coded Credentials
+	cweSensitiveFile     = 538 // Insertion of Sensitive Information into Externally-Accessible File
+)
+
+// Finding is a finding of the Generic Findings Import format.
+type Finding struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Severity    string   `json:"severity"` // Critical, High, Medium, Low или Info
+	Mitigation  string   `json:"mitigation,omitempty"`
+	Date        string   `json:"date"`
+	Cwe         int      `json:"cwe,omitempty"`
+	FilePath    string   `json:"file_path,omitempty"`
+	Endpoints   []string `json:"endpoints"`
+	// Одинаковый для одной и той же находки в разных запусках, по нему
+	// DefectDojo узнаёт уже импортированные
+	UniqueID string `json:"unique_id_from_tool"`
+	VulnID   string `json:"vuln_id_from_tool"`
+	Active   bool   `json:"active"`
+	Verified bool   `json:"verified"`
+}
+
+// Import is a file of the Generic Findings Import format.
+type Import struct {
+	Findings []Finding `json:"findings"`
+}
+
+const exposedMitigation = "Deny access to .git/ and other version control directories in the web server configuration, " +
+	"or deploy without them. Rotate every secret that was ever committed to the repository."
+
+// severity converts a severity of the report into the DefectDojo one.
+func severity(s string) string {
+	if s == "" {
+		return "Info"
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// Convert turns exposed targets of reports and their findings into DefectDojo
+// findings with the severities the targets were scored with. A target found
+// in several reports is taken from the last one.
+func Convert(reports []*report.Report) *Import {
+	latest := make(map[string]*report.Target)
+	dates := make(map[string]time.Time)
+	var order []string
+	for _, r := range reports {
+		for _, t := range r.Targets {
+			if _, ok := latest[t.Url]; !ok {
+				order = append(order, t.Url)
+			} else if r.StartedAt.Before(dates[t.Url]) {
+				continue
+			}
+			latest[t.Url], dates[t.Url] = t, r.StartedAt
+		}
+	}
+
+	imp := &Import{Findings: []Finding{}}
+	for _, u := range order {
+		t := latest[u]
+		if !t.IsExposed() {
+			continue
+		}
+		date := dates[u].UTC().Format(time.DateOnly)
+		var desc strings.Builder
+		fmt.Fprintf(&desc, "The Git repository of the site is readable at %s.\n\n", t.Url)
+		fmt.Fprintf(&desc, "- Files fetched: %d\n- Repository restored: %t\n", t.Files, t.Restored)
+		if len(t.NotableFiles) > 0 {
+			fmt.Fprintf(&desc, "- Notable files: %s\n", strings.Join(t.NotableFiles, ", "))
+		}
+		for _, note := range t.Notes {
+			fmt.Fprintf(&desc, "- Note: %s\n", note)
+		}
+		imp.Findings = append(imp.Findings, Finding{
+			Title:       "Exposed Git repository at " + t.Url,
+			Description: desc.String(),
+			Severity:    severity(t.ExposureSeverity),
+			Mitigation:  exposedMitigation,
+			Date:        date,
+			Cwe:         cweExposedRepository,
+			Endpoints:   []string{t.Url},
+			UniqueID:    uniqueID("exposed", t.Url),
+			VulnID:      "git-dump-exposed-repository",
+			Active:      true,
+			Verified:    t.Restored || t.Exposed,
+		})
+
+		for _, f := range t.Findings {
+			finding := Finding{
+				Title:       fmt.Sprintf("%s in exposed Git repository at %s", f.Kind, t.Url),
+				Description: fmt.Sprintf("%s\n\nFound by the %s extractor in %s.", f.Detail, f.Extractor, f.Url),
+				Severity:    severity(f.Severity),
+				Mitigation:  exposedMitigation,
+				Date:        date,
+				FilePath:    strings.TrimPrefix(f.Url, t.Url),
+				Endpoints:   []string{f.Url},
+				UniqueID:    uniqueID(f.Kind, t.Url, f.Url, f.Detail),
+				VulnID:      "git-dump-" + f.Kind,
+				Active:      true,
+			}
+			if f.Kind == "credential" {
+				finding.Cwe = cweCredentials
+			}
+			imp.Findings = append(imp.Findings, finding)
+		}
+		for _, name := range t.NotableFiles {
+			imp.Findings = append(imp.Findings, Finding{
+				Title:       fmt.Sprintf("Sensitive file %s in exposed Git repository at %s", name, t.Url),
+				Description: fmt.Sprintf("The restored working tree of %s contains %s, which often holds keys, passwords or data.", t.Url, name),
+				Severity:    severity(t.FileSeverities[name]),
+				Mitigation:  exposedMitigation,
+				Date:        date,
+				Cwe:         cweSensitiveFile,
+				FilePath:    name,
+				Endpoints:   []string{t.Url},
+				UniqueID:    uniqueID("notable-file", t.Url, name),
+				VulnID:      "git-dump-notable-file",
+				Active:      true,
+			})
+		}
+	}
+	return imp
+}
+
+// uniqueID hashes the parts identifying a finding, so secrets in details
+// don't end up in the identifier.
+func uniqueID(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Write encodes the import as indented JSON.
+func (imp *Import) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(imp)
+}
+
+// Target says where an import goes: an engagement by ID, or by the names of
+// the product and engagement, which are created if missing.
+type Target struct {
+	Engagement     int
+	ProductName    string
+	EngagementName string
+	Reimport       bool // Обновить последний тест вместо создания нового
+	MinSeverity    string
+}
+
+// Client uploads imports to a DefectDojo instance.
+type Client struct {
+	Url   string // Адрес DefectDojo без /api/v2
+	Token string
+	HTTP  *http.Client
+}
+
+// Push uploads imp via import-scan, or reimport-scan with Target.Reimport.
+func (c *Client) Push(ctx context.Context, target Target, imp *Import) error {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fields := map[string]string{
+		"scan_type": ScanType,
+		"active":    "true",
+		"verified":  "false",
+	}
+	if target.Engagement > 0 {
+		fields["engagement"] = fmt.Sprint(target.Engagement)
+	} else {
+		fields["product_name"] = target.ProductName
+		fields["engagement_name"] = target.EngagementName
+		fields["auto_create_context"] = "true"
+	}
+	if target.MinSeverity != "" {
+		fields["minimum_severity"] = target.MinSeverity
+	}
+	for name, value := range fields {
+		if err := mw.WriteField(name, value); err != nil {
+			return err
+		}
+	}
+	fw, err := mw.CreateFormFile("file", "git-dump.json")
+	if err != nil {
+		return err
+	}
+	if err := imp.Write(fw); err != nil {
+		return fmt.Errorf("failed to encode findings: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	endpoint := "import-scan"
+	if target.Reimport {
+		endpoint = "reimport-scan"
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimRight(c.Url, "/")+"/api/v2/"+endpoint+"/", &body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Token "+c.Token)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Accept", "application/json")
+
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send findings to DefectDojo: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("DefectDojo returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package defectdojo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/extractor"
+	"github.com/s3rgeym/git-dump/internal/report"
+)
+
+func TestConvertAndPush(t *testing.T) {
+	old := &report.Report{StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Targets: []*report.Target{
+		{Url: "https://a.example/.git/", Files: 10, Restored: true},
+	}}
+	latest := &report.Report{StartedAt: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), Targets: []*report.Target{
+		{Url: "https://a.example/.git/", Files: 12, Restored: true, Findings: []extractor.Finding{
+			{Extractor: "credentials", Url: "https://a.example/.git/config", Kind: "credential", Detail: "remote.origin.url = https://u:p@git"},
+		}},
+		{Url: "https://b.example/.git/"},
+	}}
+	rules := report.DefaultSeverityRules()
+	for _, t := range append(old.Targets, latest.Targets...) {
+		rules.Score(t)
+	}
+	imp := Convert([]*report.Report{latest, old})
+	if len(imp.Findings) != 2 {
+		t.Fatalf("got %d findings, want 2: %+v", len(imp.Findings), imp.Findings)
+	}
+	if f := imp.Findings[0]; f.Severity != "High" || f.Date != "2026-02-01" || f.Cwe != cweExposedRepository {
+		t.Errorf("unexpected exposure finding %+v", f)
+	}
+	if f := imp.Findings[1]; f.Severity != "Critical" || f.FilePath != "config" {
+		t.Errorf("unexpected credential finding %+v", f)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/import-scan/" || r.Header.Get("Authorization") != "Token secret" {
+			http.Error(w, "bad request "+r.URL.Path, http.StatusForbidden)
+			return
+		}
+		if r.FormValue("scan_type") != ScanType || r.FormValue("product_name") != "Site" {
+			http.Error(w, "bad fields", http.StatusBadRequest)
+			return
+		}
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var got Import
+		if err := json.NewDecoder(f).Decode(&got); err != nil || len(got.Findings) != 2 {
+			http.Error(w, "bad file", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	client := &Client{Url: srv.URL + "/", Token: "secret"}
+	if err := client.Push(context.Background(), Target{ProductName: "Site", EngagementName: "git-dump"}, imp); err != nil {
+		t.Fatal(err)
+	}
+	client.Token = "wrong"
+	if err := client.Push(context.Background(), Target{ProductName: "Site"}, imp); err == nil {
+		t.Error("expected an error with a wrong token")
+	}
+}
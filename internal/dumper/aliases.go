@@ -0,0 +1,164 @@
+package dumper
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+)
+
+// aliasLookupTimeout limits the DNS lookup of a host for alias detection.
+const aliasLookupTimeout = 10 * time.Second
+
+// isOriginFile reports whether a path relative to .git/ is one of the files
+// the origin fingerprint is made of.
+func isOriginFile(name string) bool {
+	return name == "HEAD" || name == "config" || name == "packed-refs" || strings.HasPrefix(name, "refs/heads/")
+}
+
+// originFingerprint hashes HEAD, config and the commit of HEAD of a dumped
+// .git directory. Identical HEAD and config alone are common, e.g. a fresh
+// clone with the default config, so the commit must match too. It returns an
+// empty string until all of them are fetched.
+func originFingerprint(repoPath string) string {
+	head, err := os.ReadFile(filepath.Join(repoPath, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	config, err := os.ReadFile(filepath.Join(repoPath, "config"))
+	if err != nil {
+		return ""
+	}
+	commit := strings.TrimSpace(string(head))
+	if ref, ok := strings.CutPrefix(commit, "ref: "); ok {
+		commit = ""
+		if validRefName(ref) {
+			if data, err := os.ReadFile(filepath.Join(repoPath, filepath.FromSlash(ref))); err == nil {
+				commit = strings.TrimSpace(string(data))
+			} else if packed, err := os.ReadFile(filepath.Join(repoPath, "packed-refs")); err == nil {
+				commit = findPackedRef(packed, ref)
+			}
+		}
+	}
+	if len(commit) != 40 || !isHexString(commit) {
+		return ""
+	}
+	sum := sha256.New()
+	for _, part := range [][]byte{head, config, []byte(commit)} {
+		sum.Write(part)
+		sum.Write([]byte{0})
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+func isHexString(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// hostIPs returns the addresses the host of baseUrl is connected to, or nil
+// if they are unknown locally, e.g. when a proxy resolves names.
+func (d *Dumper) hostIPs(baseUrl string) []string {
+	host := hostOf(baseUrl)
+	if addr, ok := d.client.ConnectAddress(host); ok {
+		if h, _, err := net.SplitHostPort(addr); err == nil {
+			addr = h
+		}
+		return []string{strings.Trim(addr, "[]")}
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return []string{ip.String()}
+	}
+	// Локальный запрос выдал бы цель DNS-серверу в обход прокси (Tor)
+	if d.client.ResolvesNames(host) {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), aliasLookupTimeout)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		logger.Debugf("Failed to resolve %s for alias detection: %v", host, err)
+		return nil
+	}
+	ret := make([]string, len(ips))
+	for i, ip := range ips {
+		ret[i] = ip.String()
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// checkAlias is called after fileName of baseUrl is saved. Once HEAD, config
+// and the commit of HEAD are fetched, the target is recorded as an alias of
+// an earlier target with the same fingerprint sharing an IP with it, and its
+// other requests are skipped. It reports whether baseUrl is an alias.
+func (d *Dumper) checkAlias(fileName, baseUrl string) bool {
+	if d.config.DumpAliases {
+		return false
+	}
+	repoPath := d.targetRepoPath(baseUrl)
+	rel, err := filepath.Rel(repoPath, fileName)
+	if err != nil || !isOriginFile(filepath.ToSlash(rel)) {
+		return d.aliasOf(baseUrl) != ""
+	}
+	d.mu.Lock()
+	primary, done := d.aliases[baseUrl], d.originSeen[baseUrl]
+	d.mu.Unlock()
+	if done {
+		return primary != ""
+	}
+	fingerprint := originFingerprint(repoPath)
+	if fingerprint == "" {
+		return false
+	}
+	ips := d.hostIPs(baseUrl)
+
+	d.mu.Lock()
+	if d.originSeen[baseUrl] {
+		// Параллельный вызов уже всё решил
+		primary = d.aliases[baseUrl]
+		d.mu.Unlock()
+		return primary != ""
+	}
+	d.originSeen[baseUrl] = true
+	for _, ip := range ips {
+		if other, ok := d.origins[ip+" "+fingerprint]; ok {
+			primary = other
+			break
+		}
+	}
+	if primary == "" {
+		for _, ip := range ips {
+			d.origins[ip+" "+fingerprint] = baseUrl
+		}
+	} else {
+		d.aliases[baseUrl] = primary
+	}
+	d.mu.Unlock()
+	if primary == "" {
+		return false
+	}
+
+	logger.Warnf("%s serves the same repository as %s from the same address, recording it as an alias", baseUrl, primary)
+	d.updateTarget(baseUrl, func(t *report.Target) {
+		t.AliasOf = primary
+		t.Notes = append(t.Notes, "alias of "+primary)
+	})
+	d.updateTarget(primary, func(t *report.Target) { t.Aliases = append(t.Aliases, baseUrl) })
+	return true
+}
+
+// aliasOf returns the target baseUrl is an alias of, or an empty string.
+func (d *Dumper) aliasOf(baseUrl string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.aliases[baseUrl]
+}
@@ -0,0 +1,146 @@
+package dumper
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/gitindex"
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/httpclient"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+)
+
+// writeOrigin makes a site root with a .git directory of one commit on master,
+// the default config and an index to restore the worktree from.
+func writeOrigin(t *testing.T, message string) string {
+	t.Helper()
+	root := t.TempDir()
+	gitDir := filepath.Join(root, ".git")
+	for _, dir := range []string{"objects", "refs/heads"} {
+		if err := os.MkdirAll(filepath.Join(gitDir, filepath.FromSlash(dir)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo, err := gitobj.Open(gitDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	blob, err := repo.WriteLoose(gitobj.TypeBlob, []byte("<?php echo 1;\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := hex.DecodeString(blob)
+	tree, err := repo.WriteLoose(gitobj.TypeTree, append([]byte("100644 index.php\x00"), raw...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repo.WriteLoose(gitobj.TypeCommit, []byte("tree "+tree+"\nauthor a <a@a> 0 +0000\ncommitter a <a@a> 0 +0000\n\n"+message+"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"HEAD":              "ref: refs/heads/master\n",
+		"config":            "[core]\n\trepositoryformatversion = 0\n\tbare = false\n",
+		"refs/heads/master": commit + "\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(gitDir, filepath.FromSlash(name)), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var index bytes.Buffer
+	entry := &gitindex.GitIndexEntry{Mode: 0100644, Size: 14, Sha1: blob, FileName: "index.php"}
+	if err := gitindex.WriteIndex(&index, gitindex.GitIndex{Version: 2, Entries: []*gitindex.GitIndexEntry{entry}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(gitDir, "index"), index.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// dumpHosts dumps a.test and b.test, which both connect to one server serving
+// the given site roots by Host.
+func dumpHosts(t *testing.T, roots map[string]string) map[string]*report.Target {
+	t.Helper()
+	// Рабочий каталог восстанавливается через git checkout
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	handlers := make(map[string]http.Handler)
+	for host, root := range roots {
+		handlers[host] = http.FileServer(http.Dir(root))
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h, ok := handlers[r.Host]; ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	addr := strings.TrimPrefix(srv.URL, "http://")
+	fs := flag.NewFlagSet("dump", flag.ContinueOnError)
+	config := config.ParseArgs(fs, []string{"-no-banner", "-o", t.TempDir(), "-connect-to", "a.test=" + addr, "-connect-to", "b.test=" + addr})
+	logger.SetupLogger(config.LogLevel)
+	client := httpclient.NewHttpClient(config)
+	defer client.Close()
+	d := New(config, client)
+	d.Run([]string{"http://a.test/", "http://b.test/"})
+
+	targets := make(map[string]*report.Target)
+	for _, target := range d.Report().Targets {
+		targets[strings.TrimSuffix(strings.TrimPrefix(target.Url, "http://"), "/.git/")] = target
+	}
+	if len(targets) != 2 {
+		t.Fatalf("targets = %v", targets)
+	}
+	return targets
+}
+
+func TestAliasSameOrigin(t *testing.T) {
+	root := writeOrigin(t, "initial")
+	targets := dumpHosts(t, map[string]string{"a.test": root, "b.test": root})
+
+	// Какая из целей первой получит HEAD, заранее неизвестно
+	primary, alias := targets["a.test"], targets["b.test"]
+	if primary.AliasOf != "" {
+		primary, alias = alias, primary
+	}
+	if alias.AliasOf != primary.Url || primary.AliasOf != "" {
+		t.Fatalf("AliasOf = %q and %q", primary.AliasOf, alias.AliasOf)
+	}
+	if len(primary.Aliases) != 1 || primary.Aliases[0] != alias.Url {
+		t.Fatalf("Aliases = %v", primary.Aliases)
+	}
+	if !primary.Restored || alias.Restored {
+		t.Fatalf("Restored = %v and %v", primary.Restored, alias.Restored)
+	}
+}
+
+func TestAliasDifferentCommit(t *testing.T) {
+	targets := dumpHosts(t, map[string]string{
+		"a.test": writeOrigin(t, "initial"),
+		"b.test": writeOrigin(t, "other"),
+	})
+	for host, target := range targets {
+		if target.AliasOf != "" || len(target.Aliases) != 0 {
+			t.Fatalf("%s: AliasOf = %q, Aliases = %v", host, target.AliasOf, target.Aliases)
+		}
+		if !target.Restored {
+			t.Fatalf("%s was not restored", host)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package dumper
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/classify"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+)
+
+// classifyFiles counts restored and downloaded files by class, lists notable
+// ones in the report and guesses the framework. With -report-html it also
+// keeps the file tree.
+func (d *Dumper) classifyFiles(target *report.Target) {
+	worktree := filepath.Dir(target.RepoPath)
+	classes := make(map[string]int)
+	var notable []string
+	var tree []report.TreeEntry
+	var paths []string
+	filepath.WalkDir(worktree, func(fileName string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(worktree, fileName)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		paths = append(paths, rel)
+		class := classify.Classify(rel, readHead(fileName))
+		classes[class]++
+		if class == classify.Secret {
+			notable = append(notable, rel)
+		}
+		if d.config.ReportHtml != "" {
+			if info, err := entry.Info(); err == nil {
+				tree = append(tree, report.TreeEntry{Path: rel, Size: info.Size(), Class: class})
+			}
+		}
+		return nil
+	})
+
+	if d.config.ReportHtml != "" {
+		d.setReportTree(target.Url, tree)
+	}
+	framework := classify.Framework(paths)
+	for _, name := range notable {
+		logger.Warnf("Notable file in %s: %s", target.Url, name)
+	}
+	if len(notable) > 0 && d.pausesOn(notableFileKind) {
+		d.pauseOnFind(target.Url, fmt.Sprintf("notable file %s", strings.Join(notable, ", ")))
+	}
+	d.updateTarget(target.Url, func(t *report.Target) {
+		if len(classes) > 0 {
+			t.FileClasses = classes
+		}
+		t.NotableFiles = notable
+		t.Framework = framework
+	})
+}
+
+// readHead returns the first bytes of a file for content sniffing.
+func readHead(fileName string) []byte {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	buf := make([]byte, 512)
+	n, _ := io.ReadFull(f, buf)
+	return buf[:n]
+}
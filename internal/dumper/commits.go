@@ -0,0 +1,43 @@
+package dumper
+
+import (
+	"path/filepath"
+
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+)
+
+// countCommits records the commit of HEAD of a dumped repository and how many
+// commits are reachable from its refs and HEAD. History behind a missing
+// commit is not counted.
+func (d *Dumper) countCommits(target *report.Target) {
+	absRepoPath, err := filepath.Abs(target.RepoPath)
+	if err != nil {
+		return
+	}
+	repo, err := gitobj.Open(absRepoPath)
+	if err != nil {
+		return
+	}
+	defer repo.Close()
+
+	refs, err := repo.Refs()
+	if err != nil {
+		logger.Errorf("Failed to read refs of %s: %v", target.Url, err)
+		return
+	}
+	tips := make([]string, 0, len(refs)+1)
+	for _, hash := range refs {
+		tips = append(tips, hash)
+	}
+	head, err := repo.Head()
+	if err == nil && head != "" {
+		tips = append(tips, head)
+	}
+	commits := len(repo.Log(tips, 0))
+	if commits > 0 {
+		logger.Infof("Recovered %d commits of %s", commits, target.Url)
+	}
+	d.updateTarget(target.Url, func(t *report.Target) { t.Commits, t.Head = commits, head })
+}
@@ -0,0 +1,149 @@
+package dumper
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+// maxStatusTargets limits the targets listed by WriteStatus.
+const maxStatusTargets = 20
+
+// TogglePause pauses starting new requests, or resumes it if the dump is
+// paused, and reports whether the dump is now paused. Requests already sent
+// are finished. Deadlines keep running during a pause.
+func (d *Dumper) TogglePause() bool {
+	if d.pause() {
+		logger.Info("Paused: no new requests are started until resumed")
+		return true
+	}
+	d.resume()
+	logger.Info("Resumed")
+	return false
+}
+
+// pause pauses the dump and reports whether it was running.
+func (d *Dumper) pause() bool {
+	d.pauseMu.Lock()
+	defer d.pauseMu.Unlock()
+	if d.resumed != nil {
+		return false
+	}
+	d.resumed = make(chan struct{})
+	return true
+}
+
+func (d *Dumper) resume() {
+	d.pauseMu.Lock()
+	defer d.pauseMu.Unlock()
+	if d.resumed != nil {
+		close(d.resumed)
+		d.resumed = nil
+	}
+}
+
+// waitResumed blocks while the dump is paused.
+func (d *Dumper) waitResumed() {
+	d.pauseMu.Lock()
+	resumed := d.resumed
+	d.pauseMu.Unlock()
+	if resumed != nil {
+		<-resumed
+	}
+}
+
+func (d *Dumper) isPaused() bool {
+	d.pauseMu.Lock()
+	defer d.pauseMu.Unlock()
+	return d.resumed != nil
+}
+
+// WriteStatus prints a snapshot of a running dump: totals over all targets
+// and the targets which are still being crawled.
+func (d *Dumper) WriteStatus(w io.Writer) {
+	d.mu.Lock()
+	elapsed := time.Since(d.report.StartedAt).Round(time.Second)
+	var requests, files, errs, restored int
+	for _, t := range d.report.Targets {
+		requests += t.Requests
+		files += t.Files
+		errs += t.Errors
+		if t.Restored {
+			restored++
+		}
+	}
+	total, totalBytes := len(d.report.Targets), d.totalBytes
+	active := make([]string, 0, len(d.pending))
+	for baseUrl, n := range d.pending {
+		active = append(active, fmt.Sprintf("%s (%d pending)", baseUrl, n))
+	}
+	d.mu.Unlock()
+	sort.Strings(active)
+
+	state := "running"
+	if d.isPaused() {
+		state = "paused"
+	}
+	fmt.Fprintf(w, "Status after %s: %s\n", elapsed, state)
+	fmt.Fprintf(w, "  targets:  %d, %d in progress, %d restored\n", total, len(active), restored)
+	fmt.Fprintf(w, "  requests: %d, %d files saved, %d errors, %d bytes\n", requests, files, errs, totalBytes)
+	if len(active) > maxStatusTargets {
+		active = append(active[:maxStatusTargets], fmt.Sprintf("and %d more", len(active)-maxStatusTargets))
+	}
+	for _, line := range active {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+}
+
+// Reload applies the rate limits (-rps, -interval, -burst) and the scope
+// limits (-max-requests-per-host, -max-objects-per-host, -max-total-bytes,
+// -max-host-bytes, -deadline, -host-deadline) of config to the running dump.
+// Other settings need a restart.
+func (d *Dumper) Reload(config config.Config) error {
+	if err := d.client.SetRate(config); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	old := d.config
+	d.config.MaxRPS, d.config.Interval, d.config.Burst = config.MaxRPS, config.Interval, config.Burst
+	d.config.MaxHostRequests, d.config.MaxHostObjects = config.MaxHostRequests, config.MaxHostObjects
+	d.config.MaxTotalBytes, d.config.MaxHostBytes = config.MaxTotalBytes, config.MaxHostBytes
+	d.config.Deadline, d.config.HostDeadline = config.Deadline, config.HostDeadline
+	d.deadline = time.Time{}
+	if config.Deadline > 0 {
+		d.deadline = d.report.StartedAt.Add(config.Deadline)
+	}
+	d.mu.Unlock()
+
+	var changes []string
+	for _, c := range []struct {
+		name     string
+		old, new any
+	}{
+		{"rps", old.MaxRPS, config.MaxRPS},
+		{"interval", old.Interval, config.Interval},
+		{"burst", old.Burst, config.Burst},
+		{"max-requests-per-host", old.MaxHostRequests, config.MaxHostRequests},
+		{"max-objects-per-host", old.MaxHostObjects, config.MaxHostObjects},
+		{"max-total-bytes", &old.MaxTotalBytes, &config.MaxTotalBytes},
+		{"max-host-bytes", &old.MaxHostBytes, &config.MaxHostBytes},
+		{"deadline", old.Deadline, config.Deadline},
+		{"host-deadline", old.HostDeadline, config.HostDeadline},
+	} {
+		if oldText, newText := fmt.Sprint(c.old), fmt.Sprint(c.new); oldText != newText {
+			changes = append(changes, fmt.Sprintf("-%s %s -> %s", c.name, oldText, newText))
+		}
+	}
+	if len(changes) == 0 {
+		logger.Info("Reloaded settings, nothing changed")
+	} else {
+		logger.Infof("Reloaded settings: %s", strings.Join(changes, ", "))
+	}
+	return nil
+}
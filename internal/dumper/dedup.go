@@ -0,0 +1,97 @@
+package dumper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/s3rgeym/git-dump/internal/fsutil"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+)
+
+const (
+	DedupHardlink = "hardlink"
+	DedupReflink  = "reflink"
+)
+
+// dedupFiles replaces restored files identical to files of other targets with
+// hard links or reflinks according to -dedup.
+func (d *Dumper) dedupFiles(target *report.Target) {
+	if d.config.Dedup == "" {
+		return
+	}
+	reflink := d.config.Dedup == DedupReflink
+
+	worktree := filepath.Dir(target.RepoPath)
+	var files int
+	var saved int64
+	filepath.WalkDir(worktree, func(fileName string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		fi, err := entry.Info()
+		if err != nil || fi.Size() == 0 {
+			return nil
+		}
+		hash, err := fileSha256(fileName)
+		if err != nil {
+			return nil
+		}
+		key := fmt.Sprintf("%s:%d", hash, fi.Size())
+
+		d.mu.Lock()
+		canonical, ok := d.dedup[key]
+		if !ok {
+			d.dedup[key] = fileName
+		}
+		d.mu.Unlock()
+		if !ok || canonical == fileName {
+			return nil
+		}
+		if cfi, err := os.Stat(canonical); err != nil || os.SameFile(cfi, fi) {
+			return nil
+		}
+
+		if err := fsutil.ReplaceWithLink(canonical, fileName, reflink); err != nil {
+			logger.Debugf("Failed to deduplicate %s: %v", fileName, err)
+			return nil
+		}
+		files++
+		saved += fi.Size()
+		return nil
+	})
+
+	if files > 0 {
+		logger.Infof("Deduplicated %d files of %s, saved %d bytes", files, target.Url, saved)
+		d.updateTarget(target.Url, func(t *report.Target) {
+			t.Notes = append(t.Notes, fmt.Sprintf("deduplicated %d files (%d bytes) via %s", files, saved, d.config.Dedup))
+		})
+	}
+}
+
+func fileSha256(fileName string) (string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
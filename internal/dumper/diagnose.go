@@ -0,0 +1,346 @@
+package dumper
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/httpclient"
+	"github.com/s3rgeym/git-dump/internal/utils"
+)
+
+const maxDiagnoseBody = 16 << 20
+
+// diagnosis collects the output of Diagnose.
+type diagnosis struct {
+	w           io.Writer
+	d           *Dumper
+	baseUrl     string
+	suggestions []string
+}
+
+// probeResult is a traced response of a single diagnostic request.
+type probeResult struct {
+	status      int
+	contentType string
+	body        []byte
+	err         error
+}
+
+// Diagnose probes a single target step by step, printing every request and
+// response and why each step succeeded or failed, and ends with suggested
+// flags. Nothing is saved. It returns false if the repository can't be
+// dumped.
+func (d *Dumper) Diagnose(w io.Writer, line string) bool {
+	targetUrl, err := d.parseTarget(line)
+	if err != nil || targetUrl == "" {
+		fmt.Fprintf(w, "✗ invalid target %q: %v\n", line, err)
+		return false
+	}
+	baseUrl, err := utils.NormalizeUrl(targetUrl)
+	if err != nil {
+		fmt.Fprintf(w, "✗ failed to normalize %s: %v\n", targetUrl, err)
+		return false
+	}
+	x := &diagnosis{w: w, d: d, baseUrl: baseUrl}
+	x.step("Target")
+	x.ok("base URL %s", baseUrl)
+	if u, err := url.Parse(baseUrl); err == nil {
+		if addr, ok := d.client.ConnectAddress(u.Hostname()); ok {
+			x.info("connecting to %s instead of resolving %s", addr, u.Hostname())
+		}
+	}
+	if d.config.ProxyUrl != "" {
+		x.info("using proxy %s", d.config.Redacted().ProxyUrl)
+	}
+	x.info("User-Agent: %s", d.config.UserAgent)
+
+	exposed := x.run()
+
+	x.step("Suggestions")
+	if len(x.suggestions) == 0 {
+		x.info("none")
+	}
+	for _, s := range x.suggestions {
+		fmt.Fprintf(w, "  • %s\n", s)
+	}
+	return exposed
+}
+
+func (x *diagnosis) run() bool {
+	x.step("Directory listing")
+	listed := false
+	if r := x.probe("."); r.err == nil {
+		if r.contentType == "text/html" {
+			links := utils.ExtractLinks(string(r.body))
+			if hasGitLinks(links) {
+				listed = true
+				x.ok("listing is enabled (%d links), the repository can be crawled without guessing", len(links))
+			} else {
+				x.fail("HTML page without links to git files (%d links): an index page, a custom error page or a catch-all route", len(links))
+			}
+		} else {
+			x.info("not a listing: %s", r.contentType)
+		}
+	}
+
+	x.step("Catch-all check")
+	random := fmt.Sprintf("git-dump-%08x", rand.Uint32())
+	catchAll := x.probe(random)
+	if catchAll.err == nil {
+		x.fail("a nonexistent file is served with status 200 (%s, %d bytes): responses can't be trusted by status alone", catchAll.contentType, len(catchAll.body))
+		x.suggest("the server answers 200 to any path; check that files saved by git-dump are real git files")
+	} else {
+		x.ok("nonexistent files are rejected")
+	}
+
+	x.step("HEAD")
+	head := x.probe("HEAD")
+	if head.err != nil {
+		if !listed {
+			x.fail("HEAD is not readable, the repository is not exposed at %s", x.baseUrl)
+			x.suggest("if the repository is in a subdirectory, pass its URL, e.g. https://host/app/.git/")
+			return false
+		}
+		x.fail("HEAD is not readable, only the listing can be used")
+		return true
+	}
+	content := strings.TrimSpace(string(head.body))
+	hash := ""
+	switch {
+	case catchAll.err == nil && bytes.Equal(head.body, catchAll.body):
+		x.fail("HEAD has the same body as a nonexistent file: this is a catch-all response")
+		return false
+	case head.contentType == "text/html":
+		x.fail("HEAD is an HTML page, not a git HEAD file")
+		return false
+	case sha1Regex.MatchString(content):
+		hash = content
+		x.ok("detached HEAD at %s", hash)
+	case symrefRegex.MatchString(content):
+		ref := symrefRegex.FindStringSubmatch(content)[1]
+		x.ok("HEAD points to %s", ref)
+		hash = x.resolveRef(ref)
+	default:
+		x.fail("HEAD doesn't look like a git HEAD file: %q", truncate(content, 80))
+		return false
+	}
+
+	x.step("config")
+	if r := x.probe("config"); r.err == nil {
+		if bytes.Contains(r.body, []byte("[core]")) {
+			x.ok("git config with a [core] section")
+		} else {
+			x.fail("no [core] section, this is not a git config")
+		}
+	}
+
+	x.step("index")
+	if r := x.probe("index"); r.err == nil {
+		if len(r.body) >= 12 && string(r.body[:4]) == "DIRC" {
+			x.ok("index version %d with %d entries: the working tree can be downloaded from the site", binary.BigEndian.Uint32(r.body[4:8]), binary.BigEndian.Uint32(r.body[8:12]))
+		} else {
+			x.fail("not a git index (no DIRC signature)")
+		}
+	}
+
+	x.step("Objects")
+	if hash == "" {
+		x.fail("HEAD could not be resolved to an object")
+		return true
+	}
+	r := x.probe("objects/" + hash[:2] + "/" + hash[2:])
+	if r.err == nil {
+		objType, data, err := gitobj.DecodeLoose(r.body)
+		switch {
+		case err != nil:
+			x.fail("failed to decode the loose object: %v", err)
+		case gitobj.HashObject(objType, data) != hash:
+			x.fail("the object doesn't match its hash")
+		default:
+			x.ok("loose %s object %s verified", objType, hash)
+			return true
+		}
+	}
+	if r := x.probe("objects/info/packs"); r.err == nil {
+		packs := 0
+		for _, line := range strings.Split(string(r.body), "\n") {
+			if strings.HasPrefix(line, "P pack-") {
+				packs++
+			}
+		}
+		x.ok("%d packs are listed, objects can be taken from them", packs)
+		return true
+	}
+	x.fail("neither the loose object nor a pack list is available; objects may only be found through packs with unknown names")
+	x.suggest("-forge-fallback to fetch objects through gitweb, cgit, Gitea or GitLab")
+	x.suggest("the working tree can still be downloaded from the site using the index")
+	return true
+}
+
+// resolveRef returns the hash of ref from its file or packed-refs.
+func (x *diagnosis) resolveRef(ref string) string {
+	if !validRefName(ref) {
+		x.fail("invalid ref name %q", ref)
+		return ""
+	}
+	if r := x.probe(ref); r.err == nil {
+		if hash := strings.TrimSpace(string(r.body)); sha1Regex.MatchString(hash) {
+			x.ok("%s is at %s", ref, hash)
+			return hash
+		}
+		x.fail("%s doesn't contain a hash", ref)
+	}
+	if r := x.probe("packed-refs"); r.err == nil {
+		if hash := findPackedRef(r.body, ref); hash != "" {
+			x.ok("%s is at %s (packed-refs)", ref, hash)
+			return hash
+		}
+		x.fail("%s is not in packed-refs", ref)
+	}
+	return ""
+}
+
+// probe fetches a file relative to the base URL and prints the exchange.
+func (x *diagnosis) probe(file string) probeResult {
+	var r probeResult
+	targetUrl, err := utils.UrlJoin(x.baseUrl, file)
+	if err != nil {
+		r.err = err
+		x.fail("invalid URL for %s: %v", file, err)
+		return r
+	}
+	start := time.Now()
+	resp, cancel, err := x.d.client.Fetch(targetUrl)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		r.err = err
+		var statusErr *httpclient.StatusError
+		if errors.As(err, &statusErr) {
+			r.status = statusErr.StatusCode
+			fmt.Fprintf(x.w, "  GET %s -> %d in %s\n", targetUrl, r.status, elapsed)
+			x.headers(statusErr.Header)
+		} else {
+			fmt.Fprintf(x.w, "  GET %s -> error in %s: %v\n", targetUrl, elapsed, err)
+		}
+		x.explain(err)
+		return r
+	}
+	defer cancel()
+	defer resp.Body.Close()
+	r.status = resp.StatusCode
+	r.contentType, _ = utils.GetMimeType(resp.Header.Get("Content-Type"))
+	r.body, r.err = io.ReadAll(io.LimitReader(resp.Body, maxDiagnoseBody))
+	fmt.Fprintf(x.w, "  GET %s -> %d %s, %d bytes in %s\n", targetUrl, r.status, r.contentType, len(r.body), elapsed)
+	x.headers(resp.Header)
+	if r.err != nil {
+		x.fail("failed to read the body: %v", r.err)
+	}
+	return r
+}
+
+// explain prints the likely cause of a failed request and suggests flags.
+func (x *diagnosis) explain(err error) {
+	var statusErr *httpclient.StatusError
+	var dnsErr *net.DNSError
+	var certErr *x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &statusErr):
+		switch statusErr.StatusCode {
+		case http.StatusNotFound:
+			x.info("not found")
+		case http.StatusUnauthorized, http.StatusForbidden:
+			x.info("access is denied, possibly by a WAF or a deny rule for dot files")
+			x.suggest("-ua to send a different User-Agent, or -proxy to come from another address")
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			x.info("the server is rate limiting")
+			x.suggest("-rps 2 or -stealth to slow down")
+		default:
+			x.info("unexpected status")
+		}
+	case errors.Is(err, httpclient.ErrCircuitOpen):
+		x.info("earlier connection failures tripped the circuit breaker")
+	case errors.As(err, &dnsErr):
+		x.info("the host name doesn't resolve")
+		x.suggest("-connect-to HOST:ADDRESS or a host= target option if you know the address")
+	case errors.As(err, &certErr), errors.As(err, &hostErr):
+		x.info("the TLS certificate is not trusted for this host")
+		x.suggest("try the http:// URL, or host= with the name the certificate was issued for")
+	case errors.As(err, &netErr) && netErr.Timeout():
+		x.info("the request timed out")
+		x.suggest("-dial-timeout and -request-timeout to wait longer, or -ip-version 4 if IPv6 is broken")
+	case strings.Contains(err.Error(), "connection refused"):
+		x.info("nothing listens on this port")
+		x.suggest("check the scheme and the port of the URL")
+	case strings.Contains(err.Error(), "too many errors"):
+		x.info("the host was skipped after earlier errors")
+		x.suggest("-maxhe to allow more errors per host")
+	}
+}
+
+func (x *diagnosis) headers(header http.Header) {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(x.w, "    %s: %s\n", key, strings.Join(header[key], ", "))
+	}
+}
+
+func (x *diagnosis) step(name string) { fmt.Fprintf(x.w, "\n== %s\n", name) }
+
+func (x *diagnosis) ok(format string, args ...any) {
+	fmt.Fprintf(x.w, "  ✓ %s\n", fmt.Sprintf(format, args...))
+}
+
+func (x *diagnosis) fail(format string, args ...any) {
+	fmt.Fprintf(x.w, "  ✗ %s\n", fmt.Sprintf(format, args...))
+}
+
+func (x *diagnosis) info(format string, args ...any) {
+	fmt.Fprintf(x.w, "  · %s\n", fmt.Sprintf(format, args...))
+}
+
+func (x *diagnosis) suggest(s string) {
+	for _, existing := range x.suggestions {
+		if existing == s {
+			return
+		}
+	}
+	x.suggestions = append(x.suggestions, s)
+}
+
+// hasGitLinks reports whether a listing links to files of a .git directory.
+func hasGitLinks(links []string) bool {
+	for _, link := range links {
+		name := strings.TrimSuffix(link, "/")
+		name = name[strings.LastIndexByte(name, '/')+1:]
+		switch name {
+		case "HEAD", "objects", "refs", "config", "index":
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,64 @@
+package dumper
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/utils"
+)
+
+// dryRun prints the probe URLs of every target and the local paths they would
+// be saved to, without sending requests. Files found later by parsing the
+// responses can't be known in advance.
+func (d *Dumper) dryRun(w io.Writer, urlList []string) {
+	seen := make(map[string]bool)
+	requests := 0
+	for _, line := range urlList {
+		targetUrl, err := d.parseTarget(line)
+		if err != nil {
+			logger.Errorf("Invalid target %q: %v", line, err)
+			continue
+		}
+		if targetUrl == "" {
+			continue
+		}
+		baseUrl, err := utils.NormalizeUrl(targetUrl)
+		if err != nil {
+			logger.Errorf("Failed to normalize URL %s: %v", targetUrl, err)
+			continue
+		}
+		if seen[baseUrl] {
+			continue
+		}
+		seen[baseUrl] = true
+		repoPath, err := utils.UrlToLocalPath(baseUrl, d.config.OutputDir, d.layout)
+		if err != nil {
+			logger.Errorf("Failed to convert URL %s to local repo path: %v", baseUrl, err)
+			continue
+		}
+		fmt.Fprintf(w, "# %s -> %s", baseUrl, repoPath)
+		if u, err := url.Parse(baseUrl); err == nil {
+			if addr, ok := d.client.ConnectAddress(u.Hostname()); ok {
+				fmt.Fprintf(w, " (connect to %s)", addr)
+			}
+		}
+		fmt.Fprintln(w)
+		for _, file := range d.config.CommonGitFiles {
+			probeUrl, err := utils.UrlJoin(baseUrl, file)
+			if err != nil {
+				logger.Errorf("Failed to convert URL %s to target URL for file %s: %v", baseUrl, file, err)
+				continue
+			}
+			fileName, err := utils.UrlToLocalPath(probeUrl, d.config.OutputDir, d.layout)
+			if err != nil {
+				logger.Errorf("Failed to convert URL %s to local path: %v", probeUrl, err)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\n", probeUrl, fileName)
+			requests++
+		}
+	}
+	logger.Infof("Dry run: %d targets, %d probe requests", len(seen), requests)
+}
@@ -0,0 +1,1256 @@
+package dumper
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/bench"
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/db"
+	"github.com/s3rgeym/git-dump/internal/extractor"
+	"github.com/s3rgeym/git-dump/internal/fsutil"
+	"github.com/s3rgeym/git-dump/internal/gitconfig"
+	"github.com/s3rgeym/git-dump/internal/gitindex"
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/httpclient"
+	"github.com/s3rgeym/git-dump/internal/ignore"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/queue"
+	"github.com/s3rgeym/git-dump/internal/report"
+	"github.com/s3rgeym/git-dump/internal/store"
+	"github.com/s3rgeym/git-dump/internal/utils"
+)
+
+var (
+	commonGitFiles = []string{
+		".",                   // Проверка на directory listing
+		"../.git-credentials", // Хранилище credential.helper=store в корне сайта
+		"COMMIT_EDITMSG",
+		"config",
+		"credentials",
+		"description",
+		"FETCH_HEAD",
+		"HEAD",
+		"hooks/", // Листинг hooks/ выдаёт установленные хуки
+		"index",
+		"info/exclude",
+		"info/grafts",
+		"info/refs",
+		"logs/HEAD",
+		"logs/refs/heads/develop",
+		"logs/refs/heads/main",
+		"logs/refs/heads/master",
+		"logs/refs/stash",
+		"objects/info/alternates",
+		"objects/info/commit-graph",
+		"objects/info/commit-graphs/commit-graph-chain",
+		"objects/info/packs",
+		"objects/pack/multi-pack-index",
+		"ORIG_HEAD",
+		"packed-refs",
+		"refs/heads/develop",
+		"refs/heads/main",
+		"refs/heads/master",
+		"refs/remotes/origin/HEAD",
+		"refs/stash",
+		"shallow",
+	}
+
+	// Пробы режима -reflog-first: журналы ссылок содержат хэши всех коммитов,
+	// включая удалённые ветки и перезаписанную force-push историю
+	reflogGitFiles = []string{
+		"logs/HEAD",
+		"logs/refs/",
+		"logs/refs/heads/",
+		"logs/refs/heads/dev",
+		"logs/refs/heads/develop",
+		"logs/refs/heads/main",
+		"logs/refs/heads/master",
+		"logs/refs/heads/production",
+		"logs/refs/heads/staging",
+		"logs/refs/remotes/origin/",
+		"logs/refs/remotes/origin/HEAD",
+		"logs/refs/remotes/origin/develop",
+		"logs/refs/remotes/origin/main",
+		"logs/refs/remotes/origin/master",
+		"logs/refs/stash",
+	}
+)
+
+// Dumper crawls exposed .git directories and restores repositories.
+type Dumper struct {
+	client     *httpclient.HttpClient
+	config     config.Config
+	extractors []extractor.Extractor
+	filter     *downloadFilter
+	restore    *ignore.Matcher    // Пути, которые не нужно восстанавливать
+	layout     *utils.Layout      // -layout
+	mirror     *template.Template // -mirror-to
+	seen       queue.SeenSet
+	sem        chan struct{} // Сетевые запросы (-w)
+	parseSem   chan struct{} // Распаковка и разбор файлов (-parse-workers)
+	wg         sync.WaitGroup
+	mu         sync.Mutex          // Мьютекс для защиты доступа к downloads, findings и targets
+	restoreMu  sync.Mutex          // Восстановление разных целей может писать в один каталог хоста
+	downloads  map[string][]string // Файлы рабочей копии по baseUrl
+	pending    map[string]int      // Незавершённые запросы по baseUrl
+	hostUsage  map[string]*hostUsage
+	totalBytes int64             // Скачано за запуск, для -max-total-bytes
+	deadline   time.Time         // Нулевое значение — без ограничения
+	dedup      map[string]string // Первый файл с таким содержимым по "sha256:размер"
+	store      store.Store
+	manifest   *manifest
+	listed     map[string]bool // Цели с листингом каталога .git (-passive)
+	crawls     map[string]*listingCrawl
+	heads      map[string][]string // Цели по коммиту HEAD
+	parked     map[string]string   // Хосты с парковочной страницей и причина
+	robots     map[string]*robotsEntry
+
+	spaceMu        sync.Mutex
+	spaceCheckedAt time.Time
+	lowSpace       bool // Места на диске не хватило, запуск прерван
+	pauseMu        sync.Mutex
+	resumed        chan struct{}     // Закрывается при снятии паузы, nil без паузы
+	findMu         sync.Mutex        // Оператора спрашивают о находках по одной (-pause-on-find)
+	stdin          *bufio.Reader     // Ответы оператора
+	noPrompt       bool              // Терминал закрыт
+	decided        map[string]bool   // Цели, после находок в которых уже была пауза
+	stopped        map[string]string // Цели, остановленные оператором, и причина
+	halted         string            // Почему оператор остановил весь запуск
+	findings       []extractor.Finding
+	targets        map[string]*report.Target
+	alternates     map[string][]string            // Альтернативные каталоги объектов по baseUrl
+	grafts         map[string]map[string][]string // Родители из shallow и info/grafts по baseUrl
+	missing        map[string][]string            // Не скачанные loose-объекты по baseUrl
+	report         report.Report
+	extras         map[string]*report.TargetExtras // Файлы и листинги целей для -report-html
+	database       *db.DB
+	bench          *bench.Recorder // -bench
+	severity       *report.SeverityRules
+	origins        map[string]string // Первая цель по IP и отпечатку HEAD, config и коммита
+	aliases        map[string]string // Цели, совпавшие с более ранней, и та цель
+	originSeen     map[string]bool   // Цели, отпечаток которых уже снят
+}
+
+func New(config config.Config, client *httpclient.HttpClient) *Dumper {
+	if len(config.CommonGitFiles) == 0 {
+		config.CommonGitFiles = probeFiles(config)
+	}
+
+	if err := ValidateConfig(config); err != nil {
+		logger.Fatalf("%v", err)
+	}
+
+	extractors := append([]extractor.Extractor{extractor.NewRemotesExtractor(), extractor.NewCredentialsExtractor(), extractor.NewHooksExtractor(), extractor.NewHoneypotExtractor()}, extractor.Registered()...)
+	for _, command := range config.Plugins {
+		extractors = append(extractors, extractor.NewExecExtractor(command))
+	}
+
+	restoreFilter, _ := ignore.Compile(config.RestoreFilter)
+	layout, _ := utils.ParseLayout(config.Layout, time.Now())
+	var mirror *template.Template
+	parseWorkers := config.ParseWorkers
+	if parseWorkers <= 0 {
+		parseWorkers = runtime.NumCPU()
+	}
+	if config.MirrorTo != "" {
+		mirror, _ = parseMirrorTemplate(config.MirrorTo)
+	}
+	st, _ := store.New(config.Store, config.OutputDir)
+	severity, _ := report.ParseSeverityRules(config.SeverityRules)
+
+	return &Dumper{
+		client:     client,
+		config:     config,
+		extractors: extractors,
+		filter:     newDownloadFilter(config, restoreFilter),
+		restore:    restoreFilter,
+		layout:     layout,
+		mirror:     mirror,
+		seen:       &queue.LocalSeenSet{},
+		sem:        make(chan struct{}, config.WorkersNum),
+		parseSem:   make(chan struct{}, parseWorkers),
+		targets:    make(map[string]*report.Target),
+		downloads:  make(map[string][]string),
+		pending:    make(map[string]int),
+		hostUsage:  make(map[string]*hostUsage),
+		dedup:      make(map[string]string),
+		store:      st,
+		severity:   severity,
+		manifest:   loadManifest(config.OutputDir),
+		listed:     make(map[string]bool),
+		crawls:     make(map[string]*listingCrawl),
+		heads:      make(map[string][]string),
+		parked:     make(map[string]string),
+		decided:    make(map[string]bool),
+		extras:     make(map[string]*report.TargetExtras),
+		stopped:    make(map[string]string),
+		robots:     make(map[string]*robotsEntry),
+		alternates: make(map[string][]string),
+		grafts:     make(map[string]map[string][]string),
+		missing:    make(map[string][]string),
+		origins:    make(map[string]string),
+		aliases:    make(map[string]string),
+		originSeen: make(map[string]bool),
+	}
+}
+
+// ValidateConfig checks dumper settings which New would otherwise reject with
+// a fatal error.
+func ValidateConfig(config config.Config) error {
+	if config.Dedup != "" && config.Dedup != DedupHardlink && config.Dedup != DedupReflink {
+		return fmt.Errorf("invalid -dedup value %q: expected %s or %s", config.Dedup, DedupHardlink, DedupReflink)
+	}
+	if _, err := store.New(config.Store, config.OutputDir); err != nil {
+		return fmt.Errorf("invalid -store value: %w", err)
+	}
+	if config.LowSpace != LowSpacePause && config.LowSpace != LowSpaceAbort {
+		return fmt.Errorf("invalid -low-space value %q: expected %s or %s", config.LowSpace, LowSpacePause, LowSpaceAbort)
+	}
+	for _, command := range config.Plugins {
+		if len(strings.Fields(command)) == 0 {
+			return fmt.Errorf("empty -plugin command")
+		}
+	}
+	if _, err := ignore.Compile(config.RestoreFilter); err != nil {
+		return fmt.Errorf("invalid restore filter: %w", err)
+	}
+	if _, err := compilePatterns(config.DownloadInclude); err != nil {
+		return fmt.Errorf("invalid -download-include: %w", err)
+	}
+	if _, err := compilePatterns(config.DownloadExclude); err != nil {
+		return fmt.Errorf("invalid -download-exclude: %w", err)
+	}
+	if _, err := utils.ParseLayout(config.Layout, time.Now()); err != nil {
+		return fmt.Errorf("invalid -layout: %w", err)
+	}
+	if config.MirrorTo != "" {
+		if _, err := parseMirrorTemplate(config.MirrorTo); err != nil {
+			return fmt.Errorf("invalid -mirror-to: %w", err)
+		}
+	}
+	if _, err := report.ParseSeverityRules(config.SeverityRules); err != nil {
+		return fmt.Errorf("invalid -severity: %w", err)
+	}
+	return nil
+}
+
+// SetSeenSet replaces the set of seen URLs, e.g. with one shared between
+// distributed workers. Must be called before Run.
+func (d *Dumper) SetSeenSet(seen queue.SeenSet) {
+	d.seen = seen
+}
+
+// SetDatabase enables recording of results into database.
+func (d *Dumper) SetDatabase(database *db.DB) {
+	d.database = database
+}
+
+// SetBench makes the dumper record parse times for the -bench summary.
+func (d *Dumper) SetBench(r *bench.Recorder) {
+	d.bench = r
+}
+
+// record calls fn if a database is configured and logs errors.
+func (d *Dumper) record(fn func(database *db.DB) error) {
+	if d.database == nil {
+		return
+	}
+	if err := fn(d.database); err != nil {
+		logger.Errorf("Failed to record to database: %v", err)
+	}
+}
+
+// Findings returns all findings emitted by extractors so far.
+func (d *Dumper) Findings() []extractor.Finding {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]extractor.Finding(nil), d.findings...)
+}
+
+// Report returns a snapshot of the run report.
+func (d *Dumper) Report() *report.Report {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r := d.report
+	r.Targets = make([]*report.Target, 0, len(d.report.Targets))
+	for _, t := range d.report.Targets {
+		target := *t
+		target.Findings = append([]extractor.Finding(nil), t.Findings...)
+		target.Notes = append([]string(nil), t.Notes...)
+		target.Aliases = append([]string(nil), t.Aliases...)
+		d.severity.Score(&target)
+		r.Targets = append(r.Targets, &target)
+	}
+	hostErrors := d.client.HostErrors()
+	for _, t := range r.Targets {
+		key, err := httpclient.OriginKey(t.Url)
+		if err != nil || hostErrors[key] == 0 {
+			continue
+		}
+		if r.HostErrors == nil {
+			r.HostErrors = make(map[string]int)
+		}
+		r.HostErrors[key] = hostErrors[key]
+	}
+	if limits := d.client.HostConcurrency(); limits != nil {
+		r.HostConcurrency = make(map[string]int)
+		for _, t := range r.Targets {
+			if key, err := httpclient.OriginKey(t.Url); err == nil && limits[key] > 0 {
+				r.HostConcurrency[key] = limits[key]
+			}
+		}
+	}
+	for _, t := range r.Targets {
+		if u, err := url.Parse(t.Url); err == nil {
+			t.Address, _ = d.client.ConnectAddress(u.Hostname())
+		}
+	}
+	r.Clusters = report.Clusters(r.Targets)
+	return &r
+}
+
+func (d *Dumper) targetRepoPath(baseUrl string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if t, ok := d.targets[baseUrl]; ok {
+		return t.RepoPath
+	}
+	return ""
+}
+
+// updateTarget calls fn with the target for baseUrl while holding the lock.
+func (d *Dumper) updateTarget(baseUrl string, fn func(t *report.Target)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if t, ok := d.targets[baseUrl]; ok {
+		fn(t)
+	}
+}
+
+// Run dumps every URL from urlList, restores repositories and downloads found files.
+func (d *Dumper) Run(urlList []string) {
+	d.mu.Lock()
+	d.report.StartedAt = time.Now()
+	d.report.ScanID, d.report.Operator = d.config.ScanID, d.config.Operator
+	if d.config.Deadline > 0 {
+		d.deadline = d.report.StartedAt.Add(d.config.Deadline)
+	}
+	d.mu.Unlock()
+
+	if d.config.Memory {
+		d.checkExposure(os.Stdout, urlList)
+		return
+	}
+	if d.config.Verify {
+		d.verify(urlList)
+		return
+	}
+	if d.config.DryRun {
+		d.dryRun(os.Stdout, urlList)
+		return
+	}
+
+	logger.Info("Starting to download Git files...")
+
+	for _, line := range urlList {
+		url, err := d.parseTarget(line)
+		if err != nil {
+			logger.Errorf("Invalid target %q: %v", line, err)
+			continue
+		}
+		if url != "" {
+			d.addTarget(url)
+		}
+	}
+
+	// Каждая цель восстанавливается сразу после завершения своего обхода
+	d.wg.Wait()
+
+	d.mu.Lock()
+	d.report.FinishedAt = time.Now()
+	d.mu.Unlock()
+
+	if d.config.ReportFile != "" {
+		if err := d.Report().WriteFile(d.config.ReportFile); err != nil {
+			logger.Errorf("Failed to write report: %v", err)
+		}
+	}
+	d.writeHtmlReport()
+	d.writeCsvReport()
+	if d.config.RunID != "" {
+		m := report.NewManifest(d.config.RunID, d.Report(), d.config.Redacted())
+		if err := m.WriteFile(filepath.Join(d.config.OutputDir, report.ManifestName)); err != nil {
+			logger.Errorf("Failed to write run manifest: %v", err)
+		}
+	}
+}
+
+// addTarget registers a new target and starts probing it. It returns false if
+// the target is invalid or already known.
+func (d *Dumper) addTarget(url string) bool {
+	baseUrl, err := utils.NormalizeUrl(url)
+	if err != nil {
+		logger.Errorf("Failed to normalize URL %s: %v", url, err)
+		return false
+	}
+	repoPath, err := utils.UrlToLocalPath(baseUrl, d.config.OutputDir, d.layout)
+	if err != nil {
+		logger.Errorf("Failed to convert URL %s to local repo path: %v", baseUrl, err)
+		return false
+	}
+	d.mu.Lock()
+	if _, ok := d.targets[baseUrl]; ok {
+		d.mu.Unlock()
+		return false
+	}
+	target := &report.Target{Url: baseUrl, RepoPath: repoPath}
+	d.targets[baseUrl] = target
+	d.report.Targets = append(d.report.Targets, target)
+	// Держим цель открытой, пока не запущены все пробы
+	d.pending[baseUrl]++
+	d.wg.Add(1)
+	d.mu.Unlock()
+	defer d.release(baseUrl)
+	d.record(func(database *db.DB) error { return database.AddTarget(baseUrl, repoPath) })
+	probes := d.config.CommonGitFiles
+	if d.config.Stealth {
+		probes = append([]string(nil), probes...)
+		rand.Shuffle(len(probes), func(i, j int) { probes[i], probes[j] = probes[j], probes[i] })
+	}
+	for _, file := range probes {
+		targetUrl, err := utils.UrlJoin(baseUrl, file)
+		if err != nil {
+			logger.Errorf("Failed to convert URL %s to target URL for file %s: %v", baseUrl, file, err)
+			continue
+		}
+
+		d.spawn(targetUrl, baseUrl)
+	}
+	return true
+}
+
+// probeFiles returns the list of paths probed for every target.
+func probeFiles(config config.Config) []string {
+	if config.Passive {
+		// HEAD запрашивается позже, только если листинга нет
+		return append([]string{"."}, config.ExtraProbes...)
+	}
+	probes := commonGitFiles
+	if config.ProbeFile != "" {
+		lines, err := utils.ReadLines(config.ProbeFile)
+		if err != nil {
+			logger.Fatalf("Failed to read probe files: %v", err)
+		}
+		probes = nil
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			probes = append(probes, line)
+		}
+	}
+	ret := make([]string, 0, len(reflogGitFiles)+len(probes)+len(config.ExtraProbes))
+	if config.ReflogFirst {
+		// Журналы идут первыми, чтобы обход коммитов начался как можно раньше
+		ret = append(ret, reflogGitFiles...)
+	}
+	ret = append(append(ret, probes...), config.ExtraProbes...)
+	if config.Stealth {
+		// Запросы каталогов (листинги) слишком заметны в логах сервера
+		filtered := ret[:0]
+		for _, probe := range ret {
+			if probe != "." && !strings.HasSuffix(probe, "/") {
+				filtered = append(filtered, probe)
+			}
+		}
+		ret = filtered
+	}
+	return ret
+}
+
+// spawn processes targetUrl in a new goroutine. It doesn't wait for a free
+// worker: the caller may be a worker itself, and with every worker waiting
+// in spawn none would be left to finish.
+func (d *Dumper) spawn(targetUrl, baseUrl string) {
+	d.mu.Lock()
+	d.pending[baseUrl]++
+	d.wg.Add(1)
+	d.mu.Unlock()
+	go d.processGitUrl(targetUrl, baseUrl)
+}
+
+// release marks a request of baseUrl as done. When the last one finishes, the
+// target is restored without waiting for other targets.
+func (d *Dumper) release(baseUrl string) {
+	d.mu.Lock()
+	d.pending[baseUrl]--
+	drained := d.pending[baseUrl] == 0
+	target := d.targets[baseUrl]
+	if drained {
+		delete(d.pending, baseUrl)
+		d.wg.Add(1)
+	}
+	d.mu.Unlock()
+	if drained {
+		go func() {
+			defer d.wg.Done()
+			d.finishTarget(target)
+		}()
+	}
+	d.wg.Done()
+}
+
+// finishTarget fetches missing objects via web interfaces if enabled, restores
+// the repository, recovers files excluded from download from objects and
+// downloads the rest of files found in its index.
+func (d *Dumper) finishTarget(target *report.Target) {
+	logger.Infof("Finished downloading Git files of %s", target.Url)
+
+	if primary := d.aliasOf(target.Url); primary != "" {
+		logger.Infof("Not restoring %s: alias of %s", target.Url, primary)
+		return
+	}
+	if reason, ok := d.parkedHost(target.Url); ok {
+		logger.Infof("Not restoring %s: %s", target.Url, reason)
+		d.updateTarget(target.Url, func(t *report.Target) { t.NotApplicable = reason })
+		return
+	}
+
+	if !d.checkSpace() {
+		logger.Warnf("Not restoring %s: low disk space", target.Url)
+		d.updateTarget(target.Url, func(t *report.Target) {
+			t.Truncated = true
+			t.Notes = append(t.Notes, "not restored: low disk space")
+		})
+		return
+	}
+
+	d.trimExpectedMissing(target)
+	if d.config.ForgeFallback && !d.pastDeadline() {
+		d.forgeFallback(target)
+	}
+
+	d.restoreTarget(target)
+	d.collectTags(target)
+	d.countCommits(target)
+	d.recoverSkippedFiles(target)
+	d.downloadFiles(target.Url)
+	markExecutables(target.RepoPath)
+	d.classifyFiles(target)
+	d.checkHoneypot(target)
+	d.dedupFiles(target)
+	// Зеркалирование до -worktree-only, который удаляет .git
+	if d.mirror != nil {
+		d.mirrorTarget(target)
+	}
+	if d.config.WorktreeOnly {
+		d.removeGitDir(target)
+	}
+	// Рабочую копию пишет git checkout, минуя хранилище
+	if s, ok := d.store.(store.Syncer); ok {
+		if err := s.Sync(filepath.Dir(target.RepoPath)); err != nil {
+			logger.Errorf("Failed to sync %s to the store: %v", target.RepoPath, err)
+		}
+	}
+}
+
+// removeGitDir deletes the dumped .git directory of a restored target, leaving
+// only its working tree (-worktree-only).
+func (d *Dumper) removeGitDir(target *report.Target) {
+	d.mu.Lock()
+	restored := target.Restored
+	d.mu.Unlock()
+	if !restored {
+		logger.Infof("Keeping %s: the repository was not restored", target.RepoPath)
+		return
+	}
+	if err := os.RemoveAll(target.RepoPath); err != nil {
+		logger.Errorf("Failed to remove %s: %v", target.RepoPath, err)
+		return
+	}
+	logger.Infof("Removed %s, only the working tree is kept", target.RepoPath)
+	d.updateTarget(target.Url, func(t *report.Target) {
+		t.Notes = append(t.Notes, ".git removed after restore")
+	})
+}
+
+// processGitUrl fetches a file of a .git directory and enqueues the files it
+// references. Fetching runs in the pool of -w workers and parsing in the pool
+// of -parse-workers, so decompression doesn't hold up network requests.
+func (d *Dumper) processGitUrl(targetUrl, baseUrl string) {
+	defer d.release(baseUrl)
+
+	if d.config.Passive && targetUrl == baseUrl {
+		defer d.probeHeadUnlessListed(baseUrl)
+	}
+
+	fileName, ok := d.fetchGitUrl(targetUrl, baseUrl)
+	if !ok || d.checkAlias(fileName, baseUrl) {
+		return
+	}
+
+	d.parseSem <- struct{}{}
+	parseStart := time.Now()
+	gitUrls, additionalUrls, ok := d.parseGitFile(fileName, targetUrl, baseUrl)
+	d.bench.Since(bench.Parse, parseStart)
+	<-d.parseSem
+	if !ok {
+		return
+	}
+
+	if d.config.Passive {
+		gitUrls = d.passiveFilter(gitUrls, baseUrl)
+	}
+	d.processGitUrls(gitUrls, baseUrl)
+
+	d.mu.Lock()
+	d.downloads[baseUrl] = append(d.downloads[baseUrl], additionalUrls...)
+	d.mu.Unlock()
+}
+
+// fetchGitUrl saves targetUrl unless a complete copy already exists. It
+// returns false if there is nothing to parse.
+func (d *Dumper) fetchGitUrl(targetUrl, baseUrl string) (string, bool) {
+	d.sem <- struct{}{}
+	defer func() { <-d.sem }()
+
+	if ok, err := d.seen.Add(targetUrl); err != nil {
+		logger.Errorf("Failed to mark URL %s as seen: %v", targetUrl, err)
+		return "", false
+	} else if ok {
+		logger.Warnf("URL already seen: %s", targetUrl)
+		return "", false
+	}
+
+	fileName, err := utils.UrlToLocalPath(targetUrl, d.config.OutputDir, d.layout)
+	if err != nil {
+		logger.Errorf("Failed to convert URL to save path: %v", err)
+		return "", false
+	}
+
+	needFetch := true
+	if !d.config.ForceFetch && utils.FileExists(fileName) {
+		if d.manifest.valid(fileName) {
+			logger.Debugf("File %s already exists, skipping fetch", fileName)
+			needFetch = false
+		} else {
+			logger.Debugf("File %s is incomplete or unknown, fetching again", fileName)
+		}
+	}
+
+	if needFetch && !d.allowFetch(targetUrl, baseUrl) {
+		return "", false
+	}
+
+	if needFetch {
+		d.updateTarget(baseUrl, func(t *report.Target) { t.Requests++ })
+		resp, cancel, err := d.client.Fetch(targetUrl)
+		if err != nil {
+			logger.Errorf("Failed to fetch URL %s: %v", targetUrl, err)
+			d.updateTarget(baseUrl, func(t *report.Target) { t.Errors++ })
+			d.record(func(database *db.DB) error { return database.AddFetch(baseUrl, targetUrl, "", 0, 0, err) })
+			d.tryAlternates(targetUrl, baseUrl)
+			d.addMissing(targetUrl, baseUrl)
+			return "", false
+		}
+		defer cancel()
+		defer resp.Body.Close()
+
+		d.checkServer(baseUrl, resp.Header)
+
+		contentType := resp.Header.Get("Content-Type")
+		mimeType, err := utils.GetMimeType(contentType)
+
+		if err != nil {
+			logger.Errorf("Invalid Content-Type for %s: %v", targetUrl, err)
+			return "", false
+		}
+
+		logger.Debugf("MIME Type for %s: %s", targetUrl, mimeType)
+
+		if mimeType == "text/html" {
+			d.handleHTMLContent(resp, targetUrl, baseUrl)
+			return "", false
+		}
+
+		if err := d.client.SaveResponse(resp, d.store, fileName); err != nil {
+			logger.Errorf("Failed to save response %s: %v", fileName, err)
+			return "", false
+		} else {
+			logger.Debugf("Saved %s", fileName)
+			d.manifest.add(fileName, targetUrl)
+			d.updateTarget(baseUrl, func(t *report.Target) { t.Files++ })
+			var size int64
+			if fi, err := os.Stat(fileName); err == nil {
+				size = fi.Size()
+			}
+			d.addBytes(targetUrl, baseUrl, size)
+			d.record(func(database *db.DB) error {
+				if sha1, ok := utils.PathToSha1(targetUrl); ok {
+					if err := database.AddObject(baseUrl, sha1); err != nil {
+						return err
+					}
+				}
+				return database.AddFetch(baseUrl, targetUrl, fileName, resp.StatusCode, size, nil)
+			})
+		}
+	}
+
+	return fileName, true
+}
+
+// parseGitFile extracts the URLs of git files and working tree files
+// referenced by a saved file and runs the extractors on it.
+func (d *Dumper) parseGitFile(fileName, targetUrl, baseUrl string) ([]string, []string, bool) {
+	gitUrls, additionalUrls, err := extractUrls(fileName, baseUrl, d.config.ReflogFirst, d.filter)
+	if err != nil {
+		logger.Errorf("Error extracting URLs from file %s: %v", fileName, err)
+		os.Remove(fileName)
+		return nil, nil, false
+	}
+
+	if strings.HasSuffix(fileName, "/objects/info/alternates") {
+		d.addAlternates(fileName, baseUrl)
+	} else if strings.HasSuffix(fileName, "/info/refs") {
+		d.addInfoRefs(fileName, baseUrl)
+	} else if strings.HasSuffix(fileName, "/shallow") || strings.HasSuffix(fileName, "/info/grafts") {
+		d.addGrafts(fileName, baseUrl)
+	} else {
+		gitUrls = d.graftParents(fileName, baseUrl, gitUrls)
+	}
+
+	file := extractor.File{Url: targetUrl, BaseUrl: baseUrl, Path: fileName}
+	for _, e := range d.extractors {
+		result, err := e.Extract(file)
+		if err != nil {
+			logger.Errorf("Extractor %s failed on %s: %v", e.Name(), fileName, err)
+			continue
+		}
+		gitUrls = append(gitUrls, result.GitUrls...)
+		additionalUrls = append(additionalUrls, result.DownloadUrls...)
+		d.addFindings(baseUrl, result.Findings)
+		d.addDiscoveredTargets(baseUrl, result.Targets)
+	}
+	return gitUrls, additionalUrls, true
+}
+
+// addAlternates remembers alternate object directories of a target so that
+// missing loose objects can be looked up there.
+func (d *Dumper) addAlternates(fileName, baseUrl string) {
+	relative, absolute, err := utils.ParseAlternates(fileName)
+	if err != nil {
+		logger.Errorf("Failed to parse alternates %s: %v", fileName, err)
+		return
+	}
+	for _, path := range absolute {
+		logger.Warnf("Alternate object directory %s of %s is an absolute path, skipping", path, baseUrl)
+		d.updateTarget(baseUrl, func(t *report.Target) {
+			t.Notes = append(t.Notes, "absolute alternate object directory: "+path)
+		})
+	}
+	d.mu.Lock()
+	d.alternates[baseUrl] = append(d.alternates[baseUrl], relative...)
+	d.mu.Unlock()
+}
+
+// addInfoRefs records the refs advertised in info/refs in the report.
+func (d *Dumper) addInfoRefs(fileName, baseUrl string) {
+	refs, err := utils.ParseInfoRefs(fileName)
+	if err != nil {
+		logger.Errorf("Failed to parse refs list %s: %v", fileName, err)
+		return
+	}
+	if len(refs) == 0 {
+		return
+	}
+	logger.Infof("%s advertises %d refs", baseUrl, len(refs))
+	d.updateTarget(baseUrl, func(t *report.Target) {
+		t.Refs = t.Refs[:0]
+		for _, ref := range refs {
+			t.Refs = append(t.Refs, report.Ref{Name: ref.Name, Hash: ref.Hash, Peeled: ref.Peeled})
+		}
+	})
+}
+
+// tryAlternates enqueues a missing loose object from the alternate object directories.
+func (d *Dumper) tryAlternates(targetUrl, baseUrl string) {
+	sha1, ok := utils.PathToSha1(targetUrl)
+	if !ok || !strings.HasPrefix(targetUrl, baseUrl+"objects/") {
+		return
+	}
+	d.mu.Lock()
+	alternates := append([]string(nil), d.alternates[baseUrl]...)
+	d.mu.Unlock()
+	for _, alt := range alternates {
+		altUrl, err := utils.UrlJoin(baseUrl, alt+sha1[:2]+"/"+sha1[2:])
+		if err != nil {
+			continue
+		}
+		if seen, _ := d.seen.Has(altUrl); !seen {
+			d.spawn(altUrl, baseUrl)
+		}
+	}
+}
+
+func (d *Dumper) addFindings(baseUrl string, findings []extractor.Finding) {
+	if len(findings) == 0 {
+		return
+	}
+	var pause *extractor.Finding
+	for i := range findings {
+		findings[i].Severity = d.severity.Finding(findings[i].Kind)
+	}
+	for _, f := range findings {
+		if f.Kind == "honeypot" {
+			d.flagHoneypot(baseUrl, f.Detail+" in "+strings.TrimPrefix(f.Url, baseUrl))
+			continue
+		}
+		if f.Kind == "credential" {
+			logger.Errorf("🔑 Credential found in %s: %s", f.Url, f.Detail)
+		} else {
+			logger.Warnf("Finding [%s/%s, %s] %s: %s", f.Extractor, f.Kind, f.Severity, f.Url, f.Detail)
+		}
+		if pause == nil && d.pausesOn(f.Kind) {
+			pause = &f
+		}
+	}
+	d.mu.Lock()
+	d.findings = append(d.findings, findings...)
+	if t, ok := d.targets[baseUrl]; ok {
+		t.Findings = append(t.Findings, findings...)
+	}
+	d.mu.Unlock()
+	for _, f := range findings {
+		d.record(func(database *db.DB) error { return database.AddFinding(baseUrl, f) })
+	}
+	if pause != nil {
+		d.pauseOnFind(baseUrl, fmt.Sprintf("%s in %s (%s)", pause.Kind, strings.TrimPrefix(pause.Url, baseUrl), pause.Detail))
+	}
+}
+
+func (d *Dumper) handleHTMLContent(resp *http.Response, targetUrl, baseUrl string) {
+	buf := new(bytes.Buffer)
+	_, err := io.Copy(buf, resp.Body)
+	if err != nil {
+		logger.Errorf("Failed to read response %s: %v", targetUrl, err)
+		return
+	}
+
+	htmlContent := buf.String()
+	///logger.Debugf("Content: %s", htmlContent)
+
+	if strings.Contains(htmlContent, "Index of /") || strings.Contains(htmlContent, "Directory listing for /") {
+		logger.Infof("Found directory listing: %s", targetUrl)
+		if targetUrl == baseUrl {
+			d.markListed(baseUrl)
+			if d.config.ReportHtml != "" && d.config.ReportListings {
+				d.keepListing(baseUrl, htmlContent)
+			}
+		}
+		if !d.startListing(targetUrl, baseUrl) {
+			return
+		}
+		links := utils.ExtractLinks(htmlContent)
+		followed := 0
+		for _, link := range links {
+			if strings.Contains(link, "?") {
+				continue
+			}
+			newUrl, err := utils.UrlJoin(targetUrl, link)
+			if err != nil {
+				logger.Errorf("Failed to join URL %s with path %s: %v", baseUrl, link, err)
+				continue
+			}
+
+			if !d.followListingLink(newUrl, baseUrl) {
+				continue
+			}
+			if d.config.MaxListingLinks > 0 && followed >= d.config.MaxListingLinks {
+				logger.Warnf("Listing %s has more than %d links, skipping the rest", targetUrl, d.config.MaxListingLinks)
+				d.updateTarget(baseUrl, func(t *report.Target) {
+					t.Notes = append(t.Notes, "listing truncated: "+targetUrl)
+				})
+				break
+			}
+			followed++
+
+			d.spawn(newUrl, baseUrl)
+		}
+	} else if reason := parkingReason(resp, htmlContent); reason != "" {
+		d.markParked(baseUrl, reason)
+	} else {
+		logger.Warnf("Skip URL: %s", targetUrl)
+		// Страница ошибки с кодом 200: повторные ссылки на этот путь не запрашиваем
+		d.client.MarkMissing(targetUrl)
+	}
+}
+
+func (d *Dumper) processGitUrls(gitUrls []string, baseUrl string) {
+	for _, newUrl := range gitUrls {
+		if ok, _ := d.seen.Has(newUrl); ok {
+			continue
+		}
+
+		d.spawn(newUrl, baseUrl)
+	}
+}
+
+func (d *Dumper) restoreTarget(target *report.Target) {
+	absRepoPath, err := filepath.Abs(target.RepoPath)
+	if err != nil {
+		logger.Errorf("Error getting absolute path for %s: %v", target.RepoPath, err)
+		return
+	}
+
+	parentDir := filepath.Dir(absRepoPath)
+
+	if !gitobj.HasContent(absRepoPath) {
+		logger.Debugf("Nothing to restore in %s", parentDir)
+		return
+	}
+
+	gitIndex, indexErr := gitindex.ParseGitIndex(filepath.Join(absRepoPath, "index"))
+	if indexErr == nil {
+		if conflicts := gitIndex.Conflicts(); len(conflicts) > 0 {
+			logger.Warnf("The index of %s has %d paths in merge conflict state, restoring our version where it exists", target.Url, len(conflicts))
+			d.updateTarget(target.Url, func(t *report.Target) { t.Conflicts = conflicts })
+		}
+	}
+
+	d.writeAdvertisedRefs(target.Url, absRepoPath)
+
+	d.restoreMu.Lock()
+	err = restoreRepository(parentDir, d.restore, d.config.AllowSymlinks)
+	d.restoreMu.Unlock()
+	if err != nil {
+		logger.Errorf("Error restoring repository in %s: %v", parentDir, err)
+		return
+	}
+
+	var submodules []report.Submodule
+	if indexErr == nil {
+		submodules = indexSubmodules(gitIndex, parentDir)
+	}
+	d.updateTarget(target.Url, func(t *report.Target) {
+		t.Restored = true
+		t.Submodules = submodules
+	})
+	d.record(func(database *db.DB) error { return d.recordRestoredFiles(database, target.Url, absRepoPath) })
+}
+
+// writeAdvertisedRefs creates loose ref files for refs of info/refs which
+// are neither loose nor packed, so HEAD resolves even when the server hides
+// refs/ and packed-refs.
+func (d *Dumper) writeAdvertisedRefs(baseUrl, repoPath string) {
+	d.mu.Lock()
+	refs := append([]report.Ref(nil), d.targets[baseUrl].Refs...)
+	d.mu.Unlock()
+	if len(refs) == 0 {
+		return
+	}
+	packed, _ := os.ReadFile(filepath.Join(repoPath, "packed-refs"))
+	for _, ref := range refs {
+		if !strings.HasPrefix(ref.Name, "refs/") || !validRefName(ref.Name) || findPackedRef(packed, ref.Name) != "" {
+			continue
+		}
+		fileName := filepath.Join(repoPath, filepath.FromSlash(ref.Name))
+		if _, err := os.Lstat(fileName); err == nil || fsutil.SymlinkInPath(repoPath, fileName) {
+			continue
+		}
+		if err := store.WriteFile(d.store, fileName, []byte(ref.Hash+"\n"), 0644); err != nil {
+			logger.Errorf("Failed to write ref %s: %v", fileName, err)
+			continue
+		}
+		logger.Debugf("Wrote ref %s of %s from info/refs", ref.Name, baseUrl)
+	}
+}
+
+// recordRestoredFiles saves index entries present in the working tree.
+func (d *Dumper) recordRestoredFiles(database *db.DB, baseUrl, repoPath string) error {
+	if err := database.SetRestored(baseUrl); err != nil {
+		return err
+	}
+	gitIndex, err := gitindex.ParseGitIndex(filepath.Join(repoPath, "index"))
+	if err != nil {
+		return err
+	}
+	worktree := filepath.Dir(repoPath)
+	for _, entry := range gitIndex.Files() {
+		if !utils.FileExists(filepath.Join(worktree, entry.FileName)) {
+			continue
+		}
+		if err := database.AddFile(baseUrl, entry.FileName, "restore"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexSubmodules returns the gitlinks of the index with URLs from the
+// restored .gitmodules.
+func indexSubmodules(gitIndex gitindex.GitIndex, worktree string) []report.Submodule {
+	urls := make(map[string]string)
+	if modules, err := gitconfig.ParseFile(filepath.Join(worktree, ".gitmodules")); err == nil {
+		for _, name := range modules.Subsections("submodule") {
+			path, _ := modules.Get("submodule", name, "path")
+			url, _ := modules.Get("submodule", name, "url")
+			urls[path] = url
+		}
+	}
+	var submodules []report.Submodule
+	for _, entry := range gitIndex.Files() {
+		if entry.Mode&fsutil.ModeTypeMask == fsutil.ModeGitlink {
+			submodules = append(submodules, report.Submodule{Path: entry.FileName, Commit: entry.Sha1, Url: urls[entry.FileName]})
+		}
+	}
+	return submodules
+}
+
+// markExecutables sets the executable bit of working tree files which are
+// 100755 in the index: files downloaded over HTTP are saved as 0644.
+func markExecutables(repoPath string) {
+	gitIndex, err := gitindex.ParseGitIndex(filepath.Join(repoPath, "index"))
+	if err != nil {
+		return
+	}
+	worktree := filepath.Dir(repoPath)
+	for _, entry := range gitIndex.Files() {
+		if entry.Mode != fsutil.ModeExecutable {
+			continue
+		}
+		fileName := filepath.Join(worktree, filepath.FromSlash(entry.FileName))
+		if !strings.HasPrefix(fileName, worktree+string(filepath.Separator)) || fsutil.SymlinkInPath(worktree, fileName) {
+			continue
+		}
+		if info, err := os.Lstat(fileName); err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0111 == 0 {
+			if err := os.Chmod(fileName, 0755); err != nil {
+				logger.Warnf("Failed to mark %s executable: %v", fileName, err)
+			}
+		}
+	}
+}
+
+func restoreRepository(parentDir string, filter *ignore.Matcher, allowSymlinks bool) error {
+	// Без каталога refs git не считает каталог репозиторием, а после gc
+	// все ссылки могут быть только в packed-refs
+	if err := os.MkdirAll(filepath.Join(parentDir, ".git", "refs", "heads"), 0755); err != nil {
+		return fmt.Errorf("failed to create refs directory in %s: %w", parentDir, err)
+	}
+
+	indexFile := filepath.Join(parentDir, ".git", "index")
+	gitIndex, err := gitindex.ParseGitIndex(indexFile)
+	conflicted := err == nil && len(gitIndex.Conflicts()) > 0
+	if filter.Empty() && !conflicted {
+		return checkout(parentDir, allowSymlinks, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse index %s: %w", indexFile, err)
+	}
+	// git checkout . отказывается работать с неслитыми путями, поэтому они
+	// восстанавливаются из стадии ours или, если её нет, theirs
+	ours, theirs := restorePaths(gitIndex, filter)
+	if ours == nil && theirs == nil {
+		logger.Infof("All paths in %s are excluded by the restore filter", parentDir)
+		return nil
+	}
+	if ours != nil {
+		if err := checkout(parentDir, allowSymlinks, ours, "--ours"); err != nil {
+			return err
+		}
+	}
+	if theirs != nil {
+		if err := checkout(parentDir, allowSymlinks, theirs, "--theirs"); err != nil {
+			return err
+		}
+	}
+	logger.Infof("Restored repository in %s", parentDir)
+	return nil
+}
+
+// checkout writes files of the index to parentDir: all of them, or the NUL
+// separated paths if given. Without allowSymlinks symlinks are written as
+// files holding the link target.
+func checkout(parentDir string, allowSymlinks bool, paths []byte, options ...string) error {
+	// Не используем os.Chdir, чтобы несколько дамперов могли работать параллельно
+	args := safeGitArgs(parentDir)
+	if !allowSymlinks {
+		args = append(args, "-c", "core.symlinks=false")
+	}
+	args = append(args, "checkout")
+	args = append(args, options...)
+	var stdin io.Reader
+	if paths == nil {
+		args = append(args, ".")
+	} else {
+		args = append(args, "--pathspec-from-file=-", "--pathspec-file-nul")
+		stdin = bytes.NewReader(paths)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = parentDir
+	cmd.Stdin = stdin
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "GIT_TERMINAL_PROMPT=0")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error restoring repository in %s: %v", parentDir, err)
+	}
+	return nil
+}
+
+// restorePaths returns NUL separated index paths not excluded by filter:
+// merged and conflicted paths with our version, and conflicted paths which
+// only have their version. Either is nil if there are no such paths.
+func restorePaths(gitIndex gitindex.GitIndex, filter *ignore.Matcher) (ours, theirs []byte) {
+	for _, entry := range gitIndex.Files() {
+		if entry.SkipWorktree() || filter.Match(entry.FileName) {
+			continue
+		}
+		// Pathspec — это шаблоны, поэтому имена передаём буквально
+		path := []byte(":(literal)" + entry.FileName + "\x00")
+		if entry.Stage() == 3 {
+			theirs = append(theirs, path...)
+		} else {
+			ours = append(ours, path...)
+		}
+	}
+	return ours, theirs
+}
+
+// safeGitArgs returns options which stop git from running commands defined
+// in the dumped (attacker controlled) config: hooks, fsmonitor and clean/smudge
+// filters referenced from .gitattributes. The work tree is pinned to parentDir,
+// so core.worktree can't redirect a checkout to an arbitrary directory.
+func safeGitArgs(parentDir string) []string {
+	gitDir := filepath.Join(parentDir, ".git")
+	args := []string{
+		"--git-dir=" + gitDir,
+		"--work-tree=" + parentDir,
+		"-c", "core.worktree=" + parentDir,
+		"-c", "core.hooksPath=" + os.DevNull,
+		"-c", "core.fsmonitor=false",
+		"-c", "core.sshCommand=false",
+	}
+	for _, name := range configuredFilters(filepath.Join(gitDir, "config"), 0) {
+		args = append(args,
+			"-c", "filter."+name+".smudge=",
+			"-c", "filter."+name+".clean=",
+			"-c", "filter."+name+".process=",
+			"-c", "filter."+name+".required=false",
+		)
+	}
+	return args
+}
+
+// configuredFilters returns filter driver names from a config file and the
+// files it includes.
+func configuredFilters(fileName string, depth int) []string {
+	cfg, err := gitconfig.ParseFile(fileName)
+	if err != nil || depth > 5 {
+		return nil
+	}
+	names := cfg.Subsections("filter")
+	for _, e := range cfg.Entries {
+		if (e.Section == "include" || e.Section == "includeif") && e.Key == "path" {
+			path := e.Value
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(filepath.Dir(fileName), path)
+			}
+			names = append(names, configuredFilters(path, depth+1)...)
+		}
+	}
+	return names
+}
+
+// uniqueUrls normalizes urls and drops duplicates keeping the first
+// occurrence, since the index may be parsed several times.
+func uniqueUrls(urls []string) []string {
+	seen := make(map[string]bool, len(urls))
+	ret := make([]string, 0, len(urls))
+	for _, u := range urls {
+		if parsed, err := url.Parse(u); err == nil {
+			parsed.Scheme = strings.ToLower(parsed.Scheme)
+			parsed.Host = strings.ToLower(parsed.Host)
+			parsed.Fragment = ""
+			if parsed.Path != "" {
+				parsed.Path = path.Clean(parsed.Path)
+			}
+			u = parsed.String()
+		}
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		ret = append(ret, u)
+	}
+	return ret
+}
+
+// downloadFiles fetches working tree files found in the index of baseUrl.
+func (d *Dumper) downloadFiles(baseUrl string) {
+	d.mu.Lock()
+	urls := uniqueUrls(d.downloads[baseUrl])
+	delete(d.downloads, baseUrl)
+	d.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, u := range urls {
+		// Файл мог быть уже скачан через другую цепочку обнаружения
+		if ok, err := d.seen.Add(u); err != nil {
+			logger.Errorf("Failed to mark URL %s as seen: %v", u, err)
+			continue
+		} else if ok {
+			logger.Debugf("Skipping already downloaded file %s", u)
+			continue
+		}
+		fileName, err := utils.UrlToLocalPath(u, d.config.OutputDir, d.layout)
+		if err != nil {
+			logger.Errorf("Failed to convert URL to save path: %v", err)
+			continue
+		}
+		if !d.allowFetch(u, baseUrl) {
+			continue
+		}
+
+		d.sem <- struct{}{}
+		wg.Add(1)
+		go func(u, fileName string) {
+			defer func() {
+				<-d.sem
+				wg.Done()
+			}()
+
+			if _, err := d.client.FetchFile(u, d.store, fileName); err != nil {
+				logger.Errorf("Failed to fetch file %s: %v", u, err)
+			} else {
+				logger.Infof("Downloaded file %s", fileName)
+				if fi, err := os.Stat(fileName); err == nil {
+					d.addBytes(u, baseUrl, fi.Size())
+				}
+				d.record(func(database *db.DB) error {
+					worktree := filepath.Dir(d.targetRepoPath(baseUrl))
+					path, err := filepath.Rel(worktree, fileName)
+					if err != nil {
+						return err
+					}
+					return database.AddFile(baseUrl, filepath.ToSlash(path), "download")
+				})
+			}
+		}(u, fileName)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,155 @@
+package dumper
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/fsutil"
+	"github.com/s3rgeym/git-dump/internal/gitindex"
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/utils"
+)
+
+func extractUrls(fileName, baseUrl string, walkTrees bool, filter *downloadFilter) ([]string, []string, error) {
+	var gitPaths []string
+	var additionalUrls []string
+
+	if strings.HasSuffix(fileName, "/index") {
+		gitIndex, err := gitindex.ParseGitIndex(fileName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing git index %s: %w", fileName, err)
+		}
+
+		// Скачиваем объекты всех стадий конфликта, а файл рабочей копии — один раз
+		for _, entry := range gitIndex.Entries {
+			// У intent-to-add записей нет содержимого в объектах, а подмодуль
+			// ссылается на коммит другого репозитория
+			if !entry.IntentToAdd() && entry.Mode&fsutil.ModeTypeMask != fsutil.ModeGitlink {
+				gitPaths = append(gitPaths, utils.Sha1ToPath(entry.Sha1))
+			}
+		}
+		for _, entry := range gitIndex.Files() {
+			// Файлов вне sparse checkout нет в рабочей копии
+			if entry.SkipWorktree() || !filter.allow(entry.FileName, int64(entry.Size)) {
+				continue
+			}
+			// Сервер отдал бы файл, на который указывает ссылка; цель ссылки
+			// восстанавливается из blob
+			if mode := entry.Mode & fsutil.ModeTypeMask; mode == fsutil.ModeGitlink || mode == fsutil.ModeSymlink {
+				continue
+			}
+			downloadUrl, err := utils.UrlJoin(baseUrl, "../"+strings.TrimLeft(entry.FileName, "/"))
+			if err != nil {
+				logger.Errorf("Error joining URL: %v", err)
+				continue
+			}
+			additionalUrls = append(additionalUrls, downloadUrl)
+		}
+	} else if strings.HasSuffix(fileName, "/objects/info/packs") {
+		var err error
+		gitPaths, err = utils.ParseInfoPacks(fileName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing packs list %s: %w", fileName, err)
+		}
+	} else if strings.HasSuffix(fileName, "/info/refs") {
+		refs, err := utils.ParseInfoRefs(fileName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing refs list %s: %w", fileName, err)
+		}
+		// Обход истории начинается со всех объявленных ссылок, а не только с
+		// тех имён, что нашлись бы регулярным выражением
+		for _, ref := range refs {
+			gitPaths = append(gitPaths, utils.Sha1ToPath(ref.Hash))
+			if ref.Peeled != "" {
+				gitPaths = append(gitPaths, utils.Sha1ToPath(ref.Peeled))
+			}
+			if strings.HasPrefix(ref.Name, "refs/") && validRefName(ref.Name) {
+				name := (&url.URL{Path: ref.Name}).EscapedPath()
+				gitPaths = append(gitPaths, name, "logs/"+name)
+			}
+		}
+	} else if strings.HasSuffix(fileName, "/objects/info/alternates") {
+		relative, _, err := utils.ParseAlternates(fileName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing alternates %s: %w", fileName, err)
+		}
+		for _, alt := range relative {
+			gitPaths = append(gitPaths, alt+"info/packs", alt+"info/alternates")
+		}
+	} else if isGraphFile(fileName) {
+		var err error
+		gitPaths, err = graphPaths(fileName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing %s: %w", fileName, err)
+		}
+	} else if strings.Contains(fileName, "/objects/pack/") {
+		// Пакеты и индексы бинарные, ссылок в них искать бессмысленно
+		return nil, nil, nil
+	} else {
+		var err error
+		gitPaths, err = utils.GetHashesAndRefs(fileName, walkTrees)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error getting object hashes and refs from file %s: %w", fileName, err)
+		}
+	}
+
+	gitUrls := make([]string, 0, len(gitPaths))
+	for _, path := range gitPaths {
+		newUrl, err := utils.UrlJoin(baseUrl, path)
+		if err != nil {
+			logger.Errorf("Failed to join URL %s with path %s: %v", baseUrl, path, err)
+			continue
+		}
+		gitUrls = append(gitUrls, newUrl)
+	}
+
+	return gitUrls, additionalUrls, nil
+}
+
+// isGraphFile reports whether fileName is a commit-graph, its chain or a
+// multi-pack-index.
+func isGraphFile(fileName string) bool {
+	slashed := filepath.ToSlash(fileName)
+	return strings.HasSuffix(slashed, "/objects/info/commit-graph") ||
+		strings.Contains(slashed, "/objects/info/commit-graphs/") ||
+		strings.HasSuffix(slashed, "/objects/pack/multi-pack-index")
+}
+
+// graphPaths returns paths referenced by commit-graph and multi-pack-index files.
+func graphPaths(fileName string) ([]string, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	switch slashed := filepath.ToSlash(fileName); {
+	case strings.HasSuffix(slashed, "/commit-graph-chain"):
+		for _, name := range gitobj.ParseCommitGraphChain(data) {
+			paths = append(paths, "objects/info/commit-graphs/"+name)
+		}
+	case strings.HasSuffix(slashed, "/multi-pack-index"):
+		// Объекты из OIDL лежат в пакетах, поэтому запрашиваем только сами пакеты
+		packs, _, err := gitobj.ParseMultiPackIndex(data)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range packs {
+			name = strings.TrimSuffix(name, ".idx")
+			paths = append(paths, "objects/pack/"+name+".idx", "objects/pack/"+name+".pack")
+		}
+	default:
+		commits, trees, err := gitobj.ParseCommitGraph(data)
+		if err != nil {
+			return nil, err
+		}
+		for _, hash := range append(commits, trees...) {
+			paths = append(paths, utils.Sha1ToPath(hash))
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,104 @@
+package dumper
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/ignore"
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+// downloadFilter decides which working tree files from the index are
+// downloaded over HTTP.
+type downloadFilter struct {
+	include []pattern
+	exclude []pattern
+	maxSize int64
+	restore *ignore.Matcher
+}
+
+// pattern is a glob or, if written as /.../, a regular expression.
+type pattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+func newDownloadFilter(config config.Config, restore *ignore.Matcher) *downloadFilter {
+	return &downloadFilter{
+		include: parsePatterns(config.DownloadInclude),
+		exclude: parsePatterns(config.DownloadExclude),
+		maxSize: config.DownloadMaxSize,
+		restore: restore,
+	}
+}
+
+func parsePatterns(value string) []pattern {
+	ret, err := compilePatterns(value)
+	if err != nil {
+		logger.Fatalf("Invalid download pattern: %v", err)
+	}
+	return ret
+}
+
+// compilePatterns parses a comma-separated list of globs and /regexps/.
+func compilePatterns(value string) ([]pattern, error) {
+	var ret []pattern
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if len(s) > 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+			re, err := regexp.Compile(s[1 : len(s)-1])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", s, err)
+			}
+			ret = append(ret, pattern{re: re})
+			continue
+		}
+		if _, err := path.Match(s, ""); err != nil {
+			return nil, fmt.Errorf("%s: %w", s, err)
+		}
+		ret = append(ret, pattern{glob: s})
+	}
+	return ret, nil
+}
+
+// match checks globs against both the full path and the base name, so *.env
+// matches config/.env.
+func (p pattern) match(fileName string) bool {
+	if p.re != nil {
+		return p.re.MatchString(fileName)
+	}
+	if ok, _ := path.Match(p.glob, fileName); ok {
+		return true
+	}
+	ok, _ := path.Match(p.glob, path.Base(fileName))
+	return ok
+}
+
+func matchAny(patterns []pattern, fileName string) bool {
+	for _, p := range patterns {
+		if p.match(fileName) {
+			return true
+		}
+	}
+	return false
+}
+
+// allow reports whether a file of the given size should be downloaded.
+func (f *downloadFilter) allow(fileName string, size int64) bool {
+	if f.restore.Match(fileName) {
+		return false
+	}
+	if f.maxSize > 0 && size > f.maxSize {
+		return false
+	}
+	if len(f.include) > 0 && !matchAny(f.include, fileName) {
+		return false
+	}
+	return !matchAny(f.exclude, fileName)
+}
@@ -0,0 +1,130 @@
+package dumper
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/forge"
+	"github.com/s3rgeym/git-dump/internal/gitconfig"
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+	"github.com/s3rgeym/git-dump/internal/store"
+	"github.com/s3rgeym/git-dump/internal/utils"
+)
+
+// addMissing remembers a loose object which could not be fetched.
+func (d *Dumper) addMissing(targetUrl, baseUrl string) {
+	sha1, ok := utils.PathToSha1(targetUrl)
+	if !ok {
+		return
+	}
+	d.mu.Lock()
+	d.missing[baseUrl] = append(d.missing[baseUrl], sha1)
+	d.mu.Unlock()
+}
+
+// forgeFallback fetches objects of target missing over the dumb protocol from
+// gitweb, cgit, Gitea or GitLab interfaces of the same site. Only cgit serves
+// commits and trees; the others can recover blobs only.
+func (d *Dumper) forgeFallback(target *report.Target) {
+	d.mu.Lock()
+	missing := append([]string(nil), d.missing[target.Url]...)
+	d.mu.Unlock()
+	if len(missing) == 0 {
+		return
+	}
+
+	repo, err := gitobj.Open(target.RepoPath)
+	if err != nil {
+		return
+	}
+	defer repo.Close()
+
+	project := targetProject(target.RepoPath, target.Url)
+	forges := forge.Detect(d.client, target.Url, project)
+	if len(forges) == 0 {
+		return
+	}
+
+	recovered := 0
+	queued := make(map[string]bool)
+	for len(missing) > 0 {
+		hash := missing[0]
+		missing = missing[1:]
+		// Хэши из полученных объектов не доверенные: tree в коммите может быть любым
+		if !sha1Regex.MatchString(hash) {
+			logger.Debugf("Skipping invalid object hash %q of %s", hash, target.Url)
+			continue
+		}
+		if queued[hash] || repo.HasObject(hash) {
+			continue
+		}
+		queued[hash] = true
+
+		for _, f := range forges {
+			objType, data, err := f.FetchObject(d.client, hash)
+			if err != nil {
+				logger.Debugf("Failed to fetch %s via %s: %v", hash, f.Kind, err)
+				continue
+			}
+			loosePath := repo.LoosePath(gitobj.HashObject(objType, data))
+			if err := store.WriteFile(d.store, loosePath, gitobj.EncodeLoose(objType, data), 0644); err != nil {
+				logger.Errorf("Failed to write object %s: %v", hash, err)
+				break
+			}
+			recovered++
+			logger.Debugf("Recovered %s %s via %s", objType, hash, f.Kind)
+			missing = append(missing, referencedObjects(objType, data)...)
+			break
+		}
+	}
+
+	if recovered > 0 {
+		logger.Infof("Recovered %d objects for %s via web interface", recovered, target.Url)
+		d.updateTarget(target.Url, func(t *report.Target) {
+			t.Notes = append(t.Notes, "objects recovered via web interface")
+		})
+	}
+}
+
+// referencedObjects returns hashes referenced by commits and trees.
+func referencedObjects(objType string, data []byte) []string {
+	switch objType {
+	case gitobj.TypeCommit:
+		if c, err := gitobj.ParseCommit("", data); err == nil {
+			return append([]string{c.Tree}, c.Parents...)
+		}
+	case gitobj.TypeTree:
+		entries, err := gitobj.ParseTree(data)
+		if err != nil {
+			return nil
+		}
+		var hashes []string
+		for _, e := range entries {
+			if !e.IsSubmodule() {
+				hashes = append(hashes, e.Hash)
+			}
+		}
+		return hashes
+	}
+	return nil
+}
+
+// targetProject guesses the repository path from the origin remote or, if
+// there is none, from the URL path of the .git directory.
+func targetProject(repoPath, baseUrl string) string {
+	if cfg, err := gitconfig.ParseFile(filepath.Join(repoPath, "config")); err == nil {
+		if remote, ok := cfg.Get("remote", "origin", "url"); ok {
+			if project := forge.ProjectFromRemote(remote); project != "" {
+				return project
+			}
+		}
+	}
+	u, err := url.Parse(baseUrl)
+	if err != nil {
+		return ""
+	}
+	return strings.Trim(strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/.git"), "/")
+}
@@ -0,0 +1,124 @@
+package dumper
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"github.com/s3rgeym/git-dump/internal/extractor"
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+)
+
+// honeypotServerRegex matches Server and X-Powered-By headers of known web
+// honeypots and tarpits.
+var honeypotServerRegex = regexp.MustCompile(`(?i)\b(glastopf|tanner|snare|honeyhttpd|honeypy|dionaea|conpot|opencanary|heralding|wordpot|shockpot|elastichoney|t-pot|tarpit)\b`)
+
+// genericRepoCommits is the longest history of a repository which is
+// suspicious when its HEAD commit is served by unrelated hosts.
+const genericRepoCommits = 3
+
+// flagHoneypot marks a target as a likely honeypot in the report.
+func (d *Dumper) flagHoneypot(baseUrl, reason string) {
+	added := false
+	d.updateTarget(baseUrl, func(t *report.Target) {
+		if !slices.Contains(t.Honeypot, reason) {
+			t.Honeypot = append(t.Honeypot, reason)
+			added = true
+		}
+	})
+	if added {
+		logger.Warnf("🍯 %s looks like a honeypot: %s", baseUrl, reason)
+		if d.pausesOn("honeypot") {
+			d.pauseOnFind(baseUrl, "honeypot sign: "+reason)
+		}
+	}
+}
+
+// checkServer records the server banner of a target and flags a target whose
+// responses carry the signature of a honeypot server.
+func (d *Dumper) checkServer(baseUrl string, header http.Header) {
+	if server, poweredBy := header.Get("Server"), header.Get("X-Powered-By"); server != "" || poweredBy != "" {
+		// Баннер первого ответа группирует цели в отчёте
+		d.updateTarget(baseUrl, func(t *report.Target) {
+			if t.Server == "" && t.PoweredBy == "" {
+				t.Server, t.PoweredBy = server, poweredBy
+			}
+		})
+	}
+	for _, name := range []string{"Server", "X-Powered-By"} {
+		if m := honeypotServerRegex.FindString(header.Get(name)); m != "" {
+			d.flagHoneypot(baseUrl, fmt.Sprintf("%s header of a honeypot: %s", name, header.Get(name)))
+		}
+	}
+}
+
+// checkHoneypot looks for canary tokens in the restored working tree and for
+// a repository of a few commits whose HEAD is also served by an unrelated
+// host, which is typical for planted repositories.
+func (d *Dumper) checkHoneypot(target *report.Target) {
+	worktree := filepath.Dir(target.RepoPath)
+	filepath.WalkDir(worktree, func(fileName string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		matches, err := extractor.CanaryTokens(fileName)
+		if err != nil || len(matches) == 0 {
+			return nil
+		}
+		rel, _ := filepath.Rel(worktree, fileName)
+		for _, m := range matches {
+			d.flagHoneypot(target.Url, fmt.Sprintf("canary token host %s in %s", m, filepath.ToSlash(rel)))
+		}
+		return nil
+	})
+
+	repo, err := gitobj.Open(target.RepoPath)
+	if err != nil {
+		return
+	}
+	head, err := repo.Head()
+	// Развёрнутый клон популярного проекта тоже встречается на многих сайтах,
+	// но у него длинная история; подброшенный репозиторий обычно из пары коммитов
+	short := err == nil && len(repo.Log([]string{head}, genericRepoCommits+1)) <= genericRepoCommits
+	repo.Close()
+	if !short {
+		return
+	}
+	host := hostOf(target.Url)
+	d.mu.Lock()
+	var unrelated []string
+	for _, other := range d.heads[head] {
+		if baseDomain(hostOf(other)) != baseDomain(host) {
+			unrelated = append(unrelated, other)
+		}
+	}
+	d.heads[head] = append(d.heads[head], target.Url)
+	d.mu.Unlock()
+	for _, other := range unrelated {
+		d.flagHoneypot(target.Url, fmt.Sprintf("HEAD commit %s is also served by %s", head, other))
+		d.flagHoneypot(other, fmt.Sprintf("HEAD commit %s is also served by %s", head, target.Url))
+	}
+}
+
+func hostOf(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,79 @@
+package dumper
+
+import (
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+)
+
+const (
+	// maxReportTreeFiles limits the working tree files of a target listed in
+	// the -report-html file.
+	maxReportTreeFiles = 5000
+	// maxReportListing limits the size of an embedded directory listing.
+	maxReportListing = 256 << 10
+)
+
+// targetExtras returns what -report-html shows about baseUrl besides the
+// report. d.mu must be held.
+func (d *Dumper) targetExtras(baseUrl string) *report.TargetExtras {
+	e, ok := d.extras[baseUrl]
+	if !ok {
+		e = &report.TargetExtras{}
+		d.extras[baseUrl] = e
+	}
+	return e
+}
+
+// keepListing saves the directory listing page of .git/ of a target for
+// -report-html-listings.
+func (d *Dumper) keepListing(baseUrl, page string) {
+	if len(page) > maxReportListing {
+		page = page[:maxReportListing]
+	}
+	d.mu.Lock()
+	d.targetExtras(baseUrl).Listing = page
+	d.mu.Unlock()
+}
+
+// setReportTree saves the working tree files of a target for -report-html.
+func (d *Dumper) setReportTree(baseUrl string, files []report.TreeEntry) {
+	omitted := 0
+	if len(files) > maxReportTreeFiles {
+		files, omitted = files[:maxReportTreeFiles], len(files)-maxReportTreeFiles
+	}
+	d.mu.Lock()
+	e := d.targetExtras(baseUrl)
+	e.Tree, e.Omitted = files, omitted
+	d.mu.Unlock()
+}
+
+// writeHtmlReport writes the -report-html file if it is set.
+func (d *Dumper) writeHtmlReport() {
+	if d.config.ReportHtml == "" {
+		return
+	}
+	r := d.Report()
+	d.mu.Lock()
+	extras := make(map[string]*report.TargetExtras, len(d.extras))
+	for baseUrl, e := range d.extras {
+		extras[baseUrl] = e
+	}
+	d.mu.Unlock()
+	if err := r.WriteHTMLFile(d.config.ReportHtml, extras); err != nil {
+		logger.Errorf("Failed to write HTML report: %v", err)
+		return
+	}
+	logger.Infof("HTML report saved to %s", d.config.ReportHtml)
+}
+
+// writeCsvReport writes the -report-csv file if it is set.
+func (d *Dumper) writeCsvReport() {
+	if d.config.ReportCsv == "" {
+		return
+	}
+	if err := d.Report().WriteCSVFile(d.config.ReportCsv); err != nil {
+		logger.Errorf("Failed to write CSV summary: %v", err)
+		return
+	}
+	logger.Infof("CSV summary saved to %s", d.config.ReportCsv)
+}
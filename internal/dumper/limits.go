@@ -0,0 +1,132 @@
+package dumper
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+	"github.com/s3rgeym/git-dump/internal/utils"
+)
+
+// hostUsage counts what was fetched from a single host.
+type hostUsage struct {
+	requests int
+	objects  int
+	bytes    int64
+	started  time.Time
+}
+
+// allowFetch counts a request to the host of targetUrl and reports whether it
+// is within -max-requests-per-host, -max-objects-per-host, -max-total-bytes,
+// -max-host-bytes, -deadline and -host-deadline. When a limit is hit the
+// target is marked as truncated and the URL is counted as skipped.
+// URLs disallowed by robots.txt with -respect-robots, URLs of parked hosts,
+// URLs of aliases of other targets and URLs of targets the operator stopped
+// after a finding are skipped too.
+func (d *Dumper) allowFetch(targetUrl, baseUrl string) bool {
+	d.waitResumed()
+	u, err := url.Parse(targetUrl)
+	if err != nil {
+		return false
+	}
+	if _, parked := d.parkedHost(targetUrl); parked {
+		return false
+	}
+	if d.aliasOf(baseUrl) != "" {
+		return false
+	}
+	if !d.robotsAllowed(targetUrl, baseUrl) {
+		return false
+	}
+	if !d.checkSpace() {
+		d.updateTarget(baseUrl, func(t *report.Target) {
+			if !t.Truncated {
+				t.Truncated = true
+				t.Notes = append(t.Notes, "low disk space")
+			}
+		})
+		return false
+	}
+	_, isObject := utils.PathToSha1(targetUrl)
+
+	d.mu.Lock()
+	usage, ok := d.hostUsage[u.Host]
+	if !ok {
+		usage = &hostUsage{started: time.Now()}
+		d.hostUsage[u.Host] = usage
+	}
+	var reason string
+	switch {
+	case d.halted != "":
+		reason = d.halted
+	case d.stopped[baseUrl] != "":
+		reason = d.stopped[baseUrl]
+	case !d.deadline.IsZero() && time.Now().After(d.deadline):
+		reason = fmt.Sprintf("run deadline of %s exceeded", d.config.Deadline)
+	case d.config.HostDeadline > 0 && time.Since(usage.started) > d.config.HostDeadline:
+		reason = fmt.Sprintf("host deadline of %s exceeded for %s", d.config.HostDeadline, u.Host)
+	case d.config.MaxHostRequests > 0 && usage.requests >= d.config.MaxHostRequests:
+		reason = fmt.Sprintf("request cap of %d reached for %s", d.config.MaxHostRequests, u.Host)
+	case isObject && d.config.MaxHostObjects > 0 && usage.objects >= d.config.MaxHostObjects:
+		reason = fmt.Sprintf("object cap of %d reached for %s", d.config.MaxHostObjects, u.Host)
+	case d.config.MaxTotalBytes > 0 && d.totalBytes >= int64(d.config.MaxTotalBytes):
+		reason = fmt.Sprintf("byte budget of %s reached", &d.config.MaxTotalBytes)
+	case d.config.MaxHostBytes > 0 && usage.bytes >= int64(d.config.MaxHostBytes):
+		reason = fmt.Sprintf("byte budget of %s reached for %s", &d.config.MaxHostBytes, u.Host)
+	default:
+		usage.requests++
+		if isObject {
+			usage.objects++
+		}
+	}
+	d.mu.Unlock()
+
+	if reason == "" {
+		return true
+	}
+	logger.Debugf("Skipping %s: %s", targetUrl, reason)
+	d.updateTarget(baseUrl, func(t *report.Target) {
+		if !t.Truncated {
+			logger.Warnf("Truncating %s: %s", baseUrl, reason)
+			t.Truncated = true
+			t.Notes = append(t.Notes, reason)
+		}
+		t.Skipped++
+	})
+	return false
+}
+
+// addBytes counts n bytes received for targetUrl towards -max-total-bytes
+// and -max-host-bytes. Downloads already running finish, so a budget can be
+// exceeded by their size.
+func (d *Dumper) addBytes(targetUrl, baseUrl string, n int64) {
+	u, err := url.Parse(targetUrl)
+	if err != nil {
+		return
+	}
+	d.mu.Lock()
+	if usage, ok := d.hostUsage[u.Host]; ok {
+		usage.bytes += n
+	}
+	before := d.totalBytes
+	d.totalBytes += n
+	total, targets := d.totalBytes, len(d.report.Targets)
+	budget := d.config.MaxTotalBytes
+	d.mu.Unlock()
+	d.updateTarget(baseUrl, func(t *report.Target) { t.Bytes += n })
+
+	// Сводка сразу, а не в конце: восстановление может идти ещё долго
+	if budget > 0 && before < int64(budget) && total >= int64(budget) {
+		logger.Warnf("Byte budget of %s reached: %d bytes received from %d targets; no new downloads are started, restoring what was collected",
+			&budget, total, targets)
+	}
+}
+
+// pastDeadline reports whether the -deadline of the run is exceeded.
+func (d *Dumper) pastDeadline() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return !d.deadline.IsZero() && time.Now().After(d.deadline)
+}
@@ -0,0 +1,154 @@
+package dumper
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+)
+
+// listingLoopLimit is how many links to already crawled paths, path variants
+// and loops the listings of a target may have before its crawl is abandoned.
+const listingLoopLimit = 100
+
+// listingCrawl tracks the directory listing crawl of a target.
+type listingCrawl struct {
+	visited map[string]bool // Канонические пути страниц и ссылок
+	pages   int
+	loops   int
+	aborted bool
+}
+
+// canonicalPath returns the decoded and cleaned path of a URL without the
+// query and trailing slash, so variants of one path compare equal.
+func canonicalPath(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+	return path.Clean("/" + u.Path)
+}
+
+// crawl returns the listing crawl state of baseUrl. d.mu must be held.
+func (d *Dumper) crawl(baseUrl string) *listingCrawl {
+	c, ok := d.crawls[baseUrl]
+	if !ok {
+		c = &listingCrawl{visited: make(map[string]bool)}
+		d.crawls[baseUrl] = c
+	}
+	return c
+}
+
+// startListing counts a listing page of baseUrl and reports whether its
+// links should be followed.
+func (d *Dumper) startListing(pageUrl, baseUrl string) bool {
+	d.mu.Lock()
+	c := d.crawl(baseUrl)
+	if c.aborted {
+		d.mu.Unlock()
+		return false
+	}
+	c.pages++
+	c.visited[canonicalPath(pageUrl)] = true
+	exceeded := d.config.MaxListings > 0 && c.pages > d.config.MaxListings
+	d.mu.Unlock()
+	if exceeded {
+		d.abortListing(baseUrl, fmt.Sprintf("more than %d listing pages", d.config.MaxListings))
+		return false
+	}
+	return true
+}
+
+// countLoop counts a link which points to a crawled path or loops, and
+// abandons the crawl of baseUrl once there are too many.
+func (d *Dumper) countLoop(baseUrl string) {
+	d.mu.Lock()
+	c := d.crawl(baseUrl)
+	c.loops++
+	exceeded := c.loops > listingLoopLimit
+	d.mu.Unlock()
+	if exceeded {
+		d.abortListing(baseUrl, fmt.Sprintf("more than %d looping links", listingLoopLimit))
+	}
+}
+
+// abortListing stops following listing links of baseUrl. Files already
+// queued are still fetched.
+func (d *Dumper) abortListing(baseUrl, reason string) {
+	d.mu.Lock()
+	c := d.crawl(baseUrl)
+	aborted := c.aborted
+	c.aborted = true
+	d.mu.Unlock()
+	if aborted {
+		return
+	}
+	logger.Warnf("Abandoning directory listings of %s: %s", baseUrl, reason)
+	d.updateTarget(baseUrl, func(t *report.Target) {
+		t.Notes = append(t.Notes, "listing crawl aborted: "+reason)
+	})
+}
+
+// followListingLink reports whether a link from a directory listing should be
+// crawled: it must stay inside the .git directory, be within -max-depth, not
+// look like a symlink loop and not lead to a path crawled before.
+func (d *Dumper) followListingLink(linkUrl, baseUrl string) bool {
+	if !strings.HasPrefix(linkUrl, baseUrl) {
+		// Ссылки на родительский каталог и другие сайты
+		return false
+	}
+	rel := strings.TrimPrefix(linkUrl, baseUrl)
+	if rel == "" {
+		return false
+	}
+	// Нормализуем путь, чтобы a//b и a/./b не обходились повторно
+	if path.Clean("/"+rel) != "/"+strings.TrimSuffix(rel, "/") {
+		d.countLoop(baseUrl)
+		return false
+	}
+	segments := strings.Split(strings.Trim(rel, "/"), "/")
+	if d.config.MaxDepth > 0 && len(segments) > d.config.MaxDepth {
+		logger.Debugf("Skipping %s: deeper than %d", linkUrl, d.config.MaxDepth)
+		return false
+	}
+	if hasCycle(segments) {
+		logger.Warnf("Skipping %s: looks like a directory loop", linkUrl)
+		d.countLoop(baseUrl)
+		return false
+	}
+	// a и a/, а также %61 и a — один и тот же путь
+	canonical := canonicalPath(linkUrl)
+	d.mu.Lock()
+	c := d.crawl(baseUrl)
+	visited, aborted := c.visited[canonical], c.aborted
+	c.visited[canonical] = true
+	d.mu.Unlock()
+	if aborted {
+		return false
+	}
+	if visited {
+		logger.Debugf("Skipping %s: %s was already crawled", linkUrl, canonical)
+		d.countLoop(baseUrl)
+		return false
+	}
+	return true
+}
+
+// hasCycle reports whether the path ends with the same sequence of segments
+// repeated three times, e.g. a/b/a/b/a/b, which is typical for symlink loops.
+func hasCycle(segments []string) bool {
+	n := len(segments)
+	for k := 1; k*3 <= n; k++ {
+		cycle := true
+		for i := n - 2*k; i < n && cycle; i++ {
+			cycle = segments[i] == segments[i-k]
+		}
+		if cycle {
+			return true
+		}
+	}
+	return false
+}
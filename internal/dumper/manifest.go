@@ -0,0 +1,172 @@
+package dumper
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/httpclient"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/utils"
+)
+
+const manifestName = ".git-dump-manifest.jsonl"
+
+// manifestEntry records a completely fetched file.
+type manifestEntry struct {
+	Path      string    `json:"path"` // Относительно каталога вывода
+	Url       string    `json:"url"`
+	Size      int64     `json:"size"`
+	Sha256    string    `json:"sha256"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// manifest tracks size and hash of fetched files, so files truncated or
+// replaced by error pages are fetched again instead of being skipped.
+type manifest struct {
+	mu       sync.Mutex
+	dir      string
+	fileName string
+	entries  map[string]manifestEntry
+}
+
+func loadManifest(dir string) *manifest {
+	m := &manifest{dir: dir, fileName: filepath.Join(dir, manifestName), entries: make(map[string]manifestEntry)}
+	f, err := os.Open(m.fileName)
+	if err != nil {
+		return m
+	}
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines++
+		var entry manifestEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil {
+			// Последняя запись о файле актуальна
+			m.entries[entry.Path] = entry
+		}
+	}
+	f.Close()
+
+	// Повторные скачивания дописывают строки, поэтому файл сжимается при загрузке
+	if lines > len(m.entries) {
+		if err := m.compact(); err != nil {
+			logger.Warnf("Failed to compact manifest %s: %v", m.fileName, err)
+		}
+	}
+	return m
+}
+
+// compact rewrites the manifest with a single line per file.
+func (m *manifest) compact() error {
+	paths := make([]string, 0, len(m.entries))
+	for path := range m.entries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	for _, path := range paths {
+		data, err := json.Marshal(m.entries[path])
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	tmpName := m.fileName + httpclient.PartSuffix
+	if err := os.WriteFile(tmpName, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, m.fileName); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+// valid reports whether fileName exists and matches its manifest entry. Loose
+// objects without an entry are accepted if their content matches the hash.
+func (m *manifest) valid(fileName string) bool {
+	fi, err := os.Stat(fileName)
+	if err != nil || fi.IsDir() {
+		return false
+	}
+	rel, err := filepath.Rel(m.dir, fileName)
+	if err != nil {
+		return false
+	}
+
+	m.mu.Lock()
+	entry, ok := m.entries[filepath.ToSlash(rel)]
+	m.mu.Unlock()
+	if !ok {
+		return validObject(fileName)
+	}
+	if entry.Size != fi.Size() {
+		return false
+	}
+	hash, err := fileSha256(fileName)
+	return err == nil && hash == entry.Sha256
+}
+
+// add records a file which was just fetched completely.
+func (m *manifest) add(fileName, url string) {
+	rel, err := filepath.Rel(m.dir, fileName)
+	if err != nil {
+		return
+	}
+	entry := manifestEntry{Path: filepath.ToSlash(rel), Url: url, FetchedAt: time.Now().UTC()}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return
+	}
+	h := sha256.New()
+	entry.Size, err = io.Copy(h, f)
+	f.Close()
+	if err != nil {
+		return
+	}
+	entry.Sha256 = hex.EncodeToString(h.Sum(nil))
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[entry.Path] = entry
+	out, err := os.OpenFile(m.fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		logger.Errorf("Failed to open manifest %s: %v", m.fileName, err)
+		return
+	}
+	defer out.Close()
+	if _, err := out.Write(append(data, '\n')); err != nil {
+		logger.Errorf("Failed to write manifest %s: %v", m.fileName, err)
+	}
+}
+
+func validObject(fileName string) bool {
+	hash, ok := utils.PathToSha1(filepath.ToSlash(fileName))
+	if !ok {
+		return false
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return false
+	}
+	objType, body, err := gitobj.DecodeLoose(data)
+	return err == nil && gitobj.HashObject(objType, body) == hash
+}
@@ -0,0 +1,42 @@
+package dumper
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+const (
+	verdictExposed    = "exposed"
+	verdictNotExposed = "not-exposed"
+)
+
+// checkExposure answers for every target whether its .git is exposed
+// (-memory). Probes are the ones of -verify, but their bodies are analyzed in
+// memory only: nothing is saved, and a "<verdict> TAB <url>" line is written
+// to w as soon as a target is checked. The report is written only with -report.
+func (d *Dumper) checkExposure(w io.Writer, urlList []string) {
+	logger.Info("Memory mode: nothing is written to disk")
+
+	d.verifyTargets(urlList, func(baseUrl string) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		verdict := verdictNotExposed
+		if t, ok := d.targets[baseUrl]; ok && t.Exposed {
+			verdict = verdictExposed
+		}
+		fmt.Fprintf(w, "%s\t%s\n", verdict, baseUrl)
+	})
+
+	d.writeHtmlReport()
+	d.writeCsvReport()
+	if d.config.ReportFile == "" {
+		return
+	}
+	if err := d.Report().WriteFile(d.config.ReportFile); err != nil {
+		logger.Errorf("Failed to write report: %v", err)
+		return
+	}
+	logger.Infof("Report saved to %s", d.config.ReportFile)
+}
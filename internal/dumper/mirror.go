@@ -0,0 +1,165 @@
+package dumper
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/fastexport"
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+	"github.com/s3rgeym/git-dump/internal/utils"
+)
+
+// MirrorFields are the values available to -mirror-to templates.
+type MirrorFields struct {
+	utils.LayoutFields
+	Path string // Путь репозитория на сайте без .git, с "-" вместо "/"
+}
+
+func parseMirrorTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("mirror-to").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	layout, _ := utils.ParseLayout("", time.Now())
+	if _, err := executeMirror(tmpl, layout, "https://example.com/.git/"); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+func executeMirror(tmpl *template.Template, layout *utils.Layout, baseUrl string) (string, error) {
+	u, err := url.Parse(baseUrl)
+	if err != nil {
+		return "", err
+	}
+	path := strings.Trim(strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), ".git"), "/")
+	fields := MirrorFields{LayoutFields: layout.Fields(u), Path: strings.ReplaceAll(path, "/", "-")}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, fields); err != nil {
+		return "", err
+	}
+	remote := strings.TrimSpace(sb.String())
+	if remote == "" || strings.HasPrefix(remote, "-") {
+		return "", fmt.Errorf("invalid remote %q", remote)
+	}
+	return remote, nil
+}
+
+// redactRemote hides the password of a remote URL in logs and reports.
+func redactRemote(remote string) string {
+	if u, err := url.Parse(remote); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return remote
+}
+
+// mirrorTarget pushes all refs of a dumped repository to the -mirror-to
+// remote. The push runs from a temporary repository which borrows the dumped
+// objects through alternates, so the dumped config (url.insteadOf, hooks)
+// can't affect it. If objects are missing, the history rebuilt by fastexport
+// is pushed instead.
+func (d *Dumper) mirrorTarget(target *report.Target) {
+	remote, err := executeMirror(d.mirror, d.layout, target.Url)
+	if err != nil {
+		logger.Errorf("Failed to build -mirror-to remote for %s: %v", target.Url, err)
+		return
+	}
+	gitDir := target.RepoPath
+	repo, err := gitobj.Open(gitDir)
+	if err != nil {
+		logger.Errorf("Failed to open %s for mirroring: %v", gitDir, err)
+		return
+	}
+	defer repo.Close()
+	refs, err := repo.Refs()
+	if err != nil || len(refs) == 0 {
+		logger.Infof("Not mirroring %s: no refs were recovered", target.Url)
+		return
+	}
+
+	tmpDir, err := os.MkdirTemp("", "git-dump-mirror-")
+	if err != nil {
+		logger.Errorf("Failed to create temporary directory: %v", err)
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	note := "mirrored to " + redactRemote(remote)
+	err = pushDumped(gitDir, refs, filepath.Join(tmpDir, "dumped.git"), remote)
+	if err != nil {
+		logger.Warnf("Failed to push %s as dumped, pushing rebuilt history: %v", target.Url, err)
+		rebuilt := filepath.Join(tmpDir, "rebuilt.git")
+		if _, err = fastexport.ImportBare(repo, rebuilt, fastexport.HeadRef(gitDir)); err == nil {
+			err = pushMirror(rebuilt, remote)
+		}
+		note = "rebuilt history mirrored to " + redactRemote(remote)
+	}
+	if err != nil {
+		logger.Errorf("Failed to mirror %s to %s: %v", target.Url, redactRemote(remote), err)
+		d.updateTarget(target.Url, func(t *report.Target) {
+			t.Notes = append(t.Notes, "mirroring failed")
+		})
+		return
+	}
+	logger.Infof("Mirrored %s to %s", target.Url, redactRemote(remote))
+	d.updateTarget(target.Url, func(t *report.Target) {
+		t.Notes = append(t.Notes, note)
+	})
+}
+
+// pushDumped creates a bare repository at dir with the refs of gitDir and
+// its objects as alternates, then pushes it.
+func pushDumped(gitDir string, refs map[string]string, dir, remote string) error {
+	if out, err := exec.Command("git", "init", "--bare", "--quiet", dir).CombinedOutput(); err != nil {
+		return fmt.Errorf("git init: %w: %s", err, out)
+	}
+	objectsDir, err := filepath.Abs(filepath.Join(gitDir, "objects"))
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "objects", "info", "alternates"), []byte(objectsDir+"\n"), 0644); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		// Имена ссылок взяты с сайта, git отверг бы packed-refs с неверным именем
+		if strings.HasPrefix(name, "refs/") && validRefName(name) && !strings.ContainsAny(name, " ~^:?*[\t\n") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	sb.WriteString("# pack-refs with: sorted\n")
+	for _, name := range names {
+		fmt.Fprintf(&sb, "%s %s\n", refs[name], name)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "packed-refs"), []byte(sb.String()), 0644); err != nil {
+		return err
+	}
+	if headRef := fastexport.HeadRef(gitDir); headRef != "" && refs[headRef] != "" {
+		if err := os.WriteFile(filepath.Join(dir, "HEAD"), []byte("ref: "+headRef+"\n"), 0644); err != nil {
+			return err
+		}
+	}
+	return pushMirror(dir, remote)
+}
+
+// pushMirror pushes all refs of a bare repository, removing other refs from
+// the remote.
+func pushMirror(dir, remote string) error {
+	cmd := exec.Command("git", "--git-dir="+dir, "push", "--mirror", "--quiet", "--", remote)
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "GIT_TERMINAL_PROMPT=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git push: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
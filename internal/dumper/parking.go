@@ -0,0 +1,75 @@
+package dumper
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+)
+
+// parkingHosts are domains of parking services and registrar landers that
+// parked domains redirect to.
+var parkingHosts = []string{
+	"sedoparking.com", "sedo.com", "bodis.com", "parkingcrew.net", "above.com",
+	"dan.com", "afternic.com", "hugedomains.com", "undeveloped.com", "parklogic.com",
+	"domainmarket.com", "namebright.com", "parkingpage.namecheap.com", "porkbun.com",
+	"uniregistry.com", "skenzo.com", "voodoo.com", "fabulous.com",
+}
+
+// parkingRegex matches the text and scripts of parking, for-sale and
+// registrar or hosting placeholder pages.
+var parkingRegex = regexp.MustCompile(`(?i)(this domain (name )?(is|may be) (parked|for sale)|domain is for sale|buy this domain|this domain has (just )?been registered|parked free,? courtesy of|parkingcrew|sedoparking|window\.park\s*=|/lander\b|future home of something quite cool|parked domain name on hostinger|this site is not published yet|domain has expired|account has been suspended)`)
+
+// parkingReason returns why a page looks like a parking or placeholder page,
+// or an empty string.
+func parkingReason(resp *http.Response, body string) string {
+	if resp.Request != nil {
+		host := strings.ToLower(resp.Request.URL.Hostname())
+		for _, parking := range parkingHosts {
+			if host == parking || strings.HasSuffix(host, "."+parking) {
+				return "redirected to parking service " + host
+			}
+		}
+	}
+	if m := parkingRegex.FindString(body); m != "" {
+		return "placeholder page: " + strings.ToLower(m)
+	}
+	return ""
+}
+
+// markParked stops all requests to the host of baseUrl, whose pages are
+// parking or placeholder pages rather than a site.
+func (d *Dumper) markParked(baseUrl, reason string) {
+	u, err := url.Parse(baseUrl)
+	if err != nil {
+		return
+	}
+	d.mu.Lock()
+	_, known := d.parked[u.Host]
+	if !known {
+		d.parked[u.Host] = reason
+	}
+	d.mu.Unlock()
+	if known {
+		return
+	}
+	logger.Warnf("Skipping host %s: %s", u.Host, reason)
+	d.updateTarget(baseUrl, func(t *report.Target) {
+		t.NotApplicable = reason
+	})
+}
+
+// parkedHost returns why requests to the host of rawUrl are skipped.
+func (d *Dumper) parkedHost(rawUrl string) (string, bool) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	reason, ok := d.parked[u.Host]
+	return reason, ok
+}
@@ -0,0 +1,53 @@
+package dumper
+
+import (
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/utils"
+)
+
+// In -passive mode only the .git directory itself is probed. If it is a
+// listing, everything is crawled from listings; otherwise only HEAD and files
+// referenced by fetched content are requested.
+
+// markListed remembers that the .git directory of baseUrl has a listing.
+func (d *Dumper) markListed(baseUrl string) {
+	d.mu.Lock()
+	d.listed[baseUrl] = true
+	d.mu.Unlock()
+}
+
+func (d *Dumper) isListed(baseUrl string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.listed[baseUrl]
+}
+
+// probeHeadUnlessListed falls back to HEAD when the .git directory of baseUrl
+// has no listing.
+func (d *Dumper) probeHeadUnlessListed(baseUrl string) {
+	if d.isListed(baseUrl) {
+		return
+	}
+	if headUrl, err := utils.UrlJoin(baseUrl, "HEAD"); err == nil {
+		d.spawn(headUrl, baseUrl)
+	}
+}
+
+// passiveFilter drops URLs guessed rather than referenced: reflogs of found
+// refs and, when a listing shows which objects exist, loose object paths
+// derived from hashes.
+func (d *Dumper) passiveFilter(gitUrls []string, baseUrl string) []string {
+	listed := d.isListed(baseUrl)
+	filtered := gitUrls[:0]
+	for _, u := range gitUrls {
+		if strings.HasPrefix(u, baseUrl+"logs/") {
+			continue
+		}
+		if _, ok := utils.PathToSha1(u); ok && listed {
+			continue
+		}
+		filtered = append(filtered, u)
+	}
+	return filtered
+}
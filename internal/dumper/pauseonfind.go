@@ -0,0 +1,125 @@
+package dumper
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+// Conditions of -pause-on-find besides finding kinds.
+const (
+	PauseSecrets = "secrets" // Учётные данные и файлы, похожие на секреты
+	PauseAny     = "any"
+
+	// notableFileKind is the kind of a restored file classified as a secret.
+	notableFileKind = "notable-file"
+)
+
+// pausesOn reports whether -pause-on-find asks to pause on a finding of kind.
+func (d *Dumper) pausesOn(kind string) bool {
+	for _, cond := range strings.Split(d.config.PauseOnFind, ",") {
+		switch cond = strings.TrimSpace(cond); cond {
+		case "":
+		case PauseAny:
+			return true
+		case PauseSecrets:
+			if kind == "credential" || kind == notableFileKind {
+				return true
+			}
+		default:
+			if cond == kind {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pauseOnFind pauses the dump after what was found in baseUrl and lets the
+// operator decide whether to go on. On a terminal the operator is asked to
+// continue, stop requests of the target or stop the run; otherwise the dump
+// stays paused until SIGUSR2. The operator is asked once per target.
+func (d *Dumper) pauseOnFind(baseUrl, what string) {
+	d.findMu.Lock()
+	defer d.findMu.Unlock()
+	d.mu.Lock()
+	decided := d.decided[baseUrl] || d.halted != ""
+	d.decided[baseUrl] = true
+	d.mu.Unlock()
+	if decided {
+		return
+	}
+
+	// Уровень логирования по умолчанию скрывает предупреждения, а оператор должен увидеть паузу
+	if !d.canPrompt() {
+		if runtime.GOOS == "windows" {
+			// Без терминала и SIGUSR2 снять паузу было бы нечем
+			fmt.Fprintf(os.Stderr, "\a🔔 Found %s at %s; not pausing without a terminal\n", what, baseUrl)
+			return
+		}
+		if d.pause() {
+			fmt.Fprintf(os.Stderr, "\a⏸ Paused after %s at %s: send SIGUSR2 to resume, e.g. kill -USR2 %d\n", what, baseUrl, os.Getpid())
+		} else {
+			fmt.Fprintf(os.Stderr, "\a🔔 Found %s at %s while paused\n", what, baseUrl)
+		}
+		return
+	}
+
+	paused := d.pause()
+	fmt.Fprintf(os.Stderr, "\a⏸ Paused after %s at %s\n", what, baseUrl)
+	for {
+		fmt.Fprint(os.Stderr, "Continue [c], stop this target [s] or stop the run [q]? ")
+		answer, err := d.stdin.ReadString('\n')
+		if err != nil {
+			// Терминал закрыт: дальше пауза снимается только по SIGUSR2
+			fmt.Fprintf(os.Stderr, "\nNo answer from the terminal: %v; continuing\n", err)
+			d.stdin, d.noPrompt = nil, true
+			answer = "c"
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "c", "continue", "":
+		case "s", "stop":
+			logger.Warnf("Stopping %s by request of the operator", baseUrl)
+			d.mu.Lock()
+			d.stopped[baseUrl] = "stopped by the operator after " + what
+			d.mu.Unlock()
+		case "q", "quit":
+			logger.Warnf("Stopping the run by request of the operator; restoring what was collected")
+			d.mu.Lock()
+			d.halted = "run stopped by the operator after " + what
+			d.mu.Unlock()
+		default:
+			continue
+		}
+		break
+	}
+	// Паузу, поставленную по SIGUSR2, снимает тоже SIGUSR2
+	if paused {
+		d.resume()
+	}
+}
+
+// canPrompt reports whether the operator can be asked on the terminal. Stdin
+// holding the URL list can't be used for answers. Called with findMu held.
+func (d *Dumper) canPrompt() bool {
+	if d.stdin != nil {
+		return true
+	}
+	if d.noPrompt || d.config.InputFile == "-" {
+		return false
+	}
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	// /dev/null тоже символьное устройство
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(info, null) {
+		return false
+	}
+	d.stdin = bufio.NewReader(os.Stdin)
+	return true
+}
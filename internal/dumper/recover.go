@@ -0,0 +1,96 @@
+package dumper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/db"
+	"github.com/s3rgeym/git-dump/internal/fsutil"
+	"github.com/s3rgeym/git-dump/internal/gitindex"
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+	"github.com/s3rgeym/git-dump/internal/store"
+)
+
+// recoverSkippedFiles writes working tree files which are excluded from HTTP
+// download (e.g. .php, which the server would execute) from the object store.
+// Files whose blobs were not fetched are noted in the report.
+func (d *Dumper) recoverSkippedFiles(target *report.Target) {
+	gitIndex, err := gitindex.ParseGitIndex(filepath.Join(target.RepoPath, "index"))
+	if err != nil {
+		return
+	}
+	repo, err := gitobj.Open(target.RepoPath)
+	if err != nil {
+		return
+	}
+	defer repo.Close()
+
+	worktree := filepath.Dir(target.RepoPath)
+	var recovered int
+	var missing []string
+	for _, entry := range gitIndex.Files() {
+		if d.filter.allow(entry.FileName, int64(entry.Size)) || d.restore.Match(entry.FileName) {
+			continue
+		}
+		fileName := filepath.Join(worktree, filepath.FromSlash(entry.FileName))
+		// Имена из индекса контролирует сервер
+		if !strings.HasPrefix(fileName, worktree+string(filepath.Separator)) || strings.Contains(entry.FileName, ".git/") {
+			continue
+		}
+		if fi, err := os.Lstat(fileName); err == nil && !fi.IsDir() {
+			continue
+		}
+		// У подмодулей нет blob-объекта, они попадают в отчёт при восстановлении
+		if entry.IntentToAdd() || entry.Mode&fsutil.ModeTypeMask == fsutil.ModeGitlink {
+			continue
+		}
+		// Каталог мог оказаться символической ссылкой из репозитория
+		if fsutil.SymlinkInPath(worktree, fileName) {
+			logger.Warnf("Not recovering %s: a directory in its path is a symlink", entry.FileName)
+			continue
+		}
+		obj, err := repo.ReadObject(entry.Sha1)
+		if err != nil || obj.Type != gitobj.TypeBlob {
+			// Файлы вне sparse checkout могли и не существовать на сервере
+			if !entry.SkipWorktree() {
+				missing = append(missing, entry.FileName)
+			}
+			continue
+		}
+		if err := d.writeGitFile(fileName, entry.Mode, obj.Data); err != nil {
+			logger.Errorf("Failed to write %s: %v", fileName, err)
+			continue
+		}
+		recovered++
+		d.record(func(database *db.DB) error { return database.AddFile(target.Url, entry.FileName, "object") })
+	}
+
+	if recovered > 0 {
+		logger.Infof("Recovered %d skipped files of %s from objects", recovered, target.Url)
+	}
+	for _, name := range missing {
+		logger.Warnf("Source of %s in %s could not be recovered: blob was not fetched", name, target.Url)
+	}
+	if len(missing) > 0 {
+		d.updateTarget(target.Url, func(t *report.Target) {
+			for _, name := range missing {
+				t.Notes = append(t.Notes, "not recovered: "+name)
+			}
+		})
+	}
+}
+
+// writeGitFile writes a blob recovered from objects to the working tree.
+// Symlinks are created on disk, other files are saved through the store.
+func (d *Dumper) writeGitFile(fileName string, mode uint32, data []byte) error {
+	if mode&fsutil.ModeTypeMask == fsutil.ModeSymlink && d.config.AllowSymlinks {
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			return err
+		}
+		return fsutil.WriteGitFile(fileName, mode, data, true)
+	}
+	return store.WriteFile(d.store, fileName, data, fsutil.FilePerm(mode))
+}
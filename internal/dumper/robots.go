@@ -0,0 +1,79 @@
+package dumper
+
+import (
+	"io"
+	"net/url"
+	"sync"
+
+	"github.com/s3rgeym/git-dump/internal/httpclient"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+	"github.com/s3rgeym/git-dump/internal/robots"
+)
+
+const maxRobotsSize = 512 << 10
+
+// robotsEntry holds robots.txt rules of an origin, fetched once.
+type robotsEntry struct {
+	once  sync.Once
+	rules *robots.Rules
+}
+
+// robotsAllowed reports whether -respect-robots lets targetUrl be fetched.
+// Skipped URLs are counted in the report of the target.
+func (d *Dumper) robotsAllowed(targetUrl, baseUrl string) bool {
+	if !d.config.RespectRobots {
+		return true
+	}
+	u, err := url.Parse(targetUrl)
+	if err != nil {
+		return false
+	}
+	origin, err := httpclient.OriginKey(targetUrl)
+	if err != nil {
+		return false
+	}
+	d.mu.Lock()
+	entry, ok := d.robots[origin]
+	if !ok {
+		entry = &robotsEntry{}
+		d.robots[origin] = entry
+	}
+	d.mu.Unlock()
+	entry.once.Do(func() { entry.rules = d.fetchRobots(u) })
+
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	if entry.rules.Allowed(path) {
+		return true
+	}
+	logger.Debugf("Skipping %s: disallowed by robots.txt", targetUrl)
+	d.updateTarget(baseUrl, func(t *report.Target) {
+		if t.RobotsDisallowed == 0 {
+			logger.Warnf("robots.txt of %s disallows some paths of %s, skipping them", origin, baseUrl)
+		}
+		t.RobotsDisallowed++
+	})
+	return false
+}
+
+// fetchRobots returns rules of robots.txt at the root of u's site. A missing
+// or unreadable file allows everything.
+func (d *Dumper) fetchRobots(u *url.URL) *robots.Rules {
+	robotsUrl := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}).String()
+	resp, cancel, err := d.client.Fetch(robotsUrl)
+	if err != nil {
+		logger.Debugf("No robots.txt at %s: %v", robotsUrl, err)
+		return nil
+	}
+	defer cancel()
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
+	if err != nil {
+		logger.Debugf("Failed to read %s: %v", robotsUrl, err)
+		return nil
+	}
+	return robots.Parse(data, d.config.UserAgent)
+}
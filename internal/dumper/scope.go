@@ -0,0 +1,76 @@
+package dumper
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/extractor"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+	"golang.org/x/net/publicsuffix"
+)
+
+// addDiscoveredTargets scans targets found by extractors if -probe-remotes is
+// set and they are in scope and resolvable.
+func (d *Dumper) addDiscoveredTargets(baseUrl string, targets []string) {
+	if !d.config.ProbeRemotes {
+		return
+	}
+	for _, target := range targets {
+		// Запрос к канареечному токену оповестит владельца
+		if extractor.CanaryRegex.MatchString(target) {
+			logger.Warnf("Not probing discovered target %s: it is a canary token", target)
+			continue
+		}
+		if !inScope(target, baseUrl) {
+			logger.Debugf("Discovered target %s is out of scope of %s", target, baseUrl)
+			continue
+		}
+		u, err := url.Parse(target)
+		if err != nil {
+			continue
+		}
+		// Если имена резолвит прокси, локальный запрос DNS выдал бы цель в обход него
+		if !d.client.ResolvesNames(u.Hostname()) {
+			if _, err := net.LookupHost(u.Hostname()); err != nil {
+				logger.Debugf("Discovered target %s is not resolvable: %v", target, err)
+				continue
+			}
+		}
+		if d.addTarget(target) {
+			logger.Infof("Added discovered target %s from %s", target, baseUrl)
+			d.updateTarget(baseUrl, func(t *report.Target) {
+				t.Notes = append(t.Notes, "discovered target: "+target)
+			})
+		}
+	}
+}
+
+// inScope reports whether candidate shares the registrable domain with baseUrl.
+func inScope(candidate, baseUrl string) bool {
+	a, err := url.Parse(candidate)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(baseUrl)
+	if err != nil {
+		return false
+	}
+	return baseDomain(a.Hostname()) == baseDomain(b.Hostname())
+}
+
+// baseDomain returns the registrable domain (eTLD+1) of a host name, so
+// example.co.uk and other.co.uk are different domains. IP addresses and hosts
+// without a registrable domain are returned as is.
+func baseDomain(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
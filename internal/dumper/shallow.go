@@ -0,0 +1,153 @@
+package dumper
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+	"github.com/s3rgeym/git-dump/internal/utils"
+)
+
+// addGrafts remembers the commits of shallow or info/grafts of a target,
+// whose parents differ from the ones stored in the commit objects.
+func (d *Dumper) addGrafts(fileName, baseUrl string) {
+	grafts, err := utils.ParseGrafts(fileName)
+	if err != nil {
+		logger.Errorf("Failed to parse grafts %s: %v", fileName, err)
+		return
+	}
+	if len(grafts) == 0 {
+		return
+	}
+	shallow := strings.HasSuffix(fileName, "/shallow")
+	d.mu.Lock()
+	if d.grafts[baseUrl] == nil {
+		d.grafts[baseUrl] = make(map[string][]string)
+	}
+	for commit, parents := range grafts {
+		d.grafts[baseUrl][commit] = parents
+	}
+	d.mu.Unlock()
+
+	if shallow {
+		logger.Infof("%s is a shallow clone with %d boundary commits", baseUrl, len(grafts))
+		commits := make([]string, 0, len(grafts))
+		for commit := range grafts {
+			commits = append(commits, commit)
+		}
+		sort.Strings(commits)
+		d.updateTarget(baseUrl, func(t *report.Target) { t.Shallow = commits })
+		return
+	}
+	logger.Infof("%s has %d grafted commits", baseUrl, len(grafts))
+	d.updateTarget(baseUrl, func(t *report.Target) { t.Grafts = grafts })
+}
+
+// graftParents replaces the parents of a grafted commit in gitUrls with the
+// grafted ones, so the parents cut off by a shallow clone are not requested.
+func (d *Dumper) graftParents(fileName, baseUrl string, gitUrls []string) []string {
+	hash, ok := utils.PathToSha1(fileName)
+	if !ok {
+		return gitUrls
+	}
+	d.mu.Lock()
+	parents, grafted := d.grafts[baseUrl][hash]
+	d.mu.Unlock()
+	if !grafted {
+		return gitUrls
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return gitUrls
+	}
+	objType, body, err := gitobj.DecodeLoose(data)
+	if err != nil || objType != gitobj.TypeCommit {
+		return gitUrls
+	}
+	commit, err := gitobj.ParseCommit(hash, body)
+	if err != nil {
+		return gitUrls
+	}
+
+	drop := make(map[string]bool)
+	for _, parent := range commit.Parents {
+		if u, err := utils.UrlJoin(baseUrl, utils.Sha1ToPath(parent)); err == nil {
+			drop[u] = true
+		}
+	}
+	var ret []string
+	for _, u := range gitUrls {
+		if !drop[u] {
+			ret = append(ret, u)
+		}
+	}
+	for _, parent := range parents {
+		if u, err := utils.UrlJoin(baseUrl, utils.Sha1ToPath(parent)); err == nil {
+			ret = append(ret, u)
+		}
+	}
+	logger.Debugf("Using grafted parents %v of %s instead of %v", parents, hash, commit.Parents)
+	return ret
+}
+
+// trimExpectedMissing forgets missing objects which the repository doesn't
+// need: parents cut off by shallow or info/grafts and objects replaced by
+// refs/replace/, so they are not looked up again via web interfaces.
+func (d *Dumper) trimExpectedMissing(target *report.Target) {
+	repo, err := gitobj.Open(target.RepoPath)
+	if err != nil {
+		return
+	}
+	defer repo.Close()
+
+	expected := make(map[string]bool)
+	d.mu.Lock()
+	grafts := d.grafts[target.Url]
+	d.mu.Unlock()
+	for hash, parents := range grafts {
+		commit, err := repo.ReadCommit(hash)
+		if err != nil {
+			continue
+		}
+		for _, parent := range commit.Parents {
+			expected[parent] = true
+		}
+		for _, parent := range parents {
+			delete(expected, parent)
+		}
+	}
+
+	replacements := make(map[string]string)
+	if refs, err := repo.Refs(); err == nil {
+		for name, hash := range refs {
+			if original, ok := strings.CutPrefix(name, "refs/replace/"); ok && sha1Regex.MatchString(original) {
+				replacements[original] = hash
+				expected[original] = true
+			}
+		}
+	}
+	if len(replacements) > 0 {
+		logger.Infof("%s replaces %d objects via refs/replace/", target.Url, len(replacements))
+		d.updateTarget(target.Url, func(t *report.Target) { t.Replacements = replacements })
+	}
+	if len(expected) == 0 {
+		return
+	}
+
+	d.mu.Lock()
+	var missing []string
+	for _, hash := range d.missing[target.Url] {
+		if !expected[hash] {
+			missing = append(missing, hash)
+		}
+	}
+	trimmed := len(d.missing[target.Url]) - len(missing)
+	d.missing[target.Url] = missing
+	d.mu.Unlock()
+	if trimmed > 0 {
+		logger.Infof("%d missing objects of %s are outside its truncated or replaced history", trimmed, target.Url)
+	}
+}
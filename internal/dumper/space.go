@@ -0,0 +1,77 @@
+package dumper
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/fsutil"
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+const (
+	LowSpacePause = "pause"
+	LowSpaceAbort = "abort"
+
+	spaceCheckInterval = time.Second
+	spacePollInterval  = 10 * time.Second
+)
+
+// checkSpace reports whether the output filesystem has at least -min-free-mb
+// free. With -low-space pause it blocks until space is freed, with abort it
+// stops the run: nothing is fetched or restored afterwards, but the report and
+// the database keep what was done.
+func (d *Dumper) checkSpace() bool {
+	if d.config.MinFreeMB <= 0 {
+		return true
+	}
+
+	d.spaceMu.Lock()
+	defer d.spaceMu.Unlock()
+	if d.lowSpace {
+		return false
+	}
+	if time.Since(d.spaceCheckedAt) < spaceCheckInterval {
+		return true
+	}
+
+	paused := false
+	for {
+		d.spaceCheckedAt = time.Now()
+		free, err := fsutil.FreeSpace(existingDir(d.config.OutputDir))
+		if err != nil {
+			logger.Debugf("Failed to check free space: %v", err)
+			return true
+		}
+		if free >= uint64(d.config.MinFreeMB)<<20 {
+			if paused {
+				logger.Info("Free space is available again, resuming")
+			}
+			return true
+		}
+		if d.config.LowSpace != LowSpacePause {
+			logger.Errorf("Only %d MB free in %s, aborting", free>>20, d.config.OutputDir)
+			d.lowSpace = true
+			return false
+		}
+		if !paused {
+			logger.Warnf("Only %d MB free in %s, pausing until space is freed", free>>20, d.config.OutputDir)
+			paused = true
+		}
+		time.Sleep(spacePollInterval)
+	}
+}
+
+// existingDir returns the closest existing parent of dir.
+func existingDir(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,59 @@
+package dumper
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+)
+
+// collectTags records the annotated tags of a dumped repository with the
+// objects they finally point to. Tags usually mark release commits.
+func (d *Dumper) collectTags(target *report.Target) {
+	absRepoPath, err := filepath.Abs(target.RepoPath)
+	if err != nil {
+		return
+	}
+	repo, err := gitobj.Open(absRepoPath)
+	if err != nil {
+		return
+	}
+	defer repo.Close()
+
+	tags, err := repo.Tags()
+	if err != nil {
+		logger.Errorf("Failed to list tags of %s: %v", target.Url, err)
+		return
+	}
+	if len(tags) == 0 {
+		return
+	}
+
+	var ret []report.Tag
+	for _, tag := range tags {
+		t := report.Tag{
+			Name:    tag.Name,
+			Hash:    tag.Hash,
+			Target:  repo.Peel(tag.Hash),
+			Message: strings.TrimSpace(tag.Message),
+		}
+		if tag.Tagger.Name != "" || tag.Tagger.Email != "" {
+			t.Tagger = tag.Tagger.Name + " <" + tag.Tagger.Email + ">"
+		}
+		if !tag.Tagger.When.IsZero() {
+			t.Date = tag.Tagger.When.Format(time.RFC3339)
+		}
+		// Цепочка обрывается на объекте, который не удалось скачать
+		if objType, err := repo.ObjectType(t.Target); err == nil {
+			t.Type = objType
+		}
+		ret = append(ret, t)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	logger.Infof("Found %d annotated tags in %s", len(ret), target.Url)
+	d.updateTarget(target.Url, func(t *report.Target) { t.Tags = ret })
+}
@@ -0,0 +1,61 @@
+package dumper
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// parseTarget returns the URL of an input line and applies options given
+// after it. With host=NAME the URL host is replaced by NAME, while connections
+// still go to the original address, so NAME is sent in the Host header and
+// SNI: "https://203.0.113.7 host=app.example.com".
+func (d *Dumper) parseTarget(line string) (string, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	targetUrl := fields[0]
+	for _, option := range fields[1:] {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "host":
+			var err error
+			if targetUrl, err = d.connectHost(targetUrl, value); err != nil {
+				return "", err
+			}
+		default:
+			return "", fmt.Errorf("unknown target option %q", option)
+		}
+	}
+	return targetUrl, nil
+}
+
+// connectHost rewrites the host of targetUrl to host and makes the client
+// connect to the address which was there.
+func (d *Dumper) connectHost(targetUrl, host string) (string, error) {
+	if host == "" || strings.ContainsAny(host, "/:@[]") {
+		return "", fmt.Errorf("invalid host %q", host)
+	}
+	if !strings.Contains(targetUrl, "://") {
+		targetUrl = "http://" + targetUrl
+	}
+	u, err := url.Parse(targetUrl)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL %s: %w", targetUrl, err)
+	}
+	addr := u.Hostname()
+	if addr == "" {
+		return "", fmt.Errorf("no address in %s", targetUrl)
+	}
+	if port := u.Port(); port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else {
+		u.Host = host
+	}
+	if err := d.client.ConnectTo(host, addr); err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
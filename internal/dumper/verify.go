@@ -0,0 +1,241 @@
+package dumper
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+	"github.com/s3rgeym/git-dump/internal/search"
+	"github.com/s3rgeym/git-dump/internal/store"
+	"github.com/s3rgeym/git-dump/internal/utils"
+)
+
+const maxEvidenceBody = 1024
+
+var (
+	sha1Regex    = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	symrefRegex  = regexp.MustCompile(`^ref: (refs/[^\s]+)$`)
+	evidenceTime = "20060102T150405Z"
+)
+
+// verify confirms exposure of every target with a minimal proof set: HEAD,
+// the ref it points to and a single object. Nothing else is fetched and
+// repositories are never restored.
+func (d *Dumper) verify(urlList []string) {
+	logger.Info("Verify mode: source code will not be reconstructed")
+	d.verifyTargets(urlList, nil)
+
+	reportFile := d.config.ReportFile
+	if reportFile == "" {
+		reportFile = filepath.Join(d.config.OutputDir, "evidence-"+time.Now().UTC().Format(evidenceTime)+".json")
+	}
+	if err := d.Report().WriteFile(reportFile); err != nil {
+		logger.Errorf("Failed to write evidence report: %v", err)
+	} else {
+		logger.Infof("Evidence report saved to %s", reportFile)
+	}
+	d.writeHtmlReport()
+	d.writeCsvReport()
+}
+
+// verifyTargets runs verifyTarget for every target and calls done, if set,
+// as soon as a target is checked.
+func (d *Dumper) verifyTargets(urlList []string, done func(baseUrl string)) {
+	for _, line := range urlList {
+		url, err := d.parseTarget(line)
+		if err != nil {
+			logger.Errorf("Invalid target %q: %v", line, err)
+			continue
+		}
+		if url == "" {
+			continue
+		}
+		baseUrl, err := utils.NormalizeUrl(url)
+		if err != nil {
+			logger.Errorf("Failed to normalize URL %s: %v", url, err)
+			continue
+		}
+		repoPath, err := utils.UrlToLocalPath(baseUrl, d.config.OutputDir, d.layout)
+		if err != nil {
+			logger.Errorf("Failed to convert URL %s to local repo path: %v", baseUrl, err)
+			continue
+		}
+		d.mu.Lock()
+		if _, ok := d.targets[baseUrl]; ok {
+			d.mu.Unlock()
+			continue
+		}
+		target := &report.Target{Url: baseUrl, RepoPath: repoPath}
+		if d.config.Memory {
+			target.RepoPath = ""
+		}
+		d.targets[baseUrl] = target
+		d.report.Targets = append(d.report.Targets, target)
+		d.mu.Unlock()
+
+		d.sem <- struct{}{}
+		d.wg.Add(1)
+		go func() {
+			defer func() {
+				<-d.sem
+				d.wg.Done()
+			}()
+			d.verifyTarget(baseUrl, repoPath)
+			if done != nil {
+				done(baseUrl)
+			}
+		}()
+	}
+
+	d.wg.Wait()
+
+	d.mu.Lock()
+	d.report.FinishedAt = time.Now()
+	d.mu.Unlock()
+}
+
+func (d *Dumper) verifyTarget(baseUrl, repoPath string) {
+	head := d.capture(baseUrl, "HEAD", repoPath)
+	if head == nil {
+		logger.Infof("Not exposed: %s", baseUrl)
+		return
+	}
+
+	content := strings.TrimSpace(string(head))
+	hash := ""
+	if sha1Regex.MatchString(content) {
+		hash = content
+	} else if m := symrefRegex.FindStringSubmatch(content); m != nil {
+		if !validRefName(m[1]) {
+			logger.Warnf("Ignoring invalid ref %q in HEAD of %s", m[1], baseUrl)
+		} else if data := d.capture(baseUrl, m[1], repoPath); data != nil {
+			hash = strings.TrimSpace(string(data))
+		} else if data := d.capture(baseUrl, "packed-refs", repoPath); data != nil {
+			hash = findPackedRef(data, m[1])
+		}
+	} else {
+		logger.Infof("Not exposed: %s (HEAD is not a git HEAD file)", baseUrl)
+		return
+	}
+
+	d.updateTarget(baseUrl, func(t *report.Target) { t.Exposed = true })
+	logger.Warnf("Exposed: %s", baseUrl)
+
+	if !sha1Regex.MatchString(hash) {
+		d.note(baseUrl, "HEAD is readable but could not be resolved to an object")
+		return
+	}
+
+	data := d.capture(baseUrl, "objects/"+hash[:2]+"/"+hash[2:], repoPath)
+	if data == nil {
+		d.note(baseUrl, "object "+hash+" is not available as a loose object")
+		return
+	}
+	objType, body, err := gitobj.DecodeLoose(data)
+	if err != nil || gitobj.HashObject(objType, body) != hash {
+		d.note(baseUrl, "object "+hash+" failed verification")
+		return
+	}
+	d.note(baseUrl, "verified "+objType+" object "+hash)
+}
+
+// capture fetches a file relative to baseUrl, saves it and records evidence.
+// With -memory the body is neither saved nor kept as evidence. It returns the
+// body or nil if the request failed.
+func (d *Dumper) capture(baseUrl, file, repoPath string) []byte {
+	targetUrl, err := utils.UrlJoin(baseUrl, file)
+	if err != nil {
+		return nil
+	}
+	evidence := report.Evidence{Time: time.Now().UTC(), Method: http.MethodGet, Url: targetUrl}
+	defer func() {
+		d.updateTarget(baseUrl, func(t *report.Target) {
+			t.Requests++
+			if !d.config.Memory {
+				t.Evidence = append(t.Evidence, evidence)
+			}
+		})
+	}()
+
+	resp, cancel, err := d.client.Fetch(targetUrl)
+	if err != nil {
+		evidence.Error = err.Error()
+		return nil
+	}
+	defer cancel()
+	defer resp.Body.Close()
+
+	evidence.Status = resp.StatusCode
+	evidence.RequestHeaders = flattenHeader(resp.Request.Header)
+	evidence.ResponseHeaders = flattenHeader(resp.Header)
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		evidence.Error = err.Error()
+		return nil
+	}
+	sum := sha256.Sum256(data)
+	evidence.BodySize = len(data)
+	evidence.BodySha256 = hex.EncodeToString(sum[:])
+	if len(data) <= maxEvidenceBody && !search.IsBinary(data) {
+		evidence.Body = string(data)
+	}
+
+	if d.config.Memory {
+		return data
+	}
+
+	// Имя ссылки приходит от сервера, поэтому путь проверяется
+	fileName, err := utils.UrlToLocalPath(targetUrl, d.config.OutputDir, d.layout)
+	if err != nil || !utils.IsSubPath(repoPath, fileName) {
+		logger.Warnf("Not saving %s outside of %s", targetUrl, repoPath)
+		return data
+	}
+	if err := store.WriteFile(d.store, fileName, data, 0644); err == nil {
+		d.updateTarget(baseUrl, func(t *report.Target) { t.Files++ })
+	}
+	return data
+}
+
+func (d *Dumper) note(baseUrl, note string) {
+	d.updateTarget(baseUrl, func(t *report.Target) { t.Notes = append(t.Notes, note) })
+}
+
+func flattenHeader(header http.Header) map[string]string {
+	ret := make(map[string]string, len(header))
+	for key, values := range header {
+		ret[key] = strings.Join(values, ", ")
+	}
+	return ret
+}
+
+// validRefName reports whether ref is safe to use as a path below .git.
+func validRefName(ref string) bool {
+	for _, part := range strings.Split(ref, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return !strings.ContainsAny(ref, "\\\x00")
+}
+
+// findPackedRef returns the hash of ref from packed-refs content.
+func findPackedRef(data []byte, ref string) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if hash, name, ok := strings.Cut(scanner.Text(), " "); ok && name == ref {
+			return hash
+		}
+	}
+	return ""
+}
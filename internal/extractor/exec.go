@@ -0,0 +1,91 @@
+package extractor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/utils"
+)
+
+// ExecExtractor runs an external command for every fetched file.
+//
+// The command is called as `command <path> <url> <base-url>` and should print
+// one directive per line to stdout:
+//
+//	url <path>              crawl a path relative to the .git directory (or an absolute URL)
+//	download <url>          download a working tree file after restore
+//	finding <kind> <detail> report a finding
+//
+// Empty lines and lines starting with # are ignored.
+type ExecExtractor struct {
+	Command string
+}
+
+func NewExecExtractor(command string) *ExecExtractor {
+	if len(strings.Fields(command)) == 0 {
+		logger.Fatalf("Empty -plugin command")
+	}
+	return &ExecExtractor{Command: command}
+}
+
+func (e *ExecExtractor) Name() string {
+	return filepath.Base(strings.Fields(e.Command)[0])
+}
+
+func (e *ExecExtractor) Extract(file File) (*Result, error) {
+	args := strings.Fields(e.Command)
+	args = append(args, file.Path, file.Url, file.BaseUrl)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("plugin %s failed: %w: %s", e.Name(), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return parseDirectives(e.Name(), file, &stdout)
+}
+
+func parseDirectives(name string, file File, r *bytes.Buffer) (*Result, error) {
+	result := &Result{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		directive, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+		switch directive {
+		case "url":
+			u, err := utils.UrlJoin(file.BaseUrl, rest)
+			if err != nil {
+				return nil, fmt.Errorf("invalid url directive %q: %w", line, err)
+			}
+			result.GitUrls = append(result.GitUrls, u)
+		case "download":
+			u, err := utils.UrlJoin(file.BaseUrl, rest)
+			if err != nil {
+				return nil, fmt.Errorf("invalid download directive %q: %w", line, err)
+			}
+			result.DownloadUrls = append(result.DownloadUrls, u)
+		case "finding":
+			kind, detail, _ := strings.Cut(rest, " ")
+			result.Findings = append(result.Findings, Finding{
+				Extractor: name,
+				Url:       file.Url,
+				Kind:      kind,
+				Detail:    strings.TrimSpace(detail),
+			})
+		default:
+			return nil, fmt.Errorf("unknown directive %q", directive)
+		}
+	}
+	return result, scanner.Err()
+}
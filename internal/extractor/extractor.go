@@ -0,0 +1,54 @@
+package extractor
+
+import (
+	"sync"
+)
+
+// File describes a fetched file passed to extractors.
+type File struct {
+	Url     string // URL the file was fetched from
+	BaseUrl string // Base URL of the .git directory
+	Path    string // Local path of the saved file
+}
+
+// Finding is something interesting an extractor wants to report.
+type Finding struct {
+	Extractor string `json:"extractor"`
+	Url       string `json:"url"`
+	Kind      string `json:"kind"`
+	Detail    string `json:"detail"`
+	Severity  string `json:"severity,omitempty"` // Set by the dumper from -severity rules
+}
+
+// Result holds everything an extractor produced for a single file.
+type Result struct {
+	GitUrls      []string  // URLs to crawl as part of the .git directory
+	DownloadUrls []string  // Working tree files to download after restore
+	Findings     []Finding // Findings to report
+	Targets      []string  // New targets which may be scanned if in scope
+}
+
+// Extractor receives fetched files and may enqueue more URLs or emit findings.
+type Extractor interface {
+	Name() string
+	Extract(file File) (*Result, error)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []Extractor
+)
+
+// Register adds an extractor which will be run for every fetched file.
+func Register(e Extractor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, e)
+}
+
+// Registered returns a copy of all registered extractors.
+func Registered() []Extractor {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return append([]Extractor(nil), registry...)
+}
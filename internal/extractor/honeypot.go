@@ -0,0 +1,74 @@
+package extractor
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// CanaryRegex matches hosts of canary token services, which alert their
+// owner when a token is resolved or requested.
+var CanaryRegex = regexp.MustCompile(`(?i)(?:[a-z0-9-]+\.)*(?:canarytokens\.(?:com|org|net)|canary\.tools)\b`)
+
+// maxHoneypotFileSize skips large files, canary tokens are planted in small
+// config files and scripts.
+const maxHoneypotFileSize = 1 << 20
+
+// HoneypotExtractor flags canary tokens in text files of the .git directory
+// such as config, hooks and logs: a repository carrying them was likely
+// planted to catch whoever dumps it.
+type HoneypotExtractor struct{}
+
+func NewHoneypotExtractor() *HoneypotExtractor {
+	return &HoneypotExtractor{}
+}
+
+func (e *HoneypotExtractor) Name() string {
+	return "honeypot"
+}
+
+func (e *HoneypotExtractor) Extract(file File) (*Result, error) {
+	name := strings.TrimPrefix(file.Url, file.BaseUrl)
+	// Объекты и индекс сжаты или бинарные, токен в них не найти
+	if name == file.Url || name == "index" || strings.HasPrefix(name, "objects/") || strings.HasSuffix(name, "/") {
+		return &Result{}, nil
+	}
+	matches, err := CanaryTokens(file.Path)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{}
+	for _, m := range matches {
+		result.Findings = append(result.Findings, Finding{
+			Extractor: e.Name(),
+			Url:       file.Url,
+			Kind:      "honeypot",
+			Detail:    "canary token host " + m,
+		})
+	}
+	return result, nil
+}
+
+// CanaryTokens returns the distinct canary token hosts found in a file.
+func CanaryTokens(fileName string) ([]string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxHoneypotFileSize))
+	if err != nil {
+		return nil, err
+	}
+	var matches []string
+	seen := make(map[string]bool)
+	for _, m := range CanaryRegex.FindAllString(string(data), -1) {
+		m = strings.ToLower(m)
+		if !seen[m] {
+			seen[m] = true
+			matches = append(matches, m)
+		}
+	}
+	return matches, nil
+}
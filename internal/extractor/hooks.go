@@ -0,0 +1,76 @@
+package extractor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// suspiciousHookRegex matches commands typical for backdoors and webshells.
+var suspiciousHookRegex = regexp.MustCompile(`(?i)\b(curl|wget|nc|ncat|socat|base64|eval|exec|bash -i|/dev/tcp|python[0-9.]* -c|perl -e|php -r|chmod \+x|crontab)\b`)
+
+// HooksExtractor flags non-sample hook scripts fetched from hooks/. Hooks are
+// only read and are never executed.
+type HooksExtractor struct{}
+
+func NewHooksExtractor() *HooksExtractor {
+	return &HooksExtractor{}
+}
+
+func (e *HooksExtractor) Name() string {
+	return "hooks"
+}
+
+func (e *HooksExtractor) Extract(file File) (*Result, error) {
+	name := strings.TrimPrefix(file.Url, file.BaseUrl+"hooks/")
+	if name == file.Url || name == "" || strings.HasSuffix(name, "/") || strings.HasSuffix(name, ".sample") {
+		return &Result{}, nil
+	}
+
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var interpreter string
+	var suspicious []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if lineNum == 1 && strings.HasPrefix(line, "#!") {
+			interpreter = strings.TrimSpace(line[2:])
+			continue
+		}
+		for _, m := range suspiciousHookRegex.FindAllString(line, -1) {
+			m = strings.ToLower(m)
+			if !seen[m] {
+				seen[m] = true
+				suspicious = append(suspicious, m)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	detail := path.Base(name)
+	if interpreter != "" {
+		detail += fmt.Sprintf(" (%s)", interpreter)
+	}
+	kind := "hook"
+	if len(suspicious) > 0 {
+		kind = "suspicious-hook"
+		detail += ": " + strings.Join(suspicious, ", ")
+	}
+	return &Result{Findings: []Finding{{
+		Extractor: e.Name(),
+		Url:       file.Url,
+		Kind:      kind,
+		Detail:    detail,
+	}}}, nil
+}
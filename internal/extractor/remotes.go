@@ -0,0 +1,69 @@
+package extractor
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/gitconfig"
+)
+
+// RemotesExtractor reports remotes configured in a dumped .git/config and
+// proposes their hosts as new targets.
+type RemotesExtractor struct{}
+
+func NewRemotesExtractor() *RemotesExtractor {
+	return &RemotesExtractor{}
+}
+
+func (e *RemotesExtractor) Name() string {
+	return "remotes"
+}
+
+func (e *RemotesExtractor) Extract(file File) (*Result, error) {
+	if file.Url != file.BaseUrl+"config" {
+		return &Result{}, nil
+	}
+
+	cfg, err := gitconfig.ParseFile(file.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	seen := make(map[string]bool)
+	for _, entry := range cfg.Entries {
+		if entry.Section != "remote" || (entry.Key != "url" && entry.Key != "pushurl") {
+			continue
+		}
+		result.Findings = append(result.Findings, Finding{
+			Extractor: e.Name(),
+			Url:       file.Url,
+			Kind:      "remote",
+			Detail:    entry.Subsection + " " + entry.Value,
+		})
+		if host := RemoteHost(entry.Value); host != "" && !seen[host] {
+			seen[host] = true
+			result.Targets = append(result.Targets, "https://"+host+"/")
+		}
+	}
+	return result, nil
+}
+
+// RemoteHost returns the host name of a remote URL in URL or scp-like syntax.
+func RemoteHost(remote string) string {
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" && u.Host != "" {
+		if u.Scheme == "file" {
+			return ""
+		}
+		return u.Hostname()
+	}
+	// scp-подобный синтаксис: [user@]host:path
+	before, _, ok := strings.Cut(remote, ":")
+	if !ok || strings.Contains(before, "/") {
+		return ""
+	}
+	if _, host, ok := strings.Cut(before, "@"); ok {
+		return host
+	}
+	return before
+}
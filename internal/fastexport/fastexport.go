@@ -0,0 +1,454 @@
+package fastexport
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+)
+
+// RecoveredPrefix is the namespace of refs created for commits which no ref
+// points at.
+const RecoveredPrefix = "refs/recovered/"
+
+// Result counts what was exported and what had to be left out.
+type Result struct {
+	Commits        int      `json:"commits"`
+	Blobs          int      `json:"blobs"`
+	Tags           int      `json:"tags"`
+	Refs           []string `json:"refs"`
+	MissingParents int      `json:"missing_parents"`
+	MissingTrees   int      `json:"missing_trees"`
+	MissingBlobs   int      `json:"missing_blobs"`
+}
+
+// exporter writes a single fast-import stream.
+type exporter struct {
+	w       *bufio.Writer
+	repo    *gitobj.Repo
+	result  *Result
+	marks   map[string]int // Метки уже записанных коммитов и blob-объектов
+	next    int
+	missing map[string]bool
+}
+
+// Write emits a git fast-import stream with every recovered commit of repo
+// and the files of their trees, so the history can be rebuilt with
+// `git fast-import` from a repository which git itself refuses to read.
+// Missing parents are dropped, missing trees and blobs leave files out.
+// Refs pointing at commits keep their names; other commits without children
+// get refs under RecoveredPrefix.
+func Write(w io.Writer, repo *gitobj.Repo) (*Result, error) {
+	x := &exporter{
+		w:       bufio.NewWriter(w),
+		repo:    repo,
+		result:  &Result{},
+		marks:   make(map[string]int),
+		next:    1,
+		missing: make(map[string]bool),
+	}
+	if err := x.run(); err != nil {
+		return x.result, err
+	}
+	return x.result, x.w.Flush()
+}
+
+func (x *exporter) run() error {
+	commits, err := x.readCommits()
+	if err != nil {
+		return err
+	}
+	refs, err := x.repo.Refs()
+	if err != nil {
+		return fmt.Errorf("failed to read refs: %w", err)
+	}
+
+	// Каждой вершине истории нужна ссылка, иначе git fast-import её потеряет
+	tips := make(map[string]string)
+	tags := make(map[string]*gitobj.Tag)
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		hash := refs[name]
+		if tag := x.readTag(hash); tag != nil && strings.HasPrefix(name, "refs/tags/") {
+			if _, ok := commits[tag.Object]; ok {
+				tags[name] = tag
+			}
+			continue
+		}
+		if _, ok := commits[hash]; ok {
+			tips[name] = hash
+		}
+	}
+	hasChild := make(map[string]bool)
+	for _, c := range commits {
+		for _, p := range c.Parents {
+			hasChild[p] = true
+		}
+	}
+	referenced := make(map[string]bool)
+	for _, hash := range tips {
+		referenced[hash] = true
+	}
+	for _, tag := range tags {
+		referenced[tag.Object] = true
+	}
+	for hash := range commits {
+		if !hasChild[hash] && !referenced[hash] {
+			tips[RecoveredPrefix+hash] = hash
+		}
+	}
+
+	heads := make(map[string]string, len(tips)+len(tags))
+	for name, hash := range tips {
+		heads[name] = hash
+	}
+	// Коммит с аннотированным тегом пишется в ссылку тега, тег затем её заменяет
+	for name, tag := range tags {
+		heads[name] = tag.Object
+	}
+	branch := assignRefs(commits, heads)
+	for _, hash := range topoOrder(commits) {
+		if err := x.writeCommit(commits[hash], branch[hash]); err != nil {
+			return err
+		}
+	}
+
+	names = names[:0]
+	for name := range tips {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(x.w, "reset %s\nfrom :%d\n\n", name, x.marks[tips[name]])
+		x.result.Refs = append(x.result.Refs, name)
+	}
+	names = names[:0]
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		tag := tags[name]
+		fmt.Fprintf(x.w, "tag %s\nfrom :%d\noriginal-oid %s\n", strings.TrimPrefix(name, "refs/tags/"), x.marks[tag.Object], tag.Hash)
+		if tag.Tagger.Name != "" || tag.Tagger.Email != "" {
+			fmt.Fprintf(x.w, "tagger %s\n", signature(tag.Tagger))
+		}
+		x.data([]byte(tag.Message))
+		x.result.Tags++
+		x.result.Refs = append(x.result.Refs, name)
+	}
+	return nil
+}
+
+// readCommits reads every commit stored in the repository, reachable or not.
+func (x *exporter) readCommits() (map[string]*gitobj.Commit, error) {
+	hashes, err := x.repo.ListObjects()
+	if err != nil {
+		return nil, err
+	}
+	commits := make(map[string]*gitobj.Commit)
+	for _, hash := range hashes {
+		// Тип читается из заголовка, чтобы не распаковывать каждый blob
+		if objType, err := x.repo.ObjectType(hash); err != nil || objType != gitobj.TypeCommit {
+			continue
+		}
+		if commit, err := x.repo.ReadCommit(hash); err == nil {
+			commits[hash] = commit
+		}
+	}
+	return commits, nil
+}
+
+func (x *exporter) readTag(hash string) *gitobj.Tag {
+	obj, err := x.repo.ReadObject(hash)
+	if err != nil || obj.Type != gitobj.TypeTag {
+		return nil
+	}
+	tag, err := gitobj.ParseTag(hash, obj.Data)
+	if err != nil || tag.Type != gitobj.TypeCommit {
+		return nil
+	}
+	return tag
+}
+
+// assignRefs picks for every commit the first ref, by name, whose history
+// contains it. fast-import needs a ref for each commit command.
+func assignRefs(commits map[string]*gitobj.Commit, tips map[string]string) map[string]string {
+	names := make([]string, 0, len(tips))
+	for name := range tips {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	branch := make(map[string]string)
+	for _, name := range names {
+		queue := []string{tips[name]}
+		for len(queue) > 0 {
+			hash := queue[0]
+			queue = queue[1:]
+			commit, ok := commits[hash]
+			if !ok || branch[hash] != "" {
+				continue
+			}
+			branch[hash] = name
+			queue = append(queue, commit.Parents...)
+		}
+	}
+	return branch
+}
+
+// topoOrder returns commits with parents before children. Commits of equal
+// depth are ordered by committer date to keep the output stable.
+func topoOrder(commits map[string]*gitobj.Commit) []string {
+	hashes := make([]string, 0, len(commits))
+	for hash := range commits {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		a, b := commits[hashes[i]], commits[hashes[j]]
+		if !a.Committer.When.Equal(b.Committer.When) {
+			return a.Committer.When.Before(b.Committer.When)
+		}
+		return a.Hash < b.Hash
+	})
+
+	order := make([]string, 0, len(commits))
+	state := make(map[string]int) // 1 — в обработке, 2 — записан
+	var visit func(hash string)
+	visit = func(hash string) {
+		if state[hash] != 0 {
+			return
+		}
+		state[hash] = 1
+		for _, p := range commits[hash].Parents {
+			if _, ok := commits[p]; ok {
+				visit(p)
+			}
+		}
+		state[hash] = 2
+		order = append(order, hash)
+	}
+	for _, hash := range hashes {
+		visit(hash)
+	}
+	return order
+}
+
+func (x *exporter) writeCommit(commit *gitobj.Commit, ref string) error {
+	files, err := x.files(commit)
+	if err != nil {
+		return err
+	}
+
+	var parents []int
+	for _, p := range commit.Parents {
+		if parent, ok := x.marks[p]; ok {
+			parents = append(parents, parent)
+		} else {
+			x.result.MissingParents++
+		}
+	}
+	if len(parents) == 0 {
+		// Без from fast-import продолжил бы текущую вершину ссылки
+		fmt.Fprintf(x.w, "reset %s\n\n", ref)
+	}
+
+	mark := x.next
+	x.next++
+	x.marks[commit.Hash] = mark
+	fmt.Fprintf(x.w, "commit %s\nmark :%d\noriginal-oid %s\n", ref, mark, commit.Hash)
+	fmt.Fprintf(x.w, "author %s\n", signature(commit.Author))
+	fmt.Fprintf(x.w, "committer %s\n", signature(commit.Committer))
+	x.data([]byte(commit.Message))
+	for i, parent := range parents {
+		if i == 0 {
+			fmt.Fprintf(x.w, "from :%d\n", parent)
+		} else {
+			fmt.Fprintf(x.w, "merge :%d\n", parent)
+		}
+	}
+	// Коммит описывается полным списком файлов, а не разницей с родителем
+	x.w.WriteString("deleteall\n")
+	for _, f := range files {
+		fmt.Fprintf(x.w, "M %s %s %s\n", f.mode, f.dataref, quotePath(f.path))
+	}
+	x.w.WriteString("\n")
+	x.result.Commits++
+	return nil
+}
+
+type file struct {
+	mode, dataref, path string
+}
+
+// files writes the blobs of a commit not written yet and returns its files.
+func (x *exporter) files(commit *gitobj.Commit) ([]file, error) {
+	var files []file
+	var walk func(tree, prefix string) error
+	walk = func(tree, prefix string) error {
+		entries, err := x.repo.ReadTree(tree)
+		if err != nil {
+			if !x.missing[tree] {
+				x.missing[tree] = true
+				x.result.MissingTrees++
+			}
+			return nil
+		}
+		for _, entry := range entries {
+			path := prefix + entry.Name
+			switch {
+			case entry.IsTree():
+				if err := walk(entry.Hash, path+"/"); err != nil {
+					return err
+				}
+			case entry.IsSubmodule():
+				files = append(files, file{entry.Mode, entry.Hash, path})
+			default:
+				mark, err := x.blob(entry.Hash)
+				if err != nil {
+					return err
+				}
+				if mark == 0 {
+					continue
+				}
+				files = append(files, file{normalizeMode(entry.Mode), fmt.Sprintf(":%d", mark), path})
+			}
+		}
+		return nil
+	}
+	return files, walk(commit.Tree, "")
+}
+
+// blob writes a blob once and returns its mark, or 0 if it is missing.
+func (x *exporter) blob(hash string) (int, error) {
+	if mark, ok := x.marks[hash]; ok {
+		return mark, nil
+	}
+	if x.missing[hash] {
+		return 0, nil
+	}
+	obj, err := x.repo.ReadObject(hash)
+	if err != nil || obj.Type != gitobj.TypeBlob {
+		x.missing[hash] = true
+		x.result.MissingBlobs++
+		return 0, nil
+	}
+	mark := x.next
+	x.next++
+	x.marks[hash] = mark
+	fmt.Fprintf(x.w, "blob\nmark :%d\noriginal-oid %s\n", mark, hash)
+	x.data(obj.Data)
+	x.result.Blobs++
+	return mark, nil
+}
+
+func (x *exporter) data(data []byte) {
+	fmt.Fprintf(x.w, "data %d\n", len(data))
+	x.w.Write(data)
+	x.w.WriteString("\n")
+}
+
+// signature formats an author line as "Name <email> seconds +zzzz".
+func signature(sig gitobj.Signature) string {
+	return fmt.Sprintf("%s <%s> %d %s", sig.Name, sig.Email, sig.When.Unix(), sig.When.Format("-0700"))
+}
+
+// normalizeMode maps legacy file modes of old trees to the ones fast-import
+// accepts.
+func normalizeMode(mode string) string {
+	switch mode {
+	case "100755", "755":
+		return "100755"
+	case "120000":
+		return "120000"
+	default:
+		return "100644"
+	}
+}
+
+// quotePath quotes paths fast-import would otherwise misread, the way git
+// quotes them: C escapes and octal for other control characters.
+func quotePath(path string) string {
+	if !strings.HasPrefix(path, `"`) && !strings.ContainsFunc(path, func(r rune) bool { return r < 0x20 || r == 0x7f }) {
+		return path
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < 0x20 || c == 0x7f {
+				fmt.Fprintf(&b, "\\%03o", c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// HeadRef returns the branch HEAD points at, or "" if HEAD is detached or
+// unreadable.
+func HeadRef(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	ref, _ := strings.CutPrefix(strings.TrimSpace(string(data)), "ref: ")
+	if !strings.HasPrefix(ref, "refs/heads/") {
+		return ""
+	}
+	return ref
+}
+
+// ImportBare creates a bare repository at dir and feeds the stream to git
+// fast-import. HEAD is pointed at headRef if it was exported.
+func ImportBare(repo *gitobj.Repo, dir, headRef string) (*Result, error) {
+	if out, err := exec.Command("git", "init", "--bare", "--quiet", dir).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("git init: %w: %s", err, out)
+	}
+	cmd := exec.Command("git", "-C", dir, "fast-import", "--quiet")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start git fast-import: %w", err)
+	}
+	result, err := Write(stdin, repo)
+	stdin.Close()
+	if waitErr := cmd.Wait(); err == nil && waitErr != nil {
+		err = fmt.Errorf("git fast-import: %w: %s", waitErr, strings.TrimSpace(stderr.String()))
+	}
+	if err != nil {
+		return result, err
+	}
+	for _, ref := range result.Refs {
+		if ref != headRef {
+			continue
+		}
+		if out, err := exec.Command("git", "-C", dir, "symbolic-ref", "HEAD", headRef).CombinedOutput(); err != nil {
+			return result, fmt.Errorf("git symbolic-ref: %w: %s", err, out)
+		}
+	}
+	return result, nil
+}
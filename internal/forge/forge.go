@@ -0,0 +1,225 @@
+package forge
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/httpclient"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/utils"
+)
+
+const maxBodySize = 64 << 20
+
+// Forge is a web interface which can serve raw objects of a repository. gitweb,
+// Gitea and GitLab only return blob contents, so commits and trees can be
+// recovered through cgit alone.
+type Forge struct {
+	Kind string
+	// objectUrls returns candidate URLs for an object hash
+	objectUrls func(hash string) []string
+	// decode converts a response body into an object type and data
+	decode func(hash string, body []byte) (string, []byte, error)
+}
+
+// signatures of supported web interfaces in their HTML pages.
+var signatures = []struct {
+	kind   string
+	needle string
+}{
+	{"gitweb", `content="gitweb`},
+	{"cgit", "generated by cgit"},
+	{"gitea", "Powered by Gitea"},
+	{"gitea", "Powered by Forgejo"},
+	{"gitlab", `content="GitLab"`},
+	{"gitlab", "gon.gitlab_url"},
+}
+
+// gitwebPaths are common locations of gitweb relative to the site root.
+var gitwebPaths = []string{"gitweb.cgi", "gitweb/", "gitweb/gitweb.cgi", "cgi-bin/gitweb.cgi"}
+
+// Detect probes the site of a dumped .git directory for gitweb, cgit, Gitea
+// or GitLab. project is the repository path (e.g. "group/repo") usually taken
+// from the remote URL in the dumped config.
+func Detect(client *httpclient.HttpClient, baseUrl, project string) []*Forge {
+	root, err := utils.UrlJoin(baseUrl, "../")
+	if err != nil {
+		return nil
+	}
+	project = strings.TrimSuffix(strings.Trim(project, "/"), ".git")
+	if project == "" {
+		return nil
+	}
+
+	var forges []*Forge
+	found := make(map[string]bool)
+	pages := append([]string{""}, gitwebPaths...)
+	pages = append(pages, "cgit/")
+	for _, page := range pages {
+		pageUrl, err := utils.UrlJoin(root, page)
+		if err != nil {
+			continue
+		}
+		body, err := fetchBody(client, pageUrl)
+		if err != nil {
+			continue
+		}
+		for _, sig := range signatures {
+			if found[sig.kind] || !strings.Contains(string(body), sig.needle) {
+				continue
+			}
+			found[sig.kind] = true
+			logger.Infof("Detected %s at %s", sig.kind, pageUrl)
+			if f := newForge(sig.kind, pageUrl, root, project); f != nil {
+				forges = append(forges, f)
+			}
+		}
+	}
+	return forges
+}
+
+func newForge(kind, pageUrl, root, project string) *Forge {
+	name := project[strings.LastIndexByte(project, '/')+1:]
+	switch kind {
+	case "gitweb":
+		return &Forge{
+			Kind: kind,
+			objectUrls: func(hash string) []string {
+				var urls []string
+				for _, p := range []string{project + ".git", project, name + ".git", name} {
+					urls = append(urls, pageUrl+"?p="+url.QueryEscape(p)+";a=blob_plain;h="+hash)
+				}
+				return urls
+			},
+			decode: rawBlob,
+		}
+	case "cgit":
+		// cgit отдаёт объекты по dumb-протоколу
+		return &Forge{
+			Kind: kind,
+			objectUrls: func(hash string) []string {
+				if len(hash) != 40 {
+					return nil
+				}
+				var urls []string
+				for _, p := range []string{project, project + ".git", name, name + ".git"} {
+					u, err := utils.UrlJoin(pageUrl, p+"/objects/"+hash[:2]+"/"+hash[2:])
+					if err == nil {
+						urls = append(urls, u)
+					}
+				}
+				return urls
+			},
+			decode: looseObject,
+		}
+	case "gitea":
+		return &Forge{
+			Kind: kind,
+			objectUrls: func(hash string) []string {
+				u, err := utils.UrlJoin(root, "api/v1/repos/"+project+"/git/blobs/"+hash)
+				if err != nil {
+					return nil
+				}
+				return []string{u}
+			},
+			decode: giteaBlob,
+		}
+	case "gitlab":
+		return &Forge{
+			Kind: kind,
+			objectUrls: func(hash string) []string {
+				return []string{root + "api/v4/projects/" + url.PathEscape(project) + "/repository/blobs/" + hash + "/raw"}
+			},
+			decode: rawBlob,
+		}
+	}
+	return nil
+}
+
+// FetchObject tries to get an object through the forge and verifies its hash.
+func (f *Forge) FetchObject(client *httpclient.HttpClient, hash string) (string, []byte, error) {
+	var lastErr error
+	for _, u := range f.objectUrls(hash) {
+		body, err := fetchBody(client, u)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		objType, data, err := f.decode(hash, body)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if got := gitobj.HashObject(objType, data); got != hash {
+			lastErr = fmt.Errorf("hash mismatch for %s: got %s", u, got)
+			continue
+		}
+		return objType, data, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no URLs to try")
+	}
+	return "", nil, lastErr
+}
+
+func rawBlob(hash string, body []byte) (string, []byte, error) {
+	return gitobj.TypeBlob, body, nil
+}
+
+func looseObject(hash string, body []byte) (string, []byte, error) {
+	return gitobj.DecodeLoose(body)
+}
+
+func giteaBlob(hash string, body []byte) (string, []byte, error) {
+	var blob struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}
+	if err := json.Unmarshal(body, &blob); err != nil {
+		return "", nil, fmt.Errorf("invalid Gitea blob response: %w", err)
+	}
+	if blob.Encoding != "base64" {
+		return "", nil, fmt.Errorf("unsupported Gitea blob encoding %q", blob.Encoding)
+	}
+	data, err := base64.StdEncoding.DecodeString(blob.Content)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid Gitea blob content: %w", err)
+	}
+	return gitobj.TypeBlob, data, nil
+}
+
+func fetchBody(client *httpclient.HttpClient, targetUrl string) ([]byte, error) {
+	resp, cancel, err := client.Fetch(targetUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	defer resp.Body.Close()
+	return io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+}
+
+// ProjectFromRemote extracts a repository path like "group/repo" from a
+// remote URL in any of the forms git accepts.
+func ProjectFromRemote(remote string) string {
+	remote = strings.TrimSpace(remote)
+	if remote == "" {
+		return ""
+	}
+	var path string
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" && u.Host != "" {
+		path = u.Path
+	} else if _, after, ok := strings.Cut(remote, ":"); ok {
+		// scp-подобный синтаксис: git@host:group/repo.git
+		path = after
+	} else {
+		path = remote
+	}
+	path = strings.Trim(path, "/")
+	path = strings.TrimPrefix(path, "scm/") // Bitbucket Server
+	return strings.TrimSuffix(path, ".git")
+}
@@ -0,0 +1,282 @@
+package fsck
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/gitindex"
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+)
+
+// Problem is a corrupt file of a dumped repository.
+type Problem struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// Missing is an object needed for a complete restore which isn't stored.
+type Missing struct {
+	Hash string `json:"hash"`
+	Type string `json:"type"`
+	// From tells what references the object: a ref, a commit, a tree path or
+	// the index
+	From string `json:"from"`
+}
+
+// Result is the outcome of checking a single .git directory.
+type Result struct {
+	GitDir       string    `json:"git_dir"`
+	LooseObjects int       `json:"loose_objects"`
+	Packs        int       `json:"packs"`
+	IndexEntries int       `json:"index_entries"`
+	Reachable    int       `json:"reachable"`
+	Corrupt      []Problem `json:"corrupt,omitempty"`
+	Missing      []Missing `json:"missing,omitempty"`
+}
+
+// OK reports whether nothing is corrupt or missing.
+func (r *Result) OK() bool {
+	return len(r.Corrupt) == 0 && len(r.Missing) == 0
+}
+
+// Check verifies the hashes of loose objects, the checksums of packs, their
+// indexes and the index, and lists objects reachable from HEAD, refs and
+// the index which are missing.
+func Check(gitDir string) (*Result, error) {
+	repo, err := gitobj.Open(gitDir)
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Close()
+
+	r := &Result{GitDir: gitDir}
+	r.checkLoose(repo)
+	r.checkPacks(gitDir)
+	r.checkIndex(repo)
+	r.checkReachable(repo)
+	return r, nil
+}
+
+func (r *Result) corrupt(path, format string, args ...any) {
+	r.Corrupt = append(r.Corrupt, Problem{Path: path, Error: fmt.Sprintf(format, args...)})
+}
+
+func (r *Result) checkLoose(repo *gitobj.Repo) {
+	dirs, _ := filepath.Glob(filepath.Join(repo.Dir, "objects", "[0-9a-f][0-9a-f]"))
+	for _, dir := range dirs {
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			hash := filepath.Base(dir) + f.Name()
+			if len(hash) != 40 || !isHex(hash) {
+				continue
+			}
+			r.LooseObjects++
+			path := filepath.Join(dir, f.Name())
+			data, err := os.ReadFile(path)
+			if err != nil {
+				r.corrupt(path, "%v", err)
+				continue
+			}
+			objType, body, err := gitobj.DecodeLoose(data)
+			if err != nil {
+				r.corrupt(path, "%v", err)
+				continue
+			}
+			if got := gitobj.HashObject(objType, body); got != hash {
+				r.corrupt(path, "hash mismatch: content hashes to %s", got)
+			}
+		}
+	}
+}
+
+func (r *Result) checkPacks(gitDir string) {
+	packFiles, _ := filepath.Glob(filepath.Join(gitDir, "objects", "pack", "*.pack"))
+	for _, packFile := range packFiles {
+		r.Packs++
+		sum, err := trailerChecksum(packFile)
+		if err != nil {
+			r.corrupt(packFile, "%v", err)
+			continue
+		}
+		idxFile := strings.TrimSuffix(packFile, ".pack") + ".idx"
+		if _, err := os.Stat(idxFile); err != nil {
+			r.corrupt(idxFile, "missing index of %s", filepath.Base(packFile))
+			continue
+		}
+		if _, err := trailerChecksum(idxFile); err != nil {
+			r.corrupt(idxFile, "%v", err)
+			continue
+		}
+		_, _, packSum, err := gitobj.ReadPackIndex(idxFile)
+		if err != nil {
+			r.corrupt(idxFile, "%v", err)
+		} else if packSum != sum {
+			r.corrupt(idxFile, "index is for pack %s, not %s", packSum, sum)
+		}
+	}
+}
+
+func (r *Result) checkIndex(repo *gitobj.Repo) {
+	indexFile := filepath.Join(repo.Dir, "index")
+	if _, err := os.Stat(indexFile); err != nil {
+		return
+	}
+	if _, err := trailerChecksum(indexFile); err != nil {
+		r.corrupt(indexFile, "%v", err)
+		return
+	}
+	index, err := gitindex.ParseGitIndex(indexFile)
+	if err != nil {
+		r.corrupt(indexFile, "%v", err)
+		return
+	}
+	r.IndexEntries = len(index.Entries)
+	for _, entry := range index.Entries {
+		// Подмодули и файлы, добавленные через git add -N, объектов не имеют
+		if entry.Mode&0170000 == 0160000 || entry.IntentToAdd() {
+			continue
+		}
+		if !repo.HasObject(entry.Sha1) {
+			r.Missing = append(r.Missing, Missing{Hash: entry.Sha1, Type: gitobj.TypeBlob, From: "index: " + entry.FileName})
+		}
+	}
+}
+
+// checkReachable walks commits, trees and tags from HEAD and all refs.
+func (r *Result) checkReachable(repo *gitobj.Repo) {
+	type item struct{ hash, typ, from string }
+	var queue []item
+	refs, _ := repo.Refs()
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if head, err := repo.Head(); err == nil && head != "" {
+		queue = append(queue, item{head, "", "HEAD"})
+	}
+	for _, name := range names {
+		queue = append(queue, item{refs[name], "", name})
+	}
+
+	seen := make(map[string]bool)
+	for len(queue) > 0 {
+		it := queue[0]
+		queue = queue[1:]
+		if seen[it.hash] || len(it.hash) != 40 {
+			continue
+		}
+		seen[it.hash] = true
+		if !repo.HasObject(it.hash) {
+			typ := it.typ
+			if typ == "" {
+				typ = "object"
+			}
+			r.Missing = append(r.Missing, Missing{Hash: it.hash, Type: typ, From: it.from})
+			continue
+		}
+		r.Reachable++
+		if it.typ == gitobj.TypeBlob {
+			continue
+		}
+		obj, err := repo.ReadObject(it.hash)
+		if err != nil {
+			r.corrupt(it.hash, "%v", err)
+			continue
+		}
+		switch obj.Type {
+		case gitobj.TypeCommit:
+			commit, err := gitobj.ParseCommit(obj.Hash, obj.Data)
+			if err != nil {
+				r.corrupt(it.hash, "%v", err)
+				continue
+			}
+			queue = append(queue, item{commit.Tree, gitobj.TypeTree, "commit " + obj.Hash})
+			for _, parent := range commit.Parents {
+				queue = append(queue, item{parent, gitobj.TypeCommit, "commit " + obj.Hash})
+			}
+		case gitobj.TypeTag:
+			tag, err := gitobj.ParseTag(obj.Hash, obj.Data)
+			if err != nil {
+				r.corrupt(it.hash, "%v", err)
+				continue
+			}
+			queue = append(queue, item{tag.Object, tag.Type, "tag " + obj.Hash})
+		case gitobj.TypeTree:
+			entries, err := gitobj.ParseTree(obj.Data)
+			if err != nil {
+				r.corrupt(it.hash, "%v", err)
+				continue
+			}
+			prefix := strings.TrimPrefix(it.from, "tree ")
+			if !strings.HasPrefix(it.from, "tree ") {
+				prefix = ""
+			}
+			for _, entry := range entries {
+				if entry.IsSubmodule() {
+					continue
+				}
+				typ := gitobj.TypeBlob
+				if entry.IsTree() {
+					typ = gitobj.TypeTree
+				}
+				queue = append(queue, item{entry.Hash, typ, "tree " + prefix + entry.Name + suffix(entry)})
+			}
+		}
+	}
+}
+
+func suffix(entry gitobj.TreeEntry) string {
+	if entry.IsTree() {
+		return "/"
+	}
+	return ""
+}
+
+// trailerChecksum checks that a pack, pack index or index file ends with the
+// SHA-1 of its content and returns it.
+func trailerChecksum(fileName string) (string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if fi.Size() < sha1.Size {
+		return "", fmt.Errorf("file is truncated")
+	}
+	h := sha1.New()
+	if _, err := io.CopyN(h, f, fi.Size()-sha1.Size); err != nil {
+		return "", err
+	}
+	trailer := make([]byte, sha1.Size)
+	if _, err := io.ReadFull(f, trailer); err != nil {
+		return "", err
+	}
+	if sum := h.Sum(nil); !bytes.Equal(sum, trailer) {
+		return "", fmt.Errorf("checksum mismatch: content hashes to %x, trailer is %x", sum, trailer)
+	}
+	return hex.EncodeToString(trailer), nil
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package fsck
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+)
+
+func writeRepo(t *testing.T) (string, *gitobj.Repo, string) {
+	t.Helper()
+	gitDir := filepath.Join(t.TempDir(), ".git")
+	if err := os.MkdirAll(filepath.Join(gitDir, "objects"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := gitobj.Open(gitDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, _ := hex.DecodeString(gitobj.HashObject(gitobj.TypeBlob, []byte("present\n")))
+	if _, err := repo.WriteLoose(gitobj.TypeBlob, []byte("present\n")); err != nil {
+		t.Fatal(err)
+	}
+	missing, _ := hex.DecodeString("1111111111111111111111111111111111111111")
+	tree := append([]byte("100644 a\x00"), blob...)
+	tree = append(append(tree, "100644 b\x00"...), missing...)
+	treeHash, err := repo.WriteLoose(gitobj.TypeTree, tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := "tree " + treeHash + "\nparent 2222222222222222222222222222222222222222\n\nmsg\n"
+	commitHash, err := repo.WriteLoose(gitobj.TypeCommit, []byte(commit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte(commitHash+"\n"), 0644)
+	return gitDir, repo, commitHash
+}
+
+func TestCheckReportsMissingObjects(t *testing.T) {
+	gitDir, _, commitHash := writeRepo(t)
+	r, err := Check(gitDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Corrupt) != 0 {
+		t.Fatalf("Corrupt = %v", r.Corrupt)
+	}
+	want := map[string]string{
+		"1111111111111111111111111111111111111111": "tree b",
+		"2222222222222222222222222222222222222222": "commit " + commitHash,
+	}
+	if len(r.Missing) != len(want) {
+		t.Fatalf("Missing = %v", r.Missing)
+	}
+	for _, m := range r.Missing {
+		if want[m.Hash] != m.From {
+			t.Errorf("missing %s from %q, want %q", m.Hash, m.From, want[m.Hash])
+		}
+	}
+	if r.Reachable != 3 {
+		t.Errorf("Reachable = %d, want 3", r.Reachable)
+	}
+}
+
+func TestCheckDetectsCorruptLooseObject(t *testing.T) {
+	gitDir, repo, commitHash := writeRepo(t)
+	// Подменяем содержимое коммита другим корректным объектом
+	if err := os.WriteFile(repo.LoosePath(commitHash), gitobj.EncodeLoose(gitobj.TypeBlob, []byte("other")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Check(gitDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Corrupt) == 0 || r.Corrupt[0].Path != repo.LoosePath(commitHash) {
+		t.Fatalf("Corrupt = %v", r.Corrupt)
+	}
+}
+
+func TestTrailerChecksum(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "index")
+	content := []byte("DIRC\x00\x00\x00\x02\x00\x00\x00\x00")
+	sum := sha1.Sum(content)
+	os.WriteFile(fileName, append(content, sum[:]...), 0644)
+	if got, err := trailerChecksum(fileName); err != nil || got != hex.EncodeToString(sum[:]) {
+		t.Fatalf("trailerChecksum() = %s, %v", got, err)
+	}
+
+	content[5] = 1
+	os.WriteFile(fileName, append(content, sum[:]...), 0644)
+	if _, err := trailerChecksum(fileName); err == nil {
+		t.Fatal("trailerChecksum() accepted a modified file")
+	}
+	os.WriteFile(fileName, []byte("short"), 0644)
+	if _, err := trailerChecksum(fileName); err == nil {
+		t.Fatal("trailerChecksum() accepted a truncated file")
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package fsutil
+
+import "errors"
+
+// Clone is only supported on Linux.
+func Clone(src, dst string) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,33 @@
+// Package fsutil contains filesystem helpers which depend on the platform.
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ReplaceWithLink replaces dst with a hard link to src, or with a reflink
+// (copy-on-write clone) if reflink is set. dst is replaced atomically, so it
+// is left untouched on failure.
+func ReplaceWithLink(src, dst string, reflink bool) error {
+	tmp := filepath.Join(filepath.Dir(dst), "."+filepath.Base(dst)+".dedup")
+	os.Remove(tmp)
+
+	var err error
+	if reflink {
+		err = Clone(src, tmp)
+	} else {
+		err = os.Link(src, tmp)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to link %s to %s: %w", dst, src, err)
+	}
+
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace %s: %w", dst, err)
+	}
+	return nil
+}
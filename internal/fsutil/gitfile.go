@@ -0,0 +1,70 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Git modes of tree and index entries.
+const (
+	ModeTypeMask   = 0170000
+	ModeExecutable = 0100755
+	ModeSymlink    = 0120000
+	ModeGitlink    = 0160000
+)
+
+// WriteGitFile writes the content of a blob to fileName according to its git
+// mode. Executable files get mode 0755. Symlinks are only created with
+// allowSymlinks; otherwise the file holds the link target, as git writes it
+// with core.symlinks=false. Gitlinks have no content and must be handled by
+// the caller.
+func WriteGitFile(fileName string, mode uint32, data []byte, allowSymlinks bool) error {
+	isSymlink := mode&ModeTypeMask == ModeSymlink && allowSymlinks
+	// Запись через существующую ссылку изменила бы файл, на который она указывает
+	if fi, err := os.Lstat(fileName); err == nil && (isSymlink && !fi.IsDir() || fi.Mode()&os.ModeSymlink != 0) {
+		os.Remove(fileName)
+	}
+	if isSymlink {
+		return os.Symlink(string(data), fileName)
+	}
+	perm := FilePerm(mode)
+	if err := os.WriteFile(fileName, data, perm); err != nil {
+		return err
+	}
+	// WriteFile применяет права только при создании файла
+	return os.Chmod(fileName, perm)
+}
+
+// FilePerm returns the permissions of a regular file with git mode.
+func FilePerm(mode uint32) os.FileMode {
+	if mode == ModeExecutable {
+		return 0755
+	}
+	return 0644
+}
+
+// SymlinkInPath reports whether a directory between root and fileName is a
+// symlink, so writing fileName could end up outside of root.
+func SymlinkInPath(root, fileName string) bool {
+	rel, err := filepath.Rel(root, filepath.Dir(fileName))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return true
+	}
+	if rel == "." {
+		return false
+	}
+	dir := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, part)
+		fi, err := os.Lstat(dir)
+		if err != nil {
+			// Дальше каталогов ещё нет, их создаст MkdirAll
+			return false
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package fsutil
+
+import "errors"
+
+// FreeSpace is only supported on Linux.
+func FreeSpace(path string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
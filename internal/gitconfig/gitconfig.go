@@ -0,0 +1,145 @@
+package gitconfig
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Entry is a single key of a git config file.
+type Entry struct {
+	Section    string // Lowercased section name
+	Subsection string // Case sensitive subsection, e.g. remote name
+	Key        string // Lowercased key name
+	Value      string
+}
+
+// Config is a parsed git config file. Entries keep the file order.
+type Config struct {
+	Entries []Entry
+}
+
+// ParseFile parses a git config file.
+func ParseFile(fileName string) (*Config, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads git config syntax. It is lenient: malformed lines are skipped.
+func Parse(r io.Reader) (*Config, error) {
+	config := &Config{}
+	var section, subsection string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			end := strings.LastIndexByte(line, ']')
+			if end == -1 {
+				continue
+			}
+			header := strings.TrimSpace(line[1:end])
+			section, subsection = header, ""
+			if i := strings.IndexAny(header, " \t"); i != -1 {
+				section = header[:i]
+				subsection = strings.Trim(strings.TrimSpace(header[i:]), `"`)
+			} else if i := strings.IndexByte(header, '.'); i != -1 {
+				// Устаревший синтаксис [section.subsection]
+				section, subsection = header[:i], header[i+1:]
+			}
+			section = strings.ToLower(section)
+			// После заголовка на той же строке может быть key = value
+			line = strings.TrimSpace(line[end+1:])
+			if line == "" {
+				continue
+			}
+		}
+		if section == "" {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !found {
+			// Ключ без значения означает true
+			value = "true"
+		}
+		config.Entries = append(config.Entries, Entry{
+			Section:    section,
+			Subsection: subsection,
+			Key:        key,
+			Value:      unquote(strings.TrimSpace(value)),
+		})
+	}
+	return config, scanner.Err()
+}
+
+// Get returns the last value of section.subsection.key.
+func (c *Config) Get(section, subsection, key string) (string, bool) {
+	section, key = strings.ToLower(section), strings.ToLower(key)
+	var value string
+	found := false
+	for _, e := range c.Entries {
+		if e.Section == section && e.Subsection == subsection && e.Key == key {
+			value, found = e.Value, true
+		}
+	}
+	return value, found
+}
+
+// Subsections returns unique subsection names of a section in file order.
+func (c *Config) Subsections(section string) []string {
+	section = strings.ToLower(section)
+	var names []string
+	seen := make(map[string]bool)
+	for _, e := range c.Entries {
+		if e.Section == section && e.Subsection != "" && !seen[e.Subsection] {
+			seen[e.Subsection] = true
+			names = append(names, e.Subsection)
+		}
+	}
+	return names
+}
+
+// Name returns the dotted name of the entry as used by `git config`.
+func (e Entry) Name() string {
+	if e.Subsection == "" {
+		return fmt.Sprintf("%s.%s", e.Section, e.Key)
+	}
+	return fmt.Sprintf("%s.%s.%s", e.Section, e.Subsection, e.Key)
+}
+
+func unquote(value string) string {
+	// Удаляем комментарии вне кавычек и сами кавычки
+	var sb strings.Builder
+	inQuotes := false
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '\\' && i+1 < len(value):
+			i++
+			switch value[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(value[i])
+			}
+		case c == '"':
+			inQuotes = !inQuotes
+		case (c == '#' || c == ';') && !inQuotes:
+			return strings.TrimSpace(sb.String())
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,60 @@
+package gitindex
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ExportedEntry is the JSON form of an index entry.
+type ExportedEntry struct {
+	Path         string    `json:"path"`
+	Sha1         string    `json:"sha1"`
+	Mode         string    `json:"mode"`
+	Size         uint32    `json:"size"`
+	Mtime        time.Time `json:"mtime"`
+	Stage        int       `json:"stage,omitempty"`
+	AssumeValid  bool      `json:"assume_valid,omitempty"`
+	SkipWorktree bool      `json:"skip_worktree,omitempty"`
+	IntentToAdd  bool      `json:"intent_to_add,omitempty"`
+}
+
+// Export returns the fields of the entry worth showing, with the mode in
+// octal like git ls-files -s.
+func (e *GitIndexEntry) Export() ExportedEntry {
+	return ExportedEntry{
+		Path:         e.FileName,
+		Sha1:         e.Sha1,
+		Mode:         fmt.Sprintf("%06o", e.Mode),
+		Size:         e.Size,
+		Mtime:        e.Mtime.UTC(),
+		Stage:        e.Stage(),
+		AssumeValid:  e.AssumeValid(),
+		SkipWorktree: e.SkipWorktree(),
+		IntentToAdd:  e.IntentToAdd(),
+	}
+}
+
+func (e *GitIndexEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Export())
+}
+
+// CSVHeader names the columns of CSVRecord.
+var CSVHeader = []string{"path", "sha1", "mode", "size", "mtime", "stage", "assume_valid", "skip_worktree", "intent_to_add"}
+
+// CSVRecord returns the entry as a CSV row.
+func (e *GitIndexEntry) CSVRecord() []string {
+	x := e.Export()
+	return []string{
+		x.Path,
+		x.Sha1,
+		x.Mode,
+		strconv.FormatUint(uint64(x.Size), 10),
+		x.Mtime.Format(time.RFC3339),
+		strconv.Itoa(x.Stage),
+		strconv.FormatBool(x.AssumeValid),
+		strconv.FormatBool(x.SkipWorktree),
+		strconv.FormatBool(x.IntentToAdd),
+	}
+}
@@ -1,6 +1,7 @@
 package gitindex
 
 import (
+	"bufio"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
@@ -21,21 +22,98 @@ type GitIndexEntry struct {
 	Size     uint32    // Размер файла
 	Sha1     string    // SHA-1 хэш объекта
 	Flags    uint16    // Флаги записи
+	ExtFlags uint16    // Расширенные флаги (версия 3 и выше)
 	FileName string    // Имя файла
 }
 
+// Flags of index entries.
+const (
+	FlagAssumeValid = 0x8000
+	stageMask       = 0x3000
+)
+
+// Extended flags of index entries.
+const (
+	FlagSkipWorktree = 0x4000
+	FlagIntentToAdd  = 0x2000
+)
+
+// Stage returns the merge stage of the entry: 0 for a merged file, 1 for the
+// common ancestor, 2 for ours and 3 for theirs of a conflicted file.
+func (e *GitIndexEntry) Stage() int {
+	return int(e.Flags&stageMask) >> 12
+}
+
+// AssumeValid reports whether git was told not to check the file for changes
+// (git update-index --assume-unchanged).
+func (e *GitIndexEntry) AssumeValid() bool {
+	return e.Flags&FlagAssumeValid != 0
+}
+
+// SkipWorktree reports whether the file is excluded from the working tree,
+// e.g. by sparse checkout.
+func (e *GitIndexEntry) SkipWorktree() bool {
+	return e.ExtFlags&FlagSkipWorktree != 0
+}
+
+// IntentToAdd reports whether the file was added with git add -N and has no
+// staged content yet.
+func (e *GitIndexEntry) IntentToAdd() bool {
+	return e.ExtFlags&FlagIntentToAdd != 0
+}
+
 type GitIndex struct {
 	Version uint32
 	Entries []*GitIndexEntry
 }
 
+// Files returns one entry per path. Of the entries of a conflicted path it
+// picks stage 2 (ours), then 3 (theirs); paths with only the common ancestor
+// were deleted on both sides and are left out.
+func (idx GitIndex) Files() []*GitIndexEntry {
+	files := make([]*GitIndexEntry, 0, len(idx.Entries))
+	var best *GitIndexEntry
+	for i, entry := range idx.Entries {
+		if entry.Stage() != 1 && (best == nil || best.Stage() != 0 && best.Stage() != 2) {
+			best = entry
+		}
+		// Записи одного пути идут подряд, отсортированные по стадии
+		if i+1 < len(idx.Entries) && idx.Entries[i+1].FileName == entry.FileName {
+			continue
+		}
+		if best != nil {
+			files = append(files, best)
+		}
+		best = nil
+	}
+	return files
+}
+
+// Conflicts returns the paths in merge conflict state.
+func (idx GitIndex) Conflicts() []string {
+	var paths []string
+	for _, entry := range idx.Entries {
+		if entry.Stage() != 0 && (len(paths) == 0 || paths[len(paths)-1] != entry.FileName) {
+			paths = append(paths, entry.FileName)
+		}
+	}
+	return paths
+}
+
 // ParseGitIndex reads the Git index file and returns a list of entries.
 func ParseGitIndex(fileName string) (GitIndex, error) {
-	index := GitIndex{}
-	r, err := os.Open(fileName)
+	f, err := os.Open(fileName)
 	if err != nil {
-		return index, err
+		return GitIndex{}, err
 	}
+	defer f.Close()
+	return ReadIndex(f)
+}
+
+// ReadIndex parses an index from r. Extensions and the checksum are not read.
+func ReadIndex(rd io.Reader) (GitIndex, error) {
+	index := GitIndex{}
+	r := bufio.NewReader(rd)
 
 	// Read the magic number
 	var magic [4]byte
@@ -62,19 +140,23 @@ func ParseGitIndex(fileName string) (GitIndex, error) {
 
 	// Read each entry
 	// entries := make([]GitIndexEntry, numEntries)
+	prevName := ""
 	for i := uint32(0); i < numEntries; i++ {
-		entry, err := readGitEntry(r, index.Version)
+		entry, err := readGitEntry(r, index.Version, prevName)
 		if err != nil {
 			return index, fmt.Errorf("failed to read entry %d: %w", i, err)
 		}
 		index.Entries = append(index.Entries, entry)
+		prevName = entry.FileName
 	}
 
 	return index, nil
 }
 
 // readGitEntry reads a single Git index entry from the provided reader.
-func readGitEntry(r io.Reader, version uint32) (*GitIndexEntry, error) {
+// prevName is the name of the previous entry, which version 4 names are
+// compressed against.
+func readGitEntry(r *bufio.Reader, version uint32, prevName string) (*GitIndexEntry, error) {
 	entry := &GitIndexEntry{}
 
 	// Read the ctime (creation time)
@@ -135,50 +217,85 @@ func readGitEntry(r io.Reader, version uint32) (*GitIndexEntry, error) {
 
 	extended := flags&0x4000 != 0
 	nameLen := flags & 0xFFF
-	var extendedFlags uint8
 
 	entryLen := 62
 	if extended && version > 2 {
-		if err := binary.Read(r, binary.BigEndian, &extendedFlags); err != nil {
+		// Расширенные флаги занимают 2 байта
+		if err := binary.Read(r, binary.BigEndian, &entry.ExtFlags); err != nil {
 			return nil, fmt.Errorf("failed to read extended flags: %w", err)
 		}
-		entryLen += 1
+		entryLen += 2
+	}
+
+	if version == 4 {
+		// Имя сжато относительно предыдущего: сколько байт отрезать с конца
+		// и NUL-terminated остаток, без выравнивания
+		strip, err := readVarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read name prefix length: %w", err)
+		}
+		if strip > uint64(len(prevName)) {
+			return nil, fmt.Errorf("name prefix length %d exceeds previous name %q", strip, prevName)
+		}
+		suffix, err := r.ReadString(0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file name: %w", err)
+		}
+		entry.FileName = prevName[:len(prevName)-int(strip)] + suffix[:len(suffix)-1]
+		return entry, nil
 	}
 
+	// Имена длиной от 0xFFF хранятся с длиной 0xFFF, настоящую даёт NUL
+	var name []byte
 	if nameLen < 0xFFF {
-		fileNameBytes := make([]byte, nameLen)
-		if _, err := io.ReadFull(r, fileNameBytes); err != nil {
+		name = make([]byte, nameLen)
+		if _, err := io.ReadFull(r, name); err != nil {
 			return nil, fmt.Errorf("failed to read file name: %w", err)
 		}
-		entry.FileName = string(fileNameBytes)
-		entryLen += int(nameLen)
 	} else {
-		// Читаем пока не встретим NULL-байт
-		fileNameBytes := make([]byte, 0, 256)
-		for {
-			var b byte
-			if err := binary.Read(r, binary.BigEndian, &b); err != nil {
-				return nil, fmt.Errorf("failed to read file name byte: %w", err)
-			}
-			entryLen += 1
-			if b == 0 {
-				break
-			}
-			fileNameBytes = append(fileNameBytes, b)
+		var err error
+		if name, err = r.ReadBytes(0); err != nil {
+			return nil, fmt.Errorf("failed to read file name: %w", err)
 		}
-		entry.FileName = string(fileNameBytes)
+		name = name[:len(name)-1]
 	}
+	entry.FileName = string(name)
 
-	// Calculate padding
-	padding := 8 - (entryLen % 8)
-	if padding == 0 {
-		padding = 8
+	// За именем идут от 1 до 8 NUL-байт до границы 8 байт
+	size := (entryLen + len(name) + 8) &^ 7
+	consumed := entryLen + len(name)
+	if nameLen == 0xFFF {
+		consumed++
 	}
-
-	// Skip padding
-	if _, err := io.CopyN(io.Discard, r, int64(padding)); err != nil {
+	padding := make([]byte, size-consumed)
+	if _, err := io.ReadFull(r, padding); err != nil {
 		return nil, fmt.Errorf("failed to skip padding: %w", err)
 	}
+	for _, b := range padding {
+		if b != 0 {
+			return nil, fmt.Errorf("non-NUL padding after file name %q", entry.FileName)
+		}
+	}
 
 	return entry, nil
 }
+
+// readVarint reads an offset encoded like OFS_DELTA offsets of packs, which
+// version 4 indexes use for name prefixes.
+func readVarint(r io.ByteReader) (uint64, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	value := uint64(b & 0x7f)
+	for b&0x80 != 0 {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, err
+		}
+		if value > (1<<57)-1 {
+			return 0, fmt.Errorf("varint overflow")
+		}
+		value = (value+1)<<7 | uint64(b&0x7f)
+	}
+	return value, nil
+}
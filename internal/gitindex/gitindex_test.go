@@ -0,0 +1,230 @@
+package gitindex
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func entry(name string, stage int) *GitIndexEntry {
+	return &GitIndexEntry{FileName: name, Flags: uint16(stage<<12) | uint16(len(name))}
+}
+
+func TestFilesAndConflicts(t *testing.T) {
+	index := GitIndex{Entries: []*GitIndexEntry{
+		entry("both-deleted", 1),
+		entry("deleted-by-us", 1),
+		entry("deleted-by-us", 3),
+		entry("f", 1),
+		entry("f", 2),
+		entry("f", 3),
+		entry("keep", 0),
+	}}
+	var files []string
+	var stages []int
+	for _, e := range index.Files() {
+		files = append(files, e.FileName)
+		stages = append(stages, e.Stage())
+	}
+	if want := []string{"deleted-by-us", "f", "keep"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("Files() = %v, want %v", files, want)
+	}
+	if want := []int{3, 2, 0}; !reflect.DeepEqual(stages, want) {
+		t.Errorf("stages = %v, want %v", stages, want)
+	}
+	if got, want := index.Conflicts(), []string{"both-deleted", "deleted-by-us", "f"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Conflicts() = %v, want %v", got, want)
+	}
+}
+
+func TestAssumeValid(t *testing.T) {
+	e := &GitIndexEntry{Flags: FlagAssumeValid | 2<<12 | 3}
+	if !e.AssumeValid() || e.Stage() != 2 {
+		t.Errorf("AssumeValid() = %v, Stage() = %d", e.AssumeValid(), e.Stage())
+	}
+}
+
+// encodeIndex builds an index file the way git writes it.
+func encodeIndex(version uint32, names []string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("DIRC")
+	binary.Write(&buf, binary.BigEndian, version)
+	binary.Write(&buf, binary.BigEndian, uint32(len(names)))
+	prev := ""
+	for i, name := range names {
+		start := buf.Len()
+		buf.Write(make([]byte, 40)) // ctime, mtime, dev, ino, mode, uid, gid, size
+		hash := make([]byte, 20)
+		hash[19] = byte(i)
+		buf.Write(hash)
+		nameLen := len(name)
+		if nameLen > 0xFFF {
+			nameLen = 0xFFF
+		}
+		flags := uint16(nameLen)
+		if version == 3 {
+			flags |= 0x4000
+		}
+		binary.Write(&buf, binary.BigEndian, flags)
+		if version == 3 {
+			binary.Write(&buf, binary.BigEndian, uint16(FlagIntentToAdd))
+		}
+		if version == 4 {
+			common := 0
+			for common < len(prev) && common < len(name) && prev[common] == name[common] {
+				common++
+			}
+			buf.Write(encodeVarint(uint64(len(prev) - common)))
+			buf.WriteString(name[common:])
+			buf.WriteByte(0)
+		} else {
+			buf.WriteString(name)
+			size := (buf.Len() - start + 8) &^ 7
+			buf.Write(make([]byte, size-(buf.Len()-start)))
+		}
+		prev = name
+	}
+	sum := sha1.Sum(buf.Bytes())
+	buf.Write(sum[:])
+	return buf.Bytes()
+}
+
+func TestParseNamePadding(t *testing.T) {
+	// Имена всех длин по модулю 8, вокруг 0xFFF и длиннее
+	var names []string
+	for _, n := range []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 0xFF8, 0xFF9, 0xFFA, 0xFFB, 0xFFC, 0xFFD, 0xFFE, 0xFFF, 0x1000, 0x1001, 0x1002, 0x1003, 0x1004, 0x1005, 0x1006, 0x1007, 5000} {
+		names = append(names, fmt.Sprintf("%04d/%s", n, strings.Repeat("x", n)))
+	}
+	for _, version := range []uint32{2, 3, 4} {
+		fileName := filepath.Join(t.TempDir(), "index")
+		if err := os.WriteFile(fileName, encodeIndex(version, names), 0644); err != nil {
+			t.Fatal(err)
+		}
+		index, err := ParseGitIndex(fileName)
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if len(index.Entries) != len(names) {
+			t.Fatalf("version %d: %d entries, want %d", version, len(index.Entries), len(names))
+		}
+		for i, e := range index.Entries {
+			if e.FileName != names[i] {
+				t.Errorf("version %d: entry %d has a name of %d bytes, want %d", version, i, len(e.FileName), len(names[i]))
+			}
+			if e.Sha1[38:] != fmt.Sprintf("%02x", i) {
+				t.Errorf("version %d: entry %d has hash %s", version, i, e.Sha1)
+			}
+			if version == 3 && !e.IntentToAdd() {
+				t.Errorf("version 3: entry %d lost its extended flags", i)
+			}
+		}
+	}
+}
+
+func TestParseGitGenerated(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return string(out)
+	}
+	git("init", "-q")
+	blob := strings.TrimSpace(git("hash-object", "-w", "--stdin"))
+	var want []string
+	for i, n := range []int{3, 0xFFE, 0xFFF, 0x1000, 4500} {
+		name := fmt.Sprintf("d%d/%s", i, strings.Repeat("y", n))
+		git("update-index", "--add", "--cacheinfo", "100644,"+blob+","+name)
+		want = append(want, name)
+	}
+	for _, version := range []string{"2", "3", "4"} {
+		git("update-index", "--index-version", version)
+		index, err := ParseGitIndex(filepath.Join(dir, ".git", "index"))
+		if err != nil {
+			t.Fatalf("version %s: %v", version, err)
+		}
+		var got []string
+		for _, e := range index.Entries {
+			got = append(got, e.FileName)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("version %s: names differ from git ls-files", version)
+		}
+	}
+}
+
+func TestWriteIndex(t *testing.T) {
+	names := []string{"a", "b/c", "b/d", strings.Repeat("e", 0x1001)}
+	for _, version := range []uint32{2, 3, 4} {
+		index, err := ReadIndex(bytes.NewReader(encodeIndex(version, names)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		index.Entries[1].Flags |= FlagAssumeValid | 2<<12
+		var buf bytes.Buffer
+		if err := WriteIndex(&buf, index); err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		data := buf.Bytes()
+		if sum := sha1.Sum(data[:len(data)-20]); !bytes.Equal(sum[:], data[len(data)-20:]) {
+			t.Errorf("version %d: bad checksum", version)
+		}
+		written, err := ReadIndex(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if !reflect.DeepEqual(written, index) {
+			t.Errorf("version %d: entries changed after writing", version)
+		}
+	}
+}
+
+func TestWriteIndexReadByGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return string(out)
+	}
+	git("init", "-q")
+	blob := strings.TrimSpace(git("hash-object", "-w", "--stdin"))
+	for _, name := range []string{"x", "dir/y", strings.Repeat("z", 0x1000)} {
+		git("update-index", "--add", "--cacheinfo", "100755,"+blob+","+name)
+	}
+	indexFile := filepath.Join(dir, ".git", "index")
+	want := git("ls-files", "-s")
+	for _, version := range []uint32{2, 4} {
+		index, err := ParseGitIndex(indexFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		index.Version = version
+		var buf bytes.Buffer
+		if err := WriteIndex(&buf, index); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(indexFile, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := git("ls-files", "-s"); got != want {
+			t.Errorf("version %d: git ls-files -s = %q, want %q", version, got, want)
+		}
+	}
+}
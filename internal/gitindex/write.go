@@ -0,0 +1,108 @@
+package gitindex
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+)
+
+const flagExtended = 0x4000
+
+// WriteIndex writes index in its version (2, 3 or 4) followed by the SHA-1
+// checksum git expects. Extensions are not written. Entries must be sorted
+// by name and stage, as git keeps them.
+func WriteIndex(w io.Writer, index GitIndex) error {
+	if index.Version < 2 || index.Version > 4 {
+		return fmt.Errorf("unsupported version: %d", index.Version)
+	}
+	h := sha1.New()
+	bw := bufio.NewWriter(io.MultiWriter(w, h))
+
+	bw.WriteString("DIRC")
+	binary.Write(bw, binary.BigEndian, index.Version)
+	binary.Write(bw, binary.BigEndian, uint32(len(index.Entries)))
+	prevName := ""
+	for i, entry := range index.Entries {
+		if err := writeEntry(bw, index.Version, entry, prevName); err != nil {
+			return fmt.Errorf("failed to write entry %d: %w", i, err)
+		}
+		prevName = entry.FileName
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	_, err := w.Write(h.Sum(nil))
+	return err
+}
+
+func writeEntry(w *bufio.Writer, version uint32, entry *GitIndexEntry, prevName string) error {
+	hash, err := hex.DecodeString(entry.Sha1)
+	if err != nil || len(hash) != 20 {
+		return fmt.Errorf("invalid hash %q", entry.Sha1)
+	}
+	extended := entry.ExtFlags != 0
+	if extended && version == 2 {
+		return fmt.Errorf("extended flags of %s need version 3 or later", entry.FileName)
+	}
+
+	ctimeSec, ctimeNsec := unixTime(entry.Ctime)
+	mtimeSec, mtimeNsec := unixTime(entry.Mtime)
+	for _, v := range []uint32{ctimeSec, ctimeNsec, mtimeSec, mtimeNsec, entry.Dev, entry.Ino, entry.Mode, entry.Uid, entry.Gid, entry.Size} {
+		binary.Write(w, binary.BigEndian, v)
+	}
+	w.Write(hash)
+
+	nameLen := len(entry.FileName)
+	if nameLen > 0xFFF {
+		nameLen = 0xFFF
+	}
+	// Стадия и assume-valid сохраняются, длина и признак расширенных флагов
+	// вычисляются заново
+	flags := entry.Flags&(FlagAssumeValid|stageMask) | uint16(nameLen)
+	if extended {
+		flags |= flagExtended
+	}
+	binary.Write(w, binary.BigEndian, flags)
+	if extended {
+		binary.Write(w, binary.BigEndian, entry.ExtFlags)
+	}
+
+	if version == 4 {
+		common := 0
+		for common < len(prevName) && common < len(entry.FileName) && prevName[common] == entry.FileName[common] {
+			common++
+		}
+		w.Write(encodeVarint(uint64(len(prevName) - common)))
+		w.WriteString(entry.FileName[common:])
+		return w.WriteByte(0)
+	}
+	w.WriteString(entry.FileName)
+	// От 1 до 8 NUL-байт до границы 8 байт
+	size := 62 + len(entry.FileName)
+	if extended {
+		size += 2
+	}
+	_, err = w.Write(make([]byte, (size+8)&^7-size))
+	return err
+}
+
+func unixTime(t time.Time) (uint32, uint32) {
+	if t.IsZero() {
+		return 0, 0
+	}
+	return uint32(t.Unix()), uint32(t.Nanosecond())
+}
+
+// encodeVarint encodes an offset the way readVarint decodes it.
+func encodeVarint(value uint64) []byte {
+	b := []byte{byte(value & 0x7f)}
+	for value >>= 7; value != 0; value >>= 7 {
+		value--
+		b = append([]byte{byte(0x80 | value&0x7f)}, b...)
+	}
+	return b
+}
@@ -0,0 +1,117 @@
+package gitobj
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// chunk is an entry of the chunk table used by commit-graph and multi-pack-index.
+type chunk struct {
+	id   string
+	data []byte
+}
+
+func readChunks(data []byte, tableOffset, numChunks int) (map[string][]byte, error) {
+	end := tableOffset + (numChunks+1)*12
+	if len(data) < end {
+		return nil, fmt.Errorf("chunk table is truncated")
+	}
+	var entries []chunk
+	offsets := make([]uint64, 0, numChunks+1)
+	for i := 0; i <= numChunks; i++ {
+		entry := data[tableOffset+i*12:]
+		entries = append(entries, chunk{id: string(entry[:4])})
+		offsets = append(offsets, binary.BigEndian.Uint64(entry[4:12]))
+	}
+	chunks := make(map[string][]byte)
+	for i := 0; i < numChunks; i++ {
+		start, stop := offsets[i], offsets[i+1]
+		if start > stop || stop > uint64(len(data)) {
+			return nil, fmt.Errorf("chunk %q is out of range", entries[i].id)
+		}
+		chunks[entries[i].id] = data[start:stop]
+	}
+	return chunks, nil
+}
+
+func readOids(data []byte) []string {
+	oids := make([]string, 0, len(data)/20)
+	for i := 0; i+20 <= len(data); i += 20 {
+		oids = append(oids, hex.EncodeToString(data[i:i+20]))
+	}
+	return oids
+}
+
+// ParseCommitGraph returns commit and root tree hashes listed in a
+// commit-graph file.
+func ParseCommitGraph(data []byte) (commits, trees []string, err error) {
+	if len(data) < 8 || string(data[:4]) != "CGPH" {
+		return nil, nil, fmt.Errorf("invalid commit-graph signature")
+	}
+	if data[4] != 1 {
+		return nil, nil, fmt.Errorf("unsupported commit-graph version %d", data[4])
+	}
+	if data[5] != 1 {
+		return nil, nil, fmt.Errorf("unsupported commit-graph hash version %d", data[5])
+	}
+	chunks, err := readChunks(data, 8, int(data[6]))
+	if err != nil {
+		return nil, nil, err
+	}
+	commits = readOids(chunks["OIDL"])
+	// CDAT: tree (20 байт), 2 родителя, поколение и время — 36 байт на коммит
+	cdat := chunks["CDAT"]
+	for i := 0; i+36 <= len(cdat); i += 36 {
+		trees = append(trees, hex.EncodeToString(cdat[i:i+20]))
+	}
+	return commits, trees, nil
+}
+
+// ParseCommitGraphChain returns graph file names from a commit-graph-chain file.
+func ParseCommitGraphChain(data []byte) []string {
+	var names []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 40 && isHex(line) {
+			names = append(names, "graph-"+line+".graph")
+		}
+	}
+	return names
+}
+
+// ParseMultiPackIndex returns pack index names and object hashes listed in a
+// multi-pack-index file.
+func ParseMultiPackIndex(data []byte) (packs, oids []string, err error) {
+	if len(data) < 12 || string(data[:4]) != "MIDX" {
+		return nil, nil, fmt.Errorf("invalid multi-pack-index signature")
+	}
+	if data[4] != 1 {
+		return nil, nil, fmt.Errorf("unsupported multi-pack-index version %d", data[4])
+	}
+	if data[5] != 1 {
+		return nil, nil, fmt.Errorf("unsupported multi-pack-index hash version %d", data[5])
+	}
+	chunks, err := readChunks(data, 12, int(data[6]))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, name := range bytes.Split(chunks["PNAM"], []byte{0}) {
+		// Имена попадают в URL, поэтому принимаются только pack-<sha1>.idx
+		if isPackIndexName(string(name)) {
+			packs = append(packs, string(name))
+		}
+	}
+	return packs, readOids(chunks["OIDL"]), nil
+}
+
+func isPackIndexName(name string) bool {
+	hash, ok := strings.CutPrefix(name, "pack-")
+	if !ok {
+		return false
+	}
+	hash, ok = strings.CutSuffix(hash, ".idx")
+	return ok && len(hash) == 40 && isHex(hash)
+}
@@ -0,0 +1,107 @@
+package gitobj
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// chunkFile builds a file with the given header followed by a chunk table.
+func chunkFile(header []byte, ids []string, chunks [][]byte) []byte {
+	offset := uint64(len(header) + (len(ids)+1)*12)
+	var table, body []byte
+	for i, id := range ids {
+		table = append(table, id...)
+		table = binary.BigEndian.AppendUint64(table, offset)
+		offset += uint64(len(chunks[i]))
+		body = append(body, chunks[i]...)
+	}
+	table = append(table, 0, 0, 0, 0)
+	table = binary.BigEndian.AppendUint64(table, offset)
+	return append(append(append([]byte{}, header...), table...), body...)
+}
+
+func TestParseCommitGraph(t *testing.T) {
+	commit := bytes.Repeat([]byte{0x11}, 20)
+	cdat := append(bytes.Repeat([]byte{0x22}, 20), make([]byte, 16)...)
+	data := chunkFile([]byte("CGPH\x01\x01\x02\x00"), []string{"OIDL", "CDAT"}, [][]byte{commit, cdat})
+
+	commits, trees, err := ParseCommitGraph(data)
+	if err != nil {
+		t.Fatalf("ParseCommitGraph() error = %v", err)
+	}
+	if len(commits) != 1 || commits[0] != strings.Repeat("11", 20) {
+		t.Fatalf("commits = %v", commits)
+	}
+	if len(trees) != 1 || trees[0] != strings.Repeat("22", 20) {
+		t.Fatalf("trees = %v", trees)
+	}
+
+	// Любой обрезанный вариант должен давать ошибку или неполный результат, но не панику
+	for n := 0; n < len(data); n++ {
+		ParseCommitGraph(data[:n])
+	}
+	if _, _, err := ParseCommitGraph(data[:20]); err == nil {
+		t.Fatal("ParseCommitGraph() accepted a truncated chunk table")
+	}
+}
+
+func TestParseCommitGraphInvalid(t *testing.T) {
+	tests := map[string][]byte{
+		"empty":         nil,
+		"signature":     []byte("XXXX\x01\x01\x00\x00"),
+		"version":       []byte("CGPH\x02\x01\x00\x00"),
+		"hash version":  []byte("CGPH\x01\x02\x00\x00"),
+		"many chunks":   []byte("CGPH\x01\x01\xff\x00"),
+		"chunk too big": chunkFile([]byte("CGPH\x01\x01\x01\x00"), []string{"OIDL"}, [][]byte{{1}})[:20],
+	}
+	for name, data := range tests {
+		if _, _, err := ParseCommitGraph(data); err == nil {
+			t.Errorf("%s: ParseCommitGraph() accepted invalid data", name)
+		}
+	}
+
+	// Смещение чанка за пределами файла
+	data := chunkFile([]byte("CGPH\x01\x01\x01\x00"), []string{"OIDL"}, [][]byte{make([]byte, 20)})
+	binary.BigEndian.PutUint64(data[8+4:], 1<<40)
+	if _, _, err := ParseCommitGraph(data); err == nil {
+		t.Fatal("ParseCommitGraph() accepted an out of range chunk")
+	}
+}
+
+func TestParseCommitGraphChain(t *testing.T) {
+	hash := strings.Repeat("ab", 20)
+	got := ParseCommitGraphChain([]byte(hash + "\n../../etc/passwd\nshort\n"))
+	if len(got) != 1 || got[0] != "graph-"+hash+".graph" {
+		t.Fatalf("ParseCommitGraphChain() = %v", got)
+	}
+}
+
+func TestParseMultiPackIndex(t *testing.T) {
+	packA := "pack-" + strings.Repeat("a", 40) + ".idx"
+	packB := "pack-" + strings.Repeat("b", 40) + ".idx"
+	pnam := []byte(packA + "\x00../../evil.idx\x00" + packB + "\x00")
+	oidl := bytes.Repeat([]byte{0x33}, 40)
+	data := chunkFile([]byte("MIDX\x01\x01\x02\x00\x00\x00\x00\x02"), []string{"PNAM", "OIDL"}, [][]byte{pnam, oidl})
+
+	packs, oids, err := ParseMultiPackIndex(data)
+	if err != nil {
+		t.Fatalf("ParseMultiPackIndex() error = %v", err)
+	}
+	if len(packs) != 2 || packs[0] != packA || packs[1] != packB {
+		t.Fatalf("packs = %v", packs)
+	}
+	if len(oids) != 2 {
+		t.Fatalf("oids = %v", oids)
+	}
+
+	for n := 0; n < len(data); n++ {
+		ParseMultiPackIndex(data[:n])
+	}
+	for _, data := range [][]byte{nil, []byte("MIDX"), []byte("MIDX\x01\x01\xff\x00\x00\x00\x00\x00")} {
+		if _, _, err := ParseMultiPackIndex(data); err == nil {
+			t.Errorf("ParseMultiPackIndex(%q) accepted invalid data", data)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package gitobj
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Object types as stored in object headers.
+const (
+	TypeCommit = "commit"
+	TypeTree   = "tree"
+	TypeBlob   = "blob"
+	TypeTag    = "tag"
+)
+
+// Object is a decompressed git object without its header.
+type Object struct {
+	Hash string
+	Type string
+	Data []byte
+}
+
+// Signature is an author, committer or tagger line.
+type Signature struct {
+	Name  string
+	Email string
+	When  time.Time
+}
+
+// Commit is a parsed commit object.
+type Commit struct {
+	Hash      string
+	Tree      string
+	Parents   []string
+	Author    Signature
+	Committer Signature
+	Message   string
+}
+
+// TreeEntry is a single entry of a tree object.
+type TreeEntry struct {
+	Mode string
+	Name string
+	Hash string
+}
+
+// Tag is a parsed annotated tag object.
+type Tag struct {
+	Hash    string
+	Object  string
+	Type    string
+	Name    string
+	Tagger  Signature
+	Message string
+}
+
+// ParseCommit parses the body of a commit object.
+func ParseCommit(hash string, data []byte) (*Commit, error) {
+	c := &Commit{Hash: hash}
+	headers, message := splitMessage(data)
+	for _, line := range headers {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "tree":
+			c.Tree = value
+		case "parent":
+			c.Parents = append(c.Parents, value)
+		case "author":
+			c.Author = parseSignature(value)
+		case "committer":
+			c.Committer = parseSignature(value)
+		}
+	}
+	if c.Tree == "" {
+		return nil, fmt.Errorf("commit %s has no tree", hash)
+	}
+	c.Message = message
+	return c, nil
+}
+
+// ParseTag parses the body of an annotated tag object.
+func ParseTag(hash string, data []byte) (*Tag, error) {
+	t := &Tag{Hash: hash}
+	headers, message := splitMessage(data)
+	for _, line := range headers {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "object":
+			t.Object = value
+		case "type":
+			t.Type = value
+		case "tag":
+			t.Name = value
+		case "tagger":
+			t.Tagger = parseSignature(value)
+		}
+	}
+	if t.Object == "" {
+		return nil, fmt.Errorf("tag %s has no object", hash)
+	}
+	t.Message = message
+	return t, nil
+}
+
+// ParseTree parses the body of a tree object.
+func ParseTree(data []byte) ([]TreeEntry, error) {
+	var entries []TreeEntry
+	for len(data) > 0 {
+		space := bytes.IndexByte(data, ' ')
+		if space == -1 {
+			return nil, fmt.Errorf("invalid tree entry: missing mode")
+		}
+		nul := bytes.IndexByte(data[space+1:], 0)
+		if nul == -1 {
+			return nil, fmt.Errorf("invalid tree entry: missing name")
+		}
+		nameEnd := space + 1 + nul
+		if len(data) < nameEnd+21 {
+			return nil, fmt.Errorf("invalid tree entry: truncated hash")
+		}
+		entries = append(entries, TreeEntry{
+			Mode: string(data[:space]),
+			Name: string(data[space+1 : nameEnd]),
+			Hash: fmt.Sprintf("%x", data[nameEnd+1:nameEnd+21]),
+		})
+		data = data[nameEnd+21:]
+	}
+	return entries, nil
+}
+
+// IsTree reports whether the entry points to a subdirectory.
+func (e TreeEntry) IsTree() bool {
+	return e.Mode == "40000" || e.Mode == "040000"
+}
+
+// FileMode returns the mode as a number, 0100644 for a regular file.
+func (e TreeEntry) FileMode() uint32 {
+	mode, _ := strconv.ParseUint(e.Mode, 8, 32)
+	return uint32(mode)
+}
+
+// IsSubmodule reports whether the entry is a gitlink.
+func (e TreeEntry) IsSubmodule() bool {
+	return e.Mode == "160000"
+}
+
+func splitMessage(data []byte) ([]string, string) {
+	text := string(data)
+	head, message, _ := strings.Cut(text, "\n\n")
+	var headers []string
+	for _, line := range strings.Split(head, "\n") {
+		// Продолжения многострочных заголовков (gpgsig) начинаются с пробела
+		if strings.HasPrefix(line, " ") {
+			continue
+		}
+		headers = append(headers, line)
+	}
+	return headers, message
+}
+
+func parseSignature(value string) Signature {
+	var sig Signature
+	lt := strings.IndexByte(value, '<')
+	gt := strings.IndexByte(value, '>')
+	if lt == -1 || gt < lt {
+		sig.Name = value
+		return sig
+	}
+	sig.Name = strings.TrimSpace(value[:lt])
+	sig.Email = value[lt+1 : gt]
+	fields := strings.Fields(value[gt+1:])
+	if len(fields) >= 1 {
+		if sec, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+			loc := time.UTC
+			if len(fields) >= 2 {
+				loc = parseTimezone(fields[1])
+			}
+			sig.When = time.Unix(sec, 0).In(loc)
+		}
+	}
+	return sig
+}
+
+func parseTimezone(tz string) *time.Location {
+	if len(tz) != 5 {
+		return time.UTC
+	}
+	hours, err1 := strconv.Atoi(tz[1:3])
+	minutes, err2 := strconv.Atoi(tz[3:5])
+	if err1 != nil || err2 != nil {
+		return time.UTC
+	}
+	offset := hours*3600 + minutes*60
+	if tz[0] == '-' {
+		offset = -offset
+	}
+	return time.FixedZone(tz, offset)
+}
+
+// HashObject returns the SHA-1 of an object as git computes it.
+func HashObject(objType string, data []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s %d\x00", objType, len(data))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// EncodeLoose returns the zlib-compressed loose object representation.
+func EncodeLoose(objType string, data []byte) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	fmt.Fprintf(zw, "%s %d\x00", objType, len(data))
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
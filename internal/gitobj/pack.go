@@ -0,0 +1,389 @@
+package gitobj
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	packObjCommit   = 1
+	packObjTree     = 2
+	packObjBlob     = 3
+	packObjTag      = 4
+	packObjOfsDelta = 6
+	packObjRefDelta = 7
+)
+
+// MaxObjectSize limits the size of a single object read from a pack. Sizes
+// come from untrusted headers, so they are never allocated blindly.
+const MaxObjectSize = 1 << 30
+
+// maxDeltaDepth limits delta chains, including REF_DELTA bases in other packs.
+const maxDeltaDepth = 64
+
+// Resolver returns an object by hash for REF_DELTA bases outside of a pack.
+// depth is the current delta chain depth and must be passed on.
+type Resolver func(hash string, depth int) (*Object, error)
+
+// TypeResolver is like Resolver, but only returns the object type.
+type TypeResolver func(hash string, depth int) (string, error)
+
+var packTypeNames = map[int]string{
+	packObjCommit: TypeCommit,
+	packObjTree:   TypeTree,
+	packObjBlob:   TypeBlob,
+	packObjTag:    TypeTag,
+}
+
+// Pack is a pack file together with its parsed .idx.
+type Pack struct {
+	Name     string
+	Hashes   []string // Отсортированы, как в .idx
+	Offsets  []int64
+	Checksum string // SHA-1 пакета, записанный в .idx
+	file     *os.File
+}
+
+// OpenPack opens a pack by the path of its .idx file.
+func OpenPack(idxPath string) (*Pack, error) {
+	hashes, offsets, checksum, err := ReadPackIndex(idxPath)
+	if err != nil {
+		return nil, err
+	}
+	packPath := strings.TrimSuffix(idxPath, ".idx") + ".pack"
+	file, err := os.Open(packPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Pack{
+		Name:     packPath,
+		Hashes:   hashes,
+		Offsets:  offsets,
+		Checksum: checksum,
+		file:     file,
+	}, nil
+}
+
+func (p *Pack) Close() error {
+	return p.file.Close()
+}
+
+// ReadPackIndex parses a v1 or v2 pack index file.
+func ReadPackIndex(idxPath string) ([]string, []int64, string, error) {
+	data, err := os.ReadFile(idxPath)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if len(data) < 8+256*4+40 {
+		return nil, nil, "", fmt.Errorf("pack index %s is too short", idxPath)
+	}
+
+	if bytes.Equal(data[:4], []byte{0xff, 't', 'O', 'c'}) {
+		version := binary.BigEndian.Uint32(data[4:8])
+		if version != 2 {
+			return nil, nil, "", fmt.Errorf("unsupported pack index version %d", version)
+		}
+		return readPackIndexV2(data[8:], data)
+	}
+
+	// Версия 1: fanout и затем пары (offset, sha1)
+	n := int(binary.BigEndian.Uint32(data[255*4:]))
+	body := data[256*4:]
+	if len(body) < n*24+40 {
+		return nil, nil, "", fmt.Errorf("pack index %s is truncated", idxPath)
+	}
+	hashes := make([]string, n)
+	offsets := make([]int64, n)
+	for i := 0; i < n; i++ {
+		entry := body[i*24:]
+		offsets[i] = int64(binary.BigEndian.Uint32(entry))
+		hashes[i] = hex.EncodeToString(entry[4:24])
+	}
+	checksum := hex.EncodeToString(body[n*24 : n*24+20])
+	return hashes, offsets, checksum, nil
+}
+
+func readPackIndexV2(body, data []byte) ([]string, []int64, string, error) {
+	n := int(binary.BigEndian.Uint32(body[255*4:]))
+	pos := 256 * 4
+	if len(body) < pos+n*(20+4+4)+40 {
+		return nil, nil, "", fmt.Errorf("pack index is truncated")
+	}
+	hashes := make([]string, n)
+	for i := 0; i < n; i++ {
+		hashes[i] = hex.EncodeToString(body[pos+i*20 : pos+i*20+20])
+	}
+	pos += n * 20
+	pos += n * 4 // CRC32
+	smallOffsets := body[pos : pos+n*4]
+	pos += n * 4
+	largeOffsets := body[pos:]
+	offsets := make([]int64, n)
+	for i := 0; i < n; i++ {
+		off := binary.BigEndian.Uint32(smallOffsets[i*4:])
+		if off&0x80000000 != 0 {
+			idx := int(off & 0x7fffffff)
+			if len(largeOffsets) < idx*8+8 {
+				return nil, nil, "", fmt.Errorf("invalid large offset index %d", idx)
+			}
+			offsets[i] = int64(binary.BigEndian.Uint64(largeOffsets[idx*8:]))
+		} else {
+			offsets[i] = int64(off)
+		}
+	}
+	checksum := hex.EncodeToString(data[len(data)-40 : len(data)-20])
+	return hashes, offsets, checksum, nil
+}
+
+// Find returns the offset of hash in the pack.
+func (p *Pack) Find(hash string) (int64, bool) {
+	i := sort.SearchStrings(p.Hashes, hash)
+	if i < len(p.Hashes) && p.Hashes[i] == hash {
+		return p.Offsets[i], true
+	}
+	return 0, false
+}
+
+// ReadAt reads and resolves the object stored at offset. resolve is used for
+// REF_DELTA bases which may live outside of the pack.
+func (p *Pack) ReadAt(offset int64, resolve Resolver, depth int) (string, []byte, error) {
+	if depth > maxDeltaDepth {
+		return "", nil, errors.New("delta chain is too deep")
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(p.file, offset, 1<<62))
+	objType, size, err := readPackObjectHeader(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch objType {
+	case packObjCommit, packObjTree, packObjBlob, packObjTag:
+		data, err := inflate(r, size)
+		if err != nil {
+			return "", nil, err
+		}
+		return packTypeNames[objType], data, nil
+	case packObjOfsDelta:
+		rel, err := readOfsDeltaOffset(r)
+		if err != nil {
+			return "", nil, err
+		}
+		delta, err := inflate(r, size)
+		if err != nil {
+			return "", nil, err
+		}
+		baseType, base, err := p.ReadAt(offset-rel, resolve, depth+1)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read delta base: %w", err)
+		}
+		data, err := ApplyDelta(base, delta)
+		return baseType, data, err
+	case packObjRefDelta:
+		var baseHash [20]byte
+		if _, err := io.ReadFull(r, baseHash[:]); err != nil {
+			return "", nil, err
+		}
+		delta, err := inflate(r, size)
+		if err != nil {
+			return "", nil, err
+		}
+		var base *Object
+		if off, ok := p.Find(hex.EncodeToString(baseHash[:])); ok {
+			base = &Object{}
+			base.Type, base.Data, err = p.ReadAt(off, resolve, depth+1)
+		} else if resolve != nil {
+			base, err = resolve(hex.EncodeToString(baseHash[:]), depth+1)
+		} else {
+			err = fmt.Errorf("delta base %x not found", baseHash)
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read delta base: %w", err)
+		}
+		data, err := ApplyDelta(base.Data, delta)
+		return base.Type, data, err
+	}
+
+	return "", nil, fmt.Errorf("unknown pack object type %d", objType)
+}
+
+// TypeAt returns the type of the object stored at offset without inflating
+// it. Deltas are followed to their base headers.
+func (p *Pack) TypeAt(offset int64, resolve TypeResolver, depth int) (string, error) {
+	if depth > maxDeltaDepth {
+		return "", errors.New("delta chain is too deep")
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(p.file, offset, 1<<62))
+	objType, _, err := readPackObjectHeader(r)
+	if err != nil {
+		return "", err
+	}
+
+	switch objType {
+	case packObjCommit, packObjTree, packObjBlob, packObjTag:
+		return packTypeNames[objType], nil
+	case packObjOfsDelta:
+		rel, err := readOfsDeltaOffset(r)
+		if err != nil {
+			return "", err
+		}
+		return p.TypeAt(offset-rel, resolve, depth+1)
+	case packObjRefDelta:
+		var baseHash [20]byte
+		if _, err := io.ReadFull(r, baseHash[:]); err != nil {
+			return "", err
+		}
+		hash := hex.EncodeToString(baseHash[:])
+		if off, ok := p.Find(hash); ok {
+			return p.TypeAt(off, resolve, depth+1)
+		}
+		if resolve != nil {
+			return resolve(hash, depth+1)
+		}
+		return "", fmt.Errorf("delta base %s not found", hash)
+	}
+
+	return "", fmt.Errorf("unknown pack object type %d", objType)
+}
+
+func readPackObjectHeader(r io.ByteReader) (int, int64, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	objType := int(b>>4) & 7
+	size := int64(b & 0x0f)
+	shift := uint(4)
+	for b&0x80 != 0 {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, 0, err
+		}
+		if shift > 56 {
+			return 0, 0, errors.New("pack object size is too large")
+		}
+		size |= int64(b&0x7f) << shift
+		shift += 7
+	}
+	return objType, size, nil
+}
+
+func readOfsDeltaOffset(r io.ByteReader) (int64, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	off := int64(b & 0x7f)
+	for b&0x80 != 0 {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, err
+		}
+		if off > 1<<55 {
+			return 0, errors.New("delta base offset is too large")
+		}
+		off = ((off + 1) << 7) | int64(b&0x7f)
+	}
+	if off <= 0 {
+		// Нулевое смещение указывает на сам объект и даёт бесконечный цикл
+		return 0, errors.New("invalid delta base offset")
+	}
+	return off, nil
+}
+
+func inflate(r io.Reader, size int64) ([]byte, error) {
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zlib reader: %w", err)
+	}
+	defer zr.Close()
+	if size < 0 || size > MaxObjectSize {
+		return nil, fmt.Errorf("object size %d exceeds limit", size)
+	}
+	// Буфер растёт по мере чтения, а не по размеру из заголовка
+	data, err := io.ReadAll(io.LimitReader(zr, size))
+	if err != nil {
+		return nil, fmt.Errorf("failed to inflate object: %w", err)
+	}
+	if int64(len(data)) != size {
+		return nil, fmt.Errorf("failed to inflate object: got %d of %d bytes", len(data), size)
+	}
+	return data, nil
+}
+
+// ApplyDelta reconstructs an object from its base and a git delta.
+func ApplyDelta(base, delta []byte) ([]byte, error) {
+	r := bytes.NewReader(delta)
+	srcSize, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid delta: %w", err)
+	}
+	if srcSize != uint64(len(base)) {
+		return nil, fmt.Errorf("delta base size mismatch: %d != %d", srcSize, len(base))
+	}
+	dstSize, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid delta: %w", err)
+	}
+
+	if dstSize > MaxObjectSize {
+		return nil, fmt.Errorf("delta result size %d exceeds limit", dstSize)
+	}
+
+	out := make([]byte, 0, min(dstSize, uint64(len(base)+len(delta))))
+	for r.Len() > 0 {
+		op, _ := r.ReadByte()
+		if op&0x80 != 0 {
+			var offset, size uint32
+			for i := uint(0); i < 4; i++ {
+				if op&(1<<i) != 0 {
+					b, err := r.ReadByte()
+					if err != nil {
+						return nil, fmt.Errorf("invalid delta copy: %w", err)
+					}
+					offset |= uint32(b) << (8 * i)
+				}
+			}
+			for i := uint(0); i < 3; i++ {
+				if op&(0x10<<i) != 0 {
+					b, err := r.ReadByte()
+					if err != nil {
+						return nil, fmt.Errorf("invalid delta copy: %w", err)
+					}
+					size |= uint32(b) << (8 * i)
+				}
+			}
+			if size == 0 {
+				size = 0x10000
+			}
+			if uint64(offset)+uint64(size) > uint64(len(base)) {
+				return nil, fmt.Errorf("delta copy out of range")
+			}
+			out = append(out, base[offset:offset+size]...)
+		} else if op != 0 {
+			chunk := make([]byte, op)
+			if _, err := io.ReadFull(r, chunk); err != nil {
+				return nil, fmt.Errorf("invalid delta insert: %w", err)
+			}
+			out = append(out, chunk...)
+		} else {
+			return nil, fmt.Errorf("invalid delta opcode 0")
+		}
+		if uint64(len(out)) > dstSize {
+			return nil, fmt.Errorf("delta result exceeds declared size %d", dstSize)
+		}
+	}
+
+	if uint64(len(out)) != dstSize {
+		return nil, fmt.Errorf("delta result size mismatch: %d != %d", len(out), dstSize)
+	}
+	return out, nil
+}
@@ -0,0 +1,224 @@
+package gitobj
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func compress(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// packHeader encodes a pack object header with type and size.
+func packHeader(objType int, size int) []byte {
+	b := byte(objType<<4) | byte(size&0x0f)
+	size >>= 4
+	var out []byte
+	for size > 0 {
+		out = append(out, b|0x80)
+		b = byte(size & 0x7f)
+		size >>= 7
+	}
+	return append(out, b)
+}
+
+// writePack writes body after a pack header to a temporary file and returns
+// a Pack with the given index entries.
+func writePack(t *testing.T, body []byte, hashes []string, offsets []int64) *Pack {
+	t.Helper()
+	data := append([]byte("PACK\x00\x00\x00\x02\x00\x00\x00\x01"), body...)
+	name := filepath.Join(t.TempDir(), "test.pack")
+	if err := os.WriteFile(name, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return &Pack{Name: name, Hashes: hashes, Offsets: offsets, file: f}
+}
+
+func delta(srcSize, dstSize uint64, ops ...byte) []byte {
+	out := binary.AppendUvarint(nil, srcSize)
+	out = binary.AppendUvarint(out, dstSize)
+	return append(out, ops...)
+}
+
+func TestApplyDelta(t *testing.T) {
+	base := []byte("hello world")
+	tests := []struct {
+		name    string
+		delta   []byte
+		want    string
+		wantErr string
+	}{
+		// Копия 5 байт с начала и вставка "!"
+		{"copy and insert", delta(11, 6, 0x90, 5, 1, '!'), "hello!", ""},
+		{"empty", nil, "", "invalid delta"},
+		{"base size mismatch", delta(3, 1, 1, 'x'), "", "base size mismatch"},
+		{"truncated insert", delta(11, 5, 5, 'a', 'b'), "", "invalid delta insert"},
+		{"truncated copy", delta(11, 5, 0x91), "", "invalid delta copy"},
+		{"copy out of range", delta(11, 5, 0x91, 8, 5), "", "out of range"},
+		{"opcode zero", delta(11, 1, 0), "", "opcode 0"},
+		{"huge result", delta(11, 1<<62, 1, 'x'), "", "exceeds limit"},
+		{"more than declared", delta(11, 1, 2, 'a', 'b'), "", "exceeds declared size"},
+		{"less than declared", delta(11, 3, 1, 'a'), "", "size mismatch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyDelta(base, tt.delta)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ApplyDelta() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyDelta() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("ApplyDelta() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInflate(t *testing.T) {
+	data := compress(t, []byte("abc"))
+	if got, err := inflate(bytes.NewReader(data), 3); err != nil || string(got) != "abc" {
+		t.Fatalf("inflate() = %q, %v", got, err)
+	}
+	if _, err := inflate(bytes.NewReader(data), 10); err == nil {
+		t.Fatal("inflate() accepted a stream shorter than the declared size")
+	}
+	if _, err := inflate(bytes.NewReader(data), MaxObjectSize+1); err == nil {
+		t.Fatal("inflate() accepted an oversized object")
+	}
+	if _, err := inflate(bytes.NewReader(data[:4]), 3); err == nil {
+		t.Fatal("inflate() accepted a truncated stream")
+	}
+}
+
+func TestReadPackObjectHeader(t *testing.T) {
+	objType, size, err := readPackObjectHeader(bytes.NewReader(packHeader(packObjBlob, 1000)))
+	if err != nil || objType != packObjBlob || size != 1000 {
+		t.Fatalf("readPackObjectHeader() = %d, %d, %v", objType, size, err)
+	}
+	if _, _, err := readPackObjectHeader(bytes.NewReader(bytes.Repeat([]byte{0xff}, 20))); err == nil {
+		t.Fatal("readPackObjectHeader() accepted an overflowing size")
+	}
+	if _, _, err := readPackObjectHeader(bytes.NewReader([]byte{0xb0})); err == nil {
+		t.Fatal("readPackObjectHeader() accepted a truncated header")
+	}
+}
+
+func TestReadOfsDeltaOffset(t *testing.T) {
+	if _, err := readOfsDeltaOffset(bytes.NewReader([]byte{0})); err == nil {
+		t.Fatal("readOfsDeltaOffset() accepted a zero offset")
+	}
+	if _, err := readOfsDeltaOffset(bytes.NewReader(bytes.Repeat([]byte{0xff}, 20))); err == nil {
+		t.Fatal("readOfsDeltaOffset() accepted an overflowing offset")
+	}
+}
+
+func TestReadAt(t *testing.T) {
+	content := []byte("blob content")
+	body := append(packHeader(packObjBlob, len(content)), compress(t, content)...)
+	p := writePack(t, body, nil, nil)
+	objType, data, err := p.ReadAt(12, nil, 0)
+	if err != nil || objType != TypeBlob || string(data) != string(content) {
+		t.Fatalf("ReadAt() = %q, %q, %v", objType, data, err)
+	}
+	if objType, err := p.TypeAt(12, nil, 0); err != nil || objType != TypeBlob {
+		t.Fatalf("TypeAt() = %q, %v", objType, err)
+	}
+
+	// Размер в заголовке больше данных
+	body = append(packHeader(packObjBlob, 1<<20), compress(t, content)...)
+	p = writePack(t, body, nil, nil)
+	if _, _, err := p.ReadAt(12, nil, 0); err == nil {
+		t.Fatal("ReadAt() accepted an object shorter than its header")
+	}
+}
+
+func TestReadAtRefDeltaCycle(t *testing.T) {
+	const hashA = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	const hashB = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	refDelta := func(base string) []byte {
+		d := delta(1, 1, 1, 'x')
+		out := packHeader(packObjRefDelta, len(d))
+		raw, _ := hex.DecodeString(base)
+		out = append(out, raw...)
+		return append(out, compress(t, d)...)
+	}
+
+	// Объект ссылается сам на себя внутри пакета
+	self := writePack(t, refDelta(hashA), []string{hashA}, []int64{12})
+	if _, _, err := self.ReadAt(12, nil, 0); err == nil || !strings.Contains(err.Error(), "too deep") {
+		t.Fatalf("ReadAt() error = %v, want too deep", err)
+	}
+	if _, err := self.TypeAt(12, nil, 0); err == nil {
+		t.Fatal("TypeAt() accepted a delta cycle")
+	}
+
+	// Два пакета ссылаются друг на друга
+	packA := writePack(t, refDelta(hashB), []string{hashA}, []int64{12})
+	packB := writePack(t, refDelta(hashA), []string{hashB}, []int64{12})
+	var resolve Resolver
+	resolve = func(hash string, depth int) (*Object, error) {
+		p := packA
+		if hash == hashB {
+			p = packB
+		}
+		objType, data, err := p.ReadAt(12, resolve, depth)
+		if err != nil {
+			return nil, err
+		}
+		return &Object{Hash: hash, Type: objType, Data: data}, nil
+	}
+	if _, _, err := packA.ReadAt(12, resolve, 0); err == nil || !strings.Contains(err.Error(), "too deep") {
+		t.Fatalf("ReadAt() error = %v, want too deep", err)
+	}
+}
+
+func TestReadPackIndexTruncated(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	if _, _, _, err := ReadPackIndex(write("short.idx", []byte{0xff, 't', 'O', 'c'})); err == nil {
+		t.Fatal("ReadPackIndex() accepted a short file")
+	}
+
+	// Fanout обещает миллион объектов, а данных нет
+	data := []byte{0xff, 't', 'O', 'c', 0, 0, 0, 2}
+	fanout := make([]byte, 256*4)
+	binary.BigEndian.PutUint32(fanout[255*4:], 1000000)
+	data = append(data, fanout...)
+	data = append(data, make([]byte, 40)...)
+	if _, _, _, err := ReadPackIndex(write("v2.idx", data)); err == nil {
+		t.Fatal("ReadPackIndex() accepted a truncated v2 index")
+	}
+
+	v1 := append(fanout, make([]byte, 40)...)
+	if _, _, _, err := ReadPackIndex(write("v1.idx", v1)); err == nil {
+		t.Fatal("ReadPackIndex() accepted a truncated v1 index")
+	}
+}
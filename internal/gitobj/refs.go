@@ -0,0 +1,159 @@
+package gitobj
+
+import (
+	"bufio"
+	"container/heap"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Refs returns all loose and packed refs mapped to object hashes.
+func (r *Repo) Refs() (map[string]string, error) {
+	refs := make(map[string]string)
+
+	if f, err := os.Open(filepath.Join(r.Dir, "packed-refs")); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "" || line[0] == '#' || line[0] == '^' {
+				continue
+			}
+			hash, name, ok := strings.Cut(line, " ")
+			if ok && len(hash) == 40 {
+				refs[name] = hash
+			}
+		}
+		f.Close()
+	}
+
+	refsDir := filepath.Join(r.Dir, "refs")
+	err := filepath.WalkDir(refsDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		hash := strings.TrimSpace(string(data))
+		if len(hash) != 40 || !isHex(hash) {
+			return nil
+		}
+		rel, _ := filepath.Rel(r.Dir, path)
+		refs[filepath.ToSlash(rel)] = hash
+		return nil
+	})
+
+	return refs, err
+}
+
+// Head resolves HEAD to a hash. It returns an empty string if HEAD points to a
+// missing ref.
+func (r *Repo) Head() (string, error) {
+	data, err := os.ReadFile(filepath.Join(r.Dir, "HEAD"))
+	if err != nil {
+		return "", err
+	}
+	head := strings.TrimSpace(string(data))
+	if ref, ok := strings.CutPrefix(head, "ref: "); ok {
+		refs, err := r.Refs()
+		if err != nil {
+			return "", err
+		}
+		return refs[ref], nil
+	}
+	return head, nil
+}
+
+// Log returns commits reachable from tips, newest first. Missing commits are
+// skipped. limit <= 0 means no limit.
+func (r *Repo) Log(tips []string, limit int) []*Commit {
+	var commits []*Commit
+	seen := make(map[string]bool)
+	queue := &commitQueue{}
+
+	push := func(hash string) {
+		if seen[hash] {
+			return
+		}
+		seen[hash] = true
+		commit, err := r.ReadCommit(hash)
+		if err != nil {
+			return
+		}
+		heap.Push(queue, commit)
+	}
+
+	for _, tip := range tips {
+		push(r.Peel(tip))
+	}
+
+	for queue.Len() > 0 && (limit <= 0 || len(commits) < limit) {
+		commit := heap.Pop(queue).(*Commit)
+		commits = append(commits, commit)
+		for _, parent := range commit.Parents {
+			push(parent)
+		}
+	}
+
+	return commits
+}
+
+// Peel follows annotated tags, including tags of tags, to the object they
+// point at. It stops at the first object which is missing.
+func (r *Repo) Peel(hash string) string {
+	for i := 0; i < 10; i++ {
+		obj, err := r.ReadObject(hash)
+		if err != nil || obj.Type != TypeTag {
+			return hash
+		}
+		tag, err := ParseTag(hash, obj.Data)
+		if err != nil {
+			return hash
+		}
+		hash = tag.Object
+	}
+	return hash
+}
+
+// Tags returns all annotated tag objects of the store.
+func (r *Repo) Tags() ([]*Tag, error) {
+	hashes, err := r.ListObjects()
+	if err != nil {
+		return nil, err
+	}
+	var tags []*Tag
+	for _, hash := range hashes {
+		if objType, err := r.ObjectType(hash); err != nil || objType != TypeTag {
+			continue
+		}
+		obj, err := r.ReadObject(hash)
+		if err != nil {
+			continue
+		}
+		if tag, err := ParseTag(hash, obj.Data); err == nil {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// commitQueue orders commits by committer date, newest first.
+type commitQueue []*Commit
+
+func (q commitQueue) Len() int { return len(q) }
+func (q commitQueue) Less(i, j int) bool {
+	return q[i].Committer.When.After(q[j].Committer.When)
+}
+func (q commitQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x interface{}) {
+	*q = append(*q, x.(*Commit))
+}
+func (q *commitQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	c := old[n-1]
+	*q = old[:n-1]
+	return c
+}
@@ -0,0 +1,42 @@
+package gitobj
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPeelTagChain(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "objects"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	commit, _ := repo.WriteLoose(TypeCommit, []byte("tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n\nrelease\n"))
+	inner, _ := repo.WriteLoose(TypeTag, []byte("object "+commit+"\ntype commit\ntag v1\n\nfirst\n"))
+	outer, _ := repo.WriteLoose(TypeTag, []byte("object "+inner+"\ntype tag\ntag v1-signed\n\nsecond\n"))
+
+	if got := repo.Peel(outer); got != commit {
+		t.Fatalf("Peel(outer) = %s, want %s", got, commit)
+	}
+	if got := repo.Peel(commit); got != commit {
+		t.Fatalf("Peel(commit) = %s, want %s", got, commit)
+	}
+
+	tags, err := repo.Tags()
+	if err != nil {
+		t.Fatalf("Tags() error = %v", err)
+	}
+	names := make(map[string]string)
+	for _, tag := range tags {
+		names[tag.Name] = tag.Object
+	}
+	if len(names) != 2 || names["v1"] != commit || names["v1-signed"] != inner {
+		t.Fatalf("Tags() = %v", names)
+	}
+}
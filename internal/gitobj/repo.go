@@ -0,0 +1,341 @@
+package gitobj
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Repo reads objects from a (possibly incomplete) .git directory.
+type Repo struct {
+	Dir string
+
+	mu    sync.Mutex
+	packs []*Pack
+	once  sync.Once
+}
+
+// Open returns a Repo for the given .git directory.
+func Open(gitDir string) (*Repo, error) {
+	fi, err := os.Stat(filepath.Join(gitDir, "objects"))
+	if err != nil {
+		return nil, fmt.Errorf("not a git directory %s: %w", gitDir, err)
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("not a git directory %s", gitDir)
+	}
+	return &Repo{Dir: gitDir}, nil
+}
+
+func (r *Repo) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.packs {
+		p.Close()
+	}
+	r.packs = nil
+	return nil
+}
+
+// Packs returns all readable packs of the repository.
+func (r *Repo) Packs() []*Pack {
+	r.once.Do(func() {
+		idxFiles, _ := filepath.Glob(filepath.Join(r.Dir, "objects", "pack", "*.idx"))
+		for _, idx := range idxFiles {
+			p, err := OpenPack(idx)
+			if err != nil {
+				continue
+			}
+			r.packs = append(r.packs, p)
+		}
+	})
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.packs
+}
+
+// LoosePath returns the path of a loose object.
+func (r *Repo) LoosePath(hash string) string {
+	return filepath.Join(r.Dir, "objects", hash[:2], hash[2:])
+}
+
+// HasObject reports whether the object is stored loose or in a pack.
+func (r *Repo) HasObject(hash string) bool {
+	if len(hash) != 40 {
+		return false
+	}
+	if _, err := os.Stat(r.LoosePath(hash)); err == nil {
+		return true
+	}
+	for _, p := range r.Packs() {
+		if _, ok := p.Find(hash); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadObject returns a loose or packed object.
+func (r *Repo) ReadObject(hash string) (*Object, error) {
+	return r.readObject(hash, 0)
+}
+
+func (r *Repo) readObject(hash string, depth int) (*Object, error) {
+	if len(hash) != 40 {
+		return nil, fmt.Errorf("invalid object hash %q", hash)
+	}
+
+	if data, err := os.ReadFile(r.LoosePath(hash)); err == nil {
+		objType, body, err := DecodeLoose(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode object %s: %w", hash, err)
+		}
+		return &Object{Hash: hash, Type: objType, Data: body}, nil
+	}
+
+	for _, p := range r.Packs() {
+		if off, ok := p.Find(hash); ok {
+			objType, data, err := p.ReadAt(off, r.readObject, depth)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read packed object %s: %w", hash, err)
+			}
+			return &Object{Hash: hash, Type: objType, Data: data}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("object %s not found", hash)
+}
+
+// ObjectType returns the type of an object reading only its header.
+func (r *Repo) ObjectType(hash string) (string, error) {
+	return r.objectType(hash, 0)
+}
+
+func (r *Repo) objectType(hash string, depth int) (string, error) {
+	if len(hash) != 40 {
+		return "", fmt.Errorf("invalid object hash %q", hash)
+	}
+
+	if f, err := os.Open(r.LoosePath(hash)); err == nil {
+		defer f.Close()
+		zr, err := zlib.NewReader(f)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode object %s: %w", hash, err)
+		}
+		defer zr.Close()
+		header, err := bufio.NewReader(io.LimitReader(zr, 64)).ReadString(' ')
+		if err != nil {
+			return "", fmt.Errorf("invalid header of object %s", hash)
+		}
+		return strings.TrimSuffix(header, " "), nil
+	}
+
+	for _, p := range r.Packs() {
+		if off, ok := p.Find(hash); ok {
+			return p.TypeAt(off, r.objectType, depth)
+		}
+	}
+
+	return "", fmt.Errorf("object %s not found", hash)
+}
+
+// ReadCommit reads and parses a commit object.
+func (r *Repo) ReadCommit(hash string) (*Commit, error) {
+	obj, err := r.ReadObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	if obj.Type != TypeCommit {
+		return nil, fmt.Errorf("object %s is a %s, not a commit", hash, obj.Type)
+	}
+	return ParseCommit(hash, obj.Data)
+}
+
+// ReadTree reads and parses a tree object.
+func (r *Repo) ReadTree(hash string) ([]TreeEntry, error) {
+	obj, err := r.ReadObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	if obj.Type != TypeTree {
+		return nil, fmt.Errorf("object %s is a %s, not a tree", hash, obj.Type)
+	}
+	return ParseTree(obj.Data)
+}
+
+// ListObjects returns the sorted hashes of all loose and packed objects.
+func (r *Repo) ListObjects() ([]string, error) {
+	seen := make(map[string]bool)
+	dirs, err := os.ReadDir(filepath.Join(r.Dir, "objects"))
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		name := dir.Name()
+		if !dir.IsDir() || len(name) != 2 || !isHex(name) {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(r.Dir, "objects", name))
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			if len(f.Name()) == 38 && isHex(f.Name()) {
+				seen[name+f.Name()] = true
+			}
+		}
+	}
+	for _, p := range r.Packs() {
+		for _, h := range p.Hashes {
+			seen[h] = true
+		}
+	}
+
+	hashes := make([]string, 0, len(seen))
+	for h := range seen {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+	return hashes, nil
+}
+
+// WalkTree calls fn for every non-tree entry reachable from tree with its full path.
+func (r *Repo) WalkTree(tree, prefix string, fn func(path string, entry TreeEntry) error) error {
+	entries, err := r.ReadTree(tree)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := entry.Name
+		if prefix != "" {
+			path = prefix + "/" + entry.Name
+		}
+		if entry.IsTree() {
+			if err := r.WalkTree(entry.Hash, path, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(path, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteLoose stores an object as a loose object and returns its hash.
+func (r *Repo) WriteLoose(objType string, data []byte) (string, error) {
+	hash := HashObject(objType, data)
+	path := r.LoosePath(hash)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	return hash, os.WriteFile(path, EncodeLoose(objType, data), 0644)
+}
+
+// DecodeLoose decompresses a loose object and splits off its header.
+func DecodeLoose(data []byte) (string, []byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create zlib reader: %w", err)
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to inflate object: %w", err)
+	}
+	space := bytes.IndexByte(raw, ' ')
+	nul := bytes.IndexByte(raw, 0)
+	if space == -1 || nul == -1 || nul < space {
+		return "", nil, fmt.Errorf("invalid object header")
+	}
+	size, err := strconv.Atoi(string(raw[space+1 : nul]))
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid object size: %w", err)
+	}
+	body := raw[nul+1:]
+	if len(body) != size {
+		return "", nil, fmt.Errorf("object size mismatch: %d != %d", len(body), size)
+	}
+	return string(raw[:space]), body, nil
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// FindGitDirs returns all .git directories below root.
+// HasContent reports whether gitDir holds HEAD and at least one loose object
+// or pack, i.e. a repository was actually dumped there.
+func HasContent(gitDir string) bool {
+	if fi, err := os.Stat(filepath.Join(gitDir, "HEAD")); err != nil || fi.IsDir() {
+		return false
+	}
+	found := false
+	filepath.WalkDir(filepath.Join(gitDir, "objects"), func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+func FindGitDirs(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			dirs = append(dirs, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// Resolve expands an abbreviated hash of at least 4 characters to the hash of
+// a stored object.
+func (r *Repo) Resolve(prefix string) (string, error) {
+	prefix = strings.ToLower(prefix)
+	if len(prefix) == 40 {
+		return prefix, nil
+	}
+	if len(prefix) < 4 || !isHex(prefix) {
+		return "", fmt.Errorf("invalid object name %q", prefix)
+	}
+	hashes, err := r.ListObjects()
+	if err != nil {
+		return "", err
+	}
+	var found []string
+	for _, hash := range hashes {
+		if strings.HasPrefix(hash, prefix) {
+			found = append(found, hash)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("object %s not found", prefix)
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("object name %s is ambiguous: %s", prefix, strings.Join(found, ", "))
+	}
+}
@@ -0,0 +1,249 @@
+package httpclient
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+// AuditEntry is a record of the -audit-log hash chain. Hash is the SHA-256 of
+// the entry encoded with an empty Hash, and Prev is the Hash of the previous
+// entry, so changing, removing or reordering entries breaks the chain.
+type AuditEntry struct {
+	Seq            int64             `json:"seq"`
+	ScanID         string            `json:"scan_id,omitempty"`
+	Time           time.Time         `json:"time"`
+	Clock          string            `json:"clock"` // ntp или local
+	RequestLine    string            `json:"request_line"`
+	Url            string            `json:"url"`
+	RequestHeaders map[string]string `json:"request_headers,omitempty"`
+	Status         int               `json:"status,omitempty"`
+	BodySize       int64             `json:"body_size,omitempty"`
+	BodySha256     string            `json:"body_sha256,omitempty"`
+	BodyIncomplete bool              `json:"body_incomplete,omitempty"` // Тело закрыто до конца, хэша нет
+	Error          string            `json:"error,omitempty"`
+	Prev           string            `json:"prev"`
+	Hash           string            `json:"hash"`
+}
+
+func (e *AuditEntry) digest() (string, error) {
+	entry := *e
+	entry.Hash = ""
+	data, err := json.Marshal(&entry)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AuditLog appends entries to the hash chain of an -audit-log file. An existing
+// file is continued from its last entry.
+type AuditLog struct {
+	mu     sync.Mutex
+	f      *os.File
+	seq    int64
+	prev   string
+	offset time.Duration
+	clock  string
+	scanID string
+}
+
+// OpenAuditLog opens fileName and takes the clock offset from ntpServer. If
+// the server is empty or doesn't answer, the local clock is used and entries
+// say so. Entries are stamped with scanID.
+func OpenAuditLog(fileName, ntpServer, scanID string) (*AuditLog, error) {
+	l := &AuditLog{clock: "local", scanID: scanID}
+	if ntpServer != "" {
+		offset, err := ntpOffset(ntpServer, 5*time.Second)
+		if err != nil {
+			logger.Warnf("Failed to query NTP server %s, the audit log uses the local clock: %v", ntpServer, err)
+		} else {
+			logger.Infof("Local clock differs from %s by %s", ntpServer, offset)
+			l.offset = offset
+			l.clock = "ntp"
+		}
+	}
+
+	if last, err := lastAuditEntry(fileName); err != nil {
+		return nil, err
+	} else if last != nil {
+		l.seq = last.Seq
+		l.prev = last.Hash
+	}
+	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log %s: %w", fileName, err)
+	}
+	l.f = f
+	return l, nil
+}
+
+func lastAuditEntry(fileName string) (*AuditEntry, error) {
+	var last *AuditEntry
+	_, err := readAuditLog(fileName, func(e *AuditEntry) error {
+		last = e
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log %s: %w", fileName, err)
+	}
+	return last, nil
+}
+
+func readAuditLog(fileName string, fn func(e *AuditEntry) error) (int, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	n := 0
+	for scanner.Scan() {
+		n++
+		var e AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return n, fmt.Errorf("line %d: %w", n, err)
+		}
+		if err := fn(&e); err != nil {
+			return n, fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	return n, scanner.Err()
+}
+
+// VerifyAuditLog checks the hash chain of an -audit-log file and returns the
+// number of entries.
+func VerifyAuditLog(fileName string) (int, error) {
+	var seq int64
+	prev := ""
+	return readAuditLog(fileName, func(e *AuditEntry) error {
+		if e.Seq != seq+1 {
+			return fmt.Errorf("entry %d follows %d", e.Seq, seq)
+		}
+		if e.Prev != prev {
+			return fmt.Errorf("entry %d doesn't link to the previous entry", e.Seq)
+		}
+		digest, err := e.digest()
+		if err != nil {
+			return err
+		}
+		if digest != e.Hash {
+			return fmt.Errorf("entry %d was modified", e.Seq)
+		}
+		seq, prev = e.Seq, e.Hash
+		return nil
+	})
+}
+
+// Write fills in the sequence number, time and hashes of e and appends it.
+func (l *AuditLog) Write(e *AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	e.Seq = l.seq
+	e.ScanID = l.scanID
+	e.Time = time.Now().Add(l.offset).UTC()
+	e.Clock = l.clock
+	e.Prev = l.prev
+	digest, err := e.digest()
+	if err != nil {
+		return err
+	}
+	e.Hash = digest
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := l.f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	l.prev = e.Hash
+	return nil
+}
+
+func (l *AuditLog) Close() error {
+	return l.f.Close()
+}
+
+// newAuditEntry describes a request and, if there is one, its response.
+func newAuditEntry(req *http.Request, resp *http.Response, err error) *AuditEntry {
+	// Версия в ответе сервера может быть ниже отправленной (HTTP/1.0)
+	proto := "HTTP/1.1"
+	if resp != nil && resp.ProtoMajor >= 2 {
+		proto = resp.Proto
+	}
+	e := &AuditEntry{
+		RequestLine:    req.Method + " " + req.URL.RequestURI() + " " + proto,
+		Url:            req.URL.String(),
+		RequestHeaders: map[string]string{"Host": req.URL.Host},
+	}
+	for key := range req.Header {
+		e.RequestHeaders[key] = req.Header.Get(key)
+	}
+	if resp != nil {
+		e.Status = resp.StatusCode
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	return e
+}
+
+// auditBody hashes a response body as it is read and writes the audit entry
+// once it is read to the end or closed.
+type auditBody struct {
+	io.ReadCloser
+	once  sync.Once
+	log   *AuditLog
+	entry *AuditEntry
+	hash  hash.Hash
+	size  int64
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.hash.Write(p[:n])
+	b.size += int64(n)
+	if err != nil {
+		b.finish(err)
+	}
+	return n, err
+}
+
+func (b *auditBody) Close() error {
+	b.finish(nil)
+	return b.ReadCloser.Close()
+}
+
+func (b *auditBody) finish(err error) {
+	b.once.Do(func() {
+		b.entry.BodySize = b.size
+		switch {
+		case err == io.EOF:
+			b.entry.BodySha256 = hex.EncodeToString(b.hash.Sum(nil))
+		case err != nil:
+			b.entry.Error = err.Error()
+			b.entry.BodyIncomplete = true
+		default:
+			b.entry.BodyIncomplete = true
+		}
+		if err := b.log.Write(b.entry); err != nil {
+			logger.Errorf("Failed to write audit log: %v", err)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package httpclient
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeAuditEntries(t *testing.T, fileName string, n int) {
+	t.Helper()
+	l, err := OpenAuditLog(fileName, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	req, _ := http.NewRequest("GET", "http://example.com/.git/HEAD", nil)
+	for i := 0; i < n; i++ {
+		if err := l.Write(newAuditEntry(req, &http.Response{StatusCode: 200}, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAuditLogChain(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "audit.jsonl")
+	writeAuditEntries(t, fileName, 3)
+	// Повторный запуск продолжает ту же цепочку
+	writeAuditEntries(t, fileName, 2)
+	if n, err := VerifyAuditLog(fileName); err != nil || n != 5 {
+		t.Fatalf("VerifyAuditLog = %d, %v; want 5 entries", n, err)
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(strings.TrimSpace(string(data)), "\n")
+	tampered := map[string]string{
+		"modified": strings.Replace(string(data), `"status":200`, `"status":404`, 1),
+		"removed":  lines[0] + strings.Join(lines[2:], ""),
+		"swapped":  lines[1] + lines[0] + strings.Join(lines[2:], ""),
+		"garbage":  string(data) + "{\n",
+	}
+	for name, content := range tampered {
+		if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := VerifyAuditLog(fileName); err == nil {
+			t.Errorf("%s log passed verification", name)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package httpclient
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+const (
+	initialHostLimit = 2
+	// Между уменьшениями лимита, чтобы одна волна ошибок не обнулила его
+	limitCooldown = time.Second
+	// Ответ медленнее baseline во столько раз и на столько считается деградацией
+	slowFactor = 3
+	slowSlack  = 100 * time.Millisecond
+)
+
+// autoTuner limits parallel requests to each origin (-auto-tune). A limit
+// starts at initialHostLimit, grows by one after a full round of healthy
+// responses and is halved on errors, 429, 5xx or responses much slower than
+// the usual latency of the origin.
+type autoTuner struct {
+	max   int
+	mu    sync.Mutex
+	cond  *sync.Cond
+	hosts map[string]*hostLimit
+}
+
+// hostLimit is the adaptive concurrency limit of an origin.
+type hostLimit struct {
+	origin       string
+	limit        int
+	inFlight     int
+	healthy      int           // Успешных ответов с последнего изменения лимита
+	baseline     time.Duration // Обычное время ответа
+	lastDecrease time.Time
+}
+
+func newAutoTuner(max int) *autoTuner {
+	a := &autoTuner{max: max, hosts: make(map[string]*hostLimit)}
+	a.cond = sync.NewCond(&a.mu)
+	return a
+}
+
+// acquire waits until a request to origin fits into its limit.
+func (a *autoTuner) acquire(origin string) *hostLimit {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	h, ok := a.hosts[origin]
+	if !ok {
+		h = &hostLimit{origin: origin, limit: min(initialHostLimit, a.max)}
+		a.hosts[origin] = h
+	}
+	for h.inFlight >= h.limit {
+		a.cond.Wait()
+	}
+	h.inFlight++
+	return h
+}
+
+// release ends a request acquired for h.
+func (a *autoTuner) release(h *hostLimit) {
+	if a == nil || h == nil {
+		return
+	}
+	a.mu.Lock()
+	h.inFlight--
+	a.mu.Unlock()
+	a.cond.Broadcast()
+}
+
+// observe adjusts the limit of h to the outcome of a request which got
+// headers after latency.
+func (a *autoTuner) observe(h *hostLimit, latency time.Duration, resp *http.Response, err error) {
+	if a == nil || h == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	healthy := err == nil && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500
+	if healthy {
+		if h.baseline == 0 || latency < h.baseline {
+			h.baseline = latency
+		} else {
+			// Базовое время медленно подтягивается к текущему, если сервер стал медленнее насовсем
+			h.baseline += (latency - h.baseline) / 100
+		}
+		if latency > slowFactor*h.baseline && latency > h.baseline+slowSlack {
+			healthy = false
+		}
+	}
+
+	if !healthy {
+		h.healthy = 0
+		if h.limit > 1 && time.Since(h.lastDecrease) >= limitCooldown {
+			h.limit = max(h.limit/2, 1)
+			h.lastDecrease = time.Now()
+			logger.Debugf("Decreased concurrency of %s to %d", h.origin, h.limit)
+		}
+		return
+	}
+	h.healthy++
+	if h.healthy >= h.limit && h.limit < a.max {
+		h.limit++
+		h.healthy = 0
+		logger.Debugf("Increased concurrency of %s to %d", h.origin, h.limit)
+		a.cond.Broadcast()
+	}
+}
+
+// limits returns the current concurrency limit of every origin.
+func (a *autoTuner) limits() map[string]int {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ret := make(map[string]int, len(a.hosts))
+	for origin, h := range a.hosts {
+		ret[origin] = h.limit
+	}
+	return ret
+}
@@ -0,0 +1,52 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAutoTuner(t *testing.T) {
+	a := newAutoTuner(8)
+	ok := &http.Response{StatusCode: http.StatusOK}
+	run := func(latency time.Duration, resp *http.Response, err error) {
+		h := a.acquire("http://example.com:80")
+		a.observe(h, latency, resp, err)
+		a.release(h)
+	}
+	limit := func() int { return a.limits()["http://example.com:80"] }
+
+	run(10*time.Millisecond, ok, nil)
+	if limit() != initialHostLimit {
+		t.Fatalf("limit after one response = %d, want %d", limit(), initialHostLimit)
+	}
+	for i := 0; i < 100; i++ {
+		run(10*time.Millisecond, ok, nil)
+	}
+	if limit() != 8 {
+		t.Fatalf("limit after healthy responses = %d, want the maximum 8", limit())
+	}
+
+	run(10*time.Millisecond, &http.Response{StatusCode: http.StatusServiceUnavailable}, nil)
+	if limit() != 4 {
+		t.Fatalf("limit after 503 = %d, want 4", limit())
+	}
+	// Повторное уменьшение только после паузы
+	run(0, nil, errors.New("connection reset"))
+	if limit() != 4 {
+		t.Fatalf("limit after error within cooldown = %d, want 4", limit())
+	}
+	a.hosts["http://example.com:80"].lastDecrease = time.Now().Add(-limitCooldown)
+	run(time.Second, ok, nil)
+	if limit() != 2 {
+		t.Fatalf("limit after slow response = %d, want 2", limit())
+	}
+	// 404 — обычный ответ при переборе путей
+	for i := 0; i < 2; i++ {
+		run(10*time.Millisecond, &http.Response{StatusCode: http.StatusNotFound}, nil)
+	}
+	if limit() != 3 {
+		t.Fatalf("limit after 404 responses = %d, want 3", limit())
+	}
+}
@@ -0,0 +1,158 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+// ErrCircuitOpen is returned for requests to a host:port which recently failed
+// to resolve or accept connections.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// breaker stops connecting to a host:port after several DNS or connect errors
+// in a row. After the cool-down a single request is let through: its success
+// closes the circuit, its failure opens it again.
+type breaker struct {
+	threshold int // 0 отключает
+	cooldown  time.Duration
+	mu        sync.Mutex
+	hosts     map[string]*breakerState
+}
+
+type breakerState struct {
+	failures int
+	openedAt time.Time     // Нулевое значение — цепь замкнута
+	trial    bool          // Пробное соединение после паузы уже идёт
+	tripped  chan struct{} // Закрывается при размыкании, прерывая ждущие соединения
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown, hosts: make(map[string]*breakerState)}
+}
+
+func (b *breaker) state(addr string) *breakerState {
+	s, ok := b.hosts[addr]
+	if !ok {
+		s = &breakerState{tripped: make(chan struct{})}
+		b.hosts[addr] = s
+	}
+	return s
+}
+
+// open reports whether requests to addr should fail without connecting.
+func (b *breaker) open(addr string) bool {
+	if b.threshold <= 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s, ok := b.hosts[strings.ToLower(addr)]
+	return ok && !s.openedAt.IsZero() && (s.trial || time.Since(s.openedAt) < b.cooldown)
+}
+
+// allow returns a channel closed once the circuit of addr opens, or
+// ErrCircuitOpen if it is open already.
+func (b *breaker) allow(addr string) (<-chan struct{}, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.state(addr)
+	if s.openedAt.IsZero() {
+		return s.tripped, nil
+	}
+	if s.trial || time.Since(s.openedAt) < b.cooldown {
+		return nil, fmt.Errorf("%w for %s", ErrCircuitOpen, addr)
+	}
+	s.trial = true
+	s.tripped = make(chan struct{})
+	return s.tripped, nil
+}
+
+func (b *breaker) success(addr string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.state(addr)
+	if !s.openedAt.IsZero() {
+		logger.Infof("Circuit breaker closed for %s", addr)
+	}
+	s.failures = 0
+	s.openedAt = time.Time{}
+	s.trial = false
+}
+
+// abort lets another request through if the trial one was cancelled.
+func (b *breaker) abort(addr string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state(addr).trial = false
+}
+
+func (b *breaker) failure(addr string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.state(addr)
+	s.failures++
+	if s.trial || (s.openedAt.IsZero() && s.failures >= b.threshold) {
+		logger.Warnf("Circuit breaker opened for %s for %s after %d connection failures: %v", addr, b.cooldown, s.failures, err)
+		s.openedAt = time.Now()
+		s.trial = false
+		close(s.tripped)
+	}
+}
+
+// dialer wraps dial: connections to an open circuit fail at once, and dials
+// still waiting when the circuit opens are cancelled.
+func (b *breaker) dialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if b.threshold <= 0 {
+		return dial
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		key := strings.ToLower(addr)
+		tripped, err := b.allow(key)
+		if err != nil {
+			return nil, err
+		}
+		dialCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-tripped:
+				cancel()
+			case <-dialCtx.Done():
+			}
+		}()
+
+		conn, err := dial(dialCtx, network, addr)
+		switch {
+		case err == nil:
+			b.success(key)
+		case ctx.Err() != nil:
+			// Запрос отменён или истёк его таймаут — хост тут ни при чём
+			b.abort(key)
+		case dialCtx.Err() != nil:
+			return nil, fmt.Errorf("%w for %s", ErrCircuitOpen, addr)
+		default:
+			b.failure(key, err)
+		}
+		return conn, err
+	}
+}
+
+// dialAddr returns host:port which the transport dials for u.
+func dialAddr(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if strings.EqualFold(u.Scheme, "https") {
+			port = "443"
+		}
+	}
+	return strings.ToLower(net.JoinHostPort(u.Hostname(), port))
+}
@@ -0,0 +1,178 @@
+package httpclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+// cacheEntry is the metadata of a cached response. The body is stored next to
+// it in a separate file.
+type cacheEntry struct {
+	Url      string      `json:"url"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	StoredAt time.Time   `json:"stored_at"`
+}
+
+// cacheTransport serves GET requests from an on-disk cache before asking next.
+// Successful responses and permanent redirects are kept forever, 404, 410 and
+// temporary redirects for negativeTTL.
+type cacheTransport struct {
+	next        http.RoundTripper
+	dir         string
+	negativeTTL time.Duration
+}
+
+func newCacheTransport(next http.RoundTripper, dir string, negativeTTL time.Duration) (*cacheTransport, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory %s: %w", dir, err)
+	}
+	return &cacheTransport{next: next, dir: dir, negativeTTL: negativeTTL}, nil
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.next.RoundTrip(req)
+	}
+
+	metaFile, bodyFile := t.paths(req.URL.String())
+	if resp := t.load(req, metaFile, bodyFile); resp != nil {
+		logger.Debugf("Cache hit: %s", req.URL)
+		return resp, nil
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || !cacheable(resp.StatusCode) {
+		return resp, err
+	}
+
+	entry := &cacheEntry{Url: req.URL.String(), Status: resp.StatusCode, Header: resp.Header, StoredAt: time.Now()}
+	tmp, err := os.CreateTemp(filepath.Dir(bodyFile), ".body-*")
+	if err != nil {
+		if err := os.MkdirAll(filepath.Dir(bodyFile), 0755); err != nil {
+			return resp, nil
+		}
+		if tmp, err = os.CreateTemp(filepath.Dir(bodyFile), ".body-*"); err != nil {
+			return resp, nil
+		}
+	}
+	resp.Body = &cacheBody{ReadCloser: resp.Body, tmp: tmp, onDone: func(complete bool) {
+		// Недочитанный ответ не кэшируем, иначе в кэш попадёт обрезанный файл
+		if !complete {
+			os.Remove(tmp.Name())
+			return
+		}
+		if err := t.store(entry, metaFile, bodyFile, tmp.Name()); err != nil {
+			logger.Errorf("Failed to cache %s: %v", entry.Url, err)
+		}
+	}}
+	return resp, nil
+}
+
+func (t *cacheTransport) paths(rawUrl string) (string, string) {
+	sum := sha256.Sum256([]byte(rawUrl))
+	key := hex.EncodeToString(sum[:])
+	base := filepath.Join(t.dir, key[:2], key)
+	return base + ".json", base + ".body"
+}
+
+func (t *cacheTransport) load(req *http.Request, metaFile, bodyFile string) *http.Response {
+	data, err := os.ReadFile(metaFile)
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Url != req.URL.String() {
+		return nil
+	}
+	if expires(entry.Status) && time.Since(entry.StoredAt) > t.negativeTTL {
+		return nil
+	}
+	body, err := os.ReadFile(bodyFile)
+	if err != nil {
+		return nil
+	}
+	resp := newResponse(req, entry.Status, entry.Header, body)
+	resp.Header.Set("X-Git-Dump-Cache", "hit")
+	return resp
+}
+
+func (t *cacheTransport) store(entry *cacheEntry, metaFile, bodyFile, tmpFile string) error {
+	if err := os.Rename(tmpFile, bodyFile); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(metaFile, data, 0644)
+}
+
+func cacheable(status int) bool {
+	switch status {
+	case http.StatusOK, http.StatusMovedPermanently, http.StatusPermanentRedirect:
+		return true
+	}
+	return expires(status)
+}
+
+// expires reports whether a cached response is valid only for negativeTTL.
+// Временный редирект на страницу входа или WAF не должен жить вечно.
+func expires(status int) bool {
+	switch status {
+	case http.StatusNotFound, http.StatusGone, http.StatusFound, http.StatusTemporaryRedirect:
+		return true
+	}
+	return false
+}
+
+// cacheBody writes the body to a temporary file while it is read.
+type cacheBody struct {
+	io.ReadCloser
+	tmp    *os.File
+	eof    bool
+	failed bool
+	onDone func(complete bool)
+}
+
+func (b *cacheBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && !b.failed {
+		if _, werr := b.tmp.Write(p[:n]); werr != nil {
+			b.failed = true
+		}
+	}
+	if err == io.EOF {
+		b.eof = true
+	}
+	return n, err
+}
+
+func (b *cacheBody) Close() error {
+	if b.tmp == nil {
+		return b.ReadCloser.Close()
+	}
+	if !b.eof {
+		// Тела ошибок и редиректов обычно не читают, дочитываем небольшие
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, b, 64<<10); err == io.EOF {
+			b.eof = true
+		}
+	}
+	err := b.ReadCloser.Close()
+	b.tmp.Close()
+	b.onDone(b.eof && !b.failed)
+	b.tmp = nil
+	return err
+}
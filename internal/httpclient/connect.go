@@ -0,0 +1,77 @@
+package httpclient
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// connectMap redirects connections to host names to other addresses, like
+// curl --connect-to. The URL keeps the host name, so it is still sent in the
+// Host header and as SNI.
+type connectMap struct {
+	mu    sync.RWMutex
+	addrs map[string]string // Адрес (IP или IP:порт) по имени хоста в нижнем регистре
+}
+
+func newConnectMap(entries []string) (*connectMap, error) {
+	m := &connectMap{addrs: make(map[string]string)}
+	for _, entry := range entries {
+		host, addr, ok := strings.Cut(entry, "=")
+		if !ok || host == "" || addr == "" {
+			return nil, fmt.Errorf("invalid -connect-to value %q: expected host=address", entry)
+		}
+		if err := m.add(host, addr); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *connectMap) add(host, addr string) error {
+	host = strings.ToLower(host)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if prev, ok := m.addrs[host]; ok && prev != addr {
+		return fmt.Errorf("%s is already connected to %s", host, prev)
+	}
+	m.addrs[host] = addr
+	return nil
+}
+
+func (m *connectMap) lookup(host string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	addr, ok := m.addrs[strings.ToLower(host)]
+	return addr, ok
+}
+
+// resolve returns the address to connect to instead of addr (host:port). The
+// port of the request is kept unless the mapped address has its own.
+func (m *connectMap) resolve(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	target, ok := m.lookup(host)
+	if !ok {
+		return addr
+	}
+	if _, _, err := net.SplitHostPort(target); err == nil {
+		return target
+	}
+	return net.JoinHostPort(strings.Trim(target, "[]"), port)
+}
+
+// ConnectTo makes requests to host connect to addr (an IP address with an
+// optional port) instead of resolving the host name.
+func (c *HttpClient) ConnectTo(host, addr string) error {
+	return c.connectTo.add(host, addr)
+}
+
+// ConnectAddress returns the address requests to host are sent to, if it was
+// overridden.
+func (c *HttpClient) ConnectAddress(host string) (string, bool) {
+	return c.connectTo.lookup(host)
+}
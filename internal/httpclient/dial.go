@@ -0,0 +1,78 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+)
+
+// ipSuffix returns the suffix of network names ("tcp4", "udp6") for
+// -ip-version, or an empty string to use both IPv4 and IPv6.
+func ipSuffix(version string) (string, error) {
+	switch version {
+	case "", "auto":
+		return "", nil
+	case "4", "6":
+		return version, nil
+	}
+	return "", fmt.Errorf("invalid -ip-version value %q: expected 4, 6 or auto", version)
+}
+
+// newDialContext returns the dial function of the transport. It applies
+// -connect-to, -ip-version, -source-ip and -interface and gives up on a connection after -dial-timeout,
+// so a broken AAAA record doesn't eat the whole request timeout. Hosts with a
+// SOCKS proxy or a chain of proxies in -proxy or -proxy-map are connected
+// through it; single HTTP proxies are left to the transport.
+func newDialContext(config config.Config, connectTo *connectMap, proxies *proxyMap) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	suffix, err := ipSuffix(config.IpVersion)
+	if err != nil {
+		return nil, err
+	}
+	sources, err := newSourceAddrs(config)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: config.DialTimeout, KeepAlive: 30 * time.Second}
+	direct := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if network == "tcp" {
+			network += suffix
+		}
+		host, _, _ := net.SplitHostPort(addr)
+		source, err := sources.pick(host, suffix)
+		if err != nil {
+			return nil, err
+		}
+		if source != nil {
+			d := *dialer
+			d.LocalAddr = &net.TCPAddr{IP: source}
+			return d.DialContext(ctx, network, addr)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+	chains := make(map[string]dialFunc)
+	httpProxies := make(map[string]bool) // Адреса HTTP-прокси, к ним транспорт подключается сам
+	for _, c := range proxies.chains() {
+		if u := c.transportProxy(); u != nil {
+			httpProxies[strings.ToLower(canonicalAddr(u))] = true
+			continue
+		}
+		chains[c.String()] = chainDialer(c, direct, suffix)
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// -connect-to подменяет адрес и за прокси
+		target := connectTo.resolve(addr)
+		if httpProxies[strings.ToLower(addr)] {
+			return direct(ctx, network, target)
+		}
+		host, _, _ := net.SplitHostPort(addr)
+		targetHost, _, _ := net.SplitHostPort(target)
+		if proxy, _ := proxies.lookup(host, targetHost); len(proxy) > 0 && proxy.transportProxy() == nil {
+			return chains[proxy.String()](ctx, network, target)
+		}
+		return direct(ctx, network, target)
+	}, nil
+}
@@ -0,0 +1,243 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// HAR 1.2 structures, see http://www.softwareishard.com/blog/har-12-spec/
+type harLog struct {
+	Log struct {
+		Version string      `json:"version"`
+		Creator harCreator  `json:"creator"`
+		Entries []*harEntry `json:"entries"`
+		Comment string      `json:"comment,omitempty"`
+	} `json:"log"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	Url         string         `json:"url"`
+	HttpVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HttpVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectUrl string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harTimings struct {
+	Send    int64 `json:"send"`
+	Wait    int64 `json:"wait"`
+	Receive int64 `json:"receive"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            int64       `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+// HarRecorder collects every request and response and writes them as a HAR file.
+type HarRecorder struct {
+	mu      sync.Mutex
+	maxBody int
+	scanID  string
+	entries []*harEntry
+}
+
+// NewHarRecorder creates a recorder. Bodies longer than maxBody bytes are
+// truncated, 0 means no limit. The scan ID goes into the comment of the log.
+func NewHarRecorder(maxBody int, scanID string) *HarRecorder {
+	return &HarRecorder{maxBody: maxBody, scanID: scanID}
+}
+
+// Record adds an entry for a completed request. For successful responses the
+// body is captured while the caller reads it and the entry is added on Close.
+func (h *HarRecorder) Record(req *http.Request, start time.Time, resp *http.Response, err error) {
+	if resp == nil {
+		entry := h.newEntry(req, start, nil)
+		if err != nil {
+			entry.Comment = err.Error()
+		}
+		h.add(entry)
+		return
+	}
+
+	// Промежуточные ответы с редиректами идут отдельными записями
+	var redirects []*http.Response
+	for r := resp.Request; r != nil && r.Response != nil; r = r.Response.Request {
+		redirects = append([]*http.Response{r.Response}, redirects...)
+	}
+	for _, r := range redirects {
+		h.add(h.newEntry(r.Request, start, r))
+	}
+
+	entry := h.newEntry(resp.Request, start, resp)
+	headersAt := time.Now()
+	resp.Body = &harBody{ReadCloser: resp.Body, limit: h.maxBody, onClose: func(b *harBody) {
+		entry.Timings.Receive = time.Since(headersAt).Milliseconds()
+		entry.Time = entry.Timings.Wait + entry.Timings.Receive
+		entry.Response.BodySize = b.size
+		entry.Response.Content.Size = b.size
+		setContent(&entry.Response.Content, b.buf.Bytes(), b.size > int64(b.buf.Len()))
+		h.add(entry)
+	}}
+}
+
+func (h *HarRecorder) newEntry(req *http.Request, start time.Time, resp *http.Response) *harEntry {
+	entry := &harEntry{StartedDateTime: start.UTC()}
+	entry.Timings.Wait = time.Since(start).Milliseconds()
+	entry.Time = entry.Timings.Wait
+	entry.Request = harRequest{
+		Method:      req.Method,
+		Url:         req.URL.String(),
+		HttpVersion: "HTTP/1.1",
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(req.Header),
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+	}
+	for key, values := range req.URL.Query() {
+		for _, value := range values {
+			entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{key, value})
+		}
+	}
+	entry.Response = harResponse{Cookies: []harNameValue{}, Headers: []harNameValue{}, HeadersSize: -1, BodySize: -1}
+	if resp != nil {
+		entry.Response.Status = resp.StatusCode
+		entry.Response.StatusText = http.StatusText(resp.StatusCode)
+		entry.Response.HttpVersion = resp.Proto
+		entry.Response.Headers = harHeaders(resp.Header)
+		entry.Response.Content.MimeType = resp.Header.Get("Content-Type")
+		entry.Response.RedirectUrl = resp.Header.Get("Location")
+	}
+	return entry
+}
+
+func (h *HarRecorder) add(entry *harEntry) {
+	h.mu.Lock()
+	h.entries = append(h.entries, entry)
+	h.mu.Unlock()
+}
+
+// WriteFile saves recorded entries ordered by start time.
+func (h *HarRecorder) WriteFile(fileName string) error {
+	h.mu.Lock()
+	var har harLog
+	har.Log.Version = "1.2"
+	har.Log.Creator = harCreator{Name: "git-dump", Version: "1.0"}
+	har.Log.Entries = append([]*harEntry{}, h.entries...)
+	if h.scanID != "" {
+		har.Log.Comment = "scan " + h.scanID
+	}
+	h.mu.Unlock()
+
+	sort.SliceStable(har.Log.Entries, func(i, j int) bool {
+		return har.Log.Entries[i].StartedDateTime.Before(har.Log.Entries[j].StartedDateTime)
+	})
+
+	data, err := json.Marshal(&har)
+	if err != nil {
+		return fmt.Errorf("failed to encode HAR: %w", err)
+	}
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		return fmt.Errorf("failed to write HAR %s: %w", fileName, err)
+	}
+	return nil
+}
+
+func harHeaders(header http.Header) []harNameValue {
+	ret := []harNameValue{}
+	for key, values := range header {
+		for _, value := range values {
+			ret = append(ret, harNameValue{key, value})
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+func setContent(content *harContent, data []byte, truncated bool) {
+	if utf8.Valid(data) && bytes.IndexByte(data, 0) == -1 {
+		content.Text = string(data)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(data)
+		content.Encoding = "base64"
+	}
+	if truncated {
+		content.Comment = fmt.Sprintf("truncated to %d bytes", len(data))
+	}
+}
+
+// harBody copies up to limit bytes of the body while it is read.
+type harBody struct {
+	io.ReadCloser
+	buf     bytes.Buffer
+	limit   int
+	size    int64
+	once    sync.Once
+	onClose func(b *harBody)
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.size += int64(n)
+		chunk := p[:n]
+		if b.limit > 0 && b.buf.Len()+n > b.limit {
+			chunk = chunk[:max(b.limit-b.buf.Len(), 0)]
+		}
+		b.buf.Write(chunk)
+	}
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.onClose(b) })
+	return err
+}
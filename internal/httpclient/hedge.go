@@ -0,0 +1,173 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+const (
+	minHedgeSamples = 20  // Столько времён ответа хоста нужно, чтобы начать хеджировать
+	hedgeWindow     = 200 // Учитываются только последние времена
+)
+
+// hedgeTransport sends a second request for the same URL if headers of the
+// first take longer than the -hedge-percentile of recent header times of the
+// origin, and returns whichever response comes first.
+type hedgeTransport struct {
+	next       http.RoundTripper
+	percentile int
+	allow      func() bool // Второй запрос тоже расходует лимит -rps
+	mu         sync.Mutex
+	latencies  map[string]*latencyWindow
+}
+
+func newHedgeTransport(next http.RoundTripper, percentile int, allow func() bool) *hedgeTransport {
+	return &hedgeTransport{next: next, percentile: percentile, allow: allow, latencies: make(map[string]*latencyWindow)}
+}
+
+// latencyWindow keeps the last hedgeWindow header times of an origin.
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+}
+
+func (w *latencyWindow) add(d time.Duration) {
+	if len(w.samples) < hedgeWindow {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % hedgeWindow
+}
+
+func (w *latencyWindow) percentile(p int) time.Duration {
+	sorted := append([]time.Duration(nil), w.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := (len(sorted)*p+99)/100 - 1
+	return sorted[max(i, 0)]
+}
+
+func (t *hedgeTransport) observe(origin string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	w, ok := t.latencies[origin]
+	if !ok {
+		w = &latencyWindow{}
+		t.latencies[origin] = w
+	}
+	w.add(d)
+}
+
+// threshold returns how long to wait for headers before hedging a request
+// to origin, or false while too few responses of it were seen.
+func (t *hedgeTransport) threshold(origin string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	w, ok := t.latencies[origin]
+	if !ok || len(w.samples) < minHedgeSamples {
+		return 0, false
+	}
+	return w.percentile(t.percentile), true
+}
+
+type hedgeResult struct {
+	resp *http.Response
+	err  error
+	n    int // Номер попытки
+}
+
+func (t *hedgeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Повторять можно только запросы без тела
+	if req.Method != http.MethodGet && req.Method != http.MethodHead || req.Body != nil && req.Body != http.NoBody {
+		return t.next.RoundTrip(req)
+	}
+	origin, err := OriginKey(req.URL.String())
+	if err != nil {
+		return t.next.RoundTrip(req)
+	}
+
+	threshold, ok := t.threshold(origin)
+	if !ok {
+		start := time.Now()
+		resp, err := t.next.RoundTrip(req)
+		if err == nil {
+			t.observe(origin, time.Since(start))
+		}
+		return resp, err
+	}
+
+	results := make(chan hedgeResult, 2)
+	var cancels []context.CancelFunc
+	send := func() {
+		ctx, cancel := context.WithCancel(req.Context())
+		n := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			start := time.Now()
+			resp, err := t.next.RoundTrip(req.Clone(ctx))
+			if err == nil {
+				t.observe(origin, time.Since(start))
+			}
+			results <- hedgeResult{resp, err, n}
+		}()
+	}
+
+	send()
+	inFlight := 1
+	timer := time.NewTimer(threshold)
+	defer timer.Stop()
+	hedgeC := timer.C
+	var firstErr error
+	for {
+		select {
+		case <-hedgeC:
+			hedgeC = nil
+			if t.allow() {
+				logger.Debugf("No headers from %s after %s, sending a hedged request", req.URL, threshold)
+				inFlight++
+				send()
+			}
+		case r := <-results:
+			inFlight--
+			if r.err == nil {
+				if inFlight > 0 {
+					// Проигравший запрос отменяется сразу, а ответ, если успел прийти, закрывается
+					cancels[1-r.n]()
+					go func() {
+						if loser := <-results; loser.resp != nil {
+							loser.resp.Body.Close()
+						}
+					}()
+				}
+				r.resp.Body = &cancelBody{ReadCloser: r.resp.Body, cancel: cancels[r.n]}
+				return r.resp, nil
+			}
+			cancels[r.n]()
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			// Ошибку до порога ретраит retryablehttp, хеджировать её не нужно
+			if inFlight == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
+
+// cancelBody cancels the context of a hedged request once its body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,67 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedgeTransport(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		if n == minHedgeSamples+1 {
+			// Первый запрос после набора статистики зависает
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	transport := newHedgeTransport(http.DefaultTransport, 95, func() bool { return true })
+	client := &http.Client{Transport: transport}
+	get := func() string {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	for i := 0; i < minHedgeSamples; i++ {
+		get()
+	}
+
+	start := time.Now()
+	if body := get(); body != "ok" {
+		t.Errorf("hedged response = %q, want ok", body)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("hedged request took %s", elapsed)
+	}
+	if n := requests.Load(); n != minHedgeSamples+2 {
+		t.Errorf("server got %d requests, want %d", n, minHedgeSamples+2)
+	}
+}
+
+func TestLatencyWindowPercentile(t *testing.T) {
+	var w latencyWindow
+	for i := 1; i <= hedgeWindow+100; i++ {
+		w.add(time.Duration(i))
+	}
+	// В окне остались 101..300
+	if got := w.percentile(50); got != 200 {
+		t.Errorf("p50 = %d, want 200", got)
+	}
+	if got := w.percentile(99); got != 298 {
+		t.Errorf("p99 = %d, want 298", got)
+	}
+}
@@ -0,0 +1,92 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+// http3Transport sends https requests over QUIC and falls back to the regular
+// transport for plain http and for servers which don't speak HTTP/3.
+type http3Transport struct {
+	h3        *http3.Transport
+	fallback  http.RoundTripper
+	connectTo *connectMap
+	network   string // udp, udp4 или udp6 в зависимости от -ip-version
+	source    net.IP // -source-ip или адрес -interface
+
+	mu   sync.Mutex
+	quic *quic.Transport // UDP-сокет открывается при первом запросе
+}
+
+func newHttp3Transport(fallback http.RoundTripper, connectTo *connectMap, config config.Config) (*http3Transport, error) {
+	suffix, _ := ipSuffix(config.IpVersion)
+	t := &http3Transport{fallback: fallback, connectTo: connectTo, network: "udp" + suffix}
+	sources, err := newSourceAddrs(config)
+	if err != nil {
+		return nil, err
+	}
+	// Сокет QUIC один на все запросы, поэтому и версия IP одна
+	if t.source, err = sources.pick("", suffix); err != nil {
+		return nil, err
+	}
+	if t.source != nil {
+		t.network = "udp6"
+		if t.source.To4() != nil {
+			t.network = "udp4"
+		}
+	}
+	t.h3 = &http3.Transport{
+		Dial: t.dial,
+		// Значения по умолчанию http3 плюс -dial-timeout на рукопожатие
+		QUICConfig: &quic.Config{
+			MaxIncomingStreams:   -1,
+			KeepAlivePeriod:      10 * time.Second,
+			HandshakeIdleTimeout: config.DialTimeout,
+		},
+	}
+	return t, nil
+}
+
+// dial connects like the TCP transport does: to the -connect-to address of the
+// host, if any, only over the IP version chosen with -ip-version and from the
+// -source-ip.
+func (t *http3Transport) dial(ctx context.Context, addr string, tlsConf *tls.Config, quicConf *quic.Config) (quic.EarlyConnection, error) {
+	udpAddr, err := net.ResolveUDPAddr(t.network, t.connectTo.resolve(addr))
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	if t.quic == nil {
+		conn, err := net.ListenUDP(t.network, &net.UDPAddr{IP: t.source})
+		if err != nil {
+			t.mu.Unlock()
+			return nil, err
+		}
+		t.quic = &quic.Transport{Conn: conn}
+	}
+	tr := t.quic
+	t.mu.Unlock()
+	return tr.DialEarly(ctx, udpAddr, tlsConf, quicConf)
+}
+
+func (t *http3Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" {
+		return t.fallback.RoundTrip(req)
+	}
+	resp, err := t.h3.RoundTrip(req)
+	if err != nil && req.Context().Err() == nil {
+		// Тело у GET-запросов пустое, так что повтор безопасен
+		logger.Debugf("HTTP/3 request to %s failed, falling back: %v", req.URL, err)
+		return t.fallback.RoundTrip(req)
+	}
+	return resp, err
+}
@@ -2,17 +2,24 @@ package httpclient
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
+	"github.com/s3rgeym/git-dump/internal/bench"
 	"github.com/s3rgeym/git-dump/internal/config"
 	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/store"
 	"golang.org/x/time/rate"
 )
 
@@ -20,36 +27,161 @@ type HttpClient struct {
 	*retryablehttp.Client
 	config     config.Config
 	mutex      *sync.Mutex
-	hostErrors map[string]int
+	hostErrors map[string]int // Ошибки по OriginKey, а не по хосту
 	rl         *rate.Limiter
+	transLog   *TransactionLog
+	har        *HarRecorder
+	connectTo  *connectMap
+	breaker    *breaker
+	proxies    *proxyMap
+	slots      *hostSlots
+	tuner      *autoTuner // -auto-tune
+	negative   *negativeCache
+	audit      *AuditLog
+	bench      *bench.Recorder // -bench
 }
 
 func NewHttpClient(config config.Config) *HttpClient {
+	client, err := newHttpClient(config)
+	if err != nil {
+		logger.Fatalf("%v", err)
+	}
+	return client
+}
+
+// ValidateConfig checks HTTP settings without opening the HTTP log, so
+// long-running servers can fail at startup instead of on the first job.
+func ValidateConfig(config config.Config) error {
+	config.HttpLogFile = ""
+	config.HarFile = ""
+	config.AuditLogFile = ""
+	_, err := newHttpClient(config)
+	return err
+}
+
+func newHttpClient(config config.Config) (*HttpClient, error) {
+	connectTo, err := newConnectMap(config.ConnectTo)
+	if err != nil {
+		return nil, err
+	}
+	proxies, err := newProxyMap(config.ProxyUrl, config.ProxyMap)
+	if err != nil {
+		return nil, err
+	}
+	dialContext, err := newDialContext(config, connectTo, proxies)
+	if err != nil {
+		return nil, err
+	}
+
+	breaker := newBreaker(config.BreakerFailures, config.BreakerCooldown)
+	jitter := config.Jitter
+	if config.Stealth && jitter == 0 {
+		jitter = defaultStealthJitter
+	}
+
+	retry, err := newRetryPolicy(config.RetryOn, config.RetryBackoff, config.RetryJitter)
+	if err != nil {
+		return nil, err
+	}
+
 	client := retryablehttp.NewClient()
 	client.RetryMax = config.MaxRetries
-	client.HTTPClient.Timeout = config.ConnTimeout
+	client.RetryWaitMin, client.RetryWaitMax = retry.min, retry.max
+	client.Backoff = retry.backoff
 	client.HTTPClient.Transport = &http.Transport{
 		ResponseHeaderTimeout: config.HeaderTimeout,
 		IdleConnTimeout:       config.KeepAliveTimeout,
-		Proxy:                 http.ProxyFromEnvironment,
+		MaxConnsPerHost:       config.MaxConnsPerHost,
+		MaxIdleConns:          config.MaxIdleConns,
+		MaxIdleConnsPerHost:   config.MaxIdlePerHost,
+		ForceAttemptHTTP2:     config.Http2,
+		Proxy:                 proxies.transportProxy,
+		DialContext:           breaker.dialer(dialContext),
 	}
 	client.Logger = nil
 	client.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
 		if resp != nil && resp.StatusCode == http.StatusMovedPermanently {
 			return false, nil
 		}
-		return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+		if errors.Is(err, ErrCircuitOpen) {
+			return false, nil
+		}
+		if err != nil || ctx.Err() != nil {
+			return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+		}
+		return retry.retryStatus(resp.StatusCode), nil
+	}
+
+	if config.Http3 {
+		if config.ProxyUrl != "" || len(config.ProxyMap) > 0 {
+			return nil, fmt.Errorf("HTTP/3 can't be used with a proxy")
+		}
+		h3, err := newHttp3Transport(client.HTTPClient.Transport, connectTo, config)
+		if err != nil {
+			return nil, err
+		}
+		client.HTTPClient.Transport = h3
+	}
+
+	rl, err := newRateLimiter(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.HedgePercentile < 0 || config.HedgePercentile > 99 {
+		return nil, fmt.Errorf("invalid -hedge-percentile %d: expected 1 to 99, or 0 to disable", config.HedgePercentile)
+	}
+	// В режиме -stealth к хосту идёт только один запрос за раз
+	if config.HedgePercentile > 0 && !config.Stealth {
+		client.HTTPClient.Transport = newHedgeTransport(client.HTTPClient.Transport, config.HedgePercentile, rl.Allow)
 	}
 
-	if config.ProxyUrl != "" {
-		proxyUrlParsed, err := url.Parse(config.ProxyUrl)
+	if config.Offline != "" {
+		transport, err := newOfflineTransport(config.Offline)
 		if err != nil {
-			logger.Fatalf("Failed to parse proxy URL: %v", err)
+			return nil, fmt.Errorf("failed to set up offline mode: %w", err)
 		}
-		client.HTTPClient.Transport.(*http.Transport).Proxy = http.ProxyURL(proxyUrlParsed)
+		client.HTTPClient.Transport = transport
+		rl = rate.NewLimiter(rate.Inf, 0)
 	}
 
-	rl := rate.NewLimiter(rate.Limit(config.MaxRPS), config.MaxRPS)
+	if config.CacheDir != "" {
+		transport, err := newCacheTransport(client.HTTPClient.Transport, config.CacheDir, config.CacheNegativeTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up HTTP cache: %w", err)
+		}
+		client.HTTPClient.Transport = transport
+	}
+
+	// Вместо http.Client.Timeout, чтобы таймаут попытки зависел от класса запроса
+	client.HTTPClient.Transport = &attemptTimeoutTransport{next: client.HTTPClient.Transport}
+
+	var transLog *TransactionLog
+	if config.HttpLogFile != "" {
+		var err error
+		if transLog, err = OpenTransactionLog(config.HttpLogFile); err != nil {
+			return nil, fmt.Errorf("failed to open HTTP log: %w", err)
+		}
+	}
+
+	var audit *AuditLog
+	if config.AuditLogFile != "" {
+		var err error
+		if audit, err = OpenAuditLog(config.AuditLogFile, config.NtpServer, config.ScanID); err != nil {
+			return nil, err
+		}
+	}
+
+	var tuner *autoTuner
+	// -stealth и так отправляет хосту один запрос за раз
+	if config.AutoTune && !config.Stealth {
+		tuner = newAutoTuner(max(config.WorkersNum, 1))
+	}
+
+	var har *HarRecorder
+	if config.HarFile != "" {
+		har = NewHarRecorder(config.HarMaxBody, config.ScanID)
+	}
 
 	return &HttpClient{
 		Client:     client,
@@ -57,11 +189,53 @@ func NewHttpClient(config config.Config) *HttpClient {
 		mutex:      &sync.Mutex{},
 		hostErrors: make(map[string]int),
 		rl:         rl,
+		transLog:   transLog,
+		har:        har,
+		connectTo:  connectTo,
+		breaker:    breaker,
+		proxies:    proxies,
+		slots:      newHostSlots(jitter, config.Stealth),
+		tuner:      tuner,
+		negative:   newNegativeCache(),
+		audit:      audit,
+	}, nil
+}
+
+// Close writes the HAR file and closes the HTTP and audit logs.
+func (c *HttpClient) Close() error {
+	if c.har != nil {
+		if err := c.har.WriteFile(c.config.HarFile); err != nil {
+			return err
+		}
+	}
+	if c.audit != nil {
+		if err := c.audit.Close(); err != nil {
+			return err
+		}
+	}
+	if c.transLog != nil {
+		return c.transLog.Close()
+	}
+	return nil
+}
+
+// SetBench makes the client record request timings for the -bench summary.
+func (c *HttpClient) SetBench(r *bench.Recorder) {
+	c.bench = r
+}
+
+// logTransaction writes request metadata to the -http-log file if it is set.
+func (c *HttpClient) logTransaction(method, targetUrl string, start time.Time, resp *http.Response, err error) {
+	if c.transLog == nil {
+		return
+	}
+	if err := c.transLog.Write(newTransaction(c.config.ScanID, method, targetUrl, start, resp, err)); err != nil {
+		logger.Errorf("Failed to write HTTP log: %v", err)
 	}
 }
 
 func (c *HttpClient) Fetch(targetUrl string) (*http.Response, context.CancelFunc, error) {
-	host, err := extractHost(targetUrl)
+	host, err := OriginKey(targetUrl)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to extract host: %w", err)
 	}
@@ -73,7 +247,28 @@ func (c *HttpClient) Fetch(targetUrl string) (*http.Response, context.CancelFunc
 	}
 	c.mutex.Unlock()
 
+	if status, ok := c.negative.status(targetUrl); ok {
+		logger.Debugf("Not requesting %s again: it was not found during this run", targetUrl)
+		return nil, nil, &StatusError{Url: targetUrl, StatusCode: status}
+	}
+
+	// Не ждём лимитера ради хоста, до которого заведомо не достучаться
+	if u, err := url.Parse(targetUrl); err == nil && c.breaker.open(dialAddr(u)) {
+		return nil, nil, fmt.Errorf("skipping URL %s: %w for %s", targetUrl, ErrCircuitOpen, dialAddr(u))
+	}
+
+	release := c.slots.acquire(host)
+	limit := c.tuner.acquire(host)
+	if limit != nil {
+		releaseSlot := release
+		release = func() {
+			c.tuner.release(limit)
+			releaseSlot()
+		}
+	}
+
 	if err := c.rl.Wait(context.TODO()); err != nil {
+		release()
 		return nil, nil, fmt.Errorf("error waiting for rate limiter: %w", err)
 	}
 
@@ -81,6 +276,7 @@ func (c *HttpClient) Fetch(targetUrl string) (*http.Response, context.CancelFunc
 
 	req, err := retryablehttp.NewRequest("GET", targetUrl, nil)
 	if err != nil {
+		release()
 		c.mutex.Lock()
 		c.hostErrors[host]++
 		c.mutex.Unlock()
@@ -94,15 +290,36 @@ func (c *HttpClient) Fetch(targetUrl string) (*http.Response, context.CancelFunc
 		"User-Agent":      c.config.UserAgent,
 	}
 
+	if c.config.ScanIdHeader {
+		headers["X-Scan-Id"] = c.config.ScanID
+	}
+
 	for key, value := range headers {
 		req.Header.Set(key, value)
 	}
 
-	ctx, cancel := context.WithTimeout(req.Context(), c.config.RequestTimeout)
-	req = req.WithContext(ctx)
+	timeout, attemptTimeout := timeouts(c.config, RequestClass(targetUrl))
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	req = req.WithContext(c.bench.WithTrace(withAttemptTimeout(ctx, attemptTimeout)))
 
+	start := time.Now()
 	resp, err := c.Do(req)
+	c.tuner.observe(limit, time.Since(start), resp, err)
+	c.logTransaction(req.Method, targetUrl, start, resp, err)
+	if c.har != nil {
+		c.har.Record(req.Request, start, resp, err)
+	}
+	if c.audit != nil {
+		if err != nil {
+			if err := c.audit.Write(newAuditEntry(req.Request, nil, err)); err != nil {
+				logger.Errorf("Failed to write audit log: %v", err)
+			}
+		} else {
+			resp.Body = &auditBody{ReadCloser: resp.Body, log: c.audit, entry: newAuditEntry(req.Request, resp, nil), hash: sha256.New()}
+		}
+	}
 	if err != nil {
+		release()
 		c.mutex.Lock()
 		c.hostErrors[host]++
 		c.mutex.Unlock()
@@ -110,55 +327,122 @@ func (c *HttpClient) Fetch(targetUrl string) (*http.Response, context.CancelFunc
 		return nil, nil, fmt.Errorf("failed to fetch URL %s: %w", targetUrl, err)
 	}
 
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
 	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+			c.negative.add(targetUrl, resp.StatusCode)
+		}
 		resp.Body.Close()
 		cancel()
-		return nil, nil, fmt.Errorf("received bad HTTP status %d for URL %s", resp.StatusCode, targetUrl)
+		return nil, nil, &StatusError{Url: targetUrl, StatusCode: resp.StatusCode, Header: resp.Header}
 	}
+	// Тарпит считается ошибкой хоста, как и обрыв соединения
+	resp.Body = newStallBody(resp.Body, targetUrl, c.config.MinSpeed, c.config.StallTime, cancel, func() {
+		c.mutex.Lock()
+		c.hostErrors[host]++
+		c.mutex.Unlock()
+	})
 
 	return resp, cancel, nil
 }
 
-func (c *HttpClient) SaveResponse(resp *http.Response, fileName string) error {
-	defer resp.Body.Close()
+// StatusError is returned by Fetch for responses other than 200 OK.
+type StatusError struct {
+	Url        string
+	StatusCode int
+	Header     http.Header
+}
 
-	err := os.MkdirAll(filepath.Dir(fileName), 0755)
-	if err != nil {
-		return fmt.Errorf("failed to create directory for file %s: %w", fileName, err)
-	}
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("received bad HTTP status %d for URL %s", e.StatusCode, e.Url)
+}
 
-	file, err := os.Create(fileName)
+// PartSuffix is appended to files while they are being downloaded.
+const PartSuffix = store.PartSuffix
+
+// SaveResponse writes the body to fileName in st. The store makes the file
+// visible only once it is complete, so interrupted downloads never leave
+// truncated files at fileName.
+func (c *HttpClient) SaveResponse(resp *http.Response, st store.Store, fileName string) error {
+	defer resp.Body.Close()
+
+	file, err := st.Create(fileName, 0644)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", fileName, err)
+		return err
 	}
-	defer file.Close()
-
-	_, err = io.Copy(file, resp.Body)
+	_, err = io.Copy(c.bench.Writer(file), c.bench.Reader(resp.Body))
+	c.bench.CountFile()
 	if err != nil {
+		file.Abort()
 		return fmt.Errorf("failed to save file %s: %w", fileName, err)
 	}
+	return file.Commit()
+}
 
-	return nil
+// RemoveStaleParts deletes partial downloads left in dir by interrupted runs.
+func RemoveStaleParts(dir string) {
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() && strings.HasSuffix(path, PartSuffix) {
+			logger.Debugf("Removing stale partial file %s", path)
+			os.Remove(path)
+		}
+		return nil
+	})
 }
 
-func (c *HttpClient) FetchFile(targetUrl, fileName string) (bool, error) {
+func (c *HttpClient) FetchFile(targetUrl string, st store.Store, fileName string) (bool, error) {
 	resp, cancel, err := c.Fetch(targetUrl)
 	if err != nil {
 		return false, err
 	}
 	defer cancel()
 	defer resp.Body.Close()
-	if err := c.SaveResponse(resp, fileName); err != nil {
+	if err := c.SaveResponse(resp, st, fileName); err != nil {
 		return false, fmt.Errorf("failed to save file %s: %w", fileName, err)
 	}
 
 	return true, nil
 }
 
-func extractHost(urlStr string) (string, error) {
+// OriginKey returns scheme://host:port of a URL with the default port filled
+// in, so errors of one service don't throttle another on the same host.
+func OriginKey(urlStr string) (string, error) {
 	u, err := url.Parse(urlStr)
 	if err != nil {
 		return "", err
 	}
-	return u.Host, nil
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("no host in %q", urlStr)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	port := u.Port()
+	if port == "" {
+		switch scheme {
+		case "https":
+			port = "443"
+		default:
+			port = "80"
+		}
+	}
+	return scheme + "://" + net.JoinHostPort(strings.ToLower(u.Hostname()), port), nil
+}
+
+// HostErrors returns a copy of error counters keyed by OriginKey.
+func (c *HttpClient) HostErrors() map[string]int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	ret := make(map[string]int, len(c.hostErrors))
+	for k, v := range c.hostErrors {
+		ret[k] = v
+	}
+	return ret
+}
+
+// HostConcurrency returns the concurrency limits of origins chosen by
+// -auto-tune, keyed by OriginKey, or nil without it.
+func (c *HttpClient) HostConcurrency() map[string]int {
+	return c.tuner.limits()
 }
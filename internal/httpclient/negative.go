@@ -0,0 +1,79 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+// maxNegativeEntries bounds the memory of the negative cache on huge scans.
+const maxNegativeEntries = 1 << 20
+
+// softNotFound is the status remembered for pages which answer 200 with an
+// HTML error page instead of the requested file.
+const softNotFound = http.StatusNotFound
+
+// negativeCache remembers URLs which were not found during the run, keyed by
+// origin and cleaned path, so references to the same missing object from
+// many commits and trees, or spelled with another case of the host or an
+// explicit port, are not requested again.
+type negativeCache struct {
+	mu      sync.Mutex
+	entries map[string]int // Статус ответа по ключу
+	full    bool
+}
+
+func newNegativeCache() *negativeCache {
+	return &negativeCache{entries: make(map[string]int)}
+}
+
+func negativeKey(targetUrl string) (string, bool) {
+	origin, err := OriginKey(targetUrl)
+	if err != nil {
+		return "", false
+	}
+	u, _ := url.Parse(targetUrl)
+	key := origin + path.Clean("/"+u.Path)
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key, true
+}
+
+// status returns the remembered status of a missing URL.
+func (n *negativeCache) status(targetUrl string) (int, bool) {
+	key, ok := negativeKey(targetUrl)
+	if !ok {
+		return 0, false
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	status, ok := n.entries[key]
+	return status, ok
+}
+
+func (n *negativeCache) add(targetUrl string, status int) {
+	key, ok := negativeKey(targetUrl)
+	if !ok {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.entries) >= maxNegativeEntries {
+		if !n.full {
+			n.full = true
+			logger.Warnf("Negative cache is full, missing URLs are no longer remembered")
+		}
+		return
+	}
+	n.entries[key] = status
+}
+
+// MarkMissing remembers a URL whose response turned out to be a soft 404,
+// i.e. an error page served with 200 OK, so it is not requested again.
+func (c *HttpClient) MarkMissing(targetUrl string) {
+	c.negative.add(targetUrl, softNotFound)
+}
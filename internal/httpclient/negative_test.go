@@ -0,0 +1,41 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+)
+
+func TestNegativeCache(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	client, err := newHttpClient(config.Config{RequestTimeout: 5e9, MaxRPS: 100, MaxHostErrors: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	object := "/.git/objects/ab/cdef0123456789abcdef0123456789abcdef01"
+	for _, u := range []string{
+		srv.URL + object,
+		strings.Replace(srv.URL, "http://", "HTTP://", 1) + "/.git/objects/../objects/ab/cdef0123456789abcdef0123456789abcdef01",
+		srv.URL + "//.git/objects/ab/cdef0123456789abcdef0123456789abcdef01",
+	} {
+		_, _, err := client.Fetch(u)
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+			t.Errorf("Fetch(%q) = %v, want 404 status error", u, err)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("server got %d requests, want 1", n)
+	}
+}
@@ -0,0 +1,174 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+)
+
+// offlineTransport answers requests from a previously saved host directory or
+// a HAR capture instead of the network. Unknown URLs get 404.
+type offlineTransport struct {
+	dir     string
+	entries map[string]*harEntry
+}
+
+func newOfflineTransport(source string) (*offlineTransport, error) {
+	fi, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open offline source: %w", err)
+	}
+	if fi.IsDir() {
+		return &offlineTransport{dir: source}, nil
+	}
+	entries, err := readHar(source)
+	if err != nil {
+		return nil, err
+	}
+	t := &offlineTransport{entries: make(map[string]*harEntry)}
+	for _, entry := range entries {
+		// Успешный ответ важнее ошибок, записанных для того же URL
+		if prev, ok := t.entries[entry.Request.Url]; ok && prev.Response.Status == http.StatusOK {
+			continue
+		}
+		t.entries[entry.Request.Url] = entry
+	}
+	return t, nil
+}
+
+func (t *offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.entries != nil {
+		return t.harResponse(req)
+	}
+	return t.fileResponse(req)
+}
+
+func (t *offlineTransport) fileResponse(req *http.Request) (*http.Response, error) {
+	// Каталог хоста называется по имени хоста: output/<host>/...
+	fileName := filepath.Join(t.dir, filepath.FromSlash(strings.TrimLeft(req.URL.Path, "/")))
+	if filepath.Base(t.dir) != req.URL.Hostname() {
+		return newResponse(req, http.StatusNotFound, nil, nil), nil
+	}
+	fi, err := os.Stat(fileName)
+	if err != nil || fi.IsDir() {
+		return newResponse(req, http.StatusNotFound, nil, nil), nil
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{"Content-Type": {"application/octet-stream"}}
+	return newResponse(req, http.StatusOK, header, data), nil
+}
+
+func (t *offlineTransport) harResponse(req *http.Request) (*http.Response, error) {
+	entry, ok := t.entries[req.URL.String()]
+	if !ok {
+		return newResponse(req, http.StatusNotFound, nil, nil), nil
+	}
+	header := http.Header{}
+	for _, h := range entry.Response.Headers {
+		header.Add(h.Name, h.Value)
+	}
+	data := []byte(entry.Response.Content.Text)
+	if entry.Response.Content.Encoding == "base64" {
+		var err error
+		if data, err = base64.StdEncoding.DecodeString(entry.Response.Content.Text); err != nil {
+			return nil, fmt.Errorf("invalid HAR body for %s: %w", req.URL, err)
+		}
+	}
+	status := entry.Response.Status
+	if status == 0 {
+		// Запрос завершился ошибкой и при записи
+		status = http.StatusBadGateway
+	}
+	return newResponse(req, status, header, data), nil
+}
+
+func newResponse(req *http.Request, status int, header http.Header, data []byte) *http.Response {
+	if header == nil {
+		header = http.Header{"Content-Type": {"text/plain"}}
+	}
+	header.Del("Content-Length")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}
+}
+
+func readHar(fileName string) ([]*harEntry, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HAR %s: %w", fileName, err)
+	}
+	var har harLog
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("failed to decode HAR %s: %w", fileName, err)
+	}
+	return har.Log.Entries, nil
+}
+
+// OfflineTargets returns target URLs available in a saved host directory or a
+// HAR capture.
+func OfflineTargets(source string) ([]string, error) {
+	fi, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open offline source: %w", err)
+	}
+
+	var targets []string
+	seen := make(map[string]bool)
+	add := func(target string) {
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+
+	if fi.IsDir() {
+		gitDirs, err := gitobj.FindGitDirs(source)
+		if err != nil {
+			return nil, err
+		}
+		host := filepath.Base(filepath.Clean(source))
+		for _, gitDir := range gitDirs {
+			rel, err := filepath.Rel(source, filepath.Dir(gitDir))
+			if err != nil {
+				continue
+			}
+			u := url.URL{Scheme: "http", Host: host, Path: "/"}
+			if rel != "." {
+				u.Path += filepath.ToSlash(rel) + "/"
+			}
+			add(u.String())
+		}
+		return targets, nil
+	}
+
+	entries, err := readHar(source)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if i := strings.Index(entry.Request.Url, "/.git/"); i != -1 {
+			add(entry.Request.Url[:i+1])
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,149 @@
+package httpclient
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// proxyRule sends connections to matching hosts through proxy, or directly
+// if proxy is empty.
+type proxyRule struct {
+	pattern string     // Имя хоста или *.домен
+	network *net.IPNet // Или подсеть
+	proxy   proxyChain
+}
+
+func (r proxyRule) match(host string) bool {
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	if r.network != nil {
+		ip := net.ParseIP(host)
+		return ip != nil && r.network.Contains(ip)
+	}
+	if domain, ok := strings.CutPrefix(r.pattern, "*."); ok {
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	}
+	return host == r.pattern
+}
+
+// proxyMap picks the proxy of a host from -proxy-map rules, falling back to
+// -proxy. The first matching rule wins.
+type proxyMap struct {
+	rules    []proxyRule
+	fallback proxyChain // -proxy; empty means proxies from the environment
+}
+
+// newProxyMap parses -proxy-map entries of the form scope=proxy. A scope is a
+// host name, *.domain, a CIDR or a file with one of those per line. The proxy
+// is a URL or chain as for -proxy, or "direct".
+func newProxyMap(fallback string, entries []string) (*proxyMap, error) {
+	m := &proxyMap{}
+	var err error
+	if m.fallback, err = parseProxyChain(fallback); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		scope, proxyText, ok := strings.Cut(entry, "=")
+		if !ok || scope == "" || proxyText == "" {
+			return nil, fmt.Errorf("invalid -proxy-map value %q: expected scope=proxy", entry)
+		}
+		var proxy proxyChain
+		if proxyText != "direct" {
+			if proxy, err = parseProxyChain(proxyText); err != nil {
+				return nil, fmt.Errorf("invalid -proxy-map value %q: %w", entry, err)
+			}
+		}
+		patterns, err := readScope(scope)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -proxy-map value %q: %w", entry, err)
+		}
+		for _, pattern := range patterns {
+			rule := proxyRule{pattern: strings.ToLower(pattern), proxy: proxy}
+			if _, network, err := net.ParseCIDR(pattern); err == nil {
+				rule.network = network
+			} else if ip := net.ParseIP(pattern); ip != nil {
+				rule.network = &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)}
+			}
+			m.rules = append(m.rules, rule)
+		}
+	}
+	return m, nil
+}
+
+// readScope returns the patterns of a scope: the lines of a file if such a
+// file exists, otherwise the scope itself.
+func readScope(scope string) ([]string, error) {
+	file, err := os.Open(scope)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{scope}, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			patterns = append(patterns, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("no hosts in %s", scope)
+	}
+	return patterns, nil
+}
+
+// lookup returns the proxy for the first of hosts matched by a rule, or the
+// fallback if none is. The second value is false when no rule matched.
+func (m *proxyMap) lookup(hosts ...string) (proxyChain, bool) {
+	for _, rule := range m.rules {
+		for _, host := range hosts {
+			if rule.match(host) {
+				return rule.proxy, true
+			}
+		}
+	}
+	return m.fallback, false
+}
+
+// chains returns every distinct proxy chain of the map.
+func (m *proxyMap) chains() []proxyChain {
+	all := []proxyChain{m.fallback}
+	for _, rule := range m.rules {
+		all = append(all, rule.proxy)
+	}
+	seen := make(map[string]bool)
+	var ret []proxyChain
+	for _, c := range all {
+		if len(c) > 0 && !seen[c.String()] {
+			seen[c.String()] = true
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// transportProxy is the Proxy function of the transport. It only gets single
+// HTTP proxies; SOCKS proxies and chains are dialed by newDialContext.
+func (m *proxyMap) transportProxy(req *http.Request) (*url.URL, error) {
+	proxy, matched := m.lookup(req.URL.Hostname())
+	if !matched && len(proxy) == 0 {
+		return http.ProxyFromEnvironment(req)
+	}
+	return proxy.transportProxy(), nil
+}
+
+// resolvesNames reports whether the proxy of host resolves host names.
+func (m *proxyMap) resolvesNames(host string) bool {
+	proxy, _ := m.lookup(host)
+	return proxy.resolvesNames()
+}
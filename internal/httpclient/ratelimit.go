@@ -0,0 +1,59 @@
+package httpclient
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"golang.org/x/time/rate"
+)
+
+// newRateLimiter returns the limiter shared by all requests. The rate is
+// -rps, or one request per -interval; -burst requests may go at once.
+func newRateLimiter(config config.Config) (*rate.Limiter, error) {
+	limit, burst, err := rateSettings(config)
+	if err != nil {
+		return nil, err
+	}
+	return rate.NewLimiter(limit, burst), nil
+}
+
+// SetRate changes -rps, -interval and -burst of a running client.
+func (c *HttpClient) SetRate(config config.Config) error {
+	limit, burst, err := rateSettings(config)
+	if err != nil {
+		return err
+	}
+	c.rl.SetLimit(limit)
+	c.rl.SetBurst(burst)
+	return nil
+}
+
+func rateSettings(config config.Config) (rate.Limit, int, error) {
+	if config.MaxRPS < 0 || math.IsNaN(config.MaxRPS) {
+		return 0, 0, fmt.Errorf("invalid -rps %g: expected a positive number, or 0 to disable the limit", config.MaxRPS)
+	}
+	if config.Interval < 0 {
+		return 0, 0, fmt.Errorf("invalid -interval %s", config.Interval)
+	}
+	if config.Burst < 0 {
+		return 0, 0, fmt.Errorf("invalid -burst %d", config.Burst)
+	}
+
+	limit := rate.Limit(config.MaxRPS)
+	if config.Interval > 0 {
+		limit = rate.Every(config.Interval)
+	} else if config.MaxRPS == 0 {
+		limit = rate.Inf
+	}
+	burst := config.Burst
+	if burst == 0 {
+		// Раньше burst всегда был равен -rps; при дробном rps и с -interval,
+		// который обещает паузу между запросами, — один запрос
+		burst = 1
+		if limit != rate.Inf && config.Interval == 0 {
+			burst = max(int(math.Ceil(float64(limit))), 1)
+		}
+	}
+	return limit, burst, nil
+}
@@ -0,0 +1,41 @@
+package httpclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"golang.org/x/time/rate"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.Config
+		limit rate.Limit
+		burst int
+	}{
+		{"integer rps", config.Config{MaxRPS: 150}, 150, 150},
+		{"fractional rps", config.Config{MaxRPS: 0.2}, 0.2, 1},
+		{"fractional above one", config.Config{MaxRPS: 2.5}, 2.5, 3},
+		{"interval", config.Config{MaxRPS: 150, Interval: 5 * time.Second}, 0.2, 1},
+		{"short interval", config.Config{Interval: 300 * time.Millisecond}, rate.Every(300 * time.Millisecond), 1},
+		{"burst", config.Config{MaxRPS: 0.5, Burst: 4}, 0.5, 4},
+		{"unlimited", config.Config{}, rate.Inf, 1},
+	}
+	for _, tt := range tests {
+		rl, err := newRateLimiter(tt.cfg)
+		if err != nil {
+			t.Fatalf("%s: error = %v", tt.name, err)
+		}
+		if rl.Limit() != tt.limit || rl.Burst() != tt.burst {
+			t.Errorf("%s: limit %v burst %d, want %v and %d", tt.name, rl.Limit(), rl.Burst(), tt.limit, tt.burst)
+		}
+	}
+
+	for _, cfg := range []config.Config{{MaxRPS: -1}, {MaxRPS: 1, Burst: -1}, {MaxRPS: 1, Interval: -time.Second}} {
+		if _, err := newRateLimiter(cfg); err == nil {
+			t.Errorf("newRateLimiter(%+v) returned no error", cfg)
+		}
+	}
+}
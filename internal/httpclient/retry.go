@@ -0,0 +1,133 @@
+package httpclient
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// retryPolicy decides which responses are retried (-retry-on) and how long
+// to wait before a retry (-retry-backoff, -retry-jitter).
+type retryPolicy struct {
+	codes  []codeRange
+	min    time.Duration
+	max    time.Duration
+	jitter float64
+}
+
+type codeRange struct{ from, to int }
+
+// Пустые значения означают прежнее поведение retryablehttp
+const (
+	defaultRetryOn      = "429,500,502-599"
+	defaultRetryBackoff = "1s..30s"
+)
+
+func newRetryPolicy(retryOn, backoff string, jitter float64) (*retryPolicy, error) {
+	if retryOn == "" {
+		retryOn = defaultRetryOn
+	}
+	if backoff == "" {
+		backoff = defaultRetryBackoff
+	}
+	p := &retryPolicy{jitter: jitter}
+	if jitter < 0 || jitter > 1 {
+		return nil, fmt.Errorf("invalid -retry-jitter %g: expected 0 to 1", jitter)
+	}
+	var err error
+	if p.codes, err = parseRetryOn(retryOn); err != nil {
+		return nil, fmt.Errorf("invalid -retry-on %q: %w", retryOn, err)
+	}
+	if p.min, p.max, err = parseBackoff(backoff); err != nil {
+		return nil, fmt.Errorf("invalid -retry-backoff %q: %w", backoff, err)
+	}
+	return p, nil
+}
+
+// parseRetryOn parses a list of status codes, ranges like 502-504 and
+// classes like 5xx. "none" retries no response, only network errors.
+func parseRetryOn(s string) ([]codeRange, error) {
+	var codes []codeRange
+	if strings.TrimSpace(s) == "none" {
+		return codes, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		if len(item) == 3 && item[1:] == "xx" && item[0] >= '1' && item[0] <= '5' {
+			from := int(item[0]-'0') * 100
+			codes = append(codes, codeRange{from, from + 99})
+			continue
+		}
+		fromText, toText, isRange := strings.Cut(item, "-")
+		from, err := strconv.Atoi(fromText)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status code %q", item)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(toText); err != nil || to < from {
+				return nil, fmt.Errorf("invalid status range %q", item)
+			}
+		}
+		if from < 100 || to > 599 {
+			return nil, fmt.Errorf("status code out of range in %q", item)
+		}
+		codes = append(codes, codeRange{from, to})
+	}
+	if len(codes) == 0 {
+		return nil, fmt.Errorf("no status codes")
+	}
+	return codes, nil
+}
+
+// parseBackoff parses "min..max" or a single constant delay.
+func parseBackoff(s string) (time.Duration, time.Duration, error) {
+	minText, maxText, ok := strings.Cut(s, "..")
+	if !ok {
+		maxText = minText
+	}
+	min, err := time.ParseDuration(strings.TrimSpace(minText))
+	if err != nil {
+		return 0, 0, err
+	}
+	max, err := time.ParseDuration(strings.TrimSpace(maxText))
+	if err != nil {
+		return 0, 0, err
+	}
+	if min < 0 || max < min {
+		return 0, 0, fmt.Errorf("expected 0 <= min <= max")
+	}
+	return min, max, nil
+}
+
+// retryStatus reports whether a response with code is retried.
+func (p *retryPolicy) retryStatus(code int) bool {
+	for _, r := range p.codes {
+		if code >= r.from && code <= r.to {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff doubles the delay from min with every attempt up to max. Retry-After
+// of 429 and 503 responses is honored, but not beyond max. With jitter the
+// delay is randomly shortened by up to that fraction.
+func (p *retryPolicy) backoff(_, _ time.Duration, attempt int, resp *http.Response) time.Duration {
+	sleep := retryablehttp.DefaultBackoff(p.min, p.max, attempt, resp)
+	if sleep > p.max {
+		sleep = p.max
+	}
+	if p.jitter > 0 {
+		sleep -= time.Duration(rand.Float64() * p.jitter * float64(sleep))
+	}
+	return sleep
+}
@@ -0,0 +1,61 @@
+package httpclient
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryOn(t *testing.T) {
+	p, err := newRetryPolicy("429, 5xx,408-409", "1s..30s", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for code, want := range map[int]bool{429: true, 500: true, 501: true, 599: true, 408: true, 409: true, 404: false, 410: false, 200: false} {
+		if got := p.retryStatus(code); got != want {
+			t.Errorf("retryStatus(%d) = %v, want %v", code, got, want)
+		}
+	}
+
+	p, err = newRetryPolicy("none", "1s", 0)
+	if err != nil || p.retryStatus(503) {
+		t.Errorf("none: %v", err)
+	}
+
+	for _, bad := range []string{"abc", "600", "504-502", "6xx", ","} {
+		if _, err := newRetryPolicy(bad, "1s..30s", 0); err == nil {
+			t.Errorf("newRetryPolicy(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	for _, bad := range []string{"1s..", "30s..1s", "fast"} {
+		if _, err := newRetryPolicy("429", bad, 0); err == nil {
+			t.Errorf("backoff %q accepted", bad)
+		}
+	}
+
+	p, err := newRetryPolicy("429", "100ms..1s", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for attempt, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		if got := p.backoff(0, 0, attempt, nil); got != want {
+			t.Errorf("attempt %d: got %s, want %s", attempt, got, want)
+		}
+	}
+
+	// Retry-After не может превысить max
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"3600"}}}
+	if got := p.backoff(0, 0, 0, resp); got != time.Second {
+		t.Errorf("Retry-After: got %s, want 1s", got)
+	}
+
+	p.jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.backoff(0, 0, 3, nil); got < 400*time.Millisecond || got > 800*time.Millisecond {
+			t.Fatalf("jittered delay %s out of range", got)
+		}
+	}
+}
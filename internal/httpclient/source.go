@@ -0,0 +1,85 @@
+package httpclient
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+)
+
+// sourceAddrs are the local addresses connections are made from: the
+// -source-ip addresses or those of the -interface. Without an address of
+// the needed IP version a connection fails rather than leaving from another
+// address, since allowlists and rules of engagement depend on it.
+type sourceAddrs struct {
+	v4, v6 net.IP
+}
+
+func newSourceAddrs(config config.Config) (*sourceAddrs, error) {
+	var ips []net.IP
+	switch {
+	case config.SourceIp != "" && config.Interface != "":
+		return nil, fmt.Errorf("-source-ip and -interface can't be used together")
+	case config.SourceIp != "":
+		for _, s := range strings.Split(config.SourceIp, ",") {
+			ip := net.ParseIP(strings.TrimSpace(s))
+			if ip == nil {
+				return nil, fmt.Errorf("invalid -source-ip address %q", s)
+			}
+			ips = append(ips, ip)
+		}
+	case config.Interface != "":
+		iface, err := net.InterfaceByName(config.Interface)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -interface: %w", err)
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get addresses of %s: %w", config.Interface, err)
+		}
+		for _, addr := range addrs {
+			// Link-local адреса без зоны не подходят для исходящих соединений
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLinkLocalUnicast() {
+				ips = append(ips, ipNet.IP)
+			}
+		}
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("interface %s has no usable addresses", config.Interface)
+		}
+	default:
+		return nil, nil
+	}
+	s := &sourceAddrs{}
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			if s.v4 == nil {
+				s.v4 = ip.To4()
+			}
+		} else if s.v6 == nil {
+			s.v6 = ip
+		}
+	}
+	return s, nil
+}
+
+// pick returns the local address for a connection to host under
+// -ip-version. Names get the IPv4 address if there is one, and the dialer
+// then only uses their IPv4 addresses.
+func (s *sourceAddrs) pick(host, suffix string) (net.IP, error) {
+	if s == nil {
+		return nil, nil
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	wantV4 := suffix == "4" || ip != nil && ip.To4() != nil
+	wantV6 := suffix == "6" || ip != nil && ip.To4() == nil
+	switch {
+	case wantV4 && s.v4 == nil:
+		return nil, fmt.Errorf("no IPv4 source address to connect to %s", host)
+	case wantV6 && s.v6 == nil:
+		return nil, fmt.Errorf("no IPv6 source address to connect to %s", host)
+	case wantV6 || s.v4 == nil:
+		return s.v6, nil
+	}
+	return s.v4, nil
+}
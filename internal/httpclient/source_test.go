@@ -0,0 +1,80 @@
+package httpclient
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+)
+
+func TestSourceAddrsPick(t *testing.T) {
+	both, err := newSourceAddrs(config.Config{SourceIp: "192.0.2.10, 2001:db8::10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v4only, _ := newSourceAddrs(config.Config{SourceIp: "192.0.2.10"})
+	tests := []struct {
+		s      *sourceAddrs
+		host   string
+		suffix string
+		want   string
+	}{
+		{both, "example.com", "", "192.0.2.10"},
+		{both, "example.com", "6", "2001:db8::10"},
+		{both, "2001:db8::1", "", "2001:db8::10"},
+		{both, "[2001:db8::1]", "", "2001:db8::10"},
+		{both, "198.51.100.1", "", "192.0.2.10"},
+		{v4only, "example.com", "", "192.0.2.10"},
+		{v4only, "2001:db8::1", "", ""},
+		{v4only, "example.com", "6", ""},
+	}
+	for _, tt := range tests {
+		ip, err := tt.s.pick(tt.host, tt.suffix)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("pick(%s, %q) = %s, want an error", tt.host, tt.suffix, ip)
+			}
+			continue
+		}
+		if err != nil || ip.String() != tt.want {
+			t.Errorf("pick(%s, %q) = %s, %v; want %s", tt.host, tt.suffix, ip, err, tt.want)
+		}
+	}
+
+	for _, c := range []config.Config{{SourceIp: "nope"}, {SourceIp: "192.0.2.1", Interface: "lo"}, {Interface: "no-such-iface0"}} {
+		if _, err := newSourceAddrs(c); err == nil {
+			t.Errorf("newSourceAddrs(%+v) succeeded", c)
+		}
+	}
+}
+
+func TestDialFromSourceIp(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	remote := make(chan string, 1)
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			remote <- conn.RemoteAddr().(*net.TCPAddr).IP.String()
+			conn.Close()
+		}
+	}()
+
+	connectTo, _ := newConnectMap(nil)
+	proxies, _ := newProxyMap("", nil)
+	dial, err := newDialContext(config.Config{SourceIp: "127.0.0.2"}, connectTo, proxies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := dial(context.Background(), "tcp", ln.Addr().String())
+	if err != nil {
+		t.Skipf("can't bind to 127.0.0.2: %v", err)
+	}
+	conn.Close()
+	if got := <-remote; got != "127.0.0.2" {
+		t.Errorf("connection came from %s, want 127.0.0.2", got)
+	}
+}
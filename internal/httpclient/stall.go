@@ -0,0 +1,80 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+// ErrStalled is returned by reads of a body that arrived slower than
+// -min-speed for -stall-time.
+var ErrStalled = errors.New("transfer stalled")
+
+// stallBody aborts a transfer whose throughput over each -stall-time window
+// is below -min-speed bytes per second. Tarpits and dying servers would
+// otherwise hold a worker until the whole request timeout expires.
+type stallBody struct {
+	io.ReadCloser
+	url     string
+	read    atomic.Int64
+	stalled atomic.Bool
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newStallBody(body io.ReadCloser, url string, minSpeed int64, window time.Duration, cancel context.CancelFunc, onStall func()) io.ReadCloser {
+	if minSpeed <= 0 || window <= 0 {
+		return body
+	}
+	b := &stallBody{ReadCloser: body, url: url, done: make(chan struct{})}
+	floor := int64(float64(minSpeed) * window.Seconds())
+	go func() {
+		ticker := time.NewTicker(window)
+		defer ticker.Stop()
+		var last int64
+		for {
+			select {
+			case <-b.done:
+				return
+			case <-ticker.C:
+				n := b.read.Load()
+				if n-last < floor {
+					logger.Warnf("Aborting %s: %d bytes in the last %s, below %d bytes/s", url, n-last, window, minSpeed)
+					b.stalled.Store(true)
+					cancel()
+					onStall()
+					return
+				}
+				last = n
+			}
+		}
+	}()
+	return b
+}
+
+func (b *stallBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read.Add(int64(n))
+	if err != nil && err != io.EOF && b.stalled.Load() {
+		err = fmt.Errorf("%w after %d bytes of %s", ErrStalled, b.read.Load(), b.url)
+	}
+	if err != nil {
+		b.stop()
+	}
+	return n, err
+}
+
+func (b *stallBody) Close() error {
+	b.stop()
+	return b.ReadCloser.Close()
+}
+
+func (b *stallBody) stop() {
+	b.once.Do(func() { close(b.done) })
+}
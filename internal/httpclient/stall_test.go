@@ -0,0 +1,44 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStallBodyAborts(t *testing.T) {
+	pr, pw := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		pw.Write([]byte("a"))
+		// Дальше сервер молчит, пока запрос не отменят
+		<-ctx.Done()
+		pw.CloseWithError(ctx.Err())
+	}()
+	stalls := 0
+	body := newStallBody(pr, "http://example.com/pack", 1000, 50*time.Millisecond, cancel, func() { stalls++ })
+	defer body.Close()
+
+	_, err := io.ReadAll(body)
+	if !errors.Is(err, ErrStalled) {
+		t.Fatalf("got %v, want ErrStalled", err)
+	}
+	if stalls != 1 {
+		t.Errorf("onStall called %d times", stalls)
+	}
+}
+
+func TestStallBodyFastEnough(t *testing.T) {
+	data := strings.Repeat("x", 1<<20)
+	body := newStallBody(io.NopCloser(strings.NewReader(data)), "http://example.com/pack", 1000, 50*time.Millisecond, func() {}, func() {
+		t.Error("fast body reported as stalled")
+	})
+	got, err := io.ReadAll(body)
+	body.Close()
+	if err != nil || len(got) != len(data) {
+		t.Fatalf("read %d bytes, err %v", len(got), err)
+	}
+}
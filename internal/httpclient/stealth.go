@@ -0,0 +1,66 @@
+package httpclient
+
+import (
+	"io"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// defaultStealthJitter is used by -stealth unless -jitter is given.
+const defaultStealthJitter = 2 * time.Second
+
+// hostSlots spaces requests out by a random delay up to jitter and, in
+// -stealth mode, lets only one request per origin run at a time.
+type hostSlots struct {
+	jitter time.Duration
+	serial bool
+	mu     sync.Mutex
+	slots  map[string]chan struct{}
+}
+
+func newHostSlots(jitter time.Duration, serial bool) *hostSlots {
+	return &hostSlots{jitter: jitter, serial: serial, slots: make(map[string]chan struct{})}
+}
+
+// acquire waits for the turn of a request to origin and returns the function
+// which ends it.
+func (h *hostSlots) acquire(origin string) func() {
+	release := func() {}
+	if h.serial {
+		h.mu.Lock()
+		slot, ok := h.slots[origin]
+		if !ok {
+			slot = make(chan struct{}, 1)
+			h.slots[origin] = slot
+		}
+		h.mu.Unlock()
+		slot <- struct{}{}
+		release = func() { <-slot }
+	}
+	if h.jitter > 0 {
+		time.Sleep(rand.N(h.jitter))
+	}
+	return release
+}
+
+// releaseBody ends the turn of a request once its body is read to the end or
+// closed, so a host is not blocked while the response is being processed.
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.once.Do(b.release)
+	}
+	return n, err
+}
+
+func (b *releaseBody) Close() error {
+	b.once.Do(b.release)
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,91 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/utils"
+)
+
+// Classes of requests with their own timeouts.
+const (
+	ClassProbe    = "probe"    // Служебные файлы .git: HEAD, config, index, refs
+	ClassObject   = "object"   // Loose-объекты
+	ClassPack     = "pack"     // Пакеты и их индексы
+	ClassWorktree = "worktree" // Файлы рабочей копии вне .git
+)
+
+// RequestClass tells which timeout applies to a URL.
+func RequestClass(targetUrl string) string {
+	u, err := url.Parse(targetUrl)
+	if err != nil {
+		return ClassProbe
+	}
+	p := path.Clean(u.Path)
+	switch {
+	case strings.Contains(p, "/objects/pack/"):
+		return ClassPack
+	case strings.Contains(p, "/objects/"):
+		if _, ok := utils.PathToSha1(p); ok {
+			return ClassObject
+		}
+		return ClassProbe
+	case strings.Contains(p, ".git/"):
+		return ClassProbe
+	}
+	return ClassWorktree
+}
+
+// timeouts returns the total timeout of a request, retries included, and
+// the timeout of a single attempt. A class timeout replaces both
+// -request-timeout and -connect-timeout.
+func timeouts(config config.Config, class string) (time.Duration, time.Duration) {
+	var timeout time.Duration
+	switch class {
+	case ClassProbe:
+		timeout = config.ProbeTimeout
+	case ClassObject:
+		timeout = config.ObjectTimeout
+	case ClassPack:
+		timeout = config.PackTimeout
+	case ClassWorktree:
+		timeout = config.WorktreeTimeout
+	}
+	if timeout > 0 {
+		return timeout, timeout
+	}
+	return config.RequestTimeout, config.ConnTimeout
+}
+
+type attemptTimeoutKey struct{}
+
+// withAttemptTimeout sets the timeout of every attempt of a request.
+func withAttemptTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, attemptTimeoutKey{}, timeout)
+}
+
+// attemptTimeoutTransport limits each attempt of a request, body included,
+// like http.Client.Timeout but with the timeout taken from the request.
+type attemptTimeoutTransport struct {
+	next http.RoundTripper
+}
+
+func (t *attemptTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timeout, _ := req.Context().Value(attemptTimeoutKey{}).(time.Duration)
+	if timeout <= 0 {
+		return t.next.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
@@ -0,0 +1,66 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+)
+
+func TestRequestClass(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"http://example.com/.git/HEAD", ClassProbe},
+		{"http://example.com/.git/index", ClassProbe},
+		{"http://example.com/.git/objects/info/packs", ClassProbe},
+		{"http://example.com/.git/objects/ab/cdef0123456789abcdef0123456789abcdef01", ClassObject},
+		{"http://example.com/.git/objects/pack/pack-0123.pack", ClassPack},
+		{"http://example.com/.git/objects/pack/pack-0123.idx", ClassPack},
+		{"http://example.com/.git/../src/main.go", ClassWorktree},
+		{"http://example.com/src/main.go", ClassWorktree},
+	}
+	for _, tt := range tests {
+		if got := RequestClass(tt.url); got != tt.want {
+			t.Errorf("RequestClass(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestClassTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("PACK"))
+		w.(http.Flusher).Flush()
+		// Тело приходит дольше -connect-timeout
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte("data"))
+	}))
+	defer srv.Close()
+
+	fetch := func(c config.Config) error {
+		client, err := newHttpClient(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, cancel, err := client.Fetch(srv.URL + "/.git/objects/pack/pack-0123.pack")
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		defer resp.Body.Close()
+		_, err = io.ReadAll(resp.Body)
+		return err
+	}
+	c := config.Config{ConnTimeout: 100 * time.Millisecond, RequestTimeout: time.Second, MaxRPS: 100, MaxHostErrors: 10}
+	if err := fetch(c); err == nil {
+		t.Error("reading the body took longer than -connect-timeout, want error")
+	}
+	c.PackTimeout = time.Second
+	if err := fetch(c); err != nil {
+		t.Errorf("with -pack-timeout: %v", err)
+	}
+}
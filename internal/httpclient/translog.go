@@ -0,0 +1,80 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transaction is the metadata of a single fetch written to the -http-log file.
+type Transaction struct {
+	ScanID     string            `json:"scan_id,omitempty"`
+	Time       time.Time         `json:"time"`
+	Method     string            `json:"method"`
+	Url        string            `json:"url"`
+	FinalUrl   string            `json:"final_url,omitempty"`
+	Redirects  []string          `json:"redirects,omitempty"`
+	Status     int               `json:"status,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	DurationMs int64             `json:"duration_ms"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// TransactionLog appends transactions to a JSONL file.
+type TransactionLog struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+func OpenTransactionLog(fileName string) (*TransactionLog, error) {
+	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transaction log %s: %w", fileName, err)
+	}
+	return &TransactionLog{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (l *TransactionLog) Write(t *Transaction) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enc.Encode(t)
+}
+
+func (l *TransactionLog) Close() error {
+	return l.f.Close()
+}
+
+// newTransaction fills a transaction from the final response. resp may be nil.
+func newTransaction(scanID, method, targetUrl string, start time.Time, resp *http.Response, err error) *Transaction {
+	t := &Transaction{
+		ScanID:     scanID,
+		Time:       start.UTC(),
+		Method:     method,
+		Url:        targetUrl,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		t.Error = err.Error()
+	}
+	if resp == nil {
+		return t
+	}
+	t.Status = resp.StatusCode
+	t.Headers = make(map[string]string, len(resp.Header))
+	for key, values := range resp.Header {
+		t.Headers[key] = strings.Join(values, ", ")
+	}
+	// Цепочка редиректов хранится в req.Response предыдущих запросов
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		t.Redirects = append([]string{req.Response.Request.URL.String()}, t.Redirects...)
+	}
+	if resp.Request != nil && len(t.Redirects) > 0 {
+		t.FinalUrl = resp.Request.URL.String()
+	}
+	return t
+}
@@ -0,0 +1,124 @@
+package ignore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Matcher matches slash separated paths against gitignore-style patterns.
+type Matcher struct {
+	rules []rule
+}
+
+type rule struct {
+	re     *regexp.Regexp
+	negate bool
+}
+
+// Compile parses gitignore-style patterns. Blank lines and # comments are
+// skipped, ! negates a pattern, a trailing / matches only directories and a
+// / at the start or in the middle anchors the pattern to the root.
+func Compile(patterns []string) (*Matcher, error) {
+	m := &Matcher{}
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" || strings.HasPrefix(p, "#") {
+			continue
+		}
+		r := rule{}
+		if strings.HasPrefix(p, "!") {
+			r.negate = true
+			p = p[1:]
+		}
+		dirOnly := strings.HasSuffix(p, "/")
+		p = strings.TrimSuffix(p, "/")
+		anchored := strings.Contains(p, "/")
+		p = strings.TrimPrefix(p, "/")
+		if p == "" {
+			continue
+		}
+
+		expr := "^"
+		if !anchored {
+			expr += "(?:.*/)?"
+		}
+		expr += globToRegexp(p)
+		if dirOnly {
+			// Путь файла совпадает, если он лежит внутри каталога
+			expr += "/.*$"
+		} else {
+			expr += "(?:/.*)?$"
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		r.re = re
+		m.rules = append(m.rules, r)
+	}
+	return m, nil
+}
+
+// Match reports whether path is excluded. The last matching pattern wins.
+func (m *Matcher) Match(path string) bool {
+	if m == nil {
+		return false
+	}
+	ignored := false
+	for _, r := range m.rules {
+		if r.re.MatchString(path) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// Empty reports whether the matcher has no patterns.
+func (m *Matcher) Empty() bool {
+	return m == nil || len(m.rules) == 0
+}
+
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			// Повторы **/ и * эквивалентны одному, но раздувают регулярное выражение
+			for strings.HasPrefix(glob[i+3:], "**/") {
+				i += 3
+			}
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			for i+2 < len(glob) && glob[i+2] == '*' {
+				i++
+			}
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end == -1 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
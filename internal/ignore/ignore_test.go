@@ -0,0 +1,86 @@
+package ignore
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		path     string
+		want     bool
+	}{
+		{[]string{"*.log"}, "a.log", true},
+		{[]string{"*.log"}, "dir/a.log", true},
+		{[]string{"*.log"}, "a.txt", false},
+		{[]string{"/build"}, "build/x.o", true},
+		{[]string{"/build"}, "src/build/x.o", false},
+		{[]string{"docs/*.md"}, "docs/a.md", true},
+		{[]string{"docs/*.md"}, "docs/sub/a.md", false},
+		{[]string{"node_modules/"}, "node_modules/a/b.js", true},
+		{[]string{"node_modules/"}, "node_modules", false},
+		{[]string{"**/vendor"}, "a/b/vendor/c.go", true},
+		{[]string{"a/**/b"}, "a/b", true},
+		{[]string{"a/**/b"}, "a/x/y/b", true},
+		{[]string{"a/**"}, "a/x/y", true},
+		{[]string{"a/**/**/b"}, "a/b", true},
+		{[]string{"a/****"}, "a/x/y", true},
+		{[]string{"file?.txt"}, "file1.txt", true},
+		{[]string{"file?.txt"}, "file/.txt", false},
+		{[]string{"[abc].txt"}, "b.txt", true},
+		{[]string{"[!abc].txt"}, "b.txt", false},
+		{[]string{"[!abc].txt"}, "d.txt", true},
+		{[]string{"[abc"}, "[abc", true},
+		{[]string{`\!important`}, "!important", true},
+		{[]string{`\#file`}, "#file", true},
+		{[]string{`trailing\`}, `trailing\`, true},
+		{[]string{"a.b"}, "axb", false},
+		{[]string{"*.log", "!keep.log"}, "keep.log", false},
+		{[]string{"*.log", "!keep.log"}, "other.log", true},
+		{[]string{"!keep.log", "*.log"}, "keep.log", true},
+		{[]string{"", "  ", "# comment", "/", "!"}, "anything", false},
+	}
+	for _, tt := range tests {
+		m, err := Compile(tt.patterns)
+		if err != nil {
+			t.Fatalf("Compile(%q): %v", tt.patterns, err)
+		}
+		if got := m.Match(tt.path); got != tt.want {
+			t.Errorf("Compile(%q).Match(%q) = %v, want %v", tt.patterns, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	for _, p := range []string{"[z-a]", "[]", `[a\]`} {
+		if _, err := Compile([]string{p}); err == nil {
+			t.Errorf("Compile(%q) succeeded", p)
+		}
+	}
+}
+
+func TestCompileLong(t *testing.T) {
+	// Длинные шаблоны и пути не должны приводить к панике или зависанию
+	patterns := []string{
+		strings.Repeat("*", 10000),
+		strings.Repeat("**/", 5000) + "x",
+		strings.Repeat("[", 10000),
+		strings.Repeat("a?", 5000),
+	}
+	path := strings.Repeat("a/", 50000) + "x"
+	for _, p := range patterns {
+		m, err := Compile([]string{p})
+		if err != nil {
+			continue
+		}
+		m.Match(path)
+	}
+}
+
+func TestNilMatcher(t *testing.T) {
+	var m *Matcher
+	if m.Match("a") || !m.Empty() {
+		t.Errorf("nil matcher should be empty and match nothing")
+	}
+}
@@ -4,7 +4,11 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-var logger = logrus.New()
+var (
+	logger = logrus.New()
+	// Все записи идут через entry, чтобы к ним добавлялись общие поля
+	entry = logrus.NewEntry(logger)
+)
 
 func SetupLogger(logLevel string) {
 	logger.SetFormatter(&logrus.TextFormatter{
@@ -19,25 +23,30 @@ func SetupLogger(logLevel string) {
 }
 
 func Debugf(format string, args ...interface{}) {
-	logger.Debugf(format, args...)
+	entry.Debugf(format, args...)
 }
 
 func Infof(format string, args ...interface{}) {
-	logger.Infof(format, args...)
+	entry.Infof(format, args...)
 }
 
 func Warnf(format string, args ...interface{}) {
-	logger.Warnf(format, args...)
+	entry.Warnf(format, args...)
 }
 
 func Errorf(format string, args ...interface{}) {
-	logger.Errorf(format, args...)
+	entry.Errorf(format, args...)
 }
 
 func Fatalf(format string, args ...interface{}) {
-	logger.Fatalf(format, args...)
+	entry.Fatalf(format, args...)
 }
 
 func Info(args ...interface{}) {
-	logger.Info(args...)
+	entry.Info(args...)
+}
+
+// SetScanID adds the scan ID to every following record.
+func SetScanID(id string) {
+	entry = logger.WithField("scan", id)
 }
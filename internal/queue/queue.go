@@ -0,0 +1,35 @@
+package queue
+
+import (
+	"sync"
+	"time"
+)
+
+// Queue is a shared queue of targets used by distributed workers.
+type Queue interface {
+	Push(values ...string) error
+	// Pop blocks up to timeout and returns ok=false if nothing was queued.
+	Pop(timeout time.Duration) (value string, ok bool, err error)
+}
+
+// SeenSet tracks URLs which were already processed.
+type SeenSet interface {
+	// Add marks key as seen and reports whether it was seen before.
+	Add(key string) (bool, error)
+	Has(key string) (bool, error)
+}
+
+// LocalSeenSet is an in-process SeenSet.
+type LocalSeenSet struct {
+	m sync.Map
+}
+
+func (s *LocalSeenSet) Add(key string) (bool, error) {
+	_, ok := s.m.LoadOrStore(key, true)
+	return ok, nil
+}
+
+func (s *LocalSeenSet) Has(key string) (bool, error) {
+	_, ok := s.m.Load(key)
+	return ok, nil
+}
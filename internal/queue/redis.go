@@ -0,0 +1,285 @@
+package queue
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timeout is the read/write deadline of a single redis command. Blocking
+// commands get their wait time on top of it.
+const Timeout = 30 * time.Second
+
+// maxIdleConns is the number of connections kept open for reuse.
+const maxIdleConns = 16
+
+// RedisClient is a minimal RESP client supporting the commands used by the
+// distributed mode. Concurrent commands use separate pooled connections.
+type RedisClient struct {
+	addr     string
+	password string
+	db       int
+	mu       sync.Mutex
+	idle     []*redisConn
+	closed   bool
+}
+
+type redisConn struct {
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// NewRedisClient parses a redis://[:password@]host[:port][/db] URL.
+func NewRedisClient(rawUrl string) (*RedisClient, error) {
+	if !strings.Contains(rawUrl, "://") {
+		rawUrl = "redis://" + rawUrl
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	c := &RedisClient{addr: u.Host}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis database %q", db)
+		}
+	}
+	return c, nil
+}
+
+// Do sends a command and returns its reply.
+func (c *RedisClient) Do(args ...string) (interface{}, error) {
+	return c.DoBlocking(0, args...)
+}
+
+// DoBlocking sends a command which may block on the server for up to wait.
+func (c *RedisClient) DoBlocking(wait time.Duration, args ...string) (interface{}, error) {
+	rc, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+
+	rc.conn.SetDeadline(time.Now().Add(Timeout + wait))
+	reply, err := rc.do(args...)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// Соединение в неизвестном состоянии, закрываем его
+		rc.conn.Close()
+		return reply, err
+	}
+	c.put(rc)
+	return reply, err
+}
+
+// get returns an idle connection or opens a new one.
+func (c *RedisClient) get() (*redisConn, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("redis client is closed")
+	}
+	if n := len(c.idle); n > 0 {
+		rc := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return rc, nil
+	}
+	c.mu.Unlock()
+	return c.connect()
+}
+
+func (c *RedisClient) put(rc *redisConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || len(c.idle) >= maxIdleConns {
+		rc.conn.Close()
+		return
+	}
+	c.idle = append(c.idle, rc)
+}
+
+func (c *RedisClient) connect() (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", c.addr, 10*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis %s: %w", c.addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(Timeout))
+	rc := &redisConn{conn: conn, rd: bufio.NewReader(conn)}
+
+	if c.password != "" {
+		if _, err := rc.do("AUTH", c.password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis auth failed: %w", err)
+		}
+	}
+	if c.db != 0 {
+		if _, err := rc.do("SELECT", strconv.Itoa(c.db)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis select failed: %w", err)
+		}
+	}
+	return rc, nil
+}
+
+func (c *redisConn) do(args ...string) (interface{}, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, sb.String()); err != nil {
+		return nil, fmt.Errorf("failed to write redis command: %w", err)
+	}
+	return c.readReply()
+}
+
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.rd.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redis reply: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid bulk length: %w", err)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.rd, buf); err != nil {
+			return nil, fmt.Errorf("failed to read bulk string: %w", err)
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid array length: %w", err)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+
+	return nil, fmt.Errorf("unexpected redis reply: %q", line)
+}
+
+func (c *RedisClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	for _, rc := range c.idle {
+		if closeErr := rc.conn.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	c.idle = nil
+	c.closed = true
+	return err
+}
+
+// RedisQueue is a Queue stored in a redis list.
+type RedisQueue struct {
+	client *RedisClient
+	key    string
+}
+
+func NewRedisQueue(client *RedisClient, key string) *RedisQueue {
+	return &RedisQueue{client: client, key: key}
+}
+
+func (q *RedisQueue) Push(values ...string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	_, err := q.client.Do(append([]string{"RPUSH", q.key}, values...)...)
+	return err
+}
+
+func (q *RedisQueue) Pop(timeout time.Duration) (string, bool, error) {
+	// Таймаут 0 означает бесконечное ожидание
+	seconds := max(int(timeout.Seconds()), 1)
+	reply, err := q.client.DoBlocking(time.Duration(seconds)*time.Second, "BLPOP", q.key, strconv.Itoa(seconds))
+	if err != nil {
+		return "", false, err
+	}
+	items, ok := reply.([]interface{})
+	if !ok || len(items) != 2 {
+		return "", false, nil
+	}
+	value, _ := items[1].(string)
+	return value, true, nil
+}
+
+// RedisSeenSet is a SeenSet shared between workers via a redis set. The set
+// expires ttl after the last added URL, so stale runs don't block new ones.
+type RedisSeenSet struct {
+	client *RedisClient
+	key    string
+	ttl    time.Duration
+}
+
+func NewRedisSeenSet(client *RedisClient, key string, ttl time.Duration) *RedisSeenSet {
+	return &RedisSeenSet{client: client, key: key, ttl: ttl}
+}
+
+func (s *RedisSeenSet) Add(key string) (bool, error) {
+	reply, err := s.client.Do("SADD", s.key, key)
+	if err != nil {
+		return false, err
+	}
+	added, _ := reply.(int64)
+	if added == 1 && s.ttl > 0 {
+		if _, err := s.client.Do("EXPIRE", s.key, strconv.Itoa(max(int(s.ttl.Seconds()), 1))); err != nil {
+			return false, err
+		}
+	}
+	return added == 0, nil
+}
+
+func (s *RedisSeenSet) Has(key string) (bool, error) {
+	reply, err := s.client.Do("SISMEMBER", s.key, key)
+	if err != nil {
+		return false, err
+	}
+	member, _ := reply.(int64)
+	return member == 1, nil
+}
@@ -0,0 +1,76 @@
+package report
+
+import (
+	"sort"
+	"strings"
+)
+
+// Kinds of clusters, in the order they are listed.
+const (
+	ClusterHead      = "head"      // Один и тот же коммит HEAD: общая кодовая база
+	ClusterServer    = "server"    // Одинаковые заголовки Server и X-Powered-By
+	ClusterFramework = "framework" // Один и тот же фреймворк
+)
+
+var clusterKinds = []string{ClusterHead, ClusterServer, ClusterFramework}
+
+// Cluster is a group of targets sharing a fingerprint.
+type Cluster struct {
+	Kind    string   `json:"kind"`
+	Value   string   `json:"value"`
+	Targets []string `json:"targets"`
+}
+
+// Banner returns the Server and X-Powered-By headers of the target.
+func (t *Target) Banner() string {
+	var parts []string
+	for _, h := range []string{t.Server, t.PoweredBy} {
+		if h != "" {
+			parts = append(parts, h)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fingerprint returns the value of the target for a kind of clusters.
+func (t *Target) fingerprint(kind string) string {
+	switch kind {
+	case ClusterHead:
+		return t.Head
+	case ClusterServer:
+		return t.Banner()
+	case ClusterFramework:
+		return t.Framework
+	}
+	return ""
+}
+
+// Clusters groups targets by HEAD commit, server banner and framework. Only
+// groups of two or more targets are returned, the largest first within each
+// kind. Aliases are left out, since they are the same site.
+func Clusters(targets []*Target) []Cluster {
+	var clusters []Cluster
+	for _, kind := range clusterKinds {
+		groups := make(map[string][]string)
+		for _, t := range targets {
+			if value := t.fingerprint(kind); value != "" && t.AliasOf == "" {
+				groups[value] = append(groups[value], t.Url)
+			}
+		}
+		var found []Cluster
+		for value, urls := range groups {
+			if len(urls) > 1 {
+				sort.Strings(urls)
+				found = append(found, Cluster{Kind: kind, Value: value, Targets: urls})
+			}
+		}
+		sort.Slice(found, func(i, j int) bool {
+			if len(found[i].Targets) != len(found[j].Targets) {
+				return len(found[i].Targets) > len(found[j].Targets)
+			}
+			return found[i].Value < found[j].Value
+		})
+		clusters = append(clusters, found...)
+	}
+	return clusters
+}
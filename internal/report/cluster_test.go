@@ -0,0 +1,25 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClusters(t *testing.T) {
+	targets := []*Target{
+		{Url: "https://a.example/.git/", Head: "1111", Server: "nginx", Framework: "Laravel"},
+		{Url: "https://b.example/.git/", Head: "1111", Server: "nginx", PoweredBy: "PHP/8.2", Framework: "Laravel"},
+		{Url: "https://c.example/.git/", Head: "2222", Server: "nginx", PoweredBy: "PHP/8.2", Framework: "WordPress"},
+		{Url: "https://www.c.example/.git/", Head: "2222", Server: "nginx", AliasOf: "https://c.example/.git/"},
+		{Url: "https://d.example/.git/", Server: "nginx", Framework: "Laravel"},
+	}
+	want := []Cluster{
+		{ClusterHead, "1111", []string{"https://a.example/.git/", "https://b.example/.git/"}},
+		{ClusterServer, "nginx", []string{"https://a.example/.git/", "https://d.example/.git/"}},
+		{ClusterServer, "nginx, PHP/8.2", []string{"https://b.example/.git/", "https://c.example/.git/"}},
+		{ClusterFramework, "Laravel", []string{"https://a.example/.git/", "https://b.example/.git/", "https://d.example/.git/"}},
+	}
+	if got := Clusters(targets); !reflect.DeepEqual(got, want) {
+		t.Errorf("Clusters() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,82 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CSVHeader names the columns of the CSV summary.
+var CSVHeader = []string{"url", "address", "severity", "exposed", "restored", "files", "commits", "secrets", "notable_files", "findings", "honeypot", "aliases", "notes"}
+
+// CSVRecord returns the target as a row of the CSV summary. Secrets are
+// credential findings; notable files are counted separately.
+func (t *Target) CSVRecord() []string {
+	secrets := 0
+	for _, f := range t.Findings {
+		if f.Kind == "credential" {
+			secrets++
+		}
+	}
+	notes := append([]string(nil), t.Notes...)
+	if t.NotApplicable != "" {
+		notes = append(notes, t.NotApplicable)
+	}
+	notes = append(notes, t.Honeypot...)
+	row := []string{
+		t.Url,
+		t.Address,
+		t.Severity,
+		strconv.FormatBool(t.IsExposed()),
+		strconv.FormatBool(t.Restored),
+		strconv.Itoa(t.Files),
+		strconv.Itoa(t.Commits),
+		strconv.Itoa(secrets),
+		strconv.Itoa(len(t.NotableFiles)),
+		strconv.Itoa(len(t.Findings)),
+		strconv.FormatBool(len(t.Honeypot) > 0),
+		strings.Join(t.Aliases, " "),
+		strings.Join(notes, "; "),
+	}
+	for i, cell := range row {
+		row[i] = spreadsheetSafe(cell)
+	}
+	return row
+}
+
+// spreadsheetSafe keeps a cell from being evaluated as a formula, since URLs
+// and notes come from the scanned servers.
+func spreadsheetSafe(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// WriteCSVFile saves the report as a CSV summary with one row per target.
+func (r *Report) WriteCSVFile(fileName string) error {
+	if dir := filepath.Dir(fileName); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory for CSV summary %s: %w", fileName, err)
+		}
+	}
+
+	f, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV summary %s: %w", fileName, err)
+	}
+	cw := csv.NewWriter(f)
+	cw.Write(CSVHeader)
+	for _, t := range r.Targets {
+		cw.Write(t.CSVRecord())
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write CSV summary %s: %w", fileName, err)
+	}
+	return f.Close()
+}
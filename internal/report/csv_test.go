@@ -0,0 +1,29 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/s3rgeym/git-dump/internal/extractor"
+)
+
+func TestCSVRecord(t *testing.T) {
+	target := &Target{
+		Url:          "https://example.com/.git/",
+		Files:        12,
+		Commits:      3,
+		Restored:     true,
+		Severity:     SeverityCritical,
+		Findings:     []extractor.Finding{{Kind: "credential"}, {Kind: "remote"}, {Kind: "credential"}},
+		NotableFiles: []string{".env"},
+		Aliases:      []string{"https://www.example.com/.git/"},
+		Notes:        []string{"=HYPERLINK(\"http://evil\")", "truncated"},
+	}
+	want := []string{"https://example.com/.git/", "", "critical", "true", "true", "12", "3", "2", "1", "3", "false", "https://www.example.com/.git/", "'=HYPERLINK(\"http://evil\"); truncated"}
+	if got := target.CSVRecord(); !reflect.DeepEqual(got, want) {
+		t.Errorf("CSVRecord() = %q, want %q", got, want)
+	}
+	if len(want) != len(CSVHeader) {
+		t.Errorf("%d columns, header has %d", len(want), len(CSVHeader))
+	}
+}
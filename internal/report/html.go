@@ -0,0 +1,181 @@
+package report
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TreeEntry is a file of a restored working tree.
+type TreeEntry struct {
+	Path  string
+	Size  int64
+	Class string // Класс файла из пакета classify
+}
+
+// TargetExtras is what the HTML report shows about a target besides its
+// entry in the report.
+type TargetExtras struct {
+	Tree    []TreeEntry
+	Omitted int    // Файлы, не вошедшие в Tree
+	Listing string // HTML листинга каталога .git/, если он сохранён
+}
+
+// treeLine is a line of a file tree: a directory or a file under the
+// directories of the previous lines.
+type treeLine struct {
+	Depth int
+	Name  string
+	Dir   bool
+	Size  int64
+	Class string
+}
+
+// treeLines turns file paths into the lines of a tree, printing every
+// directory once before its files.
+func treeLines(entries []TreeEntry) []treeLine {
+	entries = append([]TreeEntry(nil), entries...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	var lines []treeLine
+	var open []string // Открытые каталоги предыдущей строки
+	for _, e := range entries {
+		dirs := strings.Split(path.Dir(e.Path), "/")
+		if dirs[0] == "." {
+			dirs = nil
+		}
+		common := 0
+		for common < len(open) && common < len(dirs) && open[common] == dirs[common] {
+			common++
+		}
+		for i := common; i < len(dirs); i++ {
+			lines = append(lines, treeLine{Depth: i, Name: dirs[i] + "/", Dir: true})
+		}
+		open = dirs
+		lines = append(lines, treeLine{Depth: len(dirs), Name: path.Base(e.Path), Size: e.Size, Class: e.Class})
+	}
+	return lines
+}
+
+type htmlTarget struct {
+	*Target
+	Anchor  string
+	Tree    []treeLine
+	Omitted int
+	Listing string
+}
+
+var runTemplate = htmltemplate.Must(htmltemplate.Must(htmltemplate.New("run").Funcs(summaryFuncs).Funcs(htmltemplate.FuncMap{
+	"indent": func(depth int) htmltemplate.CSS {
+		return htmltemplate.CSS(fmt.Sprintf("padding-left:%.1fem", float64(depth)*1.5))
+	},
+}).Parse(severityStyle)).Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>git-dump report{{with .ScanID}} {{.}}{{end}}</title>
+<style>
+body{font-family:sans-serif;margin:2em;color:#222;max-width:80em}
+table{border-collapse:collapse;margin-bottom:1.5em}td,th{padding:.2em .8em;border-bottom:1px solid #ddd;text-align:left;vertical-align:top}
+td.num{text-align:right}pre{background:#f6f6f6;padding:.8em;overflow:auto;white-space:pre-wrap}
+section{border-top:2px solid #444;margin-top:2em}
+.exposed{color:#b00}.muted{color:#888}.secret{color:#b00;font-weight:bold}
+.tree{font-family:monospace;font-size:90%}.tree .dir{color:#555}
+iframe{width:100%;height:30em;border:1px solid #ccc}
+{{template "severityStyle"}}
+</style></head><body>
+<h1>git-dump report</h1>
+<table>
+{{with .ScanID}}<tr><th>Scan ID</th><td>{{.}}</td></tr>{{end}}
+{{with .Operator}}<tr><th>Operator</th><td>{{.}}</td></tr>{{end}}
+<tr><th>Started</th><td>{{.StartedAt.Format "2006-01-02 15:04:05 MST"}}</td></tr>
+<tr><th>Finished</th><td>{{.FinishedAt.Format "2006-01-02 15:04:05 MST"}}</td></tr>
+</table>
+<h2>Targets</h2>
+<table><tr><th>Target</th><th>Severity</th><th>Exposed</th><th>Restored</th><th>Files</th><th>Findings</th><th>Notes</th></tr>
+{{range .Targets}}<tr><td><a href="#{{.Anchor}}">{{.Url}}</a></td><td class="sev-{{.Severity}}">{{.Severity}}</td><td class="{{if .IsExposed}}exposed{{end}}">{{if .IsExposed}}yes{{else}}no{{end}}</td><td>{{if .Restored}}yes{{else}}no{{end}}</td><td class="num">{{.Files}}</td><td class="num">{{len .Findings}}</td><td>{{join .Notes "; "}}{{with .NotApplicable}} {{.}}{{end}}</td></tr>
+{{else}}<tr><td colspan="7" class="muted">No targets</td></tr>{{end}}
+</table>
+{{if .Clusters}}<h2>Clusters</h2>
+<p class="muted">Targets sharing a HEAD commit, server banner or framework.</p>
+<table><tr><th>Kind</th><th>Value</th><th>Targets</th></tr>
+{{range .Clusters}}<tr><td>{{.Kind}}</td><td>{{if eq .Kind "head"}}<code>{{.Value}}</code>{{else}}{{.Value}}{{end}}</td><td>{{join .Targets ", "}}</td></tr>
+{{end}}</table>{{end}}
+{{range .Targets}}<section id="{{.Anchor}}">
+<h2>{{.Url}}</h2>
+<table>
+{{with .Severity}}<tr><th>Severity</th><td class="sev-{{.}}">{{.}}</td></tr>{{end}}
+{{with .Address}}<tr><th>Address</th><td>{{.}}</td></tr>{{end}}
+<tr><th>Repository</th><td>{{.RepoPath}}</td></tr>
+{{with .Banner}}<tr><th>Server</th><td>{{.}}</td></tr>{{end}}
+{{with .Framework}}<tr><th>Framework</th><td>{{.}}</td></tr>{{end}}
+{{with .Head}}<tr><th>HEAD</th><td><code>{{.}}</code></td></tr>{{end}}
+<tr><th>Requests</th><td>{{.Requests}}, {{.Errors}} errors{{if .Skipped}}, {{.Skipped}} skipped{{end}}</td></tr>
+<tr><th>Files</th><td>{{.Files}}{{if .Bytes}}, {{.Bytes}} bytes{{end}}</td></tr>
+<tr><th>Restored</th><td>{{if .Restored}}yes{{else}}no{{end}}{{if .Truncated}}, truncated{{end}}{{with .ExposureSeverity}} <span class="sev-{{.}}">{{.}}</span>{{end}}</td></tr>
+{{if .Commits}}<tr><th>Commits</th><td>{{.Commits}}</td></tr>{{end}}
+{{with .AliasOf}}<tr><th>Alias of</th><td>{{.}}</td></tr>{{end}}
+{{range .Aliases}}<tr><th>Alias</th><td>{{.}}</td></tr>{{end}}
+{{range .Notes}}<tr><th>Note</th><td>{{.}}</td></tr>{{end}}
+{{range .Honeypot}}<tr><th>Honeypot</th><td class="exposed">{{.}}</td></tr>{{end}}
+</table>
+{{if .Findings}}<h3>Findings</h3>
+<table><tr><th>Severity</th><th>Kind</th><th>Detail</th><th>URL</th><th>Extractor</th></tr>
+{{range .Findings}}<tr><td class="sev-{{.Severity}}">{{.Severity}}</td><td>{{.Kind}}</td><td>{{.Detail}}</td><td>{{.Url}}</td><td>{{.Extractor}}</td></tr>{{end}}
+</table>{{end}}
+{{if .NotableFiles}}<h3>Notable files</h3>
+<ul>{{$sev := .FileSeverities}}{{range .NotableFiles}}<li class="secret">{{.}}{{with index $sev .}} <span class="sev-{{.}}">{{.}}</span>{{end}}</li>{{end}}</ul>{{end}}
+{{if .Refs}}<h3>Refs</h3>
+<table>{{range .Refs}}<tr><td>{{.Name}}</td><td><code>{{.Hash}}</code></td></tr>{{end}}</table>{{end}}
+{{if .Tags}}<h3>Tags</h3>
+<table>{{range .Tags}}<tr><td>{{.Name}}</td><td><code>{{.Target}}</code></td><td>{{.Tagger}}</td><td>{{.Message}}</td></tr>{{end}}</table>{{end}}
+{{if .Evidence}}<h3>Evidence</h3>
+{{range .Evidence}}<p><b>{{.Method}} {{.Url}}</b> <span class="muted">{{.Time.Format "2006-01-02 15:04:05 MST"}}</span></p>
+<pre>{{if .Error}}{{.Error}}{{else}}HTTP {{.Status}}{{range $k, $v := .ResponseHeaders}}
+{{$k}}: {{$v}}{{end}}
+{{if .BodySha256}}
+SHA-256 {{.BodySha256}}, {{.BodySize}} bytes{{end}}{{with .Body}}
+
+{{.}}{{end}}{{end}}</pre>
+{{end}}{{end}}
+{{if .Listing}}<h3>Directory listing</h3>
+<iframe sandbox srcdoc="{{.Listing}}"></iframe>{{end}}
+{{if .Tree}}<h3>Working tree</h3>
+<div class="tree">{{range .Tree}}<div style="{{indent .Depth}}"{{if .Dir}} class="dir"{{end}}>{{if eq .Class "secret"}}<span class="secret">{{.Name}}</span>{{else}}{{.Name}}{{end}}{{if not .Dir}} <span class="muted">{{.Size}}</span>{{end}}</div>
+{{end}}{{if .Omitted}}<div class="muted">and {{.Omitted}} more files</div>{{end}}</div>{{end}}
+</section>
+{{end}}
+</body></html>
+`))
+
+// WriteHTMLFile saves the report as a self-contained HTML page with a
+// section per target. extras are keyed by target URL and may be nil.
+func (r *Report) WriteHTMLFile(fileName string, extras map[string]*TargetExtras) error {
+	if dir := filepath.Dir(fileName); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory for report %s: %w", fileName, err)
+		}
+	}
+
+	page := struct {
+		*Report
+		Targets []htmlTarget
+	}{Report: r}
+	for i, t := range r.Targets {
+		ht := htmlTarget{Target: t, Anchor: fmt.Sprintf("target-%d", i+1)}
+		if e := extras[t.Url]; e != nil {
+			ht.Tree, ht.Omitted, ht.Listing = treeLines(e.Tree), e.Omitted, e.Listing
+		}
+		page.Targets = append(page.Targets, ht)
+	}
+
+	f, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to create report %s: %w", fileName, err)
+	}
+	if err := runTemplate.Execute(f, page); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to render report %s: %w", fileName, err)
+	}
+	return f.Close()
+}
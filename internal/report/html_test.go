@@ -0,0 +1,23 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTreeLines(t *testing.T) {
+	lines := treeLines([]TreeEntry{
+		{Path: "src/b/y.go"},
+		{Path: "README"},
+		{Path: "src/a/x.go"},
+		{Path: "src/main.go"},
+	})
+	var got []string
+	for _, l := range lines {
+		got = append(got, string(rune('0'+l.Depth))+l.Name)
+	}
+	want := []string{"0README", "0src/", "1a/", "2x.go", "1b/", "2y.go", "1main.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("treeLines() = %v, want %v", got, want)
+	}
+}
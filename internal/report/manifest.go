@@ -0,0 +1,88 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// ManifestName is the name of the manifest inside a run directory.
+const ManifestName = "manifest.json"
+
+// Manifest describes a run written into its own directory with -run-dir.
+type Manifest struct {
+	RunID      string           `json:"run_id"`
+	ScanID     string           `json:"scan_id,omitempty"`
+	Operator   string           `json:"operator,omitempty"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+	Versions   Versions         `json:"versions"`
+	Config     any              `json:"config"`
+	Targets    []ManifestTarget `json:"targets"`
+}
+
+// Versions identify the build which made the run.
+type Versions struct {
+	GitDump  string `json:"git_dump"`
+	Revision string `json:"revision,omitempty"`
+	Go       string `json:"go"`
+}
+
+// ManifestTarget is a short summary of a target; details are in the report.
+type ManifestTarget struct {
+	Url      string `json:"url"`
+	RepoPath string `json:"repo_path"`
+	Files    int    `json:"files"`
+	Errors   int    `json:"errors"`
+	Restored bool   `json:"restored"`
+}
+
+// NewManifest builds the manifest of a finished run.
+func NewManifest(runID string, r *Report, config any) *Manifest {
+	m := &Manifest{
+		RunID:      runID,
+		ScanID:     r.ScanID,
+		Operator:   r.Operator,
+		StartedAt:  r.StartedAt,
+		FinishedAt: r.FinishedAt,
+		Versions:   buildVersions(),
+		Config:     config,
+		Targets:    make([]ManifestTarget, 0, len(r.Targets)),
+	}
+	for _, t := range r.Targets {
+		m.Targets = append(m.Targets, ManifestTarget{Url: t.Url, RepoPath: t.RepoPath, Files: t.Files, Errors: t.Errors, Restored: t.Restored})
+	}
+	return m
+}
+
+func buildVersions() Versions {
+	v := Versions{GitDump: "(devel)", Go: runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v
+	}
+	if info.Main.Version != "" {
+		v.GitDump = info.Main.Version
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			v.Revision = s.Value
+		}
+	}
+	return v
+}
+
+// WriteFile saves the manifest as indented JSON.
+func (m *Manifest) WriteFile(fileName string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest %s: %w", fileName, err)
+	}
+	return nil
+}
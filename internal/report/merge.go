@@ -0,0 +1,176 @@
+package report
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/extractor"
+)
+
+// maxTopFindings limits the findings listed by a merged report.
+const maxTopFindings = 50
+
+// Target statuses in a run of a merged report.
+const (
+	StatusExposed    = "exposed"
+	StatusNotExposed = "not exposed"
+	StatusAbsent     = "" // Цель не сканировалась в этом запуске
+)
+
+// IsExposed reports whether the .git directory of the target was accessible.
+func (t *Target) IsExposed() bool {
+	return t.Exposed || t.Restored || t.Files > 0
+}
+
+// Summary aggregates the reports of several runs, ordered by start time.
+type Summary struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Runs        []RunSummary     `json:"runs"`
+	Targets     []TargetSummary  `json:"targets"`
+	Kinds       []KindCount      `json:"kinds"`
+	Findings    []FindingSummary `json:"findings"`           // Самые важные находки последних запусков
+	Clusters    []Cluster        `json:"clusters,omitempty"` // По последним запускам целей
+}
+
+// RunSummary holds the totals of a run and how it differs from the previous one.
+type RunSummary struct {
+	Name         string    `json:"name"`
+	ScanID       string    `json:"scan_id,omitempty"`
+	Operator     string    `json:"operator,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+	Targets      int       `json:"targets"`
+	Exposed      int       `json:"exposed"`
+	Restored     int       `json:"restored"`
+	Findings     int       `json:"findings"`
+	NewlyExposed int       `json:"newly_exposed"`
+	Fixed        int       `json:"fixed"` // Были открыты в прошлом запуске, теперь закрыты
+}
+
+// TargetSummary is a target across runs. Latest values come from the last
+// run which scanned the target.
+type TargetSummary struct {
+	Url      string   `json:"url"`
+	Severity string   `json:"severity,omitempty"`
+	Statuses []string `json:"statuses"` // По запускам, StatusAbsent если не сканировалась
+	Exposed  bool     `json:"exposed"`
+	Restored bool     `json:"restored"`
+	Files    int      `json:"files"`
+	Findings int      `json:"findings"`
+	LastRun  string   `json:"last_run"`
+	Notes    []string `json:"notes,omitempty"`
+}
+
+// KindCount counts findings of a kind in the latest run of every target.
+type KindCount struct {
+	Kind     string `json:"kind"`
+	Severity string `json:"severity,omitempty"` // The most urgent of its findings
+	Findings int    `json:"findings"`
+	Targets  int    `json:"targets"`
+}
+
+// FindingSummary is a finding of the latest run of a target.
+type FindingSummary struct {
+	Target string `json:"target"`
+	Run    string `json:"run"`
+	extractor.Finding
+}
+
+// Merge combines reports of several runs; names label the runs, usually the
+// paths of the report files, shown without the .json extension.
+func Merge(names []string, reports []*Report) *Summary {
+	type run struct {
+		name   string
+		report *Report
+	}
+	runs := make([]run, len(reports))
+	for i, r := range reports {
+		runs[i] = run{strings.TrimSuffix(names[i], ".json"), r}
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].report.StartedAt.Before(runs[j].report.StartedAt) })
+
+	s := &Summary{GeneratedAt: time.Now()}
+	targets := make(map[string]*TargetSummary)
+	latest := make(map[string]*Target)
+	var order []string
+	prev := make(map[string]bool) // Открыта ли цель в предыдущем запуске, где она была
+	for i, r := range runs {
+		rs := RunSummary{
+			Name:      r.name,
+			ScanID:    r.report.ScanID,
+			Operator:  r.report.Operator,
+			StartedAt: r.report.StartedAt,
+			Targets:   len(r.report.Targets),
+		}
+		for _, t := range r.report.Targets {
+			ts, ok := targets[t.Url]
+			if !ok {
+				ts = &TargetSummary{Url: t.Url, Statuses: make([]string, len(runs))}
+				targets[t.Url] = ts
+				order = append(order, t.Url)
+			}
+			exposed := t.IsExposed()
+			if exposed {
+				rs.Exposed++
+				ts.Statuses[i] = StatusExposed
+			} else {
+				ts.Statuses[i] = StatusNotExposed
+			}
+			if t.Restored {
+				rs.Restored++
+			}
+			rs.Findings += len(t.Findings)
+			if was, seen := prev[t.Url]; exposed && !was {
+				rs.NewlyExposed++
+			} else if seen && was && !exposed {
+				rs.Fixed++
+			}
+			prev[t.Url] = exposed
+
+			ts.Exposed, ts.Restored, ts.Files, ts.Findings = exposed, t.Restored, t.Files, len(t.Findings)
+			ts.LastRun, ts.Notes, ts.Severity = r.name, t.Notes, t.Severity
+			latest[t.Url] = t
+		}
+		s.Runs = append(s.Runs, rs)
+	}
+
+	sort.Strings(order)
+	kinds := make(map[string]*KindCount)
+	var latestTargets []*Target
+	for _, u := range order {
+		s.Targets = append(s.Targets, *targets[u])
+		latestTargets = append(latestTargets, latest[u])
+		seenKinds := make(map[string]bool)
+		for _, f := range latest[u].Findings {
+			k, ok := kinds[f.Kind]
+			if !ok {
+				k = &KindCount{Kind: f.Kind}
+				kinds[f.Kind] = k
+			}
+			k.Findings++
+			k.Severity = maxSeverity(k.Severity, f.Severity)
+			if !seenKinds[f.Kind] {
+				seenKinds[f.Kind] = true
+				k.Targets++
+			}
+			s.Findings = append(s.Findings, FindingSummary{Target: u, Run: targets[u].LastRun, Finding: f})
+		}
+	}
+	for _, k := range kinds {
+		s.Kinds = append(s.Kinds, *k)
+	}
+	sort.Slice(s.Kinds, func(i, j int) bool {
+		if ri, rj := SeverityRank(s.Kinds[i].Severity), SeverityRank(s.Kinds[j].Severity); ri != rj {
+			return ri > rj
+		}
+		return s.Kinds[i].Kind < s.Kinds[j].Kind
+	})
+	sort.SliceStable(s.Findings, func(i, j int) bool {
+		return SeverityRank(s.Findings[i].Severity) > SeverityRank(s.Findings[j].Severity)
+	})
+	if len(s.Findings) > maxTopFindings {
+		s.Findings = s.Findings[:maxTopFindings]
+	}
+	s.Clusters = Clusters(latestTargets)
+	return s
+}
@@ -0,0 +1,113 @@
+package report
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+)
+
+var summaryFuncs = map[string]any{
+	"cell": func(s string) string {
+		// Вертикальная черта и перевод строки ломают таблицу Markdown
+		return strings.NewReplacer("|", `\|`, "\n", " ", "\r", "").Replace(s)
+	},
+	"join": strings.Join,
+	"mark": func(status string) string {
+		switch status {
+		case StatusExposed:
+			return "●"
+		case StatusNotExposed:
+			return "○"
+		}
+		return "–"
+	},
+}
+
+var markdownTemplate = template.Must(template.New("summary").Funcs(summaryFuncs).Parse(`# git-dump summary
+
+Generated {{.GeneratedAt.Format "2006-01-02 15:04 MST"}} from {{len .Runs}} runs.
+
+## Runs
+
+| Run | Scan ID | Operator | Started | Targets | Exposed | Restored | Findings | Newly exposed | Fixed |
+|---|---|---|---|---:|---:|---:|---:|---:|---:|
+{{range .Runs}}| {{cell .Name}} | {{cell .ScanID}} | {{cell .Operator}} | {{.StartedAt.Format "2006-01-02 15:04"}} | {{.Targets}} | {{.Exposed}} | {{.Restored}} | {{.Findings}} | {{.NewlyExposed}} | {{.Fixed}} |
+{{end}}
+## Targets
+
+● exposed, ○ not exposed, – not scanned; one mark per run in the order above.
+
+| Target | Severity | Trend | Exposed | Restored | Files | Findings | Last run | Notes |
+|---|---|---|---|---|---:|---:|---|---|
+{{range .Targets}}| {{cell .Url}} | {{.Severity}} | {{range .Statuses}}{{mark .}}{{end}} | {{if .Exposed}}yes{{else}}no{{end}} | {{if .Restored}}yes{{else}}no{{end}} | {{.Files}} | {{.Findings}} | {{cell .LastRun}} | {{cell (join .Notes "; ")}} |
+{{end}}{{if .Kinds}}
+## Findings by kind
+
+| Kind | Severity | Findings | Targets |
+|---|---|---:|---:|
+{{range .Kinds}}| {{cell .Kind}} | {{.Severity}} | {{.Findings}} | {{.Targets}} |
+{{end}}
+## Top findings
+
+| Severity | Kind | Target | Detail | URL |
+|---|---|---|---|---|
+{{range .Findings}}| {{.Severity}} | {{cell .Kind}} | {{cell .Target}} | {{cell .Detail}} | {{cell .Url}} |
+{{end}}{{end}}{{if .Clusters}}
+## Clusters
+
+Targets sharing a HEAD commit, server banner or framework in their latest runs.
+
+| Kind | Value | Targets |
+|---|---|---|
+{{range .Clusters}}| {{.Kind}} | {{cell .Value}} | {{cell (join .Targets ", ")}} |
+{{end}}{{end}}`))
+
+// severityStyle colors cells of the class sev-<severity>.
+const severityStyle = `{{define "severityStyle"}}.sev-critical{color:#fff;background:#900;font-weight:bold}.sev-high{color:#b00;font-weight:bold}.sev-medium{color:#c60}.sev-low{color:#555}.sev-info{color:#888}{{end}}`
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.Must(htmltemplate.New("summary").Funcs(summaryFuncs).Parse(severityStyle)).Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>git-dump summary</title>
+<style>
+body{font-family:sans-serif;margin:2em;color:#222}
+table{border-collapse:collapse;margin-bottom:2em}td,th{padding:.2em .8em;border-bottom:1px solid #ddd;text-align:left;vertical-align:top}
+td.num{text-align:right}.trend{font-family:monospace;letter-spacing:.2em}
+.exposed{color:#b00}.muted{color:#888}
+{{template "severityStyle"}}
+</style></head><body>
+<h1>git-dump summary</h1>
+<p class="muted">Generated {{.GeneratedAt.Format "2006-01-02 15:04 MST"}} from {{len .Runs}} runs.</p>
+<h2>Runs</h2>
+<table><tr><th>Run</th><th>Scan ID</th><th>Operator</th><th>Started</th><th>Targets</th><th>Exposed</th><th>Restored</th><th>Findings</th><th>Newly exposed</th><th>Fixed</th></tr>
+{{range .Runs}}<tr><td>{{.Name}}</td><td>{{.ScanID}}</td><td>{{.Operator}}</td><td>{{.StartedAt.Format "2006-01-02 15:04"}}</td><td class="num">{{.Targets}}</td><td class="num">{{.Exposed}}</td><td class="num">{{.Restored}}</td><td class="num">{{.Findings}}</td><td class="num">{{.NewlyExposed}}</td><td class="num">{{.Fixed}}</td></tr>
+{{end}}</table>
+<h2>Targets</h2>
+<p class="muted">● exposed, ○ not exposed, – not scanned; one mark per run in the order above.</p>
+<table><tr><th>Target</th><th>Severity</th><th>Trend</th><th>Exposed</th><th>Restored</th><th>Files</th><th>Findings</th><th>Last run</th><th>Notes</th></tr>
+{{range .Targets}}<tr><td>{{.Url}}</td><td class="sev-{{.Severity}}">{{.Severity}}</td><td class="trend">{{range .Statuses}}{{mark .}}{{end}}</td><td class="{{if .Exposed}}exposed{{end}}">{{if .Exposed}}yes{{else}}no{{end}}</td><td>{{if .Restored}}yes{{else}}no{{end}}</td><td class="num">{{.Files}}</td><td class="num">{{.Findings}}</td><td>{{.LastRun}}</td><td>{{join .Notes "; "}}</td></tr>
+{{end}}</table>
+{{if .Kinds}}<h2>Findings by kind</h2>
+<table><tr><th>Kind</th><th>Severity</th><th>Findings</th><th>Targets</th></tr>
+{{range .Kinds}}<tr><td>{{.Kind}}</td><td class="sev-{{.Severity}}">{{.Severity}}</td><td class="num">{{.Findings}}</td><td class="num">{{.Targets}}</td></tr>
+{{end}}</table>
+<h2>Top findings</h2>
+<table><tr><th>Severity</th><th>Kind</th><th>Target</th><th>Detail</th><th>URL</th></tr>
+{{range .Findings}}<tr><td class="sev-{{.Severity}}">{{.Severity}}</td><td>{{.Kind}}</td><td>{{.Target}}</td><td>{{.Detail}}</td><td>{{.Url}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Clusters}}<h2>Clusters</h2>
+<p class="muted">Targets sharing a HEAD commit, server banner or framework in their latest runs.</p>
+<table><tr><th>Kind</th><th>Value</th><th>Targets</th></tr>
+{{range .Clusters}}<tr><td>{{.Kind}}</td><td>{{.Value}}</td><td>{{join .Targets ", "}}</td></tr>
+{{end}}</table>{{end}}
+</body></html>
+`))
+
+// WriteMarkdown renders the summary as Markdown tables.
+func (s *Summary) WriteMarkdown(w io.Writer) error {
+	return markdownTemplate.Execute(w, s)
+}
+
+// WriteHTML renders the summary as a self-contained HTML page.
+func (s *Summary) WriteHTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, s)
+}
@@ -0,0 +1,141 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/extractor"
+)
+
+// Target holds the results for a single dumped .git directory.
+type Target struct {
+	Url              string              `json:"url"`
+	Address          string              `json:"address,omitempty"` // Set with -connect-to or host=
+	RepoPath         string              `json:"repo_path"`
+	Requests         int                 `json:"requests"`
+	Files            int                 `json:"files"`
+	Errors           int                 `json:"errors"`
+	Restored         bool                `json:"restored"`
+	Commits          int                 `json:"commits,omitempty"`    // Reachable from refs and HEAD as far as they were recovered
+	Head             string              `json:"head,omitempty"`       // Commit of HEAD
+	Server           string              `json:"server,omitempty"`     // Server header
+	PoweredBy        string              `json:"powered_by,omitempty"` // X-Powered-By header
+	Framework        string              `json:"framework,omitempty"`  // Guessed from files of the working tree
+	Truncated        bool                `json:"truncated,omitempty"`
+	Skipped          int                 `json:"skipped,omitempty"`   // URLs not fetched because a limit was reached
+	Bytes            int64               `json:"bytes,omitempty"`     // Size of files fetched by this run
+	Conflicts        []string            `json:"conflicts,omitempty"` // Paths in merge conflict state in the index
+	Submodules       []Submodule         `json:"submodules,omitempty"`
+	Refs             []Ref               `json:"refs,omitempty"`              // Advertised in info/refs
+	Tags             []Tag               `json:"tags,omitempty"`              // Annotated tag objects
+	Shallow          []string            `json:"shallow,omitempty"`           // Boundary commits of a shallow clone
+	Grafts           map[string][]string `json:"grafts,omitempty"`            // Commits with parents from info/grafts
+	Replacements     map[string]string   `json:"replacements,omitempty"`      // Objects replaced via refs/replace/
+	RobotsDisallowed int                 `json:"robots_disallowed,omitempty"` // URLs skipped with -respect-robots
+	Findings         []extractor.Finding `json:"findings,omitempty"`
+	Notes            []string            `json:"notes,omitempty"`
+	FileClasses      map[string]int      `json:"file_classes,omitempty"`
+	NotableFiles     []string            `json:"notable_files,omitempty"`
+	Exposed          bool                `json:"exposed,omitempty"`
+	Evidence         []Evidence          `json:"evidence,omitempty"`
+	Honeypot         []string            `json:"honeypot,omitempty"`       // Why the target looks like a honeypot
+	NotApplicable    string              `json:"not_applicable,omitempty"` // Why the host was skipped, e.g. a parking page
+	AliasOf          string              `json:"alias_of,omitempty"`       // An earlier target serving the same repository from the same IP
+	Aliases          []string            `json:"aliases,omitempty"`        // Later targets recorded as aliases of this one
+	Severity         string              `json:"severity,omitempty"`       // The most urgent of the exposure, findings and notable files
+	ExposureSeverity string              `json:"exposure_severity,omitempty"`
+	FileSeverities   map[string]string   `json:"file_severities,omitempty"` // Of notable files
+}
+
+// Submodule is a gitlink of the index: a commit of another repository.
+type Submodule struct {
+	Path   string `json:"path"`
+	Commit string `json:"commit"`
+	Url    string `json:"url,omitempty"` // From .gitmodules if it was restored
+}
+
+// Ref is a ref advertised in info/refs.
+type Ref struct {
+	Name   string `json:"name"`
+	Hash   string `json:"hash"`
+	Peeled string `json:"peeled,omitempty"` // Commit an annotated tag points to
+}
+
+// Tag is an annotated tag object found in a dumped repository.
+type Tag struct {
+	Name    string `json:"name"`
+	Hash    string `json:"hash"`
+	Target  string `json:"target"`         // Object at the end of the tag chain
+	Type    string `json:"type,omitempty"` // Type of the target, empty if it is missing
+	Tagger  string `json:"tagger,omitempty"`
+	Date    string `json:"date,omitempty"` // RFC 3339
+	Message string `json:"message,omitempty"`
+}
+
+// Evidence is a captured request and response collected by -verify.
+type Evidence struct {
+	Time            time.Time         `json:"time"`
+	Method          string            `json:"method"`
+	Url             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`
+	Status          int               `json:"status,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	BodySize        int               `json:"body_size,omitempty"`
+	BodySha256      string            `json:"body_sha256,omitempty"`
+	Body            string            `json:"body,omitempty"` // Only for short text bodies
+	Error           string            `json:"error,omitempty"`
+}
+
+// Report is the summary of a single run.
+type Report struct {
+	ScanID     string    `json:"scan_id,omitempty"`
+	Operator   string    `json:"operator,omitempty"` // user@host by default
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Targets    []*Target `json:"targets"`
+	// HostErrors counts failed requests by scheme://host:port of the targets
+	HostErrors map[string]int `json:"host_errors,omitempty"`
+	// HostConcurrency is the final -auto-tune limit of parallel requests by
+	// scheme://host:port
+	HostConcurrency map[string]int `json:"host_concurrency,omitempty"`
+	// Clusters group targets sharing a HEAD commit, server banner or framework
+	Clusters []Cluster `json:"clusters,omitempty"`
+}
+
+// WriteFile saves the report as indented JSON.
+func (r *Report) WriteFile(fileName string) error {
+	if dir := filepath.Dir(fileName); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory for report %s: %w", fileName, err)
+		}
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report %s: %w", fileName, err)
+	}
+
+	return nil
+}
+
+// ReadFile loads a report previously saved with WriteFile.
+func ReadFile(fileName string) (*Report, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report %s: %w", fileName, err)
+	}
+
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to decode report %s: %w", fileName, err)
+	}
+
+	return &r, nil
+}
@@ -0,0 +1,147 @@
+package report
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Severities from the least to the most urgent.
+const (
+	SeverityInfo     = "info"
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+var severityLevels = []string{SeverityInfo, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// SeverityRank orders severities: 0 is info, 4 is critical and -1 is none.
+func SeverityRank(severity string) int {
+	for i, level := range severityLevels {
+		if level == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// maxSeverity returns the more urgent of two severities.
+func maxSeverity(a, b string) string {
+	if SeverityRank(b) > SeverityRank(a) {
+		return b
+	}
+	return a
+}
+
+// Conditions of severity rules besides finding kinds and file: patterns.
+const (
+	RuleExposedEmpty  = "exposed-empty"  // .git/ is readable, but no source was restored
+	RuleExposedSource = "exposed-source" // The working tree was restored
+	RuleNotableFile   = "notable-file"   // A notable file without a matching file: rule
+	RuleFinding       = "finding"        // A finding kind without its own rule
+)
+
+// defaultSeverityRules are applied before the rules given by the user.
+var defaultSeverityRules = []string{
+	RuleExposedEmpty + "=low",
+	RuleExposedSource + "=high",
+	RuleNotableFile + "=medium",
+	RuleFinding + "=low",
+	"credential=critical",
+	"suspicious-hook=medium",
+	"hook=info",
+	"remote=info",
+	"honeypot=info",
+}
+
+// SeverityRules assign severities to exposures, findings and notable files.
+type SeverityRules struct {
+	conditions map[string]string
+	files      []fileRule // В порядке задания, последнее совпадение выигрывает
+}
+
+type fileRule struct {
+	pattern  string
+	severity string
+}
+
+// ParseSeverityRules returns the default rules overridden by rules of the
+// form condition=severity. A condition is exposed-empty, exposed-source,
+// notable-file, finding, a finding kind such as credential, or file:PATTERN
+// with a glob matched against the name or path of a notable file.
+func ParseSeverityRules(rules []string) (*SeverityRules, error) {
+	r := &SeverityRules{conditions: make(map[string]string)}
+	for _, rule := range append(append([]string(nil), defaultSeverityRules...), rules...) {
+		condition, severity, ok := strings.Cut(rule, "=")
+		condition, severity = strings.TrimSpace(condition), strings.ToLower(strings.TrimSpace(severity))
+		if !ok || condition == "" {
+			return nil, fmt.Errorf("invalid severity rule %q: expected condition=severity", rule)
+		}
+		if SeverityRank(severity) < 0 {
+			return nil, fmt.Errorf("invalid severity rule %q: severity must be one of %s", rule, strings.Join(severityLevels, ", "))
+		}
+		if pattern, ok := strings.CutPrefix(condition, "file:"); ok {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid severity rule %q: %w", rule, err)
+			}
+			r.files = append(r.files, fileRule{pattern, severity})
+			continue
+		}
+		// Любое другое имя считается видом находки, в том числе от плагинов
+		r.conditions[condition] = severity
+	}
+	return r, nil
+}
+
+// DefaultSeverityRules returns the rules used when none are configured.
+func DefaultSeverityRules() *SeverityRules {
+	r, _ := ParseSeverityRules(nil)
+	return r
+}
+
+// Finding returns the severity of a finding kind.
+func (r *SeverityRules) Finding(kind string) string {
+	if severity, ok := r.conditions[kind]; ok {
+		return severity
+	}
+	return r.conditions[RuleFinding]
+}
+
+// NotableFile returns the severity of a notable file of the working tree.
+func (r *SeverityRules) NotableFile(name string) string {
+	severity := r.conditions[RuleNotableFile]
+	for _, rule := range r.files {
+		if ok, _ := path.Match(rule.pattern, name); ok {
+			severity = rule.severity
+		} else if ok, _ := path.Match(rule.pattern, path.Base(name)); ok {
+			severity = rule.severity
+		}
+	}
+	return severity
+}
+
+// Score sets the severities of the exposure, findings and notable files of
+// the target, and its overall severity, the most urgent of them.
+func (r *SeverityRules) Score(t *Target) {
+	t.Severity, t.ExposureSeverity, t.FileSeverities = "", "", nil
+	if t.IsExposed() {
+		t.ExposureSeverity = r.conditions[RuleExposedEmpty]
+		if t.Restored {
+			t.ExposureSeverity = r.conditions[RuleExposedSource]
+		}
+		t.Severity = t.ExposureSeverity
+	}
+	for i := range t.Findings {
+		t.Findings[i].Severity = r.Finding(t.Findings[i].Kind)
+		t.Severity = maxSeverity(t.Severity, t.Findings[i].Severity)
+	}
+	for _, name := range t.NotableFiles {
+		if t.FileSeverities == nil {
+			t.FileSeverities = make(map[string]string)
+		}
+		t.FileSeverities[name] = r.NotableFile(name)
+		t.Severity = maxSeverity(t.Severity, t.FileSeverities[name])
+	}
+}
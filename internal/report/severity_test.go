@@ -0,0 +1,47 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/s3rgeym/git-dump/internal/extractor"
+)
+
+func TestSeverityRules(t *testing.T) {
+	rules, err := ParseSeverityRules([]string{"exposed-empty=medium", "remote = Low", "file:*.sql=critical", "file:config/*=high"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	empty := &Target{Exposed: true, Findings: []extractor.Finding{{Kind: "remote"}, {Kind: "plugin-kind"}}}
+	rules.Score(empty)
+	if empty.ExposureSeverity != SeverityMedium || empty.Severity != SeverityMedium {
+		t.Errorf("empty exposure scored %q, overall %q", empty.ExposureSeverity, empty.Severity)
+	}
+	if empty.Findings[0].Severity != SeverityLow || empty.Findings[1].Severity != SeverityLow {
+		t.Errorf("findings scored %q and %q", empty.Findings[0].Severity, empty.Findings[1].Severity)
+	}
+
+	source := &Target{Restored: true, NotableFiles: []string{"db/dump.sql", "config/app.yml", ".env"}}
+	rules.Score(source)
+	want := map[string]string{"db/dump.sql": SeverityCritical, "config/app.yml": SeverityHigh, ".env": SeverityMedium}
+	for name, severity := range want {
+		if got := source.FileSeverities[name]; got != severity {
+			t.Errorf("%s scored %q, want %q", name, got, severity)
+		}
+	}
+	if source.ExposureSeverity != SeverityHigh || source.Severity != SeverityCritical {
+		t.Errorf("source exposure scored %q, overall %q", source.ExposureSeverity, source.Severity)
+	}
+
+	closed := &Target{Severity: SeverityHigh}
+	rules.Score(closed)
+	if closed.Severity != "" {
+		t.Errorf("unexposed target scored %q", closed.Severity)
+	}
+
+	for _, rule := range []string{"credential", "credential=urgent", "=high", "file:[=low"} {
+		if _, err := ParseSeverityRules([]string{rule}); err == nil {
+			t.Errorf("rule %q accepted", rule)
+		}
+	}
+}
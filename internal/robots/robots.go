@@ -0,0 +1,122 @@
+package robots
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// maxRules limits rules kept from a single robots.txt.
+const maxRules = 10000
+
+// Rules are Allow and Disallow rules of robots.txt which apply to a user agent.
+type Rules struct {
+	rules []rule
+}
+
+type rule struct {
+	pattern string
+	allow   bool
+}
+
+// Parse returns rules of the groups whose user-agent token is contained in
+// userAgent or, if there are none, of the * groups.
+func Parse(data []byte, userAgent string) *Rules {
+	userAgent = strings.ToLower(userAgent)
+	var specific, wildcard []rule
+	var agents []string
+	inRules := false // После правил новая строка user-agent начинает новую группу
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 64*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i != -1 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if inRules {
+				agents = nil
+				inRules = false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			// Пустой Disallow разрешает всё и правилом не является
+			if value == "" || len(specific)+len(wildcard) >= maxRules {
+				continue
+			}
+			r := rule{pattern: value, allow: key == "allow"}
+			for _, agent := range agents {
+				if agent == "*" {
+					wildcard = append(wildcard, r)
+				} else if agent != "" && strings.Contains(userAgent, agent) {
+					specific = append(specific, r)
+				}
+			}
+		}
+	}
+	if len(specific) > 0 {
+		return &Rules{rules: specific}
+	}
+	return &Rules{rules: wildcard}
+}
+
+// Allowed reports whether path (with the query, if any) may be fetched. The
+// longest matching rule wins and Allow wins a tie.
+func (r *Rules) Allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+	allowed := true
+	best := -1
+	for _, rule := range r.rules {
+		if !match(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > best || (n == best && rule.allow) {
+			best = n
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+// match reports whether path starts with pattern, where * matches any
+// sequence and a trailing $ anchors the pattern to the end of path.
+func match(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	// Жадное сопоставление с откатом к последней *, без экспоненциального перебора
+	p, s := 0, 0
+	star, mark := -1, 0
+	for s < len(path) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, mark = p, s
+			p++
+		case p < len(pattern) && pattern[p] == path[s]:
+			p++
+			s++
+		case p == len(pattern) && !anchored:
+			return true
+		case star != -1:
+			p = star + 1
+			mark++
+			s = mark
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
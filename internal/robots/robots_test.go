@@ -0,0 +1,84 @@
+package robots
+
+import (
+	"strings"
+	"testing"
+)
+
+const sample = `
+User-agent: *
+Disallow: /.git/
+Allow: /.git/HEAD$
+Disallow: /*.php$
+
+User-agent: Googlebot
+User-agent: Chrome
+Disallow: /private
+`
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		ua   string
+		path string
+		want bool
+	}{
+		{"git-dump", "/", true},
+		{"git-dump", "/.git/config", false},
+		{"git-dump", "/.git/HEAD", true},
+		{"git-dump", "/.git/HEAD.lock", false},
+		{"git-dump", "/index.php", false},
+		{"git-dump", "/index.php?x=1", true},
+		{"git-dump", "/a/b/index.php", false},
+		{"Mozilla/5.0 Chrome/129.0", "/.git/config", true},
+		{"Mozilla/5.0 Chrome/129.0", "/private/key", false},
+	}
+	for _, tt := range tests {
+		r := Parse([]byte(sample), tt.ua)
+		if got := r.Allowed(tt.path); got != tt.want {
+			t.Errorf("%s: Allowed(%q) = %v, want %v", tt.ua, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/a", "/abc", true},
+		{"/a$", "/abc", false},
+		{"/a$", "/a", true},
+		{"/*/c", "/a/b/c", true},
+		{"/*x*y", "/axbyc", true},
+		{"/*x*y$", "/axbyc", false},
+		{"*", "", true},
+		{"/a", "", false},
+		{"$", "", true},
+	}
+	for _, tt := range tests {
+		if got := match(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("match(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestHostile(t *testing.T) {
+	// Много звёздочек и длинные строки не должны приводить к долгому перебору
+	pattern := strings.Repeat("*a", 5000) + "b"
+	data := "User-agent: *\nDisallow: " + pattern + "\n" + strings.Repeat("Disallow: /x\n", 2*maxRules)
+	r := Parse([]byte(data), "ua")
+	if len(r.rules) != maxRules {
+		t.Errorf("got %d rules, want %d", len(r.rules), maxRules)
+	}
+	if !r.Allowed(strings.Repeat("a", 10000)) {
+		t.Errorf("path without b must be allowed")
+	}
+	if Parse([]byte("\x00\xff:::\nuser-agent"), "ua").Allowed("/") != true {
+		t.Errorf("garbage must allow everything")
+	}
+	var nilRules *Rules
+	if !nilRules.Allowed("/") {
+		t.Errorf("nil rules must allow everything")
+	}
+}
@@ -0,0 +1,157 @@
+package rundiff
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+)
+
+// TargetDiff describes content changes of a target present in both runs.
+type TargetDiff struct {
+	Target        string   `json:"target"`
+	NewCommits    []string `json:"new_commits,omitempty"`
+	NewFiles      []string `json:"new_files,omitempty"`
+	RemovedFiles  []string `json:"removed_files,omitempty"`
+	ModifiedFiles []string `json:"modified_files,omitempty"`
+}
+
+// Result is the difference between two runs.
+type Result struct {
+	NewlyExposed []string      `json:"newly_exposed"`
+	NewlyFixed   []string      `json:"newly_fixed"`
+	Changed      []*TargetDiff `json:"changed"`
+}
+
+// snapshot is what we know about a single target in a run.
+type snapshot struct {
+	commits map[string]bool
+	files   map[string]string // Путь -> SHA-1 содержимого
+}
+
+// Compare reports differences between two output directories.
+func Compare(dirA, dirB string) (*Result, error) {
+	a, err := loadRun(dirA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := loadRun(dirB)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{NewlyExposed: []string{}, NewlyFixed: []string{}, Changed: []*TargetDiff{}}
+	for target := range b {
+		if _, ok := a[target]; !ok {
+			result.NewlyExposed = append(result.NewlyExposed, target)
+		}
+	}
+	for target, snapA := range a {
+		snapB, ok := b[target]
+		if !ok {
+			result.NewlyFixed = append(result.NewlyFixed, target)
+			continue
+		}
+		if d := compareSnapshots(target, snapA, snapB); d != nil {
+			result.Changed = append(result.Changed, d)
+		}
+	}
+
+	sort.Strings(result.NewlyExposed)
+	sort.Strings(result.NewlyFixed)
+	sort.Slice(result.Changed, func(i, j int) bool {
+		return result.Changed[i].Target < result.Changed[j].Target
+	})
+	return result, nil
+}
+
+func compareSnapshots(target string, a, b *snapshot) *TargetDiff {
+	d := &TargetDiff{Target: target}
+	for hash := range b.commits {
+		if !a.commits[hash] {
+			d.NewCommits = append(d.NewCommits, hash)
+		}
+	}
+	for path, sumB := range b.files {
+		sumA, ok := a.files[path]
+		if !ok {
+			d.NewFiles = append(d.NewFiles, path)
+		} else if sumA != sumB {
+			d.ModifiedFiles = append(d.ModifiedFiles, path)
+		}
+	}
+	for path := range a.files {
+		if _, ok := b.files[path]; !ok {
+			d.RemovedFiles = append(d.RemovedFiles, path)
+		}
+	}
+	if len(d.NewCommits)+len(d.NewFiles)+len(d.RemovedFiles)+len(d.ModifiedFiles) == 0 {
+		return nil
+	}
+	sort.Strings(d.NewCommits)
+	sort.Strings(d.NewFiles)
+	sort.Strings(d.RemovedFiles)
+	sort.Strings(d.ModifiedFiles)
+	return d
+}
+
+func loadRun(root string) (map[string]*snapshot, error) {
+	gitDirs, err := gitobj.FindGitDirs(root)
+	if err != nil {
+		return nil, err
+	}
+	run := make(map[string]*snapshot)
+	for _, gitDir := range gitDirs {
+		// Пустой каталог .git ещё не значит, что репозиторий доступен
+		if !gitobj.HasContent(gitDir) {
+			continue
+		}
+		worktree := filepath.Dir(gitDir)
+		target, err := filepath.Rel(root, worktree)
+		if err != nil {
+			continue
+		}
+		snap := &snapshot{commits: make(map[string]bool), files: make(map[string]string)}
+		if repo, err := gitobj.Open(gitDir); err == nil {
+			hashes, _ := repo.ListObjects()
+			for _, hash := range hashes {
+				// Достаточно заголовка, блобы и деревья не распаковываются целиком
+				if typ, err := repo.ObjectType(hash); err == nil && typ == gitobj.TypeCommit {
+					snap.commits[hash] = true
+				}
+			}
+			repo.Close()
+		}
+		filepath.WalkDir(worktree, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() && d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, _ := filepath.Rel(worktree, path)
+			snap.files[filepath.ToSlash(rel)] = fileHash(path)
+			return nil
+		})
+		run[filepath.ToSlash(target)] = snap
+	}
+	return run, nil
+}
+
+func fileHash(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	h := sha1.New()
+	io.Copy(h, f)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,74 @@
+package rundiff
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+)
+
+// writeTarget makes a dumped target with a blob, a commit per message and the
+// given worktree files. It returns the commit hashes.
+func writeTarget(t *testing.T, worktree string, messages []string, files map[string]string) []string {
+	t.Helper()
+	gitDir := filepath.Join(worktree, ".git")
+	if err := os.MkdirAll(filepath.Join(gitDir, "objects"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/master\n"), 0644)
+	repo, err := gitobj.Open(gitDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	if _, err := repo.WriteLoose(gitobj.TypeBlob, []byte("blob\n")); err != nil {
+		t.Fatal(err)
+	}
+	var commits []string
+	for _, msg := range messages {
+		hash, err := repo.WriteLoose(gitobj.TypeCommit, []byte("tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n\n"+msg+"\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		commits = append(commits, hash)
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(worktree, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return commits
+}
+
+func TestCompare(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	writeTarget(t, filepath.Join(dirA, "a.com"), []string{"first"}, map[string]string{"x.txt": "1", "y.txt": "1"})
+	writeTarget(t, filepath.Join(dirA, "fixed.com"), []string{"first"}, nil)
+	writeTarget(t, filepath.Join(dirA, "same.com"), []string{"first"}, map[string]string{"x.txt": "1"})
+	commits := writeTarget(t, filepath.Join(dirB, "a.com"), []string{"first", "second"}, map[string]string{"x.txt": "2", "z.txt": "1"})
+	writeTarget(t, filepath.Join(dirB, "new.com"), []string{"first"}, nil)
+	writeTarget(t, filepath.Join(dirB, "same.com"), []string{"first"}, map[string]string{"x.txt": "1"})
+	// Каталог .git без объектов не считается открытым репозиторием
+	os.MkdirAll(filepath.Join(dirB, "empty.com", ".git"), 0755)
+
+	r, err := Compare(dirA, dirB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Result{
+		NewlyExposed: []string{"new.com"},
+		NewlyFixed:   []string{"fixed.com"},
+		Changed: []*TargetDiff{{
+			Target:        "a.com",
+			NewCommits:    commits[1:],
+			NewFiles:      []string{"z.txt"},
+			RemovedFiles:  []string{"y.txt"},
+			ModifiedFiles: []string{"x.txt"},
+		}},
+	}
+	if !reflect.DeepEqual(r, want) {
+		t.Fatalf("Compare = %+v, want %+v", r, want)
+	}
+}
@@ -0,0 +1,170 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/fsutil"
+	"github.com/s3rgeym/git-dump/internal/gitindex"
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+const (
+	SourceIndex     = "index"
+	SourceSubmodule = "submodule"
+)
+
+// Extracted is a file copied into the results directory.
+type Extracted struct {
+	Target string `json:"target"`
+	File   string `json:"file"`
+	Source string `json:"source"`
+	Commit string `json:"commit,omitempty"`
+	Blob   string `json:"blob,omitempty"`
+	Output string `json:"output,omitempty"`
+}
+
+// ExtractOptions control which files are extracted and where to.
+type ExtractOptions struct {
+	Paths     []string // Глобы по полному пути или имени файла
+	OutputDir string
+	History   bool // Также извлекать версии из истории
+	// Создавать символические ссылки, а не файлы с путём, на который они указывают
+	AllowSymlinks bool
+}
+
+// Extract copies files matching opts.Paths from every dumped repository below
+// root into a flat directory. Index entries are taken from the working tree or
+// the object store; with History every distinct historical version is saved too.
+func Extract(root string, opts ExtractOptions, emit func(Extracted)) error {
+	gitDirs, err := gitobj.FindGitDirs(root)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	for _, gitDir := range gitDirs {
+		worktree := filepath.Dir(gitDir)
+		target, err := filepath.Rel(root, worktree)
+		if err != nil {
+			target = worktree
+		}
+		target = filepath.ToSlash(target)
+
+		repo, err := gitobj.Open(gitDir)
+		if err != nil {
+			logger.Errorf("Failed to open repository %s: %v", gitDir, err)
+			continue
+		}
+		extractRepo(repo, worktree, target, opts, emit)
+		repo.Close()
+	}
+	return nil
+}
+
+func extractRepo(repo *gitobj.Repo, worktree, target string, opts ExtractOptions, emit func(Extracted)) {
+	saved := make(map[string]bool)
+	save := func(e Extracted, mode uint32, data []byte) {
+		if saved[e.Blob] && e.Blob != "" {
+			return
+		}
+		saved[e.Blob] = true
+		name := flatName(target, e.File)
+		if e.Source == SourceHistory {
+			name += "@" + e.Blob[:8]
+		}
+		e.Output = filepath.Join(opts.OutputDir, name)
+		if err := fsutil.WriteGitFile(e.Output, mode, data, opts.AllowSymlinks); err != nil {
+			logger.Errorf("Failed to write %s: %v", e.Output, err)
+			return
+		}
+		emit(e)
+	}
+
+	if index, err := gitindex.ParseGitIndex(filepath.Join(repo.Dir, "index")); err == nil {
+		for _, entry := range index.Files() {
+			if !matchPaths(opts.Paths, entry.FileName) {
+				continue
+			}
+			// Подмодуль — ссылка на коммит другого репозитория, содержимого нет
+			if entry.Mode&fsutil.ModeTypeMask == fsutil.ModeGitlink {
+				emit(Extracted{Target: target, File: entry.FileName, Source: SourceSubmodule, Commit: entry.Sha1})
+				continue
+			}
+			e := Extracted{Target: target, File: entry.FileName, Source: SourceIndex, Blob: entry.Sha1}
+			if obj, err := repo.ReadObject(entry.Sha1); err == nil && obj.Type == gitobj.TypeBlob {
+				save(e, entry.Mode, obj.Data)
+				continue
+			}
+			// Blob не скачан, но файл мог быть получен по HTTP
+			fileName := filepath.Join(worktree, filepath.FromSlash(entry.FileName))
+			if !strings.HasPrefix(fileName, worktree+string(filepath.Separator)) || fsutil.SymlinkInPath(worktree, fileName) {
+				continue
+			}
+			// Символическую ссылку не разыменовываем: содержимое — её цель
+			if link, err := os.Readlink(fileName); err == nil {
+				e.Source = SourceWorktree
+				save(e, entry.Mode, []byte(link))
+			} else if data, err := os.ReadFile(fileName); err == nil {
+				e.Source = SourceWorktree
+				save(e, entry.Mode, data)
+			} else {
+				logger.Warnf("Content of %s in %s is not available", entry.FileName, target)
+			}
+		}
+	}
+
+	if !opts.History {
+		return
+	}
+	hashes, err := repo.ListObjects()
+	if err != nil {
+		return
+	}
+	for _, hash := range hashes {
+		// Тип читается из заголовка, чтобы не распаковывать каждый blob
+		if objType, err := repo.ObjectType(hash); err != nil || objType != gitobj.TypeCommit {
+			continue
+		}
+		commit, err := repo.ReadCommit(hash)
+		if err != nil {
+			continue
+		}
+		repo.WalkTree(commit.Tree, "", func(file string, entry gitobj.TreeEntry) error {
+			if entry.IsSubmodule() || saved[entry.Hash] || !matchPaths(opts.Paths, file) {
+				return nil
+			}
+			obj, err := repo.ReadObject(entry.Hash)
+			if err != nil || obj.Type != gitobj.TypeBlob {
+				return nil
+			}
+			save(Extracted{Target: target, File: file, Source: SourceHistory, Commit: commit.Hash, Blob: entry.Hash}, entry.FileMode(), obj.Data)
+			return nil
+		})
+	}
+}
+
+// matchPaths reports whether file matches any glob by full path or base name.
+func matchPaths(patterns []string, file string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, file); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, path.Base(file)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// flatName builds a single file name from the target and the file path.
+func flatName(target, file string) string {
+	name := strings.ReplaceAll(target+"/"+file, "/", "_")
+	return strings.TrimLeft(strings.ReplaceAll(name, "..", "_"), "._")
+}
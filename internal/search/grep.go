@@ -0,0 +1,184 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+const (
+	SourceWorktree = "worktree"
+	SourceHistory  = "history"
+)
+
+// Match is a single line matching one of the patterns.
+type Match struct {
+	Target  string `json:"target"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Text    string `json:"text"`
+	Pattern string `json:"pattern"`
+	Source  string `json:"source"`
+	Commit  string `json:"commit,omitempty"`
+	Blob    string `json:"blob,omitempty"`
+}
+
+// Options control what is searched.
+type Options struct {
+	Patterns []*regexp.Regexp
+	History  bool // Искать также в исторических blob-объектах
+	MaxLine  int  // Обрезать совпавшие строки до этой длины
+}
+
+// Grep searches all dumped repositories below root and calls emit for every match.
+func Grep(root string, opts Options, emit func(Match)) error {
+	gitDirs, err := gitobj.FindGitDirs(root)
+	if err != nil {
+		return err
+	}
+
+	for _, gitDir := range gitDirs {
+		worktree := filepath.Dir(gitDir)
+		target, err := filepath.Rel(root, worktree)
+		if err != nil {
+			target = worktree
+		}
+		target = filepath.ToSlash(target)
+
+		if err := grepWorktree(worktree, target, opts, emit); err != nil {
+			logger.Errorf("Failed to search worktree %s: %v", worktree, err)
+		}
+
+		if opts.History {
+			if err := grepHistory(gitDir, target, opts, emit); err != nil {
+				logger.Errorf("Failed to search history in %s: %v", gitDir, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func grepWorktree(worktree, target string, opts Options, emit func(Match)) error {
+	return filepath.WalkDir(worktree, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(worktree, path)
+		grepData(data, opts, func(line int, text, pattern string) {
+			emit(Match{
+				Target:  target,
+				File:    filepath.ToSlash(rel),
+				Line:    line,
+				Text:    text,
+				Pattern: pattern,
+				Source:  SourceWorktree,
+			})
+		})
+		return nil
+	})
+}
+
+func grepHistory(gitDir, target string, opts Options, emit func(Match)) error {
+	repo, err := gitobj.Open(gitDir)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	hashes, err := repo.ListObjects()
+	if err != nil {
+		return err
+	}
+
+	// Каждый blob ищем один раз, указывая первый коммит, в котором он найден
+	searched := make(map[string]bool)
+	for _, hash := range hashes {
+		// Тип читается из заголовка, чтобы не распаковывать каждый blob
+		if objType, err := repo.ObjectType(hash); err != nil || objType != gitobj.TypeCommit {
+			continue
+		}
+		commit, err := repo.ReadCommit(hash)
+		if err != nil {
+			continue
+		}
+		err = repo.WalkTree(commit.Tree, "", func(path string, entry gitobj.TreeEntry) error {
+			if entry.IsSubmodule() || searched[entry.Hash] {
+				return nil
+			}
+			searched[entry.Hash] = true
+			obj, err := repo.ReadObject(entry.Hash)
+			if err != nil || obj.Type != gitobj.TypeBlob {
+				return nil
+			}
+			grepData(obj.Data, opts, func(line int, text, pattern string) {
+				emit(Match{
+					Target:  target,
+					File:    path,
+					Line:    line,
+					Text:    text,
+					Pattern: pattern,
+					Source:  SourceHistory,
+					Commit:  commit.Hash,
+					Blob:    entry.Hash,
+				})
+			})
+			return nil
+		})
+		if err != nil {
+			logger.Debugf("Incomplete tree for commit %s: %v", hash, err)
+		}
+	}
+
+	return nil
+}
+
+// IsBinary reports whether data looks like a binary file.
+func IsBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) != -1
+}
+
+func grepData(data []byte, opts Options, fn func(line int, text, pattern string)) {
+	if IsBinary(data) {
+		return
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		for _, re := range opts.Patterns {
+			if !re.MatchString(text) {
+				continue
+			}
+			text = strings.TrimSpace(text)
+			if opts.MaxLine > 0 && len(text) > opts.MaxLine {
+				text = text[:opts.MaxLine]
+			}
+			fn(line, text, re.String())
+			break
+		}
+	}
+}
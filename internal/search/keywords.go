@@ -0,0 +1,178 @@
+package search
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/gitindex"
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+const (
+	SourceMessage = "message"
+	SourcePath    = "path"
+)
+
+// Keyword is a word to look for with the weight of a hit.
+type Keyword struct {
+	Word   string
+	Weight int
+	re     *regexp.Regexp
+}
+
+// DefaultKeywords are words which point at secrets, backups and
+// infrastructure in commit messages and file names.
+var DefaultKeywords = []string{
+	"password:5", "passwd:5", "secret:5", "credential:5", "id_rsa:5", "private:4",
+	"token:4", "apikey:4", "api_key:4", "backup:4", "dump:3", ".sql:3", ".env:4",
+	"vpn:3", "prod:3", "deploy:2", "admin:2", "config:1", "key:1",
+}
+
+// ParseKeyword parses "word" or "word:weight". The weight defaults to 1.
+func ParseKeyword(s string) (Keyword, error) {
+	word, weight := s, 1
+	if i := strings.LastIndexByte(s, ':'); i != -1 {
+		n, err := strconv.Atoi(s[i+1:])
+		if err != nil || n < 1 {
+			return Keyword{}, fmt.Errorf("invalid weight in %q", s)
+		}
+		word, weight = s[:i], n
+	}
+	word = strings.ToLower(strings.TrimSpace(word))
+	if word == "" {
+		return Keyword{}, fmt.Errorf("empty keyword in %q", s)
+	}
+	// Ключевое слово должно начинать слово: prod находит production, но не reproduce
+	expr := regexp.QuoteMeta(word)
+	if c := word[0]; c >= 'a' && c <= 'z' || c >= '0' && c <= '9' {
+		expr = `(^|[^a-z0-9])` + expr
+	}
+	re := regexp.MustCompile(expr)
+	return Keyword{Word: word, Weight: weight, re: re}, nil
+}
+
+// Hit is a commit message or a file path containing keywords.
+type Hit struct {
+	Target   string   `json:"target"`
+	Source   string   `json:"source"`
+	Text     string   `json:"text"`
+	Commit   string   `json:"commit,omitempty"`
+	Keywords []string `json:"keywords"`
+	Score    int      `json:"score"`
+}
+
+// matchKeywords returns the keywords found in text and the sum of their weights.
+func matchKeywords(keywords []Keyword, text string) ([]string, int) {
+	text = strings.ToLower(text)
+	var found []string
+	score := 0
+	for _, k := range keywords {
+		if k.re.MatchString(text) {
+			found = append(found, k.Word)
+			score += k.Weight
+		}
+	}
+	return found, score
+}
+
+// Keywords scans commit messages and file paths of all dumped repositories
+// below root and returns hits ranked by score. Paths are taken from the index
+// and the trees of all recovered commits; each path is reported once per
+// repository.
+func Keywords(root string, keywords []Keyword) ([]Hit, error) {
+	gitDirs, err := gitobj.FindGitDirs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []Hit
+	for _, gitDir := range gitDirs {
+		worktree := filepath.Dir(gitDir)
+		target, err := filepath.Rel(root, worktree)
+		if err != nil {
+			target = worktree
+		}
+		target = filepath.ToSlash(target)
+
+		repo, err := gitobj.Open(gitDir)
+		if err != nil {
+			logger.Errorf("Failed to open repository %s: %v", gitDir, err)
+			continue
+		}
+		hits = append(hits, keywordsRepo(repo, target, keywords)...)
+		repo.Close()
+	}
+
+	SortHits(hits)
+	return hits, nil
+}
+
+// SortHits orders hits by score, then by target and text.
+func SortHits(hits []Hit) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		return a.Text < b.Text
+	})
+}
+
+func keywordsRepo(repo *gitobj.Repo, target string, keywords []Keyword) []Hit {
+	var hits []Hit
+	paths := make(map[string]bool)
+	checkPath := func(file, commit string) {
+		if paths[file] {
+			return
+		}
+		paths[file] = true
+		if found, score := matchKeywords(keywords, file); score > 0 {
+			hits = append(hits, Hit{Target: target, Source: SourcePath, Text: file, Commit: commit, Keywords: found, Score: score})
+		}
+	}
+
+	if index, err := gitindex.ParseGitIndex(filepath.Join(repo.Dir, "index")); err == nil {
+		for _, entry := range index.Files() {
+			checkPath(entry.FileName, "")
+		}
+	}
+
+	hashes, err := repo.ListObjects()
+	if err != nil {
+		return hits
+	}
+	trees := make(map[string]bool)
+	for _, hash := range hashes {
+		// Тип читается из заголовка, чтобы не распаковывать каждый blob
+		if objType, err := repo.ObjectType(hash); err != nil || objType != gitobj.TypeCommit {
+			continue
+		}
+		commit, err := repo.ReadCommit(hash)
+		if err != nil {
+			continue
+		}
+		if found, score := matchKeywords(keywords, commit.Message); score > 0 {
+			hits = append(hits, Hit{Target: target, Source: SourceMessage, Text: strings.TrimSpace(commit.Message), Commit: commit.Hash, Keywords: found, Score: score})
+		}
+		if trees[commit.Tree] {
+			continue
+		}
+		trees[commit.Tree] = true
+		err = repo.WalkTree(commit.Tree, "", func(file string, entry gitobj.TreeEntry) error {
+			checkPath(file, commit.Hash)
+			return nil
+		})
+		if err != nil {
+			logger.Debugf("Incomplete tree for commit %s: %v", hash, err)
+		}
+	}
+	return hits
+}
@@ -0,0 +1,48 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeyword(t *testing.T) {
+	k, err := ParseKeyword("VPN:3")
+	if err != nil || k.Word != "vpn" || k.Weight != 3 {
+		t.Fatalf("ParseKeyword(VPN:3) = %+v, %v", k, err)
+	}
+	if k, err := ParseKeyword("backup"); err != nil || k.Weight != 1 {
+		t.Fatalf("ParseKeyword(backup) = %+v, %v", k, err)
+	}
+	for _, s := range []string{"", ":2", "vpn:0", "vpn:x"} {
+		if _, err := ParseKeyword(s); err == nil {
+			t.Errorf("ParseKeyword(%q) succeeded", s)
+		}
+	}
+}
+
+func TestMatchKeywords(t *testing.T) {
+	var keywords []Keyword
+	for _, s := range []string{"password:5", "prod:3", ".sql:3"} {
+		k, err := ParseKeyword(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keywords = append(keywords, k)
+	}
+	tests := []struct {
+		text  string
+		found []string
+		score int
+	}{
+		{"Remove production passwords", []string{"password", "prod"}, 8},
+		{"backups/db.sql", []string{".sql"}, 3},
+		{"Reproduce bug", nil, 0},
+		{"config/db_Password.yml", []string{"password"}, 5},
+	}
+	for _, tt := range tests {
+		found, score := matchKeywords(keywords, tt.text)
+		if !reflect.DeepEqual(found, tt.found) || score != tt.score {
+			t.Errorf("matchKeywords(%q) = %v, %d, want %v, %d", tt.text, found, score, tt.found, tt.score)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/db"
+	"github.com/s3rgeym/git-dump/internal/dumper"
+	"github.com/s3rgeym/git-dump/internal/httpclient"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+)
+
+const (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusDone    = "done"
+)
+
+// Job is a set of targets submitted through the API.
+type Job struct {
+	ID         int       `json:"id"`
+	Urls       []string  `json:"urls"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+	OutputDir  string    `json:"output_dir"`
+
+	dumper *dumper.Dumper
+	report *report.Report // Итоговый отчёт; дампер после завершения не хранится
+}
+
+// Server exposes a REST API for queueing targets.
+type Server struct {
+	config   config.Config
+	queue    chan *Job
+	mu       sync.Mutex
+	jobs     map[int]*Job
+	lastID   int
+	database *db.DB
+	jobTTL   time.Duration // Сколько хранить завершённые задания, 0 - без срока
+	keepJobs int           // Сколько завершённых заданий хранить, 0 - без ограничения
+}
+
+// New creates a server. Every job gets its own HTTP client, output directory
+// and report, log and HAR files derived from config.
+func New(config config.Config, queueSize int) *Server {
+	return &Server{
+		config: config,
+		queue:  make(chan *Job, queueSize),
+		jobs:   make(map[int]*Job),
+	}
+}
+
+// SetRetention makes the server forget finished jobs and their reports ttl
+// after they finish, keeping at most keep of them. Zero disables a limit.
+func (s *Server) SetRetention(ttl time.Duration, keep int) {
+	s.mu.Lock()
+	s.jobTTL, s.keepJobs = ttl, keep
+	s.mu.Unlock()
+}
+
+// SetDatabase makes every job record its results into database.
+func (s *Server) SetDatabase(database *db.DB) {
+	s.database = database
+}
+
+// Start launches workersNum goroutines processing queued jobs.
+func (s *Server) Start(workersNum int) {
+	for i := 0; i < workersNum; i++ {
+		go s.worker()
+	}
+}
+
+func (s *Server) worker() {
+	for job := range s.queue {
+		jobConfig := s.jobConfig(job.ID)
+		client := httpclient.NewHttpClient(jobConfig)
+		d := dumper.New(jobConfig, client)
+		if s.database != nil {
+			d.SetDatabase(s.database)
+		}
+
+		s.mu.Lock()
+		job.Status = StatusRunning
+		job.StartedAt = time.Now()
+		job.dumper = d
+		s.mu.Unlock()
+
+		logger.Infof("Starting job %d with %d targets", job.ID, len(job.Urls))
+		d.Run(job.Urls)
+		if err := client.Close(); err != nil {
+			logger.Errorf("Failed to close HTTP client of job %d: %v", job.ID, err)
+		}
+
+		r := d.Report()
+		s.mu.Lock()
+		job.Status = StatusDone
+		job.FinishedAt = time.Now()
+		job.report, job.dumper = r, nil
+		s.prune(job.FinishedAt)
+		s.mu.Unlock()
+		logger.Infof("Finished job %d", job.ID)
+	}
+}
+
+// prune forgets finished jobs older than jobTTL and the oldest ones beyond
+// keepJobs. s.mu must be held.
+func (s *Server) prune(now time.Time) {
+	var finished []*Job
+	for id, job := range s.jobs {
+		if job.Status != StatusDone {
+			continue
+		}
+		if s.jobTTL > 0 && now.Sub(job.FinishedAt) > s.jobTTL {
+			delete(s.jobs, id)
+			continue
+		}
+		finished = append(finished, job)
+	}
+	if s.keepJobs <= 0 || len(finished) <= s.keepJobs {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].FinishedAt.Before(finished[j].FinishedAt) })
+	for _, job := range finished[:len(finished)-s.keepJobs] {
+		delete(s.jobs, job.ID)
+	}
+}
+
+// jobConfig returns config with per-job output directory and files, so
+// concurrent jobs never write the same report, manifest or log.
+func (s *Server) jobConfig(id int) config.Config {
+	c := s.config
+	c.OutputDir = filepath.Join(s.config.OutputDir, fmt.Sprintf("job-%d", id))
+	c.ScanID = fmt.Sprintf("%s-job-%d", s.config.ScanID, id)
+	c.ReportFile = jobFile(c.ReportFile, id)
+	c.ReportHtml = jobFile(c.ReportHtml, id)
+	c.ReportCsv = jobFile(c.ReportCsv, id)
+	c.HttpLogFile = jobFile(c.HttpLogFile, id)
+	c.HarFile = jobFile(c.HarFile, id)
+	c.AuditLogFile = jobFile(c.AuditLogFile, id)
+	return c
+}
+
+// jobFile inserts the job id before the extension: report.json -> report-3.json.
+func jobFile(fileName string, id int) string {
+	if fileName == "" {
+		return ""
+	}
+	ext := filepath.Ext(fileName)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(fileName, ext), id, ext)
+}
+
+// Handler returns the HTTP handler with all API routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /targets", s.handleSubmit)
+	mux.HandleFunc("GET /targets", s.handleList)
+	mux.HandleFunc("GET /targets/{id}", s.handleStatus)
+	mux.HandleFunc("GET /targets/{id}/report", s.handleReport)
+	return mux
+}
+
+// Submit queues urls for dumping and returns the created job.
+func (s *Server) Submit(urls []string) (*Job, error) {
+	s.mu.Lock()
+	s.lastID++
+	job := &Job{ID: s.lastID, Urls: urls, Status: StatusQueued, CreatedAt: time.Now()}
+	job.OutputDir = s.jobConfig(job.ID).OutputDir
+	s.jobs[job.ID] = job
+	s.mu.Unlock()
+
+	select {
+	case s.queue <- job:
+		return job, nil
+	default:
+		s.mu.Lock()
+		delete(s.jobs, job.ID)
+		s.mu.Unlock()
+		return nil, fmt.Errorf("queue is full")
+	}
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	urls, err := readUrls(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(urls) == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("no urls given"))
+		return
+	}
+
+	job, err := s.Submit(urls)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	s.writeJob(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.prune(time.Now())
+	jobs := make([]Job, 0, len(s.jobs))
+	for id := 1; id <= s.lastID; id++ {
+		if job, ok := s.jobs[id]; ok {
+			jobs = append(jobs, *job)
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.lookup(r)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job not found"))
+		return
+	}
+
+	s.writeJob(w, http.StatusOK, job)
+}
+
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.lookup(r)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job not found"))
+		return
+	}
+
+	s.mu.Lock()
+	d, rep := job.dumper, job.report
+	s.mu.Unlock()
+
+	switch {
+	case rep != nil:
+		writeJSON(w, http.StatusOK, rep)
+	case d != nil:
+		writeJSON(w, http.StatusOK, d.Report())
+	default:
+		writeJSON(w, http.StatusOK, &report.Report{Targets: []*report.Target{}})
+	}
+}
+
+func (s *Server) lookup(r *http.Request) (*Job, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(time.Now())
+	job, ok := s.jobs[id]
+	return job, ok
+}
+
+func (s *Server) writeJob(w http.ResponseWriter, status int, job *Job) {
+	s.mu.Lock()
+	j := *job
+	s.mu.Unlock()
+	writeJSON(w, status, j)
+}
+
+// readUrls accepts either {"urls": [...]} JSON or a plain text list of URLs.
+func readUrls(r *http.Request) ([]string, error) {
+	var urls []string
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body struct {
+			Urls []string `json:"urls"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return nil, fmt.Errorf("invalid JSON body: %w", err)
+		}
+		urls = body.Urls
+	} else {
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			urls = append(urls, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	ret := make([]string, 0, len(urls))
+	for _, u := range urls {
+		if u = strings.TrimSpace(u); u != "" {
+			ret = append(ret, u)
+		}
+	}
+	return ret, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Errorf("Failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/report"
+)
+
+func newServer(t *testing.T, queueSize int) *Server {
+	t.Helper()
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	return New(config.ParseArgs(fs, []string{"-no-banner", "-o", t.TempDir()}), queueSize)
+}
+
+func call(t *testing.T, h http.Handler, method, path, contentType, body string, v interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if v != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: %v: %s", method, path, err, rec.Body)
+		}
+	}
+	return rec.Code
+}
+
+func TestAPI(t *testing.T) {
+	site := httptest.NewServer(http.NotFoundHandler())
+	defer site.Close()
+
+	srv := newServer(t, 10)
+	h := srv.Handler()
+
+	var job Job
+	if code := call(t, h, "POST", "/targets", "application/json", `{"urls": ["`+site.URL+`/"]}`, &job); code != http.StatusAccepted {
+		t.Fatalf("POST /targets = %d", code)
+	}
+	if job.ID != 1 || job.Status != StatusQueued || len(job.Urls) != 1 {
+		t.Fatalf("job = %+v", job)
+	}
+	if code := call(t, h, "POST", "/targets", "text/plain", "\n"+site.URL+"/a/\n", &job); code != http.StatusAccepted {
+		t.Fatalf("POST /targets (text) = %d", code)
+	}
+	if job.ID != 2 || len(job.Urls) != 1 || job.Urls[0] != site.URL+"/a/" {
+		t.Fatalf("job = %+v", job)
+	}
+	if code := call(t, h, "POST", "/targets", "text/plain", "", nil); code != http.StatusBadRequest {
+		t.Fatalf("POST /targets without urls = %d", code)
+	}
+
+	var jobs []Job
+	if call(t, h, "GET", "/targets", "", "", &jobs); len(jobs) != 2 || jobs[0].ID != 1 || jobs[1].ID != 2 {
+		t.Fatalf("GET /targets = %+v", jobs)
+	}
+	var r report.Report
+	if code := call(t, h, "GET", "/targets/1/report", "", "", &r); code != http.StatusOK || len(r.Targets) != 0 {
+		t.Fatalf("report of a queued job = %d %+v", code, r)
+	}
+	if code := call(t, h, "GET", "/targets/3", "", "", nil); code != http.StatusNotFound {
+		t.Fatalf("GET /targets/3 = %d", code)
+	}
+
+	srv.Start(1)
+	deadline := time.Now().Add(30 * time.Second)
+	for job.Status != StatusDone {
+		if time.Now().After(deadline) {
+			t.Fatalf("job did not finish: %+v", job)
+		}
+		time.Sleep(50 * time.Millisecond)
+		call(t, h, "GET", "/targets/2", "", "", &job)
+	}
+	if code := call(t, h, "GET", "/targets/2/report", "", "", &r); code != http.StatusOK || len(r.Targets) != 1 {
+		t.Fatalf("report = %d %+v", code, r)
+	}
+}
+
+func TestSubmitQueueFull(t *testing.T) {
+	h := newServer(t, 1).Handler()
+	if code := call(t, h, "POST", "/targets", "", "http://127.0.0.1:1/", nil); code != http.StatusAccepted {
+		t.Fatalf("first POST = %d", code)
+	}
+	if code := call(t, h, "POST", "/targets", "", "http://127.0.0.1:1/", nil); code != http.StatusServiceUnavailable {
+		t.Fatalf("POST to a full queue = %d", code)
+	}
+	var jobs []Job
+	if call(t, h, "GET", "/targets", "", "", &jobs); len(jobs) != 1 {
+		t.Fatalf("rejected job is listed: %+v", jobs)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	srv := newServer(t, 10)
+	srv.SetRetention(time.Hour, 2)
+	now := time.Now()
+	for i, age := range []time.Duration{2 * time.Hour, 30 * time.Minute, 20 * time.Minute, 10 * time.Minute, 0} {
+		job, err := srv.Submit([]string{"http://127.0.0.1:1/"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < 4 {
+			job.Status, job.FinishedAt = StatusDone, now.Add(-age)
+		}
+	}
+
+	srv.mu.Lock()
+	srv.prune(now)
+	var ids []int
+	for id := range srv.jobs {
+		ids = append(ids, id)
+	}
+	srv.mu.Unlock()
+
+	// 1 устарело, 2 вытеснено лимитом, 5 ещё в очереди
+	if len(ids) != 3 || srv.jobs[3] == nil || srv.jobs[4] == nil || srv.jobs[5] == nil {
+		t.Fatalf("jobs left = %v", ids)
+	}
+}
@@ -0,0 +1,302 @@
+package sources
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/httpclient"
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+const maxBodySize = 32 << 20
+
+// Source pulls targets from a search engine API. Targets are input lines
+// understood by the dumper, e.g. "https://203.0.113.7:8443 host=example.com".
+type Source struct {
+	Name  string
+	query string
+	// fetch returns targets of one result page and the cursor of the next
+	// one, empty if there are no more results
+	fetch func(client *http.Client, query, cursor string) ([]string, string, error)
+}
+
+// FromConfig returns sources for the -shodan-query, -censys-query,
+// -fofa-query, -urlscan-query and -commoncrawl-domain flags. API keys are read
+// from the environment.
+func FromConfig(config config.Config) ([]*Source, error) {
+	var sources []*Source
+	if config.ShodanQuery != "" {
+		key := os.Getenv("SHODAN_API_KEY")
+		if key == "" {
+			return nil, fmt.Errorf("-shodan-query requires SHODAN_API_KEY")
+		}
+		sources = append(sources, &Source{Name: "shodan", query: config.ShodanQuery, fetch: shodan(key)})
+	}
+	if config.CensysQuery != "" {
+		id, secret := os.Getenv("CENSYS_API_ID"), os.Getenv("CENSYS_API_SECRET")
+		if id == "" || secret == "" {
+			return nil, fmt.Errorf("-censys-query requires CENSYS_API_ID and CENSYS_API_SECRET")
+		}
+		sources = append(sources, &Source{Name: "censys", query: config.CensysQuery, fetch: censys(id, secret)})
+	}
+	if config.FofaQuery != "" {
+		key := os.Getenv("FOFA_KEY")
+		if key == "" {
+			return nil, fmt.Errorf("-fofa-query requires FOFA_KEY")
+		}
+		sources = append(sources, &Source{Name: "fofa", query: config.FofaQuery, fetch: fofa(os.Getenv("FOFA_EMAIL"), key)})
+	}
+	if config.UrlscanQuery != "" {
+		sources = append(sources, &Source{Name: "urlscan", query: config.UrlscanQuery, fetch: urlscan(os.Getenv("URLSCAN_API_KEY"))})
+	}
+	if config.CommonCrawlDomain != "" {
+		sources = append(sources, &Source{Name: "commoncrawl", query: config.CommonCrawlDomain, fetch: commonCrawl()})
+	}
+	return sources, nil
+}
+
+// Collect returns unique targets of all sources, at most -source-limit from
+// each (0 means no limit).
+func Collect(config config.Config, sources []*Source) ([]string, error) {
+	client, err := newClient(config)
+	if err != nil {
+		return nil, err
+	}
+	var targets []string
+	seen := make(map[string]bool)
+	for _, s := range sources {
+		count := 0
+		cursor := ""
+		for {
+			page, next, err := s.fetch(client, s.query, cursor)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", s.Name, err)
+			}
+			for _, t := range page {
+				if limit := config.SourceLimit; limit > 0 && count >= limit {
+					break
+				}
+				if !seen[t] {
+					seen[t] = true
+					targets = append(targets, t)
+					count++
+				}
+			}
+			if next == "" || len(page) == 0 || (config.SourceLimit > 0 && count >= config.SourceLimit) {
+				break
+			}
+			cursor = next
+		}
+		logger.Infof("Got %d targets from %s", count, s.Name)
+	}
+	return targets, nil
+}
+
+// newClient returns a client for API requests. It doesn't go through the
+// dumper's client, so API keys never end up in -http-log or -har.
+func newClient(config config.Config) (*http.Client, error) {
+	transport, err := httpclient.NewProxyTransport(config)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport, Timeout: config.RequestTimeout}, nil
+}
+
+// getBody returns the body of a successful API response.
+func getBody(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		// Ключ может быть в строке запроса, поэтому URL в ошибку не попадает
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned status %d: %.200s", resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// getJSON decodes the JSON response of an API request into v.
+func getJSON(client *http.Client, req *http.Request, v any) error {
+	body, err := getBody(client, req)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// target builds an input line from a scheme, address and port, adding the
+// virtual host name if it differs from the address.
+func target(scheme, addr string, port int, host string) string {
+	u := scheme + "://" + net.JoinHostPort(addr, strconv.Itoa(port))
+	if host != "" && host != addr && net.ParseIP(host) == nil && !strings.ContainsAny(host, "/:@[] ") {
+		u += " host=" + host
+	}
+	return u
+}
+
+func shodan(key string) func(client *http.Client, query, cursor string) ([]string, string, error) {
+	return func(client *http.Client, query, cursor string) ([]string, string, error) {
+		page := 1
+		if cursor != "" {
+			page, _ = strconv.Atoi(cursor)
+		}
+		params := url.Values{"key": {key}, "query": {query}, "page": {strconv.Itoa(page)}}
+		req, err := http.NewRequest("GET", "https://api.shodan.io/shodan/host/search?"+params.Encode(), nil)
+		if err != nil {
+			return nil, "", err
+		}
+		var result struct {
+			Matches []struct {
+				IP   string          `json:"ip_str"`
+				Port int             `json:"port"`
+				SSL  json.RawMessage `json:"ssl"`
+				HTTP struct {
+					Host string `json:"host"`
+				} `json:"http"`
+			} `json:"matches"`
+			Total int `json:"total"`
+		}
+		if err := getJSON(client, req, &result); err != nil {
+			return nil, "", err
+		}
+		var targets []string
+		for _, m := range result.Matches {
+			scheme := "http"
+			if len(m.SSL) > 0 && string(m.SSL) != "null" {
+				scheme = "https"
+			}
+			targets = append(targets, target(scheme, m.IP, m.Port, m.HTTP.Host))
+		}
+		// Shodan отдаёт по 100 результатов на страницу
+		if page*100 >= result.Total {
+			return targets, "", nil
+		}
+		return targets, strconv.Itoa(page + 1), nil
+	}
+}
+
+func censys(id, secret string) func(client *http.Client, query, cursor string) ([]string, string, error) {
+	return func(client *http.Client, query, cursor string) ([]string, string, error) {
+		params := url.Values{"q": {query}, "per_page": {"100"}}
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+		req, err := http.NewRequest("GET", "https://search.censys.io/api/v2/hosts/search?"+params.Encode(), nil)
+		if err != nil {
+			return nil, "", err
+		}
+		req.SetBasicAuth(id, secret)
+		var result struct {
+			Result struct {
+				Hits []struct {
+					IP       string `json:"ip"`
+					Name     string `json:"name"` // Только для виртуальных хостов
+					Services []struct {
+						Port         int    `json:"port"`
+						ServiceName  string `json:"service_name"`
+						ExtendedName string `json:"extended_service_name"`
+					} `json:"services"`
+				} `json:"hits"`
+				Links struct {
+					Next string `json:"next"`
+				} `json:"links"`
+			} `json:"result"`
+		}
+		if err := getJSON(client, req, &result); err != nil {
+			return nil, "", err
+		}
+		var targets []string
+		for _, hit := range result.Result.Hits {
+			for _, s := range hit.Services {
+				if s.ServiceName != "HTTP" {
+					continue
+				}
+				scheme := "http"
+				if s.ExtendedName == "HTTPS" {
+					scheme = "https"
+				}
+				targets = append(targets, target(scheme, hit.IP, s.Port, hit.Name))
+			}
+		}
+		return targets, result.Result.Links.Next, nil
+	}
+}
+
+func fofa(email, key string) func(client *http.Client, query, cursor string) ([]string, string, error) {
+	return func(client *http.Client, query, cursor string) ([]string, string, error) {
+		page := 1
+		if cursor != "" {
+			page, _ = strconv.Atoi(cursor)
+		}
+		params := url.Values{
+			"key":     {key},
+			"qbase64": {base64.StdEncoding.EncodeToString([]byte(query))},
+			"fields":  {"host,ip,port,protocol"},
+			"size":    {"100"},
+			"page":    {strconv.Itoa(page)},
+		}
+		if email != "" {
+			params.Set("email", email)
+		}
+		req, err := http.NewRequest("GET", "https://fofa.info/api/v1/search/all?"+params.Encode(), nil)
+		if err != nil {
+			return nil, "", err
+		}
+		var result struct {
+			Error   bool       `json:"error"`
+			Message string     `json:"errmsg"`
+			Size    int        `json:"size"`
+			Results [][]string `json:"results"`
+		}
+		if err := getJSON(client, req, &result); err != nil {
+			return nil, "", err
+		}
+		if result.Error {
+			return nil, "", fmt.Errorf("API error: %s", result.Message)
+		}
+		var targets []string
+		for _, r := range result.Results {
+			if len(r) < 4 {
+				continue
+			}
+			port, err := strconv.Atoi(r[2])
+			if err != nil {
+				continue
+			}
+			scheme := "http"
+			if r[3] == "https" {
+				scheme = "https"
+			}
+			// host — это "домен:порт" или "https://домен:порт"
+			host := r[0]
+			if i := strings.Index(host, "://"); i != -1 {
+				host = host[i+3:]
+			}
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			targets = append(targets, target(scheme, r[1], port, host))
+		}
+		if page*100 >= result.Size {
+			return targets, "", nil
+		}
+		return targets, strconv.Itoa(page + 1), nil
+	}
+}
@@ -0,0 +1,184 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/httpclient"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/utils"
+	"golang.org/x/net/publicsuffix"
+)
+
+// ExpandSubdomains adds subdomains of apex domains among targets, taken from
+// the -subdomains wordlist and, with -crtsh, from certificate transparency
+// logs. Only names which resolve are added; they keep the scheme and port of
+// the apex target. Names answered by a wildcard DNS catch-all are dropped
+// unless -keep-wildcard-subdomains is set.
+func ExpandSubdomains(config config.Config, targets []string) ([]string, error) {
+	if config.SubdomainWordlist == "" && !config.CrtSh {
+		return targets, nil
+	}
+	var words []string
+	if config.SubdomainWordlist != "" {
+		lines, err := utils.ReadLines(config.SubdomainWordlist)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read subdomain wordlist: %w", err)
+		}
+		for _, line := range lines {
+			if word := strings.ToLower(strings.TrimSpace(line)); word != "" && !strings.HasPrefix(word, "#") {
+				words = append(words, word)
+			}
+		}
+	}
+	client, err := newClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, t := range targets {
+		seen[t] = true
+	}
+	var candidates []string
+	for _, t := range targets {
+		u, apex := apexTarget(t)
+		if u == nil {
+			continue
+		}
+		names := make(map[string]bool)
+		for _, word := range words {
+			names[word+"."+apex] = true
+		}
+		if config.CrtSh {
+			found, err := crtSh(client, apex)
+			if err != nil {
+				logger.Errorf("Failed to query crt.sh for %s: %v", apex, err)
+			}
+			for _, name := range found {
+				names[name] = true
+			}
+		}
+		for name := range names {
+			sub := *u
+			if port := u.Port(); port != "" {
+				sub.Host = net.JoinHostPort(name, port)
+			} else {
+				sub.Host = name
+			}
+			if s := sub.String(); !seen[s] {
+				seen[s] = true
+				candidates = append(candidates, s)
+			}
+		}
+	}
+
+	found := resolvable(config, candidates)
+	logger.Infof("Found %d resolvable subdomains out of %d candidates", len(found), len(candidates))
+	if !config.KeepWildcardSubs {
+		found = dropCatchAll(client, config, found)
+	}
+	return append(targets, found...), nil
+}
+
+// apexTarget returns the parsed target and its host if the target is the root
+// of a registrable domain such as example.com or example.co.uk.
+func apexTarget(target string) (*url.URL, string) {
+	fields := strings.Fields(target)
+	// Цели с опциями (host=) указывают на конкретный адрес, их не расширяем
+	if len(fields) != 1 {
+		return nil, ""
+	}
+	raw := fields[0]
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Path != "" && u.Path != "/") {
+		return nil, ""
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" || net.ParseIP(host) != nil {
+		return nil, ""
+	}
+	if apex, err := publicsuffix.EffectiveTLDPlusOne(host); err != nil || apex != host {
+		return nil, ""
+	}
+	return u, host
+}
+
+// crtSh returns names under apex from certificates logged in crt.sh.
+func crtSh(client *http.Client, apex string) ([]string, error) {
+	params := url.Values{"q": {"%." + apex}, "output": {"json"}}
+	req, err := http.NewRequest("GET", "https://crt.sh/?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var certs []struct {
+		NameValue string `json:"name_value"`
+	}
+	if err := getJSON(client, req, &certs); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, cert := range certs {
+		// В name_value перечислены все имена сертификата через перевод строки
+		for _, name := range strings.Split(cert.NameValue, "\n") {
+			name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "*."))
+			if strings.HasSuffix(name, "."+apex) && !strings.ContainsAny(name, "*/:@ ") {
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// resolvable returns targets whose host names resolve, looking them up in
+// parallel with -w workers. When the -proxy resolves names, local lookups
+// would leak them, so every target is kept.
+func resolvable(config config.Config, targets []string) []string {
+	if httpclient.ProxyResolvesNames(config.ProxyUrl) {
+		logger.Debugf("Not resolving %d subdomains locally: the proxy resolves names", len(targets))
+		return targets
+	}
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		found []string
+	)
+	jobs := make(chan string)
+	for i := 0; i < max(config.WorkersNum, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range jobs {
+				u, err := url.Parse(t)
+				if err != nil {
+					continue
+				}
+				ctx, cancel := context.WithTimeout(context.Background(), config.DialTimeout)
+				_, err = net.DefaultResolver.LookupHost(ctx, u.Hostname())
+				cancel()
+				if err != nil {
+					logger.Debugf("Subdomain %s doesn't resolve: %v", u.Hostname(), err)
+					continue
+				}
+				mu.Lock()
+				found = append(found, t)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, t := range targets {
+		jobs <- t
+	}
+	close(jobs)
+	wg.Wait()
+	return found
+}
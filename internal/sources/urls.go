@@ -0,0 +1,159 @@
+package sources
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// maxPathDepth limits directories of a seen URL which are probed for .git.
+const maxPathDepth = 2
+
+// urlTargets returns .git URLs of the site root and the parent directories of
+// a URL seen by urlscan.io or Common Crawl, e.g. for
+// https://example.com/app/admin/login.php: /.git/, /app/.git/ and
+// /app/admin/.git/.
+func urlTargets(rawUrl string) []string {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Hostname() == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil
+	}
+	root := u.Scheme + "://" + strings.ToLower(u.Host) + "/"
+	targets := []string{root + ".git/"}
+	segments := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+	// Последний сегмент — файл, если путь не оканчивается на /
+	if !strings.HasSuffix(u.EscapedPath(), "/") {
+		segments = segments[:len(segments)-1]
+	}
+	dir := root
+	for i, segment := range segments {
+		if i == maxPathDepth || segment == "" || segment == "." || segment == ".." || segment == ".git" {
+			break
+		}
+		dir += segment + "/"
+		targets = append(targets, dir+".git/")
+	}
+	return targets
+}
+
+func urlscan(key string) func(client *http.Client, query, cursor string) ([]string, string, error) {
+	return func(client *http.Client, query, cursor string) ([]string, string, error) {
+		params := url.Values{"q": {query}, "size": {"100"}}
+		if cursor != "" {
+			params.Set("search_after", cursor)
+		}
+		req, err := http.NewRequest("GET", "https://urlscan.io/api/v1/search/?"+params.Encode(), nil)
+		if err != nil {
+			return nil, "", err
+		}
+		if key != "" {
+			req.Header.Set("API-Key", key)
+		}
+		var result struct {
+			Results []struct {
+				Page struct {
+					Url string `json:"url"`
+				} `json:"page"`
+				Task struct {
+					Url string `json:"url"`
+				} `json:"task"`
+				Sort []any `json:"sort"`
+			} `json:"results"`
+			HasMore bool `json:"has_more"`
+		}
+		if err := getJSON(client, req, &result); err != nil {
+			return nil, "", err
+		}
+		var targets []string
+		for _, r := range result.Results {
+			targets = append(targets, urlTargets(r.Task.Url)...)
+			if r.Page.Url != r.Task.Url {
+				targets = append(targets, urlTargets(r.Page.Url)...)
+			}
+		}
+		if !result.HasMore || len(result.Results) == 0 {
+			return targets, "", nil
+		}
+		// Следующая страница начинается после sort-ключа последнего результата
+		var after []string
+		for _, v := range result.Results[len(result.Results)-1].Sort {
+			after = append(after, fmt.Sprint(v))
+		}
+		return targets, strings.Join(after, ","), nil
+	}
+}
+
+// commonCrawl queries the index of the latest Common Crawl collection for URLs
+// of a domain and its subdomains.
+func commonCrawl() func(client *http.Client, domain, cursor string) ([]string, string, error) {
+	var api string
+	var pages int
+	return func(client *http.Client, domain, cursor string) ([]string, string, error) {
+		params := url.Values{"url": {"*." + strings.TrimPrefix(domain, "*.")}, "output": {"json"}, "fl": {"url"}}
+		if api == "" {
+			req, err := http.NewRequest("GET", "https://index.commoncrawl.org/collinfo.json", nil)
+			if err != nil {
+				return nil, "", err
+			}
+			var collections []struct {
+				CdxApi string `json:"cdx-api"`
+			}
+			if err := getJSON(client, req, &collections); err != nil {
+				return nil, "", err
+			}
+			if len(collections) == 0 {
+				return nil, "", fmt.Errorf("no collections")
+			}
+			// Первая коллекция в списке — самая свежая
+			api = collections[0].CdxApi
+
+			params.Set("showNumPages", "true")
+			req, err = http.NewRequest("GET", api+"?"+params.Encode(), nil)
+			if err != nil {
+				return nil, "", err
+			}
+			var info struct {
+				Pages int `json:"pages"`
+			}
+			if err := getJSON(client, req, &info); err != nil {
+				return nil, "", err
+			}
+			pages = info.Pages
+			params.Del("showNumPages")
+		}
+
+		page, _ := strconv.Atoi(cursor)
+		if page >= pages {
+			return nil, "", nil
+		}
+		params.Set("page", strconv.Itoa(page))
+		req, err := http.NewRequest("GET", api+"?"+params.Encode(), nil)
+		if err != nil {
+			return nil, "", err
+		}
+		body, err := getBody(client, req)
+		if err != nil {
+			return nil, "", err
+		}
+		var targets []string
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			var record struct {
+				Url string `json:"url"`
+			}
+			if json.Unmarshal(scanner.Bytes(), &record) == nil {
+				targets = append(targets, urlTargets(record.Url)...)
+			}
+		}
+		if page+1 >= pages {
+			return targets, "", nil
+		}
+		return targets, strconv.Itoa(page + 1), nil
+	}
+}
@@ -0,0 +1,265 @@
+package sources
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+	"github.com/s3rgeym/git-dump/internal/httpclient"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	// wildcardProbes is how many random labels are looked up under an apex
+	// domain; a catch-all behind a CDN may answer each with other addresses.
+	wildcardProbes = 2
+	// maxProbeBody limits the body of a .git/HEAD response compared with
+	// the one of the catch-all.
+	maxProbeBody = 64 << 10
+)
+
+var headRegex = regexp.MustCompile(`^(ref: refs/\S+|[0-9a-f]{40})\s*$`)
+
+// lookupHost resolves names of subdomains and random labels; tests replace it.
+var lookupHost = net.DefaultResolver.LookupHost
+
+// probeResponse is what a host answered to GET /.git/HEAD.
+type probeResponse struct {
+	status      int
+	contentType string
+	size        int
+	hash        string // Тела без имени хоста, которое заглушки часто вставляют
+	git         bool   // Тело похоже на настоящий HEAD
+}
+
+// same reports whether two responses look like pages of the same catch-all.
+// Sizes may differ slightly, since placeholder pages often have dates or
+// tokens in them.
+func (a *probeResponse) same(b *probeResponse) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.status != b.status || a.contentType != b.contentType || a.git != b.git {
+		return false
+	}
+	if a.hash == b.hash {
+		return true
+	}
+	diff := a.size - b.size
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= 64 || diff*20 <= max(a.size, b.size)
+}
+
+// probeHead fetches /.git/HEAD of the site of rawUrl. It returns nil if the
+// host doesn't answer.
+func probeHead(client *http.Client, config config.Config, rawUrl string) *probeResponse {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil
+	}
+	u.Path, u.RawQuery, u.Fragment = "/.git/HEAD", "", ""
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("User-Agent", config.UserAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		logger.Debugf("No response from %s: %v", u, err)
+		return nil
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxProbeBody))
+	contentType, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	sum := sha256.Sum256(bytes.ReplaceAll(body, []byte(u.Hostname()), nil))
+	return &probeResponse{
+		status:      resp.StatusCode,
+		contentType: strings.TrimSpace(contentType),
+		size:        len(body),
+		hash:        hex.EncodeToString(sum[:]),
+		git:         resp.StatusCode == http.StatusOK && headRegex.Match(body),
+	}
+}
+
+// randomLabel returns a label no real subdomain is likely to have.
+func randomLabel() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "gd-" + hex.EncodeToString(b)
+}
+
+// catchAll is the wildcard DNS record of an apex domain and what its hosts
+// answer.
+type catchAll struct {
+	ips  map[string]bool // nil, если имена разрешает прокси
+	resp *probeResponse
+}
+
+// findCatchAll looks up random labels under the apex of sample, a subdomain
+// target, and returns the catch-all if they resolve. When the -proxy resolves
+// names, a random host answering HTTP means a catch-all, unless the answer is
+// a gateway error of the proxy itself, e.g. 502 for an unknown host.
+func findCatchAll(client *http.Client, config config.Config, sample *url.URL, apex string) *catchAll {
+	viaProxy := httpclient.ProxyResolvesNames(config.ProxyUrl)
+	c := &catchAll{}
+	var host string
+	for i := 0; i < wildcardProbes; i++ {
+		host = randomLabel() + "." + apex
+		if viaProxy {
+			break
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), config.DialTimeout)
+		addrs, err := lookupHost(ctx, host)
+		cancel()
+		if err != nil {
+			return nil
+		}
+		if c.ips == nil {
+			c.ips = make(map[string]bool)
+		}
+		for _, addr := range addrs {
+			c.ips[addr] = true
+		}
+	}
+	u := *sample
+	if port := sample.Port(); port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else {
+		u.Host = host
+	}
+	c.resp = probeHead(client, config, u.String())
+	if viaProxy && (c.resp == nil || isGatewayError(c.resp.status)) {
+		return nil
+	}
+	return c
+}
+
+func isGatewayError(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// dropCatchAll removes subdomain targets answered by the wildcard DNS
+// catch-all of their apex domain: those which resolve only to its addresses
+// and answer /.git/HEAD like a random name does. If the catch-all itself
+// serves a repository, the first such target is kept to dump it once.
+func dropCatchAll(client *http.Client, config config.Config, targets []string) []string {
+	byApex := make(map[string][]string)
+	var apexes []string
+	for _, t := range targets {
+		u, err := url.Parse(t)
+		if err != nil {
+			continue
+		}
+		apex, err := publicsuffix.EffectiveTLDPlusOne(u.Hostname())
+		if err != nil {
+			continue
+		}
+		if _, ok := byApex[apex]; !ok {
+			apexes = append(apexes, apex)
+		}
+		byApex[apex] = append(byApex[apex], t)
+	}
+
+	var kept []string
+	for _, apex := range apexes {
+		subs := byApex[apex]
+		sample, _ := url.Parse(subs[0])
+		c := findCatchAll(client, config, sample, apex)
+		if c == nil {
+			kept = append(kept, subs...)
+			continue
+		}
+		logger.Infof("%s has wildcard DNS, comparing %d subdomains with its catch-all", apex, len(subs))
+
+		var mu sync.Mutex
+		keep := make(map[string]bool)
+		gitKept := false
+		parallel(config.WorkersNum, subs, func(t string) {
+			if !c.answers(client, config, t) {
+				mu.Lock()
+				keep[t] = true
+				mu.Unlock()
+				return
+			}
+			if c.resp != nil && c.resp.git {
+				mu.Lock()
+				if !gitKept {
+					gitKept, keep[t] = true, true
+				}
+				mu.Unlock()
+			}
+		})
+		dropped := 0
+		for _, t := range subs {
+			if keep[t] {
+				kept = append(kept, t)
+			} else {
+				dropped++
+			}
+		}
+		if dropped > 0 {
+			logger.Warnf("Skipping %d of %d subdomains of %s answered by its wildcard DNS catch-all", dropped, len(subs), apex)
+		}
+		if gitKept {
+			logger.Warnf("The wildcard DNS catch-all of %s serves .git/HEAD; it is scanned once", apex)
+		}
+	}
+	return kept
+}
+
+// answers reports whether the catch-all answers for the target: the target
+// resolves only to catch-all addresses and its /.git/HEAD looks the same.
+func (c *catchAll) answers(client *http.Client, config config.Config, target string) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	if c.ips != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), config.DialTimeout)
+		addrs, err := lookupHost(ctx, u.Hostname())
+		cancel()
+		if err != nil {
+			return false
+		}
+		for _, addr := range addrs {
+			// Свой адрес означает отдельную запись DNS, а не wildcard
+			if !c.ips[addr] {
+				return false
+			}
+		}
+	}
+	return c.resp.same(probeHead(client, config, target))
+}
+
+// parallel calls fn for every item with up to workers goroutines.
+func parallel(workers int, items []string, fn func(string)) {
+	var wg sync.WaitGroup
+	jobs := make(chan string)
+	for i := 0; i < max(workers, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				fn(item)
+			}
+		}()
+	}
+	for _, item := range items {
+		jobs <- item
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,99 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/config"
+)
+
+func TestDropCatchAll(t *testing.T) {
+	// HTTP-прокси сам разрешает имена, поэтому catch-all определяется по ответам
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.URL.Hostname()
+		switch {
+		case host == "dev.example.com":
+			fmt.Fprint(w, "ref: refs/heads/main\n")
+		case strings.HasSuffix(host, ".example.com"):
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "<html>%s is parked, generated %d</html>", host, time.Now().UnixNano()%1000)
+		case host == "www.example.org":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			http.Error(w, "no such host", http.StatusBadGateway)
+		}
+	}))
+	defer proxy.Close()
+
+	cfg := config.Config{ProxyUrl: proxy.URL, WorkersNum: 4, DialTimeout: time.Second, RequestTimeout: 5 * time.Second}
+	client, err := newClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ошибка 502 самого прокси для случайного имени не означает catch-all
+	targets := []string{"http://www.example.com", "http://dev.example.com", "http://mail.example.com", "http://www.example.org", "http://down.example.net"}
+	got := dropCatchAll(client, cfg, targets)
+	want := []string{"http://dev.example.com", "http://www.example.org", "http://down.example.net"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dropCatchAll() = %v, want %v", got, want)
+	}
+}
+
+func TestDropCatchAllLocalDNS(t *testing.T) {
+	// Случайные имена example.com отдаются разными адресами CDN
+	var mu sync.Mutex
+	probes := 0
+	records := map[string][]string{
+		"www.example.com":  {"10.0.0.1"},
+		"mail.example.com": {"10.0.0.2"},
+		"dev.example.com":  {"10.0.0.1"},
+		"own.example.com":  {"10.0.0.9"},
+		"both.example.com": {"10.0.0.1", "10.0.0.9"},
+		"www.example.org":  {"10.0.1.1"},
+	}
+	lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		if strings.HasSuffix(host, ".example.com") && strings.HasPrefix(host, "gd-") {
+			mu.Lock()
+			defer mu.Unlock()
+			probes++
+			return []string{fmt.Sprintf("10.0.0.%d", probes%2+1)}, nil
+		}
+		if addrs, ok := records[host]; ok {
+			return addrs, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	defer func() { lookupHost = net.DefaultResolver.LookupHost }()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host == "dev.example.com" {
+			fmt.Fprint(w, "ref: refs/heads/main\n")
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "<html>%s is parked</html>", r.Host)
+	}))
+	defer srv.Close()
+	// Все имена ведут на тестовый сервер, адреса берутся только из lookupHost
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+		},
+	}}
+
+	cfg := config.Config{WorkersNum: 4, DialTimeout: time.Second}
+	targets := []string{"http://www.example.com", "http://mail.example.com", "http://dev.example.com", "http://own.example.com", "http://both.example.com", "http://www.example.org"}
+	got := dropCatchAll(client, cfg, targets)
+	want := []string{"http://dev.example.com", "http://own.example.com", "http://both.example.com", "http://www.example.org"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dropCatchAll() = %v, want %v", got, want)
+	}
+}
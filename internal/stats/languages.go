@@ -0,0 +1,65 @@
+package stats
+
+import (
+	"path"
+	"strings"
+)
+
+// languages maps file extensions to language names, like GitHub linguist
+// does for the most common ones.
+var languages = map[string]string{
+	".c": "C", ".h": "C",
+	".cc": "C++", ".cpp": "C++", ".cxx": "C++", ".hpp": "C++",
+	".cs":   "C#",
+	".css":  "CSS",
+	".scss": "SCSS", ".sass": "SCSS",
+	".dart": "Dart",
+	".ex":   "Elixir", ".exs": "Elixir",
+	".erl":    "Erlang",
+	".go":     "Go",
+	".groovy": "Groovy", ".gradle": "Groovy",
+	".hs":   "Haskell",
+	".html": "HTML", ".htm": "HTML",
+	".java": "Java",
+	".js":   "JavaScript", ".jsx": "JavaScript", ".mjs": "JavaScript", ".cjs": "JavaScript",
+	".json": "JSON",
+	".kt":   "Kotlin", ".kts": "Kotlin",
+	".lua": "Lua",
+	".md":  "Markdown",
+	".m":   "Objective-C",
+	".pl":  "Perl", ".pm": "Perl",
+	".php": "PHP", ".php4": "PHP", ".php5": "PHP", ".phtml": "PHP",
+	".ps1": "PowerShell",
+	".py":  "Python",
+	".r":   "R",
+	".rb":  "Ruby", ".erb": "Ruby",
+	".rs":    "Rust",
+	".scala": "Scala",
+	".sh":    "Shell", ".bash": "Shell", ".zsh": "Shell",
+	".sql":   "SQL",
+	".swift": "Swift",
+	".tf":    "HCL",
+	".ts":    "TypeScript", ".tsx": "TypeScript",
+	".twig": "Twig",
+	".vue":  "Vue",
+	".xml":  "XML",
+	".yaml": "YAML", ".yml": "YAML",
+}
+
+// fileLanguages are languages of files without a telling extension.
+var fileLanguages = map[string]string{
+	"dockerfile":  "Dockerfile",
+	"makefile":    "Makefile",
+	"gemfile":     "Ruby",
+	"rakefile":    "Ruby",
+	"jenkinsfile": "Groovy",
+}
+
+// LanguageOf returns the language of a file or "" if it isn't known.
+func LanguageOf(file string) string {
+	name := strings.ToLower(path.Base(file))
+	if lang, ok := fileLanguages[name]; ok {
+		return lang
+	}
+	return languages[path.Ext(name)]
+}
@@ -0,0 +1,172 @@
+package stats
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/gitindex"
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+)
+
+// Count is a name with a number of commits.
+type Count struct {
+	Name    string `json:"name"`
+	Commits int    `json:"commits"`
+}
+
+// File is a file of the working tree with its size.
+type File struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// Language is the share of a language in the working tree.
+type Language struct {
+	Name  string `json:"name"`
+	Files int    `json:"files"`
+	Bytes int64  `json:"bytes"`
+}
+
+// Stats summarizes a recovered repository.
+type Stats struct {
+	GitDir       string     `json:"git_dir"`
+	Branches     int        `json:"branches"`
+	Tags         int        `json:"tags"`
+	Commits      int        `json:"commits"`
+	FirstCommit  time.Time  `json:"first_commit,omitempty"`
+	LastCommit   time.Time  `json:"last_commit,omitempty"`
+	Committers   []Count    `json:"committers,omitempty"`
+	Files        int        `json:"files"`
+	LargestFiles []File     `json:"largest_files,omitempty"`
+	Languages    []Language `json:"languages,omitempty"`
+}
+
+// Collect gathers statistics of a .git directory. Commits are counted from
+// all refs and HEAD as far as they were recovered; files are taken from the
+// index, or from the tree of HEAD if there is no index. top limits the
+// committer and largest file lists.
+func Collect(gitDir string, top int) (*Stats, error) {
+	repo, err := gitobj.Open(gitDir)
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Close()
+
+	s := &Stats{GitDir: gitDir}
+	refs, err := repo.Refs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read refs: %w", err)
+	}
+	var tips []string
+	for name, hash := range refs {
+		switch {
+		case strings.HasPrefix(name, "refs/heads/"):
+			s.Branches++
+		case strings.HasPrefix(name, "refs/tags/"):
+			s.Tags++
+		}
+		tips = append(tips, hash)
+	}
+	head, _ := repo.Head()
+	if head != "" {
+		tips = append(tips, head)
+	}
+
+	authors := make(map[string]int)
+	for _, commit := range repo.Log(tips, 0) {
+		s.Commits++
+		when := commit.Committer.When
+		if s.FirstCommit.IsZero() || when.Before(s.FirstCommit) {
+			s.FirstCommit = when
+		}
+		if when.After(s.LastCommit) {
+			s.LastCommit = when
+		}
+		authors[fmt.Sprintf("%s <%s>", commit.Author.Name, commit.Author.Email)]++
+	}
+	for name, n := range authors {
+		s.Committers = append(s.Committers, Count{Name: name, Commits: n})
+	}
+	sort.Slice(s.Committers, func(i, j int) bool {
+		a, b := s.Committers[i], s.Committers[j]
+		return a.Commits > b.Commits || a.Commits == b.Commits && a.Name < b.Name
+	})
+	s.Committers = truncate(s.Committers, top)
+
+	files := indexFiles(gitDir)
+	if files == nil && head != "" {
+		files = treeFiles(repo, head)
+	}
+	s.Files = len(files)
+	langs := make(map[string]*Language)
+	for _, f := range files {
+		name := LanguageOf(f.Path)
+		if name == "" {
+			continue
+		}
+		lang := langs[name]
+		if lang == nil {
+			lang = &Language{Name: name}
+			langs[name] = lang
+		}
+		lang.Files++
+		lang.Bytes += f.Size
+	}
+	for _, lang := range langs {
+		s.Languages = append(s.Languages, *lang)
+	}
+	sort.Slice(s.Languages, func(i, j int) bool {
+		a, b := s.Languages[i], s.Languages[j]
+		return a.Bytes > b.Bytes || a.Bytes == b.Bytes && a.Name < b.Name
+	})
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Size > files[j].Size || files[i].Size == files[j].Size && files[i].Path < files[j].Path
+	})
+	s.LargestFiles = truncate(files, top)
+	return s, nil
+}
+
+// indexFiles returns the files listed in the index or nil without an index.
+func indexFiles(gitDir string) []File {
+	index, err := gitindex.ParseGitIndex(filepath.Join(gitDir, "index"))
+	if err != nil {
+		return nil
+	}
+	files := make([]File, 0, len(index.Entries))
+	for _, entry := range index.Files() {
+		if entry.Mode&0170000 == 0160000 {
+			continue
+		}
+		files = append(files, File{Path: entry.FileName, Size: int64(entry.Size)})
+	}
+	return files
+}
+
+// treeFiles returns the files of a commit which were recovered.
+func treeFiles(repo *gitobj.Repo, commitHash string) []File {
+	commit, err := repo.ReadCommit(commitHash)
+	if err != nil {
+		return nil
+	}
+	var files []File
+	repo.WalkTree(commit.Tree, "", func(path string, entry gitobj.TreeEntry) error {
+		if entry.IsSubmodule() {
+			return nil
+		}
+		if obj, err := repo.ReadObject(entry.Hash); err == nil {
+			files = append(files, File{Path: path, Size: int64(len(obj.Data))})
+		}
+		return nil
+	})
+	return files
+}
+
+func truncate[T any](list []T, n int) []T {
+	if n > 0 && len(list) > n {
+		return list[:n]
+	}
+	return list
+}
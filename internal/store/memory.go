@@ -0,0 +1,84 @@
+package store
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Memory keeps files in memory. It is meant for tests.
+type Memory struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	perms map[string]os.FileMode
+}
+
+func NewMemory() *Memory {
+	return &Memory{files: make(map[string][]byte), perms: make(map[string]os.FileMode)}
+}
+
+func (m *Memory) Create(name string, perm os.FileMode) (Writer, error) {
+	return &memWriter{m: m, name: name, perm: perm}, nil
+}
+
+func (m *Memory) Open(name string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *Memory) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(m.files, name)
+	delete(m.perms, name)
+	return nil
+}
+
+// Names returns the sorted names of saved files.
+func (m *Memory) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Perm returns the mode name was saved with.
+func (m *Memory) Perm(name string) os.FileMode {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.perms[name]
+}
+
+type memWriter struct {
+	bytes.Buffer
+	m    *Memory
+	name string
+	perm os.FileMode
+}
+
+func (w *memWriter) Commit() error {
+	w.m.mu.Lock()
+	defer w.m.mu.Unlock()
+	w.m.files[w.name] = bytes.Clone(w.Bytes())
+	w.m.perms[w.name] = w.perm
+	return nil
+}
+
+func (w *memWriter) Abort() {
+	w.Reset()
+}
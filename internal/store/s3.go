@@ -0,0 +1,297 @@
+package store
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/logger"
+)
+
+// S3 saves files to a local store and uploads them to an S3 compatible
+// bucket under the same path relative to the output directory. Credentials
+// and the endpoint are taken from the usual AWS_* environment variables.
+type S3 struct {
+	local     Store
+	root      string
+	bucket    string
+	prefix    string
+	endpoint  *url.URL
+	region    string
+	accessKey string
+	secretKey string
+	token     string
+	client    *http.Client
+
+	mu       sync.Mutex
+	uploaded map[string]time.Time // Ключ -> время изменения загруженного файла
+}
+
+// NewS3 parses s3://bucket/prefix and reads the credentials.
+func NewS3(spec, root string, local Store) (*S3, error) {
+	u, err := url.Parse(spec)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 location %q: expected s3://bucket/prefix", spec)
+	}
+	s := &S3{
+		local:     local,
+		root:      root,
+		bucket:    u.Host,
+		prefix:    strings.Trim(u.Path, "/"),
+		region:    firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+		accessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		token:     os.Getenv("AWS_SESSION_TOKEN"),
+		client:    &http.Client{Timeout: 10 * time.Minute},
+		uploaded:  make(map[string]time.Time),
+	}
+	if s.accessKey == "" || s.secretKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set for %s", spec)
+	}
+	if s.region == "" {
+		s.region = "us-east-1"
+	}
+	endpoint := firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL")
+	if endpoint == "" {
+		endpoint = "https://s3." + s.region + ".amazonaws.com"
+	}
+	if s.endpoint, err = url.Parse(endpoint); err != nil || s.endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+	}
+	return s, nil
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func (s *S3) Create(name string, perm os.FileMode) (Writer, error) {
+	w, err := s.local.Create(name, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Writer{Writer: w, s: s, name: name}, nil
+}
+
+func (s *S3) Open(name string) (io.ReadCloser, error) {
+	return s.local.Open(name)
+}
+
+func (s *S3) Remove(name string) error {
+	if err := s.local.Remove(name); err != nil {
+		return err
+	}
+	key, ok := s.key(name)
+	if !ok {
+		return nil
+	}
+	s.mu.Lock()
+	delete(s.uploaded, key)
+	s.mu.Unlock()
+	if err := s.do(http.MethodDelete, key, nil, 0, emptySha256); err != nil {
+		logger.Errorf("Failed to delete s3://%s/%s: %v", s.bucket, key, err)
+	}
+	return nil
+}
+
+// Sync uploads files below dir which are new or changed since their upload.
+func (s *S3) Sync(dir string) error {
+	return filepath.WalkDir(dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() || strings.HasSuffix(name, PartSuffix) {
+			return nil
+		}
+		s.upload(name)
+		return nil
+	})
+}
+
+type s3Writer struct {
+	Writer
+	s    *S3
+	name string
+}
+
+func (w *s3Writer) Commit() error {
+	if err := w.Writer.Commit(); err != nil {
+		return err
+	}
+	// Локальная копия уже сохранена, ошибка выгрузки не прерывает дамп
+	w.s.upload(w.name)
+	return nil
+}
+
+// key returns the object key of a file in the output directory.
+func (s *S3) key(name string) (string, bool) {
+	rel, err := filepath.Rel(s.root, name)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	key := filepath.ToSlash(rel)
+	if s.prefix != "" {
+		key = s.prefix + "/" + key
+	}
+	return key, true
+}
+
+func (s *S3) upload(name string) {
+	key, ok := s.key(name)
+	if !ok {
+		logger.Warnf("Not uploading %s: it is outside of %s", name, s.root)
+		return
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	done := s.uploaded[key].Equal(fi.ModTime())
+	s.mu.Unlock()
+	if done {
+		return
+	}
+
+	sum, err := fileSha256(name)
+	if err != nil {
+		logger.Errorf("Failed to hash %s: %v", name, err)
+		return
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		logger.Errorf("Failed to open %s: %v", name, err)
+		return
+	}
+	defer f.Close()
+	if err := s.do(http.MethodPut, key, f, fi.Size(), sum); err != nil {
+		logger.Errorf("Failed to upload %s to s3://%s/%s: %v", name, s.bucket, key, err)
+		return
+	}
+	logger.Debugf("Uploaded %s to s3://%s/%s", name, s.bucket, key)
+	s.mu.Lock()
+	s.uploaded[key] = fi.ModTime()
+	s.mu.Unlock()
+}
+
+const emptySha256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func (s *S3) do(method, key string, body io.Reader, size int64, payloadHash string) error {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+	u.RawPath = s3Escape(u.Path)
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if body == nil {
+		req.Body = http.NoBody
+	}
+	if s.token != "" {
+		req.Header.Set("X-Amz-Security-Token", s.token)
+	}
+	s.sign(req, payloadHash, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header covering the
+// host and every header already set on req.
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3Escape(req.URL.Path),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSha256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSha256(key, s.region)
+	key = hmacSha256(key, "s3")
+	key = hmacSha256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Escape percent-encodes a path as S3 expects: everything except
+// unreserved characters and slashes.
+func s3Escape(path string) string {
+	var sb strings.Builder
+	for _, b := range []byte(path) {
+		if 'A' <= b && b <= 'Z' || 'a' <= b && b <= 'z' || '0' <= b && b <= '9' || strings.IndexByte("-_.~/", b) >= 0 {
+			sb.WriteByte(b)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
+
+func fileSha256(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,125 @@
+// Package store saves the files of a dump. Parsers and git still read the
+// output directory, so every backend keeps files on disk; the content
+// addressed one shares identical files and the S3 one uploads them too.
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PartSuffix is appended to files while they are being written.
+const PartSuffix = ".git-dump.part"
+
+// Store saves files by their path in the output directory.
+type Store interface {
+	// Create starts writing name. The file appears under name only after
+	// Commit, so interrupted writes never leave truncated files.
+	Create(name string, perm os.FileMode) (Writer, error)
+	Open(name string) (io.ReadCloser, error)
+	Remove(name string) error
+}
+
+// Writer is a file being written to a Store.
+type Writer interface {
+	io.Writer
+	Commit() error
+	Abort()
+}
+
+// Syncer is implemented by stores which must also see files written around
+// them, e.g. a working tree checked out by git.
+type Syncer interface {
+	Sync(dir string) error
+}
+
+// WriteFile saves data as name.
+func WriteFile(st Store, name string, data []byte, perm os.FileMode) error {
+	w, err := st.Create(name, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, bytes.NewReader(data)); err != nil {
+		w.Abort()
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return w.Commit()
+}
+
+// New returns the store for -store: fs, cas or s3://bucket/prefix.
+func New(spec, outputDir string) (Store, error) {
+	switch {
+	case spec == "" || spec == "fs":
+		return FS{}, nil
+	case spec == "cas":
+		return &CAS{Dir: filepath.Join(outputDir, ".cas")}, nil
+	case strings.HasPrefix(spec, "s3://"):
+		return NewS3(spec, outputDir, FS{})
+	}
+	return nil, fmt.Errorf("unknown store %q: expected fs, cas or s3://bucket/prefix", spec)
+}
+
+// FS writes files to the local filesystem.
+type FS struct{}
+
+func (FS) Create(name string, perm os.FileMode) (Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for file %s: %w", name, err)
+	}
+	file, err := createPart(name, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &fsWriter{File: file, name: name}, nil
+}
+
+// createPart creates a temporary file next to name. Its name is unique, so
+// two targets saving the same path don't write into one file.
+func createPart(name string, perm os.FileMode) (*os.File, error) {
+	file, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*"+PartSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file for %s: %w", name, err)
+	}
+	if err := file.Chmod(perm); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("failed to set mode of %s: %w", file.Name(), err)
+	}
+	return file, nil
+}
+
+func (FS) Open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (FS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+type fsWriter struct {
+	*os.File
+	name string
+}
+
+func (w *fsWriter) Commit() error {
+	partName := w.File.Name()
+	if err := w.File.Close(); err != nil {
+		os.Remove(partName)
+		return fmt.Errorf("failed to save file %s: %w", w.name, err)
+	}
+	// Переименование заменяет и символическую ссылку, а не файл, на который она указывает
+	if err := os.Rename(partName, w.name); err != nil {
+		os.Remove(partName)
+		return fmt.Errorf("failed to rename %s: %w", partName, err)
+	}
+	return nil
+}
+
+func (w *fsWriter) Abort() {
+	w.File.Close()
+	os.Remove(w.File.Name())
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readAll(t *testing.T, st Store, name string) string {
+	t.Helper()
+	r, err := st.Open(name)
+	if err != nil {
+		t.Fatalf("Open(%s) error = %v", name, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestFS(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "a", "b", "file")
+	if err := WriteFile(FS{}, name, []byte("data"), 0755); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if got := readAll(t, FS{}, name); got != "data" {
+		t.Fatalf("content = %q", got)
+	}
+	if fi, _ := os.Stat(name); fi.Mode().Perm()&0100 == 0 {
+		t.Fatalf("mode = %v, want executable", fi.Mode())
+	}
+
+	w, err := FS{}.Create(name, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("partial"))
+	w.Abort()
+	if got := readAll(t, FS{}, name); got != "data" {
+		t.Fatalf("content after Abort = %q", got)
+	}
+	if _, err := os.Stat(name + PartSuffix); !os.IsNotExist(err) {
+		t.Fatalf("part file left after Abort: %v", err)
+	}
+}
+
+func TestFSReplacesSymlink(t *testing.T) {
+	dir := t.TempDir()
+	outside := filepath.Join(dir, "outside")
+	os.WriteFile(outside, []byte("keep"), 0644)
+	name := filepath.Join(dir, "link")
+	if err := os.Symlink(outside, name); err != nil {
+		t.Skip(err)
+	}
+	if err := WriteFile(FS{}, name, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(outside); string(data) != "keep" {
+		t.Fatalf("write went through the symlink: %q", data)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	if err := WriteFile(m, "x/y", []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	w, _ := m.Create("x/z", 0644)
+	w.Write([]byte("lost"))
+	w.Abort()
+	if names := m.Names(); len(names) != 1 || names[0] != "x/y" {
+		t.Fatalf("Names() = %v", names)
+	}
+	if got := readAll(t, m, "x/y"); got != "data" || m.Perm("x/y") != 0600 {
+		t.Fatalf("content = %q, perm = %v", got, m.Perm("x/y"))
+	}
+	if err := m.Remove("x/y"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Open("x/y"); !os.IsNotExist(err) {
+		t.Fatalf("Open() after Remove error = %v", err)
+	}
+}
+
+func TestCASSharesContent(t *testing.T) {
+	dir := t.TempDir()
+	st, err := New("cas", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := filepath.Join(dir, "site1", "jquery.js")
+	b := filepath.Join(dir, "site2", "lib", "jquery.js")
+	c := filepath.Join(dir, "site3", "other.js")
+	for name, data := range map[string]string{a: "same", b: "same", c: "other"} {
+		if err := WriteFile(st, name, []byte(data), 0644); err != nil {
+			t.Fatalf("WriteFile(%s) error = %v", name, err)
+		}
+	}
+	fa, _ := os.Stat(a)
+	fb, _ := os.Stat(b)
+	fc, _ := os.Stat(c)
+	if !os.SameFile(fa, fb) {
+		t.Fatal("identical files are not shared")
+	}
+	if os.SameFile(fa, fc) {
+		t.Fatal("different files are shared")
+	}
+
+	// Перезапись одного имени не меняет остальные
+	if err := WriteFile(st, a, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, st, b); got != "same" {
+		t.Fatalf("content of the other name = %q", got)
+	}
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultLayout keeps the files of every host in output/<host>.
+const DefaultLayout = "{{.Host}}"
+
+// LayoutFields are the values available to -layout templates.
+type LayoutFields struct {
+	Scheme string
+	Host   string
+	Port   string
+	Date   string // Start of the run, 2006-01-02
+	Time   string // Start of the run, 150405 (UTC)
+}
+
+// Layout places the directories of dumped hosts inside the output directory.
+type Layout struct {
+	text  string
+	tmpl  *template.Template
+	start time.Time
+}
+
+// ParseLayout compiles a -layout template. start is used for the Date and
+// Time fields so that all targets of a run end up in the same directory.
+func ParseLayout(text string, start time.Time) (*Layout, error) {
+	if strings.TrimSpace(text) == "" {
+		text = DefaultLayout
+	}
+	tmpl, err := template.New("layout").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid layout %q: %w", text, err)
+	}
+	l := &Layout{text: text, tmpl: tmpl, start: start.UTC()}
+	// Проверяем, что шаблон выполняется и не выходит за пределы каталога
+	if _, err := l.dir(&url.URL{Scheme: "https", Host: "example.com"}); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Layout) dir(u *url.URL) (string, error) {
+	fields := l.Fields(u)
+	var sb strings.Builder
+	if err := l.tmpl.Execute(&sb, fields); err != nil {
+		return "", fmt.Errorf("failed to execute layout: %w", err)
+	}
+	dir := filepath.Clean(filepath.FromSlash(sb.String()))
+	if dir == "." || filepath.IsAbs(dir) || !IsSubPath(".", dir) {
+		return "", fmt.Errorf("layout %q gives a path outside of the output directory: %s", l.text, sb.String())
+	}
+	return dir, nil
+}
+
+// Fields returns the template values of a URL.
+func (l *Layout) Fields(u *url.URL) LayoutFields {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return LayoutFields{
+		Scheme: u.Scheme,
+		Host:   u.Hostname(),
+		Port:   port,
+		Date:   l.start.Format("2006-01-02"),
+		Time:   l.start.Format("150405"),
+	}
+}
+
+// UrlToLocalPath maps a URL to a local file path: the directory given by
+// layout (output/<host> if nil) followed by the URL path.
+func UrlToLocalPath(targetUrl string, outputDir string, layout *Layout) (string, error) {
+	u, err := url.Parse(targetUrl)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse target URL %s: %w", targetUrl, err)
+	}
+	if layout == nil {
+		return filepath.Join(outputDir, u.Hostname(), strings.TrimLeft(u.Path, "/")), nil
+	}
+	dir, err := layout.dir(u)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(outputDir, dir, strings.TrimLeft(u.Path, "/")), nil
+}
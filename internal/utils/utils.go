@@ -14,6 +14,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/s3rgeym/git-dump/internal/gitobj"
 	"github.com/s3rgeym/git-dump/internal/logger"
 )
 
@@ -22,8 +23,12 @@ var hashRegex = regexp.MustCompile(`\b(?:pack-)?[a-f0-9]{40}\b`)
 var refsRegex = regexp.MustCompile(`\brefs(?:/[a-z0-9_.-]+)+`)
 var htmlContentRegex = regexp.MustCompile(`(?i)<html`)
 var linkRegex = regexp.MustCompile(`<a href="([^"]+)`)
+var packNameRegex = regexp.MustCompile(`^pack-[a-f0-9]{40}\.pack$`)
 
-func GetHashesAndRefs(fileName string) ([]string, error) {
+// GetHashesAndRefs returns object and ref paths referenced by a file. If
+// walkTrees is set, entries of tree objects are returned too, so the whole
+// history of walked commits is fetched.
+func GetHashesAndRefs(fileName string, walkTrees bool) ([]string, error) {
 	data, err := os.ReadFile(fileName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file %s: %w", fileName, err)
@@ -44,6 +49,23 @@ func GetHashesAndRefs(fileName string) ([]string, error) {
 			logger.Debugf("Skipping parsing blob file: %s", fileName)
 			return nil, nil
 		}
+
+		// Аннотированный тег указывает на коммит релиза или на другой тег.
+		// Испорченный тег разбирается регуляркой, как и раньше
+		if objectType == "tag" {
+			hash, _ := PathToSha1(fileName)
+			if tag, err := gitobj.ParseTag(hash, data[bytes.IndexByte(data, 0)+1:]); err == nil && isHash(tag.Object) {
+				return []string{Sha1ToPath(tag.Object)}, nil
+			}
+		}
+
+		// Хэши в tree хранятся в бинарном виде и не находятся регуляркой
+		if objectType == "tree" {
+			if !walkTrees {
+				return nil, nil
+			}
+			return treeEntryPaths(data)
+		}
 	}
 
 	if htmlContentRegex.Match(data) {
@@ -53,6 +75,139 @@ func GetHashesAndRefs(fileName string) ([]string, error) {
 	return extractObjectsAndRefs(data), nil
 }
 
+// ParseInfoPacks parses objects/info/packs and returns pack and index paths
+// relative to the .git directory.
+func ParseInfoPacks(fileName string) ([]string, error) {
+	lines, err := ReadLines(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", fileName, err)
+	}
+	var paths []string
+	for _, line := range lines {
+		// Формат: "P pack-<sha1>.pack"
+		kind, name, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok || kind != "P" || !packNameRegex.MatchString(name) {
+			continue
+		}
+		name = strings.TrimSuffix(name, ".pack")
+		paths = append(paths, "objects/pack/"+name+".pack", "objects/pack/"+name+".idx")
+	}
+	return paths, nil
+}
+
+// InfoRef is a ref advertised in info/refs.
+type InfoRef struct {
+	Name   string
+	Hash   string
+	Peeled string // Для аннотированного тега — объект, на который он указывает
+}
+
+// ParseInfoRefs parses info/refs written by git update-server-info for the
+// dumb HTTP protocol: "<hash> TAB <ref>" lines, where "<ref>^{}" lines give
+// the object an annotated tag points to.
+func ParseInfoRefs(fileName string) ([]InfoRef, error) {
+	lines, err := ReadLines(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", fileName, err)
+	}
+	var refs []InfoRef
+	index := make(map[string]int)
+	for _, line := range lines {
+		hash, name, ok := strings.Cut(strings.TrimRight(line, "\r"), "\t")
+		if !ok || !isHash(hash) || name == "" {
+			continue
+		}
+		if tag, ok := strings.CutSuffix(name, "^{}"); ok {
+			if i, ok := index[tag]; ok {
+				refs[i].Peeled = hash
+			}
+			continue
+		}
+		if _, ok := index[name]; ok {
+			continue
+		}
+		index[name] = len(refs)
+		refs = append(refs, InfoRef{Name: name, Hash: hash})
+	}
+	return refs, nil
+}
+
+// ParseGrafts parses info/grafts ("<commit> <parent>..." lines) or shallow
+// (one commit per line, i.e. a commit without parents) and returns the
+// parents each listed commit is treated as having.
+func ParseGrafts(fileName string) (map[string][]string, error) {
+	lines, err := ReadLines(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", fileName, err)
+	}
+	grafts := make(map[string][]string)
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") || !isHash(fields[0]) {
+			continue
+		}
+		parents := []string{}
+		for _, parent := range fields[1:] {
+			if isHash(parent) {
+				parents = append(parents, parent)
+			}
+		}
+		grafts[fields[0]] = parents
+	}
+	return grafts, nil
+}
+
+func isHash(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseAlternates parses objects/info/alternates and returns alternate object
+// directories relative to the .git directory. Absolute filesystem paths can't
+// be mapped to URLs and are returned separately.
+func ParseAlternates(fileName string) ([]string, []string, error) {
+	lines, err := ReadLines(fileName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read file %s: %w", fileName, err)
+	}
+	var relative, absolute []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "/") || filepath.IsAbs(line) {
+			absolute = append(absolute, line)
+			continue
+		}
+		// Относительные пути указываются относительно каталога objects
+		relative = append(relative, strings.TrimSuffix("objects/"+line, "/")+"/")
+	}
+	return relative, absolute, nil
+}
+
+func treeEntryPaths(data []byte) ([]string, error) {
+	entries, err := gitobj.ParseTree(data[bytes.IndexByte(data, 0)+1:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tree: %w", err)
+	}
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsSubmodule() {
+			continue
+		}
+		paths = append(paths, Sha1ToPath(entry.Hash))
+	}
+	return paths, nil
+}
+
 func extractObjectsAndRefs(data []byte) []string {
 	ret := make([]string, 0)
 	matches := hashRegex.FindAllString(string(data), -1)
@@ -65,10 +220,22 @@ func extractObjectsAndRefs(data []byte) []string {
 			ret = append(ret, Sha1ToPath(hash))
 		}
 	}
-	ret = append(ret, refsRegex.FindAllString(string(data), -1)...)
+	for _, ref := range refsRegex.FindAllString(string(data), -1) {
+		// Журнал ссылки часто единственный источник хэшей старых коммитов
+		ret = append(ret, ref, "logs/"+ref)
+	}
 	return ret
 }
 
+// PathToSha1 returns the object hash for a loose object path or URL.
+func PathToSha1(path string) (string, bool) {
+	if !objectNameRegex.MatchString(path) {
+		return "", false
+	}
+	n := len(path)
+	return path[n-41:n-39] + path[n-38:], true
+}
+
 func Sha1ToPath(hash string) string {
 	return fmt.Sprintf("objects/%s/%s", hash[:2], hash[2:])
 }
@@ -184,15 +351,6 @@ func UrlJoin(baseURL string, paths ...string) (string, error) {
 	return base.String(), nil
 }
 
-func UrlToLocalPath(targetUrl string, outputDir string) (string, error) {
-	u, err := url.Parse(targetUrl)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse target URL %s: %w", targetUrl, err)
-	}
-	host := u.Hostname()
-	return filepath.Join(outputDir, host, strings.TrimLeft(u.Path, "/")), nil
-}
-
 func ExtractLinks(htmlContent string) []string {
 	matches := linkRegex.FindAllStringSubmatch(htmlContent, -1)
 	var links []string
@@ -213,7 +371,16 @@ func GetMimeType(ct string) (string, error) {
 	return strings.ToLower(parts[0]) + "/" + strings.ToLower(parts[1]), nil
 }
 
+// IsSubPath reports whether path is dir itself or lies inside it.
+func IsSubPath(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || filepath.IsAbs(rel) {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 func FileExists(fileName string) bool {
-	_, err := os.Stat(fileName)
-	return err == nil
+	fi, err := os.Stat(fileName)
+	return err == nil && !fi.IsDir()
 }
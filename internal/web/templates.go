@@ -0,0 +1,57 @@
+package web
+
+import (
+	"html/template"
+)
+
+const layout = `{{define "head"}}<!doctype html>
+<html><head><meta charset="utf-8"><title>Git Dump</title>
+<style>
+body{font-family:sans-serif;margin:2em;color:#222}
+table{border-collapse:collapse}td,th{padding:.2em .8em;border-bottom:1px solid #ddd;text-align:left}
+pre{background:#f6f6f6;padding:1em;overflow:auto}
+.missing{color:#b00}.muted{color:#888}
+</style></head><body><p><a href="/">Targets</a></p>{{end}}
+{{define "foot"}}</body></html>{{end}}`
+
+func mustParse(name, text string) *template.Template {
+	return template.Must(template.Must(template.New(name).Parse(layout)).Parse(text))
+}
+
+var indexTemplate = mustParse("index", `{{template "head"}}
+<h1>Recovered targets</h1>
+<table><tr><th>Target</th><th>Files</th><th>Commits</th><th>Findings</th></tr>
+{{range .Targets}}<tr><td><a href="/target?name={{.Name}}">{{.Name}}</a></td><td>{{.Files}}</td><td>{{.Commits}}</td><td>{{.Findings}}</td></tr>
+{{else}}<tr><td colspan="4" class="muted">Nothing found</td></tr>{{end}}
+</table>
+{{template "foot"}}`)
+
+var targetTemplate = mustParse("target", `{{template "head"}}
+<h1>{{.Name}}</h1>
+{{if .Findings}}<h2>Findings</h2>
+<table><tr><th>Kind</th><th>Detail</th><th>URL</th><th>Extractor</th></tr>
+{{range .Findings}}<tr><td>{{.Kind}}</td><td>{{.Detail}}</td><td>{{.Url}}</td><td>{{.Extractor}}</td></tr>{{end}}
+</table>{{end}}
+<h2>Refs</h2>
+<table>{{range $name, $hash := .Refs}}<tr><td>{{$name}}</td><td><a href="/commit?name={{$.Name}}&hash={{$hash}}">{{$hash}}</a></td></tr>{{end}}</table>
+<h2>Commits</h2>
+<table><tr><th>Commit</th><th>Date</th><th>Author</th><th>Message</th></tr>
+{{range .Commits}}<tr><td><a href="/commit?name={{$.Name}}&hash={{.Hash}}">{{printf "%.10s" .Hash}}</a></td><td>{{.Committer.When.Format "2006-01-02 15:04"}}</td><td>{{.Author.Name}} &lt;{{.Author.Email}}&gt;</td><td>{{printf "%.80s" .Message}}</td></tr>{{end}}
+</table>
+<h2>Files</h2>
+<ul>{{range .Files}}<li><a href="/file?name={{$.Name}}&path={{.}}">{{.}}</a></li>{{end}}</ul>
+{{template "foot"}}`)
+
+var commitTemplate = mustParse("commit", `{{template "head"}}
+<h1><a href="/target?name={{.Name}}">{{.Name}}</a> / {{.Commit.Hash}}</h1>
+<p>{{.Commit.Author.Name}} &lt;{{.Commit.Author.Email}}&gt; — {{.Commit.Committer.When}}</p>
+<p>Parents: {{range .Commit.Parents}}<a href="/commit?name={{$.Name}}&hash={{.}}">{{printf "%.10s" .}}</a> {{end}}</p>
+<pre>{{.Commit.Message}}</pre>
+{{if .Error}}<p class="missing">Tree is incomplete: {{.Error}}</p>{{end}}
+<table>{{range .Entries}}<tr><td>{{if .Missing}}<span class="missing">{{.Path}}</span>{{else}}<a href="/blob?name={{$.Name}}&hash={{.Hash}}&path={{.Path}}">{{.Path}}</a>{{end}}</td><td class="muted">{{.Hash}}</td></tr>{{end}}</table>
+{{template "foot"}}`)
+
+var fileTemplate = mustParse("file", `{{template "head"}}
+<h1><a href="/target?name={{.Name}}">{{.Name}}</a> / {{.Path}}</h1>
+<pre>{{.Content}}</pre>
+{{template "foot"}}`)
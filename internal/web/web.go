@@ -0,0 +1,348 @@
+package web
+
+import (
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/s3rgeym/git-dump/internal/extractor"
+	"github.com/s3rgeym/git-dump/internal/gitobj"
+	"github.com/s3rgeym/git-dump/internal/logger"
+	"github.com/s3rgeym/git-dump/internal/report"
+	"github.com/s3rgeym/git-dump/internal/search"
+)
+
+const (
+	maxFileSize    = 1 << 20
+	commitCacheTTL = time.Minute
+)
+
+// Server serves a browsable view of dumped repositories.
+type Server struct {
+	root     string
+	findings map[string][]extractor.Finding // По хосту
+
+	mu      sync.Mutex
+	commits map[string]commitCount // Число коммитов по цели для главной страницы
+}
+
+type commitCount struct {
+	count int
+	at    time.Time
+}
+
+func New(root string, reports []*report.Report) *Server {
+	s := &Server{root: root, findings: make(map[string][]extractor.Finding), commits: make(map[string]commitCount)}
+	for _, r := range reports {
+		for _, t := range r.Targets {
+			target, err := filepath.Rel(root, filepath.Dir(t.RepoPath))
+			if err != nil {
+				continue
+			}
+			target = filepath.ToSlash(target)
+			s.findings[target] = append(s.findings[target], t.Findings...)
+		}
+	}
+	return s
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleIndex)
+	mux.HandleFunc("GET /target", s.handleTarget)
+	mux.HandleFunc("GET /file", s.handleFile)
+	mux.HandleFunc("GET /commit", s.handleCommit)
+	mux.HandleFunc("GET /blob", s.handleBlob)
+	return mux
+}
+
+type targetInfo struct {
+	Name     string
+	Commits  int
+	Files    int
+	Findings int
+}
+
+func (s *Server) targets() ([]string, error) {
+	gitDirs, err := gitobj.FindGitDirs(s.root)
+	if err != nil {
+		return nil, err
+	}
+	var targets []string
+	for _, gitDir := range gitDirs {
+		rel, err := filepath.Rel(s.root, filepath.Dir(gitDir))
+		if err != nil {
+			continue
+		}
+		targets = append(targets, filepath.ToSlash(rel))
+	}
+	sort.Strings(targets)
+	return targets, nil
+}
+
+// resolve returns the worktree of a target making sure it is inside root.
+func (s *Server) resolve(target string) (string, bool) {
+	worktree := filepath.Join(s.root, filepath.FromSlash(target))
+	rel, err := filepath.Rel(s.root, worktree)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	if !isDir(filepath.Join(worktree, ".git")) {
+		return "", false
+	}
+	return worktree, true
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	targets, err := s.targets()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	infos := make([]targetInfo, 0, len(targets))
+	for _, target := range targets {
+		worktree, _ := s.resolve(target)
+		info := targetInfo{Name: target, Findings: len(s.findings[target])}
+		info.Files = len(listFiles(worktree))
+		info.Commits = s.commitCount(target, worktree)
+		infos = append(infos, info)
+	}
+	render(w, indexTemplate, map[string]interface{}{"Targets": infos})
+}
+
+// commitCount returns the number of commits reachable from the refs of a
+// target. Walking the history is slow, so counts are cached for a while.
+func (s *Server) commitCount(target, worktree string) int {
+	s.mu.Lock()
+	cached, ok := s.commits[target]
+	s.mu.Unlock()
+	if ok && time.Since(cached.at) < commitCacheTTL {
+		return cached.count
+	}
+
+	count := 0
+	if repo, err := gitobj.Open(filepath.Join(worktree, ".git")); err == nil {
+		count = len(repo.Log(refTips(repo), 0))
+		repo.Close()
+	}
+	s.mu.Lock()
+	s.commits[target] = commitCount{count: count, at: time.Now()}
+	s.mu.Unlock()
+	return count
+}
+
+func (s *Server) handleTarget(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("name")
+	worktree, ok := s.resolve(target)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	repo, err := gitobj.Open(filepath.Join(worktree, ".git"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer repo.Close()
+
+	refs, _ := repo.Refs()
+	render(w, targetTemplate, map[string]interface{}{
+		"Name":     target,
+		"Refs":     refs,
+		"Files":    listFiles(worktree),
+		"Commits":  repo.Log(refTips(repo), 500),
+		"Findings": s.findings[target],
+	})
+}
+
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("name")
+	worktree, ok := s.resolve(target)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	path := filepath.Join(worktree, filepath.FromSlash(r.URL.Query().Get("path")))
+	rel, err := filepath.Rel(worktree, path)
+	if err != nil || !allowedPath(rel) {
+		http.NotFound(w, r)
+		return
+	}
+	// Восстановленные симлинки могут указывать на локальные файлы
+	realWorktree, err := filepath.EvalSymlinks(worktree)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	realRel, err := filepath.Rel(realWorktree, realPath)
+	if err != nil || !allowedPath(realRel) {
+		http.NotFound(w, r)
+		return
+	}
+	if isDir(realPath) {
+		http.NotFound(w, r)
+		return
+	}
+	data, err := readLimited(realPath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	renderContent(w, target, filepath.ToSlash(rel), data)
+}
+
+func (s *Server) handleCommit(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("name")
+	worktree, ok := s.resolve(target)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	repo, err := gitobj.Open(filepath.Join(worktree, ".git"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer repo.Close()
+
+	commit, err := repo.ReadCommit(r.URL.Query().Get("hash"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	type entry struct {
+		Path    string
+		Hash    string
+		Missing bool
+	}
+	var entries []entry
+	walkErr := repo.WalkTree(commit.Tree, "", func(path string, e gitobj.TreeEntry) error {
+		entries = append(entries, entry{Path: path, Hash: e.Hash, Missing: !repo.HasObject(e.Hash)})
+		return nil
+	})
+
+	render(w, commitTemplate, map[string]interface{}{
+		"Name":    target,
+		"Commit":  commit,
+		"Entries": entries,
+		"Error":   walkErr,
+	})
+}
+
+func (s *Server) handleBlob(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("name")
+	worktree, ok := s.resolve(target)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	repo, err := gitobj.Open(filepath.Join(worktree, ".git"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer repo.Close()
+
+	obj, err := repo.ReadObject(r.URL.Query().Get("hash"))
+	if err != nil || obj.Type != gitobj.TypeBlob {
+		http.NotFound(w, r)
+		return
+	}
+	data := obj.Data
+	if len(data) > maxFileSize {
+		data = data[:maxFileSize]
+	}
+	renderContent(w, target, r.URL.Query().Get("path"), data)
+}
+
+func renderContent(w http.ResponseWriter, target, path string, data []byte) {
+	content := string(data)
+	if search.IsBinary(data) {
+		content = "(binary file)"
+	}
+	render(w, fileTemplate, map[string]interface{}{
+		"Name":    target,
+		"Path":    path,
+		"Content": content,
+	})
+}
+
+func refTips(repo *gitobj.Repo) []string {
+	var tips []string
+	if head, err := repo.Head(); err == nil && head != "" {
+		tips = append(tips, head)
+	}
+	refs, _ := repo.Refs()
+	for _, hash := range refs {
+		tips = append(tips, hash)
+	}
+	return tips
+}
+
+func listFiles(worktree string) []string {
+	var files []string
+	filepath.WalkDir(worktree, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			rel, _ := filepath.Rel(worktree, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files
+}
+
+// allowedPath reports whether rel is inside the worktree and outside .git.
+func allowedPath(rel string) bool {
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	first, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	return first != ".git"
+}
+
+// readLimited reads up to maxFileSize bytes of a file.
+func readLimited(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFileSize {
+		data = data[:maxFileSize]
+	}
+	return data, nil
+}
+
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
+
+func render(w http.ResponseWriter, tmpl *template.Template, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, data); err != nil {
+		logger.Errorf("Failed to render template: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAllowedPath(t *testing.T) {
+	for rel, want := range map[string]bool{
+		"a.txt":            true,
+		"src/main.go":      true,
+		"..config":         true,
+		".gitignore":       true,
+		"..":               false,
+		"../secret":        false,
+		"/etc/passwd":      false,
+		".git":             false,
+		".git/config":      false,
+		"src/../.git/HEAD": false,
+	} {
+		if got := allowedPath(filepath.Clean(filepath.FromSlash(rel))); got != want {
+			t.Errorf("allowedPath(%q) = %v, want %v", rel, got, want)
+		}
+	}
+}
+
+// writeSite makes root/site with a .git directory, a file and symlinks out of
+// the worktree and into .git, next to a file outside of root.
+func writeSite(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	worktree := filepath.Join(root, "site")
+	for _, d := range []string{filepath.Join(worktree, ".git"), filepath.Join(root, "other", ".git")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(dir, "secret"):                 "outside\n",
+		filepath.Join(root, "other", "secret"):       "other target\n",
+		filepath.Join(worktree, ".git", "config"):    "[core]\n",
+		filepath.Join(worktree, "index.php"):         "<?php echo 1;\n",
+		filepath.Join(worktree, "dir", "nested.txt"): "nested\n",
+	}
+	for path, data := range files {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"outside": filepath.Join(dir, "secret"),
+		"up":      "../../secret",
+		"gitconf": ".git/config",
+		"inside":  "dir/nested.txt",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(worktree, name)); err != nil {
+			t.Skipf("symlinks are not supported: %v", err)
+		}
+	}
+	return root
+}
+
+func TestResolve(t *testing.T) {
+	root := writeSite(t)
+	// Репозиторий рядом с root не должен быть доступен
+	if err := os.MkdirAll(filepath.Join(filepath.Dir(root), "outside", ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	s := New(root, nil)
+	for target, want := range map[string]bool{
+		"site":         true,
+		"other":        true,
+		"site/dir":     false, // Без .git
+		"..":           false,
+		"../root/site": true,
+		"../outside":   false,
+		"site/../../x": false,
+		"missing":      false,
+	} {
+		if _, ok := s.resolve(target); ok != want {
+			t.Errorf("resolve(%q) = %v, want %v", target, ok, want)
+		}
+	}
+}
+
+func TestHandleFile(t *testing.T) {
+	h := New(writeSite(t), nil).Handler()
+	for path, want := range map[string]int{
+		"index.php":        http.StatusOK,
+		"inside":           http.StatusOK,
+		"dir":              http.StatusNotFound,
+		"missing":          http.StatusNotFound,
+		".git/config":      http.StatusNotFound,
+		"../other/secret":  http.StatusNotFound,
+		"../../secret":     http.StatusNotFound,
+		"dir/../../secret": http.StatusNotFound,
+		"outside":          http.StatusNotFound,
+		"up":               http.StatusNotFound,
+		"gitconf":          http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/file?name=site&path="+url.QueryEscape(path), nil))
+		if rec.Code != want {
+			t.Errorf("GET /file %q = %d, want %d", path, rec.Code, want)
+		}
+		if rec.Code == http.StatusOK && strings.Contains(rec.Body.String(), "outside") {
+			t.Errorf("GET /file %q served a file outside of the worktree", path)
+		}
+	}
+}